@@ -20,14 +20,26 @@
 package ad_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/RoaringBitmap/roaring/roaring64"
 	"github.com/specterops/bloodhound/analysis"
 	adAnalysis "github.com/specterops/bloodhound/analysis/ad"
 	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/dawgs/ops"
+	"github.com/specterops/bloodhound/dawgs/query"
 	"github.com/specterops/bloodhound/graphschema/ad"
+	"github.com/specterops/bloodhound/graphschema/azure"
 	"github.com/specterops/bloodhound/graphschema/common"
+	adPost "github.com/specterops/bloodhound/src/analysis/ad"
 	"github.com/specterops/bloodhound/src/test/integration"
 	"github.com/stretchr/testify/require"
 )
@@ -1043,3 +1055,2465 @@ func TestFetchUserSessionCompleteness(t *testing.T) {
 		require.Equal(t, .5, completeness)
 	})
 }
+
+func TestGetDCSyncers_FromGraphSpec(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Users: []integration.PrincipalSpec{
+			{Name: "NestedUser", Domain: "Domain"},
+			{Name: "PartialUser", Domain: "Domain"},
+		},
+		Groups: []integration.PrincipalSpec{
+			{Name: "ReplicationGroup", Domain: "Domain"},
+			{Name: "NestingGroup", Domain: "Domain"},
+		},
+		Memberships: []integration.MembershipSpec{
+			{Member: "NestedUser", Group: "NestingGroup"},
+			{Member: "NestingGroup", Group: "ReplicationGroup"},
+		},
+		Edges: []integration.EdgeSpec{
+			{From: "ReplicationGroup", To: "Domain", Kind: ad.GetChanges},
+			{From: "ReplicationGroup", To: "Domain", Kind: ad.GetChangesAll},
+			{From: "PartialUser", To: "Domain", Kind: ad.GetChanges},
+		},
+	})
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		dcSyncers, err := analysis.GetDCSyncers(tx, testContext.SpecNode("Domain"), false)
+		require.Nil(t, err)
+
+		ids := make([]graph.ID, 0, len(dcSyncers))
+		for _, node := range dcSyncers {
+			ids = append(ids, node.ID)
+		}
+
+		require.Contains(t, ids, testContext.SpecNode("NestedUser").ID)
+		require.NotContains(t, ids, testContext.SpecNode("PartialUser").ID)
+
+		return nil
+	}))
+}
+
+func TestPostReadLAPSPasswordFromGenericAll(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Computers: []integration.ComputerSpec{
+			{Name: "LAPSComputer", Domain: "Domain", HasLAPS: true},
+			{Name: "NoLAPSComputer", Domain: "Domain"},
+		},
+		Users:  []integration.PrincipalSpec{{Name: "ExplicitReader", Domain: "Domain"}},
+		Groups: []integration.PrincipalSpec{{Name: "ControlGroup", Domain: "Domain"}},
+		Edges: []integration.EdgeSpec{
+			{From: "ControlGroup", To: "LAPSComputer", Kind: ad.GenericAll},
+			{From: "ControlGroup", To: "NoLAPSComputer", Kind: ad.GenericAll},
+			{From: "ExplicitReader", To: "LAPSComputer", Kind: ad.GenericAll},
+			{From: "ExplicitReader", To: "LAPSComputer", Kind: ad.ReadLAPSPassword},
+		},
+	})
+
+	_, err := adAnalysis.PostReadLAPSPasswordFromGenericAll(context.Background(), db)
+	require.Nil(t, err)
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		derived, err := ops.FetchRelationships(tx.Relationships().Filterf(func() graph.Criteria {
+			return query.And(
+				query.Kind(query.Relationship(), ad.ReadLAPSPassword),
+				query.Exists(query.RelationshipProperty(adAnalysis.LAPSReadSourceProperty)),
+			)
+		}))
+		require.Nil(t, err)
+		require.Equal(t, 1, len(derived))
+		require.Equal(t, testContext.SpecNode("ControlGroup").ID, derived[0].StartID)
+		require.Equal(t, testContext.SpecNode("LAPSComputer").ID, derived[0].EndID)
+
+		return nil
+	}))
+
+	_, err = adAnalysis.DeleteDerivedLAPSReadEdges(context.Background(), db)
+	require.Nil(t, err)
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		// Only the collected edge must survive the cleanup
+		remaining, err := ops.FetchRelationships(tx.Relationships().Filterf(func() graph.Criteria {
+			return query.Kind(query.Relationship(), ad.ReadLAPSPassword)
+		}))
+		require.Nil(t, err)
+		require.Equal(t, 1, len(remaining))
+		require.Equal(t, testContext.SpecNode("ExplicitReader").ID, remaining[0].StartID)
+
+		return nil
+	}))
+}
+
+func TestPostAdminToFromLAPSRead(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains:   []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Computers: []integration.ComputerSpec{{Name: "LAPSComputer", Domain: "Domain", HasLAPS: true}},
+		Users: []integration.PrincipalSpec{
+			{Name: "ExplicitReader", Domain: "Domain"},
+			{Name: "Controller", Domain: "Domain"},
+		},
+		Edges: []integration.EdgeSpec{
+			{From: "ExplicitReader", To: "LAPSComputer", Kind: ad.ReadLAPSPassword},
+			{From: "Controller", To: "LAPSComputer", Kind: ad.GenericAll},
+		},
+	})
+
+	// GenericAll chains through the derived password read into AdminTo
+	_, err := adPost.PostWithOptions(context.Background(), db, adPost.PostOptions{DeriveLAPSReadFromGenericAll: true})
+	require.Nil(t, err)
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		admins, err := ops.FetchRelationships(tx.Relationships().Filterf(func() graph.Criteria {
+			return query.And(
+				query.Kind(query.Relationship(), ad.AdminTo),
+				query.Equals(query.EndID(), testContext.SpecNode("LAPSComputer").ID),
+			)
+		}))
+		require.Nil(t, err)
+		require.Equal(t, 2, len(admins))
+
+		for _, admin := range admins {
+			require.Contains(t, []graph.ID{testContext.SpecNode("ExplicitReader").ID, testContext.SpecNode("Controller").ID}, admin.StartID)
+
+			source, err := admin.Properties.Get(adAnalysis.LAPSAdminSourceProperty).String()
+			require.Nil(t, err)
+			require.Equal(t, ad.ReadLAPSPassword.String(), source)
+		}
+
+		return nil
+	}))
+}
+
+func TestPostAdminToFromPrivileges(t *testing.T) {
+	for _, privilege := range adAnalysis.EscalationPrivilegeRelationships() {
+		t.Run(privilege.String(), func(t *testing.T) {
+			testContext := integration.NewGraphTestContext(t)
+			db := testContext.BuildADTestGraph(integration.GraphSpec{
+				Domains:   []integration.DomainSpec{{Name: "Domain", Collected: true}},
+				Computers: []integration.ComputerSpec{{Name: "Computer", Domain: "Domain"}},
+				Users:     []integration.PrincipalSpec{{Name: "Holder", Domain: "Domain"}},
+				Edges: []integration.EdgeSpec{
+					{From: "Holder", To: "Computer", Kind: privilege},
+				},
+			})
+
+			fetchAdmins := func() []*graph.Relationship {
+				var admins []*graph.Relationship
+
+				require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+					fetched, err := ops.FetchRelationships(tx.Relationships().Filterf(func() graph.Criteria {
+						return query.And(
+							query.Kind(query.Relationship(), ad.AdminTo),
+							query.Equals(query.EndID(), testContext.SpecNode("Computer").ID),
+						)
+					}))
+
+					admins = fetched
+					return err
+				}))
+
+				return admins
+			}
+
+			_, err := adPost.PostWithOptions(context.Background(), db, adPost.PostOptions{})
+			require.Nil(t, err)
+
+			admins := fetchAdmins()
+			require.Equal(t, 1, len(admins))
+			require.Equal(t, testContext.SpecNode("Holder").ID, admins[0].StartID)
+
+			source, err := admins[0].Properties.Get(adAnalysis.PrivilegeAdminSourceProperty).String()
+			require.Nil(t, err)
+			require.Equal(t, privilege.String(), source)
+
+			// Leaving the privilege out of the configured list suppresses the edge
+			var otherPrivileges []graph.Kind
+
+			for _, otherPrivilege := range adAnalysis.EscalationPrivilegeRelationships() {
+				if otherPrivilege != privilege {
+					otherPrivileges = append(otherPrivileges, otherPrivilege)
+				}
+			}
+
+			_, err = adPost.PostWithOptions(context.Background(), db, adPost.PostOptions{AdminEscalationPrivileges: otherPrivileges})
+			require.Nil(t, err)
+			require.Empty(t, fetchAdmins())
+		})
+	}
+}
+
+func TestPostReadLAPSPasswordFromGenericAll_BroadPrincipal(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains:   []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Computers: []integration.ComputerSpec{{Name: "LAPSComputer", Domain: "Domain", HasLAPS: true}},
+		Groups:    []integration.PrincipalSpec{{Name: "Everyone", Domain: "Domain", RID: adAnalysis.EveryoneSuffix}},
+		Edges: []integration.EdgeSpec{
+			{From: "Everyone", To: "LAPSComputer", Kind: ad.GenericAll},
+		},
+	})
+
+	// Running twice must not create a second meta-node or link
+	require.Nil(t, adAnalysis.EnsureBroadPrincipalMetaNodes(context.Background(), db))
+	require.Nil(t, adAnalysis.EnsureBroadPrincipalMetaNodes(context.Background(), db))
+
+	_, err := adAnalysis.PostReadLAPSPasswordFromGenericAll(context.Background(), db)
+	require.Nil(t, err)
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		var (
+			everyoneObjectID  = testContext.NodeObjectID(testContext.SpecNode("Everyone"))
+			authUsersObjectID = strings.TrimSuffix(everyoneObjectID, adAnalysis.EveryoneSuffix) + adAnalysis.AuthenticatedUsersSuffix
+		)
+
+		authUsers, err := ops.FetchNodes(tx.Nodes().Filterf(func() graph.Criteria {
+			return query.Equals(query.NodeProperty(common.ObjectID.String()), authUsersObjectID)
+		}))
+		require.Nil(t, err)
+		require.Equal(t, 1, len(authUsers))
+
+		links, err := ops.FetchRelationships(tx.Relationships().Filterf(func() graph.Criteria {
+			return query.And(
+				query.Kind(query.Relationship(), ad.MemberOf),
+				query.Equals(query.StartID(), authUsers[0].ID),
+				query.Equals(query.EndID(), testContext.SpecNode("Everyone").ID),
+			)
+		}))
+		require.Nil(t, err)
+		require.Equal(t, 1, len(links))
+
+		// The meta-node reaches the computer through its membership of Everyone, so only Everyone gets the edge
+		derived, err := ops.FetchRelationships(tx.Relationships().Filterf(func() graph.Criteria {
+			return query.Kind(query.Relationship(), ad.ReadLAPSPassword)
+		}))
+		require.Nil(t, err)
+		require.Equal(t, 1, len(derived))
+		require.Equal(t, testContext.SpecNode("Everyone").ID, derived[0].StartID)
+
+		return nil
+	}))
+}
+
+func TestFetchDomainAdminsBitmap(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{
+			{Name: "Domain", Collected: true},
+			{Name: "EmptyDomain", Collected: true},
+		},
+		Users: []integration.PrincipalSpec{
+			{Name: "DirectAdmin", Domain: "Domain"},
+			{Name: "NestedAdmin", Domain: "Domain"},
+			{Name: "NotAnAdmin", Domain: "Domain"},
+		},
+		Groups: []integration.PrincipalSpec{
+			{Name: "DomainAdmins", Domain: "Domain", RID: adAnalysis.DomainAdminsGroupSIDSuffix},
+			{Name: "NestedGroup", Domain: "Domain"},
+		},
+		Memberships: []integration.MembershipSpec{
+			{Member: "DirectAdmin", Group: "DomainAdmins"},
+			{Member: "NestedGroup", Group: "DomainAdmins"},
+			{Member: "NestedAdmin", Group: "NestedGroup"},
+		},
+	})
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		domainAdmins, err := adAnalysis.FetchDomainAdminsBitmap(tx, testContext.SpecNode("Domain"))
+		require.Nil(t, err)
+		require.Equal(t, uint64(3), domainAdmins.Cardinality())
+		require.True(t, domainAdmins.Contains(testContext.SpecNode("DirectAdmin").ID.Uint32()))
+		require.True(t, domainAdmins.Contains(testContext.SpecNode("NestedGroup").ID.Uint32()))
+		require.True(t, domainAdmins.Contains(testContext.SpecNode("NestedAdmin").ID.Uint32()))
+
+		// A domain without a Domain Admins group yields an empty bitmap
+		domainAdmins, err = adAnalysis.FetchDomainAdminsBitmap(tx, testContext.SpecNode("EmptyDomain"))
+		require.Nil(t, err)
+		require.Equal(t, uint64(0), domainAdmins.Cardinality())
+
+		return nil
+	}))
+}
+
+func TestPostForceChangePasswordFromGenericAll(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Users: []integration.PrincipalSpec{
+			{Name: "Target", Domain: "Domain"},
+			{Name: "GenericAllHolder", Domain: "Domain"},
+			{Name: "BothSources", Domain: "Domain"},
+		},
+		Groups: []integration.PrincipalSpec{{Name: "NotAUser", Domain: "Domain"}},
+		Edges: []integration.EdgeSpec{
+			{From: "GenericAllHolder", To: "Target", Kind: ad.GenericAll},
+			{From: "GenericAllHolder", To: "NotAUser", Kind: ad.GenericAll},
+			{From: "BothSources", To: "Target", Kind: ad.GenericAll},
+			{From: "BothSources", To: "Target", Kind: ad.ForceChangePassword},
+		},
+	})
+
+	_, err := adAnalysis.PostForceChangePasswordFromGenericAll(context.Background(), db)
+	require.Nil(t, err)
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		relationships, err := ops.FetchRelationships(tx.Relationships().Filterf(func() graph.Criteria {
+			return query.Kind(query.Relationship(), ad.ForceChangePassword)
+		}))
+		require.Nil(t, err)
+
+		// One collected edge and one edge derived from GenericAll; BothSources must not be duplicated
+		require.Equal(t, 2, len(relationships))
+
+		for _, relationship := range relationships {
+			source, err := relationship.Properties.Get(adAnalysis.ForceChangePasswordSourceProperty).String()
+
+			if relationship.StartID == testContext.SpecNode("BothSources").ID {
+				require.True(t, graph.IsErrPropertyNotFound(err))
+			} else {
+				require.Nil(t, err)
+				require.Equal(t, testContext.SpecNode("GenericAllHolder").ID, relationship.StartID)
+				require.Equal(t, adAnalysis.ForceChangePasswordSourceGenericAll, source)
+			}
+		}
+
+		return nil
+	}))
+}
+
+func TestEstimatePostProcessingVolume(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{
+			{Name: "Domain", Collected: true},
+			{Name: "OtherDomain", Collected: true},
+		},
+		Computers: []integration.ComputerSpec{
+			{Name: "Computer", Domain: "Domain"},
+			{Name: "ComputerWithoutRDPGroup", Domain: "Domain"},
+		},
+		Users: []integration.PrincipalSpec{
+			{Name: "Syncer", Domain: "Domain"},
+			{Name: "RDPUser", Domain: "Domain"},
+		},
+		LocalGroups: []integration.LocalGroupSpec{
+			{Name: "RDPGroup", Computer: "Computer", SIDSuffix: adAnalysis.RDPGroupSuffix},
+		},
+		Memberships: []integration.MembershipSpec{
+			{Member: "RDPUser", Group: "RDPGroup"},
+		},
+		Edges: []integration.EdgeSpec{
+			{From: "Syncer", To: "Domain", Kind: ad.GetChangesAll},
+			{From: "Syncer", To: "OtherDomain", Kind: ad.GetChangesAll},
+		},
+	})
+
+	estimates, err := adAnalysis.EstimatePostProcessingVolume(context.Background(), db, []graph.Kind{ad.DCSync, ad.CanRDP})
+	require.Nil(t, err)
+
+	// One distinct syncer multiplied by two collected domains
+	require.Equal(t, 2, estimates[ad.DCSync])
+	require.Equal(t, 1, estimates[ad.CanRDP])
+
+	_, err = adAnalysis.EstimatePostProcessingVolume(context.Background(), db, []graph.Kind{ad.MemberOf})
+	require.NotNil(t, err)
+}
+
+func TestGetDCSyncersWithStrategy(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Users: []integration.PrincipalSpec{
+			{Name: "DirectUser", Domain: "Domain"},
+			{Name: "NestedUser", Domain: "Domain"},
+		},
+		Groups: []integration.PrincipalSpec{
+			{Name: "ReplicationGroup", Domain: "Domain"},
+			{Name: "NestingGroup", Domain: "Domain"},
+		},
+		Memberships: []integration.MembershipSpec{
+			{Member: "DirectUser", Group: "ReplicationGroup"},
+			{Member: "NestingGroup", Group: "ReplicationGroup"},
+			{Member: "NestedUser", Group: "NestingGroup"},
+		},
+		Edges: []integration.EdgeSpec{
+			{From: "ReplicationGroup", To: "Domain", Kind: ad.GetChanges},
+			{From: "ReplicationGroup", To: "Domain", Kind: ad.GetChangesAll},
+		},
+	})
+
+	syncerIDs := func(tx graph.Transaction, strategy analysis.ExpansionStrategy, limitedDepth int) []graph.ID {
+		dcSyncers, err := analysis.GetDCSyncersWithStrategy(tx, testContext.SpecNode("Domain"), false, strategy, limitedDepth)
+		require.Nil(t, err)
+
+		ids := make([]graph.ID, 0, len(dcSyncers))
+		for _, node := range dcSyncers {
+			ids = append(ids, node.ID)
+		}
+
+		return ids
+	}
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		transitive := syncerIDs(tx, analysis.ExpansionTransitive, 0)
+		require.Contains(t, transitive, testContext.SpecNode("DirectUser").ID)
+		require.Contains(t, transitive, testContext.SpecNode("NestedUser").ID)
+
+		firstDegree := syncerIDs(tx, analysis.ExpansionFirstDegreeOnly, 0)
+		require.Contains(t, firstDegree, testContext.SpecNode("DirectUser").ID)
+		require.Contains(t, firstDegree, testContext.SpecNode("NestingGroup").ID)
+		require.NotContains(t, firstDegree, testContext.SpecNode("NestedUser").ID)
+
+		limited := syncerIDs(tx, analysis.ExpansionLimitedDepth, 2)
+		require.Contains(t, limited, testContext.SpecNode("NestedUser").ID)
+
+		none := syncerIDs(tx, analysis.ExpansionNone, 0)
+		require.Equal(t, []graph.ID{testContext.SpecNode("ReplicationGroup").ID}, none)
+
+		_, err := analysis.GetDCSyncersWithStrategy(tx, testContext.SpecNode("Domain"), false, analysis.ExpansionLimitedDepth, 0)
+		require.NotNil(t, err)
+
+		return nil
+	}))
+}
+
+func TestFetchComputersWithoutURACollection(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Computers: []integration.ComputerSpec{
+			{Name: "CollectedComputer", Domain: "Domain", HasURA: true},
+			{Name: "UncollectedComputer", Domain: "Domain"},
+			{Name: "NeverCollectedComputer", Domain: "Domain"},
+		},
+	})
+
+	// A computer that has never been through URA collection carries no HasURA property at all
+	require.Nil(t, db.WriteTransaction(context.Background(), func(tx graph.Transaction) error {
+		node := testContext.SpecNode("NeverCollectedComputer")
+		node.Properties.Delete(ad.HasURA.String())
+		return tx.UpdateNode(node)
+	}))
+
+	computers, err := adAnalysis.FetchComputersWithoutURACollection(context.Background(), db)
+	require.Nil(t, err)
+	require.Equal(t, uint64(2), computers.GetCardinality())
+	require.True(t, computers.Contains(testContext.SpecNode("UncollectedComputer").ID.Uint64()))
+	require.True(t, computers.Contains(testContext.SpecNode("NeverCollectedComputer").ID.Uint64()))
+	require.False(t, computers.Contains(testContext.SpecNode("CollectedComputer").ID.Uint64()))
+}
+
+func TestPostWriteGPLink(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains:   []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Computers: []integration.ComputerSpec{{Name: "Workstation", Domain: "Domain"}},
+		Users: []integration.PrincipalSpec{
+			{Name: "Linker", Domain: "Domain"},
+			{Name: "LinkerWithoutGPO", Domain: "Domain"},
+			{Name: "Employee", Domain: "Domain"},
+			{Name: "ProtectedEmployee", Domain: "Domain"},
+		},
+		GPOs: []integration.PrincipalSpec{{Name: "GPO", Domain: "Domain"}},
+		OUs: []integration.OUSpec{
+			{Name: "OU", Domain: "Domain"},
+			{Name: "BlockingOU", Domain: "Domain", BlocksInheritance: true},
+			{Name: "ChildOfBlockingOU", Domain: "Domain"},
+		},
+		Edges: []integration.EdgeSpec{
+			{From: "OU", To: "Workstation", Kind: ad.Contains},
+			{From: "OU", To: "Employee", Kind: ad.Contains},
+			{From: "OU", To: "BlockingOU", Kind: ad.Contains},
+			{From: "BlockingOU", To: "ChildOfBlockingOU", Kind: ad.Contains},
+			{From: "ChildOfBlockingOU", To: "ProtectedEmployee", Kind: ad.Contains},
+			{From: "Linker", To: "OU", Kind: ad.WriteGPLink},
+			{From: "Linker", To: "GPO", Kind: ad.GenericWrite},
+			{From: "LinkerWithoutGPO", To: "OU", Kind: ad.WriteGPLink},
+		},
+	})
+
+	_, err := adAnalysis.PostWriteGPLink(context.Background(), db)
+	require.Nil(t, err)
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		targets, err := ops.FetchEndNodes(tx.Relationships().Filterf(func() graph.Criteria {
+			return query.And(
+				query.Kind(query.Relationship(), ad.CanApplyGPO),
+				query.Equals(query.StartID(), testContext.SpecNode("Linker").ID),
+			)
+		}))
+		require.Nil(t, err)
+
+		require.True(t, targets.Contains(testContext.SpecNode("Workstation")))
+		require.True(t, targets.Contains(testContext.SpecNode("Employee")))
+		require.False(t, targets.Contains(testContext.SpecNode("ProtectedEmployee")))
+
+		count, err := tx.Relationships().Filterf(func() graph.Criteria {
+			return query.And(
+				query.Kind(query.Relationship(), ad.CanApplyGPO),
+				query.Equals(query.StartID(), testContext.SpecNode("LinkerWithoutGPO").ID),
+			)
+		}).Count()
+		require.Nil(t, err)
+		require.Equal(t, int64(0), count)
+
+		return nil
+	}))
+}
+
+func TestIsDomainController(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{
+			{Name: "Domain", Collected: true},
+			{Name: "OtherDomain", Collected: true},
+		},
+		Computers: []integration.ComputerSpec{
+			{Name: "DC", Domain: "Domain"},
+			{Name: "Workstation", Domain: "Domain"},
+			{Name: "OtherDC", Domain: "OtherDomain"},
+		},
+		Groups: []integration.PrincipalSpec{
+			{Name: "DomainControllers", Domain: "Domain", RID: adAnalysis.DomainControllersGroupSIDSuffix},
+			{Name: "OtherDomainControllers", Domain: "OtherDomain", RID: adAnalysis.DomainControllersGroupSIDSuffix},
+		},
+		Edges: []integration.EdgeSpec{
+			{From: "DC", To: "DomainControllers", Kind: ad.MemberOf},
+			{From: "OtherDC", To: "OtherDomainControllers", Kind: ad.MemberOf},
+		},
+	})
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		isDC, err := adAnalysis.IsDomainController(tx, testContext.SpecNode("DC").ID)
+		require.Nil(t, err)
+		require.True(t, isDC)
+
+		isDC, err = adAnalysis.IsDomainController(tx, testContext.SpecNode("Workstation").ID)
+		require.Nil(t, err)
+		require.False(t, isDC)
+
+		return nil
+	}))
+
+	domainSID, err := testContext.SpecNode("Domain").Properties.Get(ad.DomainSID.String()).String()
+	require.Nil(t, err)
+
+	domainControllers, err := adAnalysis.FetchDomainControllers(context.Background(), db, domainSID)
+	require.Nil(t, err)
+	require.Equal(t, 1, domainControllers.Len())
+	require.True(t, domainControllers.Contains(testContext.SpecNode("DC")))
+}
+
+func TestLoadPostProcessingConfig(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+	})
+
+	config, err := adAnalysis.LoadPostProcessingConfig(context.Background(), db)
+	require.Nil(t, err)
+	require.True(t, config.Enabled(ad.DCSync))
+	require.Empty(t, config.DisabledKinds())
+
+	require.Nil(t, db.WriteTransaction(context.Background(), func(tx graph.Transaction) error {
+		_, err := tx.CreateNode(graph.AsProperties(graph.PropertyMap{
+			ad.DCSync:                    false,
+			ad.SameForestTrust:           false,
+			ad.CanRDP:                    true,
+			graph.StringKind("NotAKind"): false,
+		}), common.PostProcessingConfig)
+
+		return err
+	}))
+
+	config, err = adAnalysis.LoadPostProcessingConfig(context.Background(), db)
+	require.Nil(t, err)
+	require.False(t, config.Enabled(ad.DCSync))
+	require.True(t, config.Enabled(ad.CanRDP))
+	require.True(t, config.Enabled(ad.SameForestTrust, ad.CrossForestTrust))
+	require.Equal(t, graph.Kinds{ad.DCSync, ad.SameForestTrust}, config.DisabledKinds())
+}
+
+func TestExportComputedEdges(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Users: []integration.PrincipalSpec{
+			{Name: "Target", Domain: "Domain"},
+			{Name: "GenericAllHolder", Domain: "Domain"},
+		},
+		Edges: []integration.EdgeSpec{
+			{From: "GenericAllHolder", To: "Target", Kind: ad.GenericAll},
+		},
+	})
+
+	_, err := adAnalysis.PostForceChangePasswordFromGenericAll(context.Background(), db)
+	require.Nil(t, err)
+
+	var ndjsonExport bytes.Buffer
+	require.Nil(t, adAnalysis.ExportComputedEdges(context.Background(), db, []graph.Kind{ad.ForceChangePassword}, &ndjsonExport, adAnalysis.ExportFormatNDJSON))
+
+	var exportedEdge adAnalysis.ExportedEdge
+	decoder := json.NewDecoder(&ndjsonExport)
+	require.Nil(t, decoder.Decode(&exportedEdge))
+	require.False(t, decoder.More())
+
+	require.Equal(t, testContext.NodeObjectID(testContext.SpecNode("GenericAllHolder")), exportedEdge.SourceSID)
+	require.Equal(t, testContext.NodeObjectID(testContext.SpecNode("Target")), exportedEdge.TargetSID)
+	require.Equal(t, ad.ForceChangePassword.String(), exportedEdge.Kind)
+	require.Equal(t, adAnalysis.ForceChangePasswordSourceGenericAll, exportedEdge.Provenance[adAnalysis.ForceChangePasswordSourceProperty])
+
+	var csvExport bytes.Buffer
+	require.Nil(t, adAnalysis.ExportComputedEdges(context.Background(), db, []graph.Kind{ad.ForceChangePassword}, &csvExport, adAnalysis.ExportFormatCSV))
+
+	records, err := csv.NewReader(&csvExport).ReadAll()
+	require.Nil(t, err)
+	require.Equal(t, 2, len(records))
+	require.Equal(t, []string{"source_sid", "target_sid", "kind"}, records[0][:3])
+	require.Equal(t, exportedEdge.SourceSID, records[1][0])
+}
+
+func TestPostTrustAccountCompromise(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{
+			{Name: "TRUSTING.LOCAL", Collected: true},
+			{Name: "TRUSTED.LOCAL", Collected: true},
+		},
+		Users: []integration.PrincipalSpec{
+			{Name: "TrustAccount", Domain: "TRUSTED.LOCAL", SamAccountName: "TRUSTING$"},
+			{Name: "ServiceAccount", Domain: "TRUSTED.LOCAL", SamAccountName: "SERVICE$"},
+			{Name: "TrustAccountController", Domain: "TRUSTED.LOCAL"},
+			{Name: "ServiceAccountController", Domain: "TRUSTED.LOCAL"},
+		},
+		Edges: []integration.EdgeSpec{
+			{From: "TRUSTED.LOCAL", To: "TRUSTING.LOCAL", Kind: ad.TrustedBy},
+			{From: "TrustAccountController", To: "TrustAccount", Kind: ad.ForceChangePassword},
+			{From: "ServiceAccountController", To: "ServiceAccount", Kind: ad.GenericAll},
+		},
+	})
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		trustAccounts, err := adAnalysis.FetchTrustAccounts(tx)
+		require.Nil(t, err)
+		require.Equal(t, map[graph.ID]graph.ID{
+			testContext.SpecNode("TrustAccount").ID: testContext.SpecNode("TRUSTING.LOCAL").ID,
+		}, trustAccounts)
+
+		return nil
+	}))
+
+	_, err := adAnalysis.PostTrustAccountCompromise(context.Background(), db)
+	require.Nil(t, err)
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		relationships, err := ops.FetchRelationships(tx.Relationships().Filterf(func() graph.Criteria {
+			return query.Kind(query.Relationship(), ad.HasTrustKeys)
+		}))
+		require.Nil(t, err)
+		require.Equal(t, 1, len(relationships))
+		require.Equal(t, testContext.SpecNode("TrustAccountController").ID, relationships[0].StartID)
+		require.Equal(t, testContext.SpecNode("TRUSTING.LOCAL").ID, relationships[0].EndID)
+
+		return nil
+	}))
+}
+
+func TestPostCanTakeOver(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Users: []integration.PrincipalSpec{
+			{Name: "Target", Domain: "Domain"},
+			{Name: "OwnerWithGenericAll", Domain: "Domain"},
+			{Name: "DACLAndOwnerWriter", Domain: "Domain"},
+			{Name: "DACLWriter", Domain: "Domain"},
+		},
+		Edges: []integration.EdgeSpec{
+			{From: "OwnerWithGenericAll", To: "Target", Kind: ad.Owns},
+			{From: "OwnerWithGenericAll", To: "Target", Kind: ad.GenericAll},
+			{From: "DACLAndOwnerWriter", To: "Target", Kind: ad.WriteDACL},
+			{From: "DACLAndOwnerWriter", To: "Target", Kind: ad.WriteOwner},
+			{From: "DACLWriter", To: "Target", Kind: ad.WriteDACL},
+		},
+	})
+
+	_, err := adAnalysis.PostCanTakeOver(context.Background(), db)
+	require.Nil(t, err)
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		relationships, err := ops.FetchRelationships(tx.Relationships().Filterf(func() graph.Criteria {
+			return query.Kind(query.Relationship(), ad.CanTakeOver)
+		}))
+		require.Nil(t, err)
+
+		// Owns and GenericAll from the same principal collapse into a single edge, and WriteDACL alone is not enough
+		require.Equal(t, 2, len(relationships))
+
+		for _, relationship := range relationships {
+			sources := relationship.Properties.Get(adAnalysis.CanTakeOverSourcesProperty).Any()
+
+			sourceIDs, err := relationship.Properties.Get(adAnalysis.CanTakeOverSourceIDsProperty).IDSlice()
+			require.Nil(t, err)
+			require.Equal(t, 2, len(sourceIDs))
+
+			cost, err := relationship.Properties.Get(analysis.EdgeCostProperty).Int()
+			require.Nil(t, err)
+
+			switch relationship.StartID {
+			case testContext.SpecNode("OwnerWithGenericAll").ID:
+				require.Equal(t, []any{ad.GenericAll.String(), ad.Owns.String()}, sources)
+				require.Equal(t, int(analysis.EdgeCostLow), cost)
+			case testContext.SpecNode("DACLAndOwnerWriter").ID:
+				require.Equal(t, []any{ad.WriteDACL.String(), ad.WriteOwner.String()}, sources)
+				require.Equal(t, int(analysis.EdgeCostMedium), cost)
+			default:
+				t.Fatalf("unexpected CanTakeOver edge from node %d", relationship.StartID)
+			}
+		}
+
+		return nil
+	}))
+}
+
+func TestPostCrossSession(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Computers: []integration.ComputerSpec{
+			{Name: "Computer", Domain: "Domain"},
+			{Name: "OtherComputer", Domain: "Domain"},
+		},
+		Users: []integration.PrincipalSpec{
+			{Name: "Admin", Domain: "Domain"},
+			{Name: "SessionUser", Domain: "Domain"},
+			{Name: "UnrelatedUser", Domain: "Domain"},
+		},
+		Edges: []integration.EdgeSpec{
+			{From: "Admin", To: "Computer", Kind: ad.AdminTo},
+			{From: "Computer", To: "SessionUser", Kind: ad.HasSession},
+			{From: "Computer", To: "Admin", Kind: ad.HasSession},
+			{From: "Admin", To: "OtherComputer", Kind: ad.AdminTo},
+			{From: "OtherComputer", To: "SessionUser", Kind: ad.HasSession},
+		},
+	})
+
+	_, err := adAnalysis.PostCrossSession(context.Background(), db)
+	require.Nil(t, err)
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		relationships, err := ops.FetchRelationships(tx.Relationships().Filterf(func() graph.Criteria {
+			return query.Kind(query.Relationship(), ad.CanImpersonate)
+		}))
+		require.Nil(t, err)
+
+		// The admin's own session is ignored and sharing two computers with the session user still yields one edge
+		require.Equal(t, 1, len(relationships))
+		require.Equal(t, testContext.SpecNode("Admin").ID, relationships[0].StartID)
+		require.Equal(t, testContext.SpecNode("SessionUser").ID, relationships[0].EndID)
+
+		// The session's lastseen is carried over as the time the edge's source data was collected
+		require.True(t, relationships[0].Properties.Exists(analysis.SourceCollectedAtProperty))
+
+		// Session-derived edges expire unless post-processing refreshes them
+		require.True(t, relationships[0].Properties.Exists(analysis.ValidUntilProperty))
+
+		return nil
+	}))
+}
+
+func TestPostRDPSessionCapture(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Computers: []integration.ComputerSpec{
+			{Name: "TerminalServer", Domain: "Domain"},
+			{Name: "SessionlessServer", Domain: "Domain"},
+		},
+		Users: []integration.PrincipalSpec{
+			{Name: "RDPUser", Domain: "Domain"},
+			{Name: "SessionUser", Domain: "Domain"},
+		},
+		Edges: []integration.EdgeSpec{
+			{From: "RDPUser", To: "TerminalServer", Kind: ad.CanRDP},
+			{From: "RDPUser", To: "SessionlessServer", Kind: ad.CanRDP},
+			{From: "TerminalServer", To: "SessionUser", Kind: ad.HasSession},
+			{From: "TerminalServer", To: "RDPUser", Kind: ad.HasSession},
+		},
+	})
+
+	_, err := adAnalysis.PostRDPSessionCapture(context.Background(), db)
+	require.Nil(t, err)
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		relationships, err := ops.FetchRelationships(tx.Relationships().Filterf(func() graph.Criteria {
+			return query.Kind(query.Relationship(), ad.RDPSessionCapture)
+		}))
+		require.Nil(t, err)
+
+		// The RDP user's own session is ignored and the server without sessions contributes nothing
+		require.Equal(t, 1, len(relationships))
+		require.Equal(t, testContext.SpecNode("RDPUser").ID, relationships[0].StartID)
+		require.Equal(t, testContext.SpecNode("SessionUser").ID, relationships[0].EndID)
+
+		return nil
+	}))
+}
+
+func TestPostSharedAdminLateral(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Computers: []integration.ComputerSpec{
+			{Name: "ComputerX", Domain: "Domain"},
+			{Name: "ComputerY", Domain: "Domain"},
+			{Name: "ComputerZ", Domain: "Domain"},
+		},
+		Users: []integration.PrincipalSpec{
+			{Name: "SharedAdmin", Domain: "Domain"},
+			{Name: "OtherSharedAdmin", Domain: "Domain"},
+			{Name: "SingleAdmin", Domain: "Domain"},
+		},
+		Edges: []integration.EdgeSpec{
+			{From: "SharedAdmin", To: "ComputerX", Kind: ad.AdminTo},
+			{From: "SharedAdmin", To: "ComputerY", Kind: ad.AdminTo},
+			{From: "OtherSharedAdmin", To: "ComputerX", Kind: ad.AdminTo},
+			{From: "OtherSharedAdmin", To: "ComputerY", Kind: ad.AdminTo},
+			{From: "SingleAdmin", To: "ComputerZ", Kind: ad.AdminTo},
+		},
+	})
+
+	_, err := adAnalysis.PostSharedAdminLateral(context.Background(), db)
+	require.Nil(t, err)
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		relationships, err := ops.FetchRelationships(tx.Relationships().Filterf(func() graph.Criteria {
+			return query.Kind(query.Relationship(), ad.SharedAdminLateral)
+		}))
+		require.Nil(t, err)
+
+		// Sharing two admins still yields one edge in each direction, and ComputerZ shares no admin
+		pairs := make([][2]graph.ID, 0, len(relationships))
+
+		for _, relationship := range relationships {
+			pairs = append(pairs, [2]graph.ID{relationship.StartID, relationship.EndID})
+		}
+
+		require.ElementsMatch(t, [][2]graph.ID{
+			{testContext.SpecNode("ComputerX").ID, testContext.SpecNode("ComputerY").ID},
+			{testContext.SpecNode("ComputerY").ID, testContext.SpecNode("ComputerX").ID},
+		}, pairs)
+
+		return nil
+	}))
+}
+
+func TestPostDCSyncForPartition(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{
+			{Name: "TenantADomain", Collected: true},
+			{Name: "TenantBDomain", Collected: true},
+		},
+		Users: []integration.PrincipalSpec{
+			{Name: "TenantASyncer", Domain: "TenantADomain"},
+			{Name: "TenantBSyncer", Domain: "TenantBDomain"},
+		},
+		Edges: []integration.EdgeSpec{
+			{From: "TenantASyncer", To: "TenantADomain", Kind: ad.GetChanges},
+			{From: "TenantASyncer", To: "TenantADomain", Kind: ad.GetChangesAll},
+			{From: "TenantBSyncer", To: "TenantBDomain", Kind: ad.GetChanges},
+			{From: "TenantBSyncer", To: "TenantBDomain", Kind: ad.GetChangesAll},
+		},
+	})
+
+	var (
+		tenantA = adAnalysis.PartitionSelector{Property: "tenant", Value: "a"}
+		tenantB = adAnalysis.PartitionSelector{Property: "tenant", Value: "b"}
+	)
+
+	require.Nil(t, db.WriteTransaction(context.Background(), func(tx graph.Transaction) error {
+		for name, tenant := range map[string]string{"TenantADomain": "a", "TenantBDomain": "b"} {
+			domain := testContext.SpecNode(name)
+			domain.Properties.Set("tenant", tenant)
+
+			if err := tx.UpdateNode(domain); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}))
+
+	fetchDCSyncEnds := func() []graph.ID {
+		var ends []graph.ID
+
+		require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+			relationships, err := ops.FetchRelationships(tx.Relationships().Filterf(func() graph.Criteria {
+				return query.Kind(query.Relationship(), ad.DCSync)
+			}))
+			require.Nil(t, err)
+
+			for _, relationship := range relationships {
+				ends = append(ends, relationship.EndID)
+			}
+
+			return nil
+		}))
+
+		return ends
+	}
+
+	_, err := adAnalysis.PostDCSyncForPartition(context.Background(), db, tenantA)
+	require.Nil(t, err)
+	require.Equal(t, []graph.ID{testContext.SpecNode("TenantADomain").ID}, fetchDCSyncEnds())
+
+	_, err = adAnalysis.PostDCSyncForPartition(context.Background(), db, tenantB)
+	require.Nil(t, err)
+	require.ElementsMatch(t, []graph.ID{testContext.SpecNode("TenantADomain").ID, testContext.SpecNode("TenantBDomain").ID}, fetchDCSyncEnds())
+
+	// Purging one partition leaves the relationships of the other in place
+	_, err = adAnalysis.DeletePartitionEdges(context.Background(), db, tenantA, ad.DCSync)
+	require.Nil(t, err)
+	require.Equal(t, []graph.ID{testContext.SpecNode("TenantBDomain").ID}, fetchDCSyncEnds())
+}
+
+func TestPostReadGMSAPasswordFromControl(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Users: []integration.PrincipalSpec{
+			{Name: "GMSA", Domain: "Domain"},
+			{Name: "NotAGMSA", Domain: "Domain"},
+			{Name: "Reader", Domain: "Domain"},
+			{Name: "GenericAllHolder", Domain: "Domain"},
+			{Name: "GenericWriteHolder", Domain: "Domain"},
+			{Name: "BothRightsHolder", Domain: "Domain"},
+		},
+		Edges: []integration.EdgeSpec{
+			{From: "Reader", To: "GMSA", Kind: ad.ReadGMSAPassword},
+			{From: "Reader", To: "GMSA", Kind: ad.GenericAll},
+			{From: "GenericAllHolder", To: "GMSA", Kind: ad.GenericAll},
+			{From: "GenericAllHolder", To: "NotAGMSA", Kind: ad.GenericAll},
+			{From: "GenericWriteHolder", To: "GMSA", Kind: ad.GenericWrite},
+			{From: "BothRightsHolder", To: "GMSA", Kind: ad.GenericWrite},
+			{From: "BothRightsHolder", To: "GMSA", Kind: ad.GenericAll},
+		},
+	})
+
+	_, err := adAnalysis.PostReadGMSAPasswordFromControl(context.Background(), db)
+	require.Nil(t, err)
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		relationships, err := ops.FetchRelationships(tx.Relationships().Filterf(func() graph.Criteria {
+			return query.Kind(query.Relationship(), ad.ReadGMSAPassword)
+		}))
+		require.Nil(t, err)
+
+		// The collected edge plus one derived edge for each controller; the reader is not duplicated and the account
+		// without a collected reader is not recognized as a GMSA
+		require.Equal(t, 4, len(relationships))
+
+		for _, relationship := range relationships {
+			require.Equal(t, testContext.SpecNode("GMSA").ID, relationship.EndID)
+
+			source, err := relationship.Properties.Get(adAnalysis.GMSAReadSourceProperty).String()
+
+			switch relationship.StartID {
+			case testContext.SpecNode("Reader").ID:
+				require.True(t, graph.IsErrPropertyNotFound(err))
+			case testContext.SpecNode("GenericAllHolder").ID, testContext.SpecNode("BothRightsHolder").ID:
+				require.Nil(t, err)
+				require.Equal(t, adAnalysis.GMSAReadSourceGenericAll, source)
+			case testContext.SpecNode("GenericWriteHolder").ID:
+				require.Nil(t, err)
+				require.Equal(t, adAnalysis.GMSAReadSourceGenericWrite, source)
+			default:
+				t.Fatalf("unexpected ReadGMSAPassword edge from node %d", relationship.StartID)
+			}
+		}
+
+		return nil
+	}))
+}
+
+func TestPostAdminToViaGMSA(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Users: []integration.PrincipalSpec{
+			{Name: "GMSA", Domain: "Domain"},
+			{Name: "IdleGMSA", Domain: "Domain"},
+			{Name: "UnreadableAdmin", Domain: "Domain"},
+			{Name: "UserReader", Domain: "Domain"},
+		},
+		Computers: []integration.ComputerSpec{
+			{Name: "Host", Domain: "Domain"},
+			{Name: "GroupHost", Domain: "Domain"},
+			{Name: "Target", Domain: "Domain"},
+			{Name: "OtherTarget", Domain: "Domain"},
+		},
+		Groups: []integration.PrincipalSpec{{Name: "HostGroup", Domain: "Domain"}},
+		Memberships: []integration.MembershipSpec{
+			{Member: "GroupHost", Group: "HostGroup"},
+		},
+		Edges: []integration.EdgeSpec{
+			{From: "Host", To: "GMSA", Kind: ad.ReadGMSAPassword},
+			{From: "HostGroup", To: "GMSA", Kind: ad.ReadGMSAPassword},
+			{From: "UserReader", To: "GMSA", Kind: ad.ReadGMSAPassword},
+			{From: "GMSA", To: "Target", Kind: ad.AdminTo},
+			{From: "GMSA", To: "Host", Kind: ad.AdminTo},
+			{From: "Host", To: "IdleGMSA", Kind: ad.ReadGMSAPassword},
+			{From: "UnreadableAdmin", To: "OtherTarget", Kind: ad.AdminTo},
+		},
+	})
+
+	_, err := adAnalysis.PostAdminToViaGMSA(context.Background(), db)
+	require.Nil(t, err)
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		relationships, err := ops.FetchRelationships(tx.Relationships().Filterf(func() graph.Criteria {
+			return query.Kind(query.Relationship(), ad.AdminToViaGMSA)
+		}))
+		require.Nil(t, err)
+
+		// Users allowed to read the password are not hosts, a host administering itself gets no edge and GMSAs
+		// without AdminTo or without hosts produce nothing
+		pairs := make([][2]graph.ID, 0, len(relationships))
+
+		for _, relationship := range relationships {
+			pairs = append(pairs, [2]graph.ID{relationship.StartID, relationship.EndID})
+		}
+
+		require.ElementsMatch(t, [][2]graph.ID{
+			{testContext.SpecNode("Host").ID, testContext.SpecNode("Target").ID},
+			{testContext.SpecNode("GroupHost").ID, testContext.SpecNode("Target").ID},
+			{testContext.SpecNode("GroupHost").ID, testContext.SpecNode("Host").ID},
+		}, pairs)
+
+		return nil
+	}))
+}
+
+func TestPostEnterpriseAdminsAdminTo(t *testing.T) {
+	parentChildTrust := map[string]any{ad.TrustType.String(): adAnalysis.TrustTypeParentChild}
+
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{
+			{Name: "ForestRoot", Collected: true},
+			{Name: "ChildDomain", Collected: true},
+			{Name: "OtherForest", Collected: true},
+		},
+		Users: []integration.PrincipalSpec{
+			{Name: "DirectAdmin", Domain: "ForestRoot"},
+			{Name: "NestedAdmin", Domain: "ChildDomain"},
+			{Name: "OtherAdmin", Domain: "OtherForest"},
+		},
+		Groups: []integration.PrincipalSpec{
+			{Name: "EnterpriseAdmins", Domain: "ForestRoot", RID: adAnalysis.EnterpriseAdminsGroupSIDSuffix},
+			{Name: "NestedGroup", Domain: "ChildDomain"},
+			{Name: "OtherEnterpriseAdmins", Domain: "OtherForest", RID: adAnalysis.EnterpriseAdminsGroupSIDSuffix},
+		},
+		Computers: []integration.ComputerSpec{
+			{Name: "RootComputer", Domain: "ForestRoot"},
+			{Name: "ChildComputer", Domain: "ChildDomain"},
+			{Name: "OtherComputer", Domain: "OtherForest"},
+		},
+		Memberships: []integration.MembershipSpec{
+			{Member: "DirectAdmin", Group: "EnterpriseAdmins"},
+			{Member: "NestedGroup", Group: "EnterpriseAdmins"},
+			{Member: "NestedAdmin", Group: "NestedGroup"},
+		},
+		Edges: []integration.EdgeSpec{
+			{From: "ForestRoot", To: "ChildDomain", Kind: ad.TrustedBy, Properties: parentChildTrust},
+			{From: "EnterpriseAdmins", To: "ChildComputer", Kind: ad.AdminTo},
+		},
+	})
+
+	_, err := adAnalysis.PostEnterpriseAdminsAdminTo(context.Background(), db)
+	require.Nil(t, err)
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		relationships, err := ops.FetchRelationships(tx.Relationships().Filterf(func() graph.Criteria {
+			return query.And(
+				query.Kind(query.Relationship(), ad.AdminTo),
+				query.Exists(query.RelationshipProperty(adAnalysis.EnterpriseAdminsSourceProperty)),
+			)
+		}))
+		require.Nil(t, err)
+
+		// Each Enterprise Admins group reaches every computer of its forest only, members get no edge of their own and
+		// the collected AdminTo pair is not duplicated
+		var (
+			pairs         = make([][2]graph.ID, 0, len(relationships))
+			groupObjectID = map[graph.ID]string{}
+		)
+
+		for _, groupName := range []string{"EnterpriseAdmins", "OtherEnterpriseAdmins"} {
+			group := testContext.SpecNode(groupName)
+			groupObjectID[group.ID] = testContext.NodeObjectID(group)
+		}
+
+		for _, relationship := range relationships {
+			pairs = append(pairs, [2]graph.ID{relationship.StartID, relationship.EndID})
+
+			source, err := relationship.Properties.Get(adAnalysis.EnterpriseAdminsSourceProperty).String()
+			require.Nil(t, err)
+			require.Equal(t, groupObjectID[relationship.StartID], source)
+		}
+
+		require.ElementsMatch(t, [][2]graph.ID{
+			{testContext.SpecNode("EnterpriseAdmins").ID, testContext.SpecNode("RootComputer").ID},
+			{testContext.SpecNode("OtherEnterpriseAdmins").ID, testContext.SpecNode("OtherComputer").ID},
+		}, pairs)
+
+		return nil
+	}))
+}
+
+func TestComputeComputerExposure(t *testing.T) {
+	var (
+		lowCost  = map[string]any{analysis.EdgeCostProperty: int(analysis.EdgeCostLow)}
+		highCost = map[string]any{analysis.EdgeCostProperty: int(analysis.EdgeCostHigh)}
+	)
+
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Users: []integration.PrincipalSpec{
+			{Name: "Admin", Domain: "Domain"},
+			{Name: "RemoteUser", Domain: "Domain"},
+		},
+		Computers: []integration.ComputerSpec{
+			{Name: "Exposed", Domain: "Domain"},
+			{Name: "Quiet", Domain: "Domain"},
+			{Name: "Unexposed", Domain: "Domain"},
+		},
+		Edges: []integration.EdgeSpec{
+			{From: "Admin", To: "Exposed", Kind: ad.AdminTo, Properties: lowCost},
+			{From: "RemoteUser", To: "Exposed", Kind: ad.CanRDP, Properties: highCost},
+			{From: "RemoteUser", To: "Exposed", Kind: ad.CanPSRemote},
+			{From: "RemoteUser", To: "Quiet", Kind: ad.ExecuteDCOM},
+
+			// Relationships outside the exposure kinds are not counted
+			{From: "Admin", To: "Unexposed", Kind: ad.GenericAll},
+		},
+	})
+
+	exposure, err := adAnalysis.ComputeComputerExposure(context.Background(), db)
+	require.Nil(t, err)
+	require.Equal(t, map[graph.ID]int{
+		testContext.SpecNode("Exposed").ID: 3,
+		testContext.SpecNode("Quiet").ID:   1,
+	}, exposure)
+
+	// Weighted by cost the low cost AdminTo counts 3, the high cost CanRDP 1 and the unscored relationships 1 each
+	exposure, err = adAnalysis.ComputeComputerExposureWithOptions(context.Background(), db, adAnalysis.ComputerExposureOptions{WeightByCost: true})
+	require.Nil(t, err)
+	require.Equal(t, map[graph.ID]int{
+		testContext.SpecNode("Exposed").ID: 5,
+		testContext.SpecNode("Quiet").ID:   1,
+	}, exposure)
+
+	exposure, err = adAnalysis.ComputeComputerExposureWithOptions(context.Background(), db, adAnalysis.ComputerExposureOptions{Kinds: []graph.Kind{ad.AdminTo}})
+	require.Nil(t, err)
+	require.Equal(t, map[graph.ID]int{testContext.SpecNode("Exposed").ID: 1}, exposure)
+}
+
+func TestPostAddKeyCredentialLinkFromControl(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Computers: []integration.ComputerSpec{
+			{Name: "Workstation", Domain: "Domain"},
+			{Name: "DC", Domain: "Domain"},
+		},
+		Users: []integration.PrincipalSpec{
+			{Name: "Target", Domain: "Domain"},
+			{Name: "Writer", Domain: "Domain"},
+			{Name: "GenericAllHolder", Domain: "Domain"},
+			{Name: "GenericWriteHolder", Domain: "Domain"},
+		},
+		Groups: []integration.PrincipalSpec{
+			{Name: "Domain Controllers", Domain: "Domain", RID: adAnalysis.DomainControllersGroupSIDSuffix},
+		},
+		Memberships: []integration.MembershipSpec{
+			{Member: "DC", Group: "Domain Controllers"},
+		},
+		Edges: []integration.EdgeSpec{
+			{From: "Writer", To: "Workstation", Kind: ad.AddKeyCredentialLink},
+			{From: "Writer", To: "Workstation", Kind: ad.GenericWrite},
+			{From: "GenericAllHolder", To: "Workstation", Kind: ad.GenericAll},
+			{From: "GenericWriteHolder", To: "DC", Kind: ad.GenericWrite},
+			{From: "GenericWriteHolder", To: "Target", Kind: ad.GenericWrite},
+		},
+	})
+
+	_, err := adAnalysis.PostAddKeyCredentialLinkFromControl(context.Background(), db)
+	require.Nil(t, err)
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		relationships, err := ops.FetchRelationships(tx.Relationships().Filterf(func() graph.Criteria {
+			return query.Kind(query.Relationship(), ad.AddKeyCredentialLink)
+		}))
+		require.Nil(t, err)
+
+		// The collected edge plus one derived edge per controlled target; the collected writer is not duplicated
+		require.Equal(t, 4, len(relationships))
+
+		for _, relationship := range relationships {
+			var (
+				source, sourceErr     = relationship.Properties.Get(adAnalysis.KeyCredentialLinkSourceProperty).String()
+				severity, severityErr = relationship.Properties.Get(adAnalysis.KeyCredentialLinkSeverityProperty).String()
+			)
+
+			switch {
+			case relationship.StartID == testContext.SpecNode("Writer").ID:
+				require.True(t, graph.IsErrPropertyNotFound(sourceErr))
+				require.True(t, graph.IsErrPropertyNotFound(severityErr))
+			case relationship.StartID == testContext.SpecNode("GenericAllHolder").ID:
+				require.Equal(t, testContext.SpecNode("Workstation").ID, relationship.EndID)
+				require.Nil(t, sourceErr)
+				require.Equal(t, adAnalysis.KeyCredentialLinkSourceGenericAll, source)
+				require.Nil(t, severityErr)
+				require.Equal(t, adAnalysis.KeyCredentialLinkSeverityHigh, severity)
+			case relationship.EndID == testContext.SpecNode("DC").ID:
+				require.Nil(t, sourceErr)
+				require.Equal(t, adAnalysis.KeyCredentialLinkSourceGenericWrite, source)
+				require.Nil(t, severityErr)
+				require.Equal(t, adAnalysis.KeyCredentialLinkSeverityCritical, severity)
+			case relationship.EndID == testContext.SpecNode("Target").ID:
+				require.Nil(t, sourceErr)
+				require.Equal(t, adAnalysis.KeyCredentialLinkSourceGenericWrite, source)
+				require.Nil(t, severityErr)
+				require.Equal(t, adAnalysis.KeyCredentialLinkSeverityHigh, severity)
+			default:
+				t.Fatalf("unexpected AddKeyCredentialLink edge from node %d to node %d", relationship.StartID, relationship.EndID)
+			}
+		}
+
+		return nil
+	}))
+}
+
+func TestFetchDomainControllerSet(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{
+			{Name: "Domain", Collected: true},
+			{Name: "OtherDomain", Collected: true},
+		},
+		Computers: []integration.ComputerSpec{
+			{Name: "DC", Domain: "Domain"},
+			{Name: "Workstation", Domain: "Domain"},
+			{Name: "OtherDC", Domain: "OtherDomain"},
+		},
+		Groups: []integration.PrincipalSpec{
+			{Name: "Domain Controllers", Domain: "Domain", RID: adAnalysis.DomainControllersGroupSIDSuffix},
+			{Name: "Other Domain Controllers", Domain: "OtherDomain", RID: adAnalysis.DomainControllersGroupSIDSuffix},
+			{Name: "Server Operators", Domain: "Domain", RID: adAnalysis.ServerOperatorsGroupSIDSuffix},
+		},
+		Memberships: []integration.MembershipSpec{
+			{Member: "DC", Group: "Domain Controllers"},
+			{Member: "OtherDC", Group: "Other Domain Controllers"},
+		},
+	})
+
+	domainControllers, err := adAnalysis.FetchDomainControllerSet(context.Background(), db)
+	require.Nil(t, err)
+	require.True(t, domainControllers.Contains(testContext.SpecNode("DC").ID))
+	require.True(t, domainControllers.Contains(testContext.SpecNode("OtherDC").ID))
+	require.False(t, domainControllers.Contains(testContext.SpecNode("Workstation").ID))
+
+	domainSID, err := testContext.SpecNode("Domain").Properties.Get(ad.DomainSID.String()).String()
+	require.Nil(t, err)
+	require.Equal(t, []graph.ID{testContext.SpecNode("DC").ID}, domainControllers.InDomain(domainSID))
+
+	// Processors given the set use it in place of their own detection
+	_, err = adAnalysis.PostPrivilegedBuiltinGroupsWithDomainControllers(context.Background(), db, []adAnalysis.PrivilegedGroupCapability{{
+		SIDSuffix: adAnalysis.ServerOperatorsGroupSIDSuffix,
+		Kind:      ad.AdminTo,
+		Scope:     adAnalysis.PrivilegedGroupScopeDomainControllers,
+	}}, domainControllers, adAnalysis.DefaultWellKnownRIDs())
+	require.Nil(t, err)
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		admins, err := ops.FetchRelationships(tx.Relationships().Filterf(func() graph.Criteria {
+			return query.Kind(query.Relationship(), ad.AdminTo)
+		}))
+		require.Nil(t, err)
+		require.Equal(t, 1, len(admins))
+		require.Equal(t, testContext.SpecNode("Server Operators").ID, admins[0].StartID)
+		require.Equal(t, testContext.SpecNode("DC").ID, admins[0].EndID)
+
+		return nil
+	}))
+}
+
+func TestFetchSIDResolver(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{
+			{Name: "DomainA", Collected: true},
+			{Name: "DomainB", Collected: true},
+		},
+		Users: []integration.PrincipalSpec{
+			{Name: "DirectAdmin", Domain: "DomainA"},
+			{Name: "ForeignAdmin", Domain: "DomainB"},
+		},
+		Groups: []integration.PrincipalSpec{
+			{Name: "DomainAdmins", Domain: "DomainA", RID: adAnalysis.DomainAdminsGroupSIDSuffix},
+		},
+		Memberships: []integration.MembershipSpec{
+			{Member: "DirectAdmin", Group: "DomainAdmins"},
+			{Member: "ForeignAdmin", Group: "DomainAdmins"},
+		},
+	})
+
+	resolver, err := adAnalysis.FetchSIDResolver(context.Background(), db)
+	require.Nil(t, err)
+
+	domainAdminsSID := testContext.NodeObjectID(testContext.SpecNode("DomainAdmins"))
+
+	// SIDs are matched case-insensitively
+	id, found := resolver.Resolve(strings.ToLower(domainAdminsSID))
+	require.True(t, found)
+	require.Equal(t, testContext.SpecNode("DomainAdmins").ID, id)
+
+	id, found = resolver.Resolve(testContext.NodeObjectID(testContext.SpecNode("DomainA")))
+	require.True(t, found)
+	require.Equal(t, testContext.SpecNode("DomainA").ID, id)
+
+	_, found = resolver.Resolve("S-1-5-21-0-0-0-1234")
+	require.False(t, found)
+
+	suffixMatches := resolver.ResolveSuffixes(adAnalysis.DomainAdminsGroupSIDSuffix)
+	require.Equal(t, uint64(1), suffixMatches.Cardinality())
+	require.True(t, suffixMatches.Contains(testContext.SpecNode("DomainAdmins").ID.Uint32()))
+
+	name, isWellKnown := resolver.WellKnown(domainAdminsSID)
+	require.True(t, isWellKnown)
+	require.Equal(t, "Domain Admins", name)
+
+	_, isWellKnown = resolver.WellKnown(testContext.NodeObjectID(testContext.SpecNode("DirectAdmin")))
+	require.False(t, isWellKnown)
+
+	// Lookups through the resolver match the graph lookups they replace
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		expected, err := adAnalysis.FetchDomainAdminsBitmap(tx, testContext.SpecNode("DomainA"))
+		require.Nil(t, err)
+
+		domainAdmins, err := adAnalysis.FetchDomainAdminsBitmapWithResolver(tx, testContext.SpecNode("DomainA"), resolver)
+		require.Nil(t, err)
+		require.Equal(t, expected.Slice(), domainAdmins.Slice())
+		require.True(t, domainAdmins.Contains(testContext.SpecNode("ForeignAdmin").ID.Uint32()))
+
+		return nil
+	}))
+}
+
+func TestPostPrivilegedBuiltinAdminTo(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{
+			{Name: "Domain", Collected: true},
+			{Name: "OtherDomain", Collected: true},
+		},
+		Computers: []integration.ComputerSpec{
+			{Name: "DC", Domain: "Domain"},
+			{Name: "Workstation", Domain: "Domain"},
+			{Name: "OtherDC", Domain: "OtherDomain"},
+		},
+		Groups: []integration.PrincipalSpec{
+			{Name: "Domain Controllers", Domain: "Domain", RID: adAnalysis.DomainControllersGroupSIDSuffix},
+			{Name: "Other Domain Controllers", Domain: "OtherDomain", RID: adAnalysis.DomainControllersGroupSIDSuffix},
+			{Name: "Server Operators", Domain: "Domain", RID: adAnalysis.ServerOperatorsGroupSIDSuffix},
+			{Name: "Backup Operators", Domain: "Domain", RID: adAnalysis.BackupOperatorsGroupSIDSuffix},
+			{Name: "Print Operators", Domain: "Domain", RID: adAnalysis.PrintOperatorsGroupSIDSuffix},
+		},
+		Memberships: []integration.MembershipSpec{
+			{Member: "DC", Group: "Domain Controllers"},
+			{Member: "OtherDC", Group: "Other Domain Controllers"},
+		},
+	})
+
+	fetchAdminToPairs := func() map[graph.ID][]graph.ID {
+		pairs := map[graph.ID][]graph.ID{}
+
+		require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+			return tx.Relationships().Filterf(func() graph.Criteria {
+				return query.Kind(query.Relationship(), ad.AdminTo)
+			}).FetchTriples(func(cursor graph.Cursor[graph.RelationshipTripleResult]) error {
+				for result := range cursor.Chan() {
+					pairs[result.StartID] = append(pairs[result.StartID], result.EndID)
+				}
+
+				return cursor.Error()
+			})
+		}))
+
+		return pairs
+	}
+
+	_, err := adAnalysis.PostPrivilegedBuiltinAdminTo(context.Background(), db, adAnalysis.ServerOperatorsGroupSIDSuffix, adAnalysis.BackupOperatorsGroupSIDSuffix)
+	require.Nil(t, err)
+
+	// AdminTo is only granted over the DC of the group's own domain
+	pairs := fetchAdminToPairs()
+	require.Equal(t, 2, len(pairs))
+
+	for _, group := range []string{"Server Operators", "Backup Operators"} {
+		require.Equal(t, []graph.ID{testContext.SpecNode("DC").ID}, pairs[testContext.SpecNode(group).ID])
+	}
+
+	// Running again with the default RIDs adds Print Operators without duplicating existing pairs
+	_, err = adAnalysis.PostPrivilegedBuiltinAdminTo(context.Background(), db)
+	require.Nil(t, err)
+
+	pairs = fetchAdminToPairs()
+	require.Equal(t, 3, len(pairs))
+
+	for _, group := range []string{"Server Operators", "Backup Operators", "Print Operators"} {
+		require.Equal(t, []graph.ID{testContext.SpecNode("DC").ID}, pairs[testContext.SpecNode(group).ID])
+	}
+}
+
+func TestPostPrivilegedBuiltinGroups_AccountOperators(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{
+			{Name: "Domain", Collected: true},
+			{Name: "OtherDomain", Collected: true},
+		},
+		Users: []integration.PrincipalSpec{
+			{Name: "User", Domain: "Domain"},
+			{Name: "ProtectedUser", Domain: "Domain"},
+			{Name: "OtherUser", Domain: "OtherDomain"},
+		},
+		Groups: []integration.PrincipalSpec{
+			{Name: "Account Operators", Domain: "Domain", RID: adAnalysis.AccountOperatorsGroupSIDSuffix},
+			{Name: "Group", Domain: "Domain"},
+			{Name: "CollectedControlGroup", Domain: "Domain"},
+		},
+		Edges: []integration.EdgeSpec{
+			{From: "Account Operators", To: "CollectedControlGroup", Kind: ad.GenericAll},
+		},
+	})
+
+	// Members of AdminSDHolder protected groups are stamped with admincount and are out of reach of Account Operators
+	require.Nil(t, db.WriteTransaction(context.Background(), func(tx graph.Transaction) error {
+		for _, name := range []string{"ProtectedUser", "Account Operators"} {
+			node := testContext.SpecNode(name)
+			node.Properties.Set(ad.AdminCount.String(), true)
+
+			if err := tx.UpdateNode(node); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}))
+
+	_, err := adAnalysis.PostPrivilegedBuiltinGroups(context.Background(), db, adAnalysis.PrivilegedGroupCapabilities())
+	require.Nil(t, err)
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		accountOperators := testContext.SpecNode("Account Operators")
+
+		derivedTargets, err := ops.FetchEndNodes(tx.Relationships().Filterf(func() graph.Criteria {
+			return query.And(
+				query.Kind(query.Relationship(), ad.AccountOperatorControl),
+				query.Equals(query.StartID(), accountOperators.ID),
+				query.Exists(query.RelationshipProperty(adAnalysis.PrivilegedGroupSourceProperty)),
+			)
+		}))
+		require.Nil(t, err)
+
+		// The collected GenericAll is of another kind and does not stop CollectedControlGroup from being a target
+		require.Equal(t, 3, derivedTargets.Len())
+		require.True(t, derivedTargets.Contains(testContext.SpecNode("CollectedControlGroup")))
+		require.True(t, derivedTargets.Contains(testContext.SpecNode("User")))
+		require.True(t, derivedTargets.Contains(testContext.SpecNode("Group")))
+
+		// No GenericAll is emitted, leaving the collected one as the only GenericAll in the graph
+		numGenericAll, err := tx.Relationships().Filterf(func() graph.Criteria {
+			return query.Kind(query.Relationship(), ad.GenericAll)
+		}).Count()
+		require.Nil(t, err)
+		require.Equal(t, int64(1), numGenericAll)
+
+		return nil
+	}))
+}
+
+func TestFetchDirectTargets(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{
+			{Name: "DomainA", Collected: true},
+			{Name: "DomainB", Collected: true},
+		},
+		Computers: []integration.ComputerSpec{
+			{Name: "ComputerA", Domain: "DomainA"},
+			{Name: "ComputerB", Domain: "DomainA"},
+		},
+		Users: []integration.PrincipalSpec{
+			{Name: "Syncer", Domain: "DomainA"},
+			{Name: "RDPUser", Domain: "DomainA"},
+		},
+		Groups: []integration.PrincipalSpec{
+			{Name: "RDPGroup", Domain: "DomainA"},
+		},
+		LocalGroups: []integration.LocalGroupSpec{
+			{Name: "RemoteDesktopUsersA", Computer: "ComputerA", SIDSuffix: adAnalysis.RDPGroupSuffix},
+			{Name: "RemoteDesktopUsersB", Computer: "ComputerB", SIDSuffix: adAnalysis.RDPGroupSuffix},
+		},
+		Memberships: []integration.MembershipSpec{
+			{Member: "RDPUser", Group: "RemoteDesktopUsersA"},
+			{Member: "RDPGroup", Group: "RemoteDesktopUsersB"},
+			{Member: "RDPUser", Group: "RDPGroup"},
+		},
+		Edges: []integration.EdgeSpec{
+			{From: "Syncer", To: "DomainA", Kind: ad.GetChanges},
+			{From: "Syncer", To: "DomainA", Kind: ad.GetChangesAll},
+			{From: "Syncer", To: "DomainB", Kind: ad.GetChanges},
+			{From: "Syncer", To: "DomainB", Kind: ad.GetChangesAll},
+		},
+	})
+
+	_, err := adAnalysis.PostDCSync(context.Background(), db)
+	require.Nil(t, err)
+
+	_, err = adPost.PostLocalGroups(context.Background(), db)
+	require.Nil(t, err)
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		domains, err := adAnalysis.FetchDirectTargets(tx, testContext.SpecNode("Syncer").ID, ad.DCSync)
+		require.Nil(t, err)
+		require.Equal(t, 2, domains.Len())
+		require.True(t, domains.Contains(testContext.SpecNode("DomainA")))
+		require.True(t, domains.Contains(testContext.SpecNode("DomainB")))
+
+		// CanRDP over ComputerB is held by RDPGroup, so only the user's own edge is returned
+		computers, err := adAnalysis.FetchDirectTargets(tx, testContext.SpecNode("RDPUser").ID, ad.CanRDP)
+		require.Nil(t, err)
+		require.Equal(t, 1, computers.Len())
+		require.True(t, computers.Contains(testContext.SpecNode("ComputerA")))
+
+		computers, err = adAnalysis.FetchDirectTargets(tx, testContext.SpecNode("RDPGroup").ID, ad.CanRDP)
+		require.Nil(t, err)
+		require.Equal(t, 1, computers.Len())
+		require.True(t, computers.Contains(testContext.SpecNode("ComputerB")))
+
+		none, err := adAnalysis.FetchDirectTargets(tx, testContext.SpecNode("RDPUser").ID, ad.DCSync)
+		require.Nil(t, err)
+		require.Equal(t, 0, none.Len())
+
+		return nil
+	}))
+}
+
+func TestFetchComputedEdgesForNode(t *testing.T) {
+	computed := map[string]any{analysis.AnalysisVersionProperty: analysis.AnalysisVersion}
+
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Users: []integration.PrincipalSpec{
+			{Name: "Admin", Domain: "Domain"},
+			{Name: "Other", Domain: "Domain"},
+		},
+		Computers: []integration.ComputerSpec{
+			{Name: "Pivot", Domain: "Domain"},
+			{Name: "Target", Domain: "Domain"},
+		},
+		Edges: []integration.EdgeSpec{
+			{From: "Admin", To: "Pivot", Kind: ad.AdminTo, Properties: computed},
+			{From: "Pivot", To: "Target", Kind: ad.SharedAdminLateral, Properties: computed},
+			{From: "Other", To: "Target", Kind: ad.AdminTo, Properties: computed},
+
+			// A collected relationship of a kind that is also derived is not computed
+			{From: "Admin", To: "Pivot", Kind: ad.ReadLAPSPassword},
+		},
+	})
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		edges, err := adAnalysis.FetchComputedEdgesForNode(tx, testContext.SpecNode("Pivot").ID, append(adAnalysis.PostProcessedRelationships(), ad.ReadLAPSPassword))
+		require.Nil(t, err)
+		require.Equal(t, 2, edges.Len())
+
+		for _, edge := range edges {
+			require.Contains(t, []graph.Kind{ad.AdminTo, ad.SharedAdminLateral}, edge.Kind)
+			require.True(t, edge.StartID == testContext.SpecNode("Pivot").ID || edge.EndID == testContext.SpecNode("Pivot").ID)
+		}
+
+		edges, err = adAnalysis.FetchComputedEdgesForNode(tx, testContext.SpecNode("Pivot").ID, []graph.Kind{ad.SharedAdminLateral})
+		require.Nil(t, err)
+		require.Equal(t, 1, edges.Len())
+
+		edges, err = adAnalysis.FetchComputedEdgesForNode(tx, testContext.SpecNode("Pivot").ID, nil)
+		require.Nil(t, err)
+		require.Equal(t, 0, edges.Len())
+
+		return nil
+	}))
+}
+
+func TestFetchTransitiveControl(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains:   []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Computers: []integration.ComputerSpec{{Name: "Computer", Domain: "Domain"}},
+		Users: []integration.PrincipalSpec{
+			{Name: "Attacker", Domain: "Domain"},
+			{Name: "Victim", Domain: "Domain"},
+			{Name: "Unrelated", Domain: "Domain"},
+		},
+		Groups: []integration.PrincipalSpec{
+			{Name: "Helpdesk", Domain: "Domain"},
+		},
+		Edges: []integration.EdgeSpec{
+			{From: "Attacker", To: "Helpdesk", Kind: ad.GenericAll},
+			{From: "Helpdesk", To: "Victim", Kind: ad.WriteDACL},
+			{From: "Victim", To: "Computer", Kind: ad.Owns},
+			// Control cycles back to the attacker and must not be expanded again
+			{From: "Computer", To: "Attacker", Kind: ad.GenericAll},
+			// Not a control kind and never followed
+			{From: "Victim", To: "Unrelated", Kind: ad.HasSIDHistory},
+		},
+	})
+
+	controlKinds := []graph.Kind{ad.GenericAll, ad.WriteDACL, ad.Owns}
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		controlled, err := adAnalysis.FetchTransitiveControl(tx, testContext.SpecNode("Attacker").ID, controlKinds, 0)
+		require.Nil(t, err)
+		require.Equal(t, 3, controlled.Len())
+		require.True(t, controlled.Contains(testContext.SpecNode("Helpdesk")))
+		require.True(t, controlled.Contains(testContext.SpecNode("Victim")))
+		require.True(t, controlled.Contains(testContext.SpecNode("Computer")))
+
+		controlled, err = adAnalysis.FetchTransitiveControl(tx, testContext.SpecNode("Attacker").ID, controlKinds, 2)
+		require.Nil(t, err)
+		require.Equal(t, 2, controlled.Len())
+		require.True(t, controlled.Contains(testContext.SpecNode("Helpdesk")))
+		require.True(t, controlled.Contains(testContext.SpecNode("Victim")))
+
+		// Restricting the control kinds stops the expansion at the first edge of another kind
+		controlled, err = adAnalysis.FetchTransitiveControl(tx, testContext.SpecNode("Attacker").ID, []graph.Kind{ad.GenericAll}, 0)
+		require.Nil(t, err)
+		require.Equal(t, 1, controlled.Len())
+		require.True(t, controlled.Contains(testContext.SpecNode("Helpdesk")))
+
+		return nil
+	}))
+}
+func TestFetchAllDCSyncers(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{
+			{Name: "DomainA", Collected: true},
+			{Name: "DomainB", Collected: true},
+		},
+		Users: []integration.PrincipalSpec{
+			{Name: "SyncerA", Domain: "DomainA"},
+			{Name: "PartialSyncerA", Domain: "DomainA"},
+			{Name: "GroupMemberB", Domain: "DomainB"},
+		},
+		Groups: []integration.PrincipalSpec{
+			{Name: "SyncGroupB", Domain: "DomainB"},
+		},
+		Memberships: []integration.MembershipSpec{
+			{Member: "GroupMemberB", Group: "SyncGroupB"},
+		},
+		Edges: []integration.EdgeSpec{
+			{From: "SyncerA", To: "DomainA", Kind: ad.GetChanges},
+			{From: "SyncerA", To: "DomainA", Kind: ad.GetChangesAll},
+			{From: "PartialSyncerA", To: "DomainA", Kind: ad.GetChanges},
+			{From: "SyncGroupB", To: "DomainB", Kind: ad.GetChanges},
+			{From: "SyncGroupB", To: "DomainB", Kind: ad.GetChangesAll},
+		},
+	})
+
+	dcSyncersByDomain, err := adAnalysis.FetchAllDCSyncers(context.Background(), db)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(dcSyncersByDomain))
+
+	// Holding only one of the two replication rights is not enough
+	domainASyncers := dcSyncersByDomain[testContext.SpecNode("DomainA").ID]
+	require.Equal(t, 1, domainASyncers.Len())
+	require.True(t, domainASyncers.Contains(testContext.SpecNode("SyncerA")))
+
+	// Rights held by a group extend to its members
+	domainBSyncers := dcSyncersByDomain[testContext.SpecNode("DomainB").ID]
+	require.True(t, domainBSyncers.Contains(testContext.SpecNode("GroupMemberB")))
+	require.False(t, domainBSyncers.Contains(testContext.SpecNode("SyncerA")))
+}
+
+func TestFetchDomainsPrincipalCanDCSync(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{
+			{Name: "DomainA", Collected: true},
+			{Name: "DomainB", Collected: true},
+			{Name: "DomainC", Collected: true},
+		},
+		Users: []integration.PrincipalSpec{
+			{Name: "Principal", Domain: "DomainA"},
+		},
+		Groups: []integration.PrincipalSpec{
+			{Name: "SyncGroup", Domain: "DomainA"},
+			{Name: "NestedSyncGroup", Domain: "DomainA"},
+		},
+		Memberships: []integration.MembershipSpec{
+			{Member: "Principal", Group: "SyncGroup"},
+			{Member: "SyncGroup", Group: "NestedSyncGroup"},
+		},
+		Edges: []integration.EdgeSpec{
+			{From: "Principal", To: "DomainA", Kind: ad.GetChanges},
+			{From: "Principal", To: "DomainA", Kind: ad.GetChangesAll},
+			{From: "SyncGroup", To: "DomainB", Kind: ad.GetChanges},
+			{From: "NestedSyncGroup", To: "DomainB", Kind: ad.GetChangesAll},
+			{From: "SyncGroup", To: "DomainC", Kind: ad.GetChanges},
+		},
+	})
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		domains, err := adAnalysis.FetchDomainsPrincipalCanDCSync(tx, testContext.SpecNode("Principal").ID)
+		require.Nil(t, err)
+
+		// DomainB rights are split across nested groups and DomainC is missing GetChangesAll
+		require.Equal(t, 2, domains.Len())
+		require.True(t, domains.Contains(testContext.SpecNode("DomainA")))
+		require.True(t, domains.Contains(testContext.SpecNode("DomainB")))
+
+		return nil
+	}))
+}
+
+func TestPostWithOptions_NoDelete(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Users:   []integration.PrincipalSpec{{Name: "Syncer", Domain: "Domain"}},
+		Edges: []integration.EdgeSpec{
+			{From: "Syncer", To: "Domain", Kind: ad.GetChanges},
+			{From: "Syncer", To: "Domain", Kind: ad.GetChangesAll},
+		},
+	})
+
+	countDCSync := func() int64 {
+		var count int64
+
+		require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+			var err error
+
+			count, err = tx.Relationships().Filterf(func() graph.Criteria {
+				return query.Kind(query.Relationship(), ad.DCSync)
+			}).Count()
+
+			return err
+		}))
+
+		return count
+	}
+
+	_, err := adPost.Post(context.Background(), db)
+	require.Nil(t, err)
+	require.Equal(t, int64(1), countDCSync())
+
+	// The relationship from the first run is kept next to the one created by the shadow run
+	_, err = adPost.PostWithOptions(context.Background(), db, adPost.PostOptions{NoDelete: true})
+	require.Nil(t, err)
+	require.Equal(t, int64(2), countDCSync())
+
+	// A regular run cleans up after both
+	_, err = adPost.Post(context.Background(), db)
+	require.Nil(t, err)
+	require.Equal(t, int64(1), countDCSync())
+}
+
+func TestFetchPrioritizedComputers(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Computers: []integration.ComputerSpec{
+			{Name: "Workstation", Domain: "Domain"},
+			{Name: "Server", Domain: "Domain"},
+			{Name: "TierZeroServer", Domain: "Domain"},
+		},
+	})
+
+	require.Nil(t, db.WriteTransaction(context.Background(), func(tx graph.Transaction) error {
+		tierZeroServer := testContext.SpecNode("TierZeroServer")
+		tierZeroServer.Properties.Set(common.SystemTags.String(), ad.AdminTierZero)
+
+		return tx.UpdateNode(tierZeroServer)
+	}))
+
+	computers, err := adAnalysis.FetchPrioritizedComputers(context.Background(), db, nil)
+	require.Nil(t, err)
+	require.Equal(t, 3, len(computers))
+
+	// Prioritizing only changes the order of the computers returned
+	prioritizedComputers, err := adAnalysis.FetchPrioritizedComputers(context.Background(), db, adAnalysis.TierZeroComputerCriteria())
+	require.Nil(t, err)
+	require.ElementsMatch(t, computers, prioritizedComputers)
+	require.Equal(t, testContext.SpecNode("TierZeroServer").ID.Uint64(), prioritizedComputers[0])
+}
+
+func TestPostDCSyncIncludingDACLControl(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Users: []integration.PrincipalSpec{
+			{Name: "Syncer", Domain: "Domain"},
+			{Name: "DACLWriter", Domain: "Domain"},
+		},
+		Edges: []integration.EdgeSpec{
+			{From: "Syncer", To: "Domain", Kind: ad.GetChanges},
+			{From: "Syncer", To: "Domain", Kind: ad.GetChangesAll},
+			{From: "Syncer", To: "Domain", Kind: ad.WriteDACL},
+			{From: "DACLWriter", To: "Domain", Kind: ad.WriteDACL},
+		},
+	})
+
+	fetchDCSyncSources := func() map[graph.ID]string {
+		sources := map[graph.ID]string{}
+
+		require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+			return tx.Relationships().Filterf(func() graph.Criteria {
+				return query.Kind(query.Relationship(), ad.DCSync)
+			}).Fetch(func(cursor graph.Cursor[*graph.Relationship]) error {
+				for relationship := range cursor.Chan() {
+					source, err := relationship.Properties.Get(adAnalysis.DCSyncSourceProperty).String()
+					require.Nil(t, err)
+
+					sources[relationship.StartID] = source
+				}
+
+				return cursor.Error()
+			})
+		}))
+
+		return sources
+	}
+
+	// Without the flag only the principal holding the replication rights can DCSync
+	_, err := adAnalysis.PostDCSync(context.Background(), db)
+	require.Nil(t, err)
+	require.Equal(t, map[graph.ID]string{
+		testContext.SpecNode("Syncer").ID: adAnalysis.DCSyncSourceReplicationRights,
+	}, fetchDCSyncSources())
+
+	_, err = analysis.DeleteTransitEdges(context.Background(), db, ad.Entity, ad.Domain, ad.DCSync)
+	require.Nil(t, err)
+
+	_, err = adAnalysis.PostDCSyncIncludingDACLControl(context.Background(), db)
+	require.Nil(t, err)
+	require.Equal(t, map[graph.ID]string{
+		testContext.SpecNode("Syncer").ID:     adAnalysis.DCSyncSourceReplicationRights,
+		testContext.SpecNode("DACLWriter").ID: adAnalysis.DCSyncSourceDACLModification,
+	}, fetchDCSyncSources())
+}
+
+func TestPostDCSyncWithOptions_ExchangeGroups(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Users: []integration.PrincipalSpec{
+			{Name: "Syncer", Domain: "Domain"},
+			{Name: "ExchangeAdmin", Domain: "Domain"},
+			{Name: "Operator", Domain: "Domain"},
+		},
+		Groups: []integration.PrincipalSpec{
+			{Name: "EXCHANGE WINDOWS PERMISSIONS@DOMAIN.LOCAL", Domain: "Domain"},
+			{Name: "Backup Replicators", Domain: "Domain", RID: "-4242"},
+		},
+		Memberships: []integration.MembershipSpec{
+			{Member: "Syncer", Group: "EXCHANGE WINDOWS PERMISSIONS@DOMAIN.LOCAL"},
+			{Member: "ExchangeAdmin", Group: "EXCHANGE WINDOWS PERMISSIONS@DOMAIN.LOCAL"},
+			{Member: "Operator", Group: "Backup Replicators"},
+		},
+		Edges: []integration.EdgeSpec{
+			{From: "Syncer", To: "Domain", Kind: ad.GetChanges},
+			{From: "Syncer", To: "Domain", Kind: ad.GetChangesAll},
+			{From: "EXCHANGE WINDOWS PERMISSIONS@DOMAIN.LOCAL", To: "Domain", Kind: ad.GetChangesAll},
+			{From: "Backup Replicators", To: "Domain", Kind: ad.GetChangesAll},
+		},
+	})
+
+	fetchDCSyncSources := func() map[graph.ID]string {
+		sources := map[graph.ID]string{}
+
+		require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+			return tx.Relationships().Filterf(func() graph.Criteria {
+				return query.Kind(query.Relationship(), ad.DCSync)
+			}).Fetch(func(cursor graph.Cursor[*graph.Relationship]) error {
+				for relationship := range cursor.Chan() {
+					source, err := relationship.Properties.Get(adAnalysis.DCSyncSourceProperty).String()
+					require.Nil(t, err)
+
+					sources[relationship.StartID] = source
+				}
+
+				return cursor.Error()
+			})
+		}))
+
+		return sources
+	}
+
+	// The default heuristic matches the Exchange group by name regardless of case and domain suffix. Syncer holds the
+	// replication rights itself and keeps its original provenance.
+	defaultHeuristic := adAnalysis.DefaultExchangeGroupHeuristic()
+
+	_, err := adAnalysis.PostDCSyncWithOptions(context.Background(), db, adAnalysis.DCSyncOptions{ExchangeGroups: &defaultHeuristic})
+	require.Nil(t, err)
+	require.Equal(t, map[graph.ID]string{
+		testContext.SpecNode("Syncer").ID:                                    adAnalysis.DCSyncSourceReplicationRights,
+		testContext.SpecNode("EXCHANGE WINDOWS PERMISSIONS@DOMAIN.LOCAL").ID: adAnalysis.DCSyncSourceExchangeReplication,
+		testContext.SpecNode("ExchangeAdmin").ID:                             adAnalysis.DCSyncSourceExchangeReplication,
+	}, fetchDCSyncSources())
+
+	_, err = analysis.DeleteTransitEdges(context.Background(), db, ad.Entity, ad.Domain, ad.DCSync)
+	require.Nil(t, err)
+
+	// A custom heuristic can recognize groups by RID instead
+	customHeuristic := adAnalysis.ExchangeGroupHeuristic{SIDSuffixes: []string{"-4242"}}
+
+	_, err = adAnalysis.PostDCSyncWithOptions(context.Background(), db, adAnalysis.DCSyncOptions{ExchangeGroups: &customHeuristic})
+	require.Nil(t, err)
+	require.Equal(t, map[graph.ID]string{
+		testContext.SpecNode("Syncer").ID:             adAnalysis.DCSyncSourceReplicationRights,
+		testContext.SpecNode("Backup Replicators").ID: adAnalysis.DCSyncSourceExchangeReplication,
+		testContext.SpecNode("Operator").ID:           adAnalysis.DCSyncSourceExchangeReplication,
+	}, fetchDCSyncSources())
+}
+
+func TestPostDCSync_SourceCollector(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Users: []integration.PrincipalSpec{
+			{Name: "SameCollector", Domain: "Domain"},
+			{Name: "OtherCollector", Domain: "Domain"},
+			{Name: "NoCollector", Domain: "Domain"},
+		},
+		Edges: []integration.EdgeSpec{
+			{From: "SameCollector", To: "Domain", Kind: ad.GetChanges},
+			{From: "SameCollector", To: "Domain", Kind: ad.GetChangesAll},
+			{From: "OtherCollector", To: "Domain", Kind: ad.GetChanges},
+			{From: "OtherCollector", To: "Domain", Kind: ad.GetChangesAll},
+			{From: "NoCollector", To: "Domain", Kind: ad.GetChanges},
+			{From: "NoCollector", To: "Domain", Kind: ad.GetChangesAll},
+		},
+	})
+
+	fetchSourceCollectors := func() map[graph.ID]string {
+		sources := map[graph.ID]string{}
+
+		require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+			return tx.Relationships().Filterf(func() graph.Criteria {
+				return query.Kind(query.Relationship(), ad.DCSync)
+			}).Fetch(func(cursor graph.Cursor[*graph.Relationship]) error {
+				for relationship := range cursor.Chan() {
+					if relationship.Properties.Exists(analysis.SourceCollectorProperty) {
+						source, err := relationship.Properties.Get(analysis.SourceCollectorProperty).String()
+						require.Nil(t, err)
+
+						sources[relationship.StartID] = source
+					} else {
+						sources[relationship.StartID] = ""
+					}
+				}
+
+				return cursor.Error()
+			})
+		}))
+
+		return sources
+	}
+
+	// Without federated collection the property is left unset
+	_, err := adAnalysis.PostDCSync(context.Background(), db)
+	require.Nil(t, err)
+	require.Equal(t, map[graph.ID]string{
+		testContext.SpecNode("SameCollector").ID:  "",
+		testContext.SpecNode("OtherCollector").ID: "",
+		testContext.SpecNode("NoCollector").ID:    "",
+	}, fetchSourceCollectors())
+
+	_, err = analysis.DeleteTransitEdges(context.Background(), db, ad.Entity, ad.Domain, ad.DCSync)
+	require.Nil(t, err)
+
+	require.Nil(t, db.WriteTransaction(context.Background(), func(tx graph.Transaction) error {
+		for name, collectorID := range map[string]string{
+			"Domain":         "collector-a",
+			"SameCollector":  "collector-a",
+			"OtherCollector": "collector-b",
+		} {
+			node := testContext.SpecNode(name)
+			node.Properties.Set(analysis.CollectorIDProperty, collectorID)
+
+			if err := tx.UpdateNode(node); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}))
+
+	_, err = adAnalysis.PostDCSync(context.Background(), db)
+	require.Nil(t, err)
+	require.Equal(t, map[graph.ID]string{
+		testContext.SpecNode("SameCollector").ID:  "collector-a",
+		testContext.SpecNode("OtherCollector").ID: analysis.SourceCollectorMultiple,
+		testContext.SpecNode("NoCollector").ID:    "collector-a",
+	}, fetchSourceCollectors())
+}
+
+func TestPostCanDCSyncViaReset(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Users: []integration.PrincipalSpec{
+			{Name: "Syncer", Domain: "Domain"},
+			{Name: "Helpdesk", Domain: "Domain"},
+			{Name: "Controller", Domain: "Domain"},
+			{Name: "SyncingResetter", Domain: "Domain"},
+			{Name: "Bystander", Domain: "Domain"},
+		},
+		Edges: []integration.EdgeSpec{
+			{From: "Syncer", To: "Domain", Kind: ad.GetChanges},
+			{From: "Syncer", To: "Domain", Kind: ad.GetChangesAll},
+			{From: "SyncingResetter", To: "Domain", Kind: ad.GetChanges},
+			{From: "SyncingResetter", To: "Domain", Kind: ad.GetChangesAll},
+			{From: "Helpdesk", To: "Syncer", Kind: ad.ForceChangePassword},
+			// Derived into ForceChangePassword before the chain is computed
+			{From: "Controller", To: "Syncer", Kind: ad.GenericAll},
+			// Already holds DCSync and gains nothing from the reset
+			{From: "SyncingResetter", To: "Syncer", Kind: ad.ForceChangePassword},
+			// Resetting a principal without DCSync does not chain
+			{From: "Helpdesk", To: "Bystander", Kind: ad.ForceChangePassword},
+		},
+	})
+
+	_, err := adPost.PostWithOptions(context.Background(), db, adPost.PostOptions{})
+	require.Nil(t, err)
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		chains, err := ops.FetchRelationships(tx.Relationships().Filterf(func() graph.Criteria {
+			return query.Kind(query.Relationship(), ad.CanDCSyncViaReset)
+		}))
+		require.Nil(t, err)
+		require.Equal(t, 2, len(chains))
+
+		for _, chain := range chains {
+			require.Contains(t, []graph.ID{testContext.SpecNode("Helpdesk").ID, testContext.SpecNode("Controller").ID}, chain.StartID)
+			require.Equal(t, testContext.SpecNode("Domain").ID, chain.EndID)
+		}
+
+		return nil
+	}))
+}
+
+func TestPostLocalGroupsWithOptions_ComputerIDs(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Computers: []integration.ComputerSpec{
+			{Name: "ComputerA", Domain: "Domain"},
+			{Name: "ComputerB", Domain: "Domain"},
+			{Name: "ComputerC", Domain: "Domain"},
+			{Name: "ComputerD", Domain: "Domain"},
+		},
+		Users: []integration.PrincipalSpec{
+			{Name: "Admin", Domain: "Domain"},
+		},
+		LocalGroups: []integration.LocalGroupSpec{
+			{Name: "AdministratorsA", Computer: "ComputerA", SIDSuffix: adAnalysis.AdminGroupSuffix},
+			{Name: "AdministratorsB", Computer: "ComputerB", SIDSuffix: adAnalysis.AdminGroupSuffix},
+			{Name: "AdministratorsC", Computer: "ComputerC", SIDSuffix: adAnalysis.AdminGroupSuffix},
+			{Name: "AdministratorsD", Computer: "ComputerD", SIDSuffix: adAnalysis.AdminGroupSuffix},
+		},
+		Memberships: []integration.MembershipSpec{
+			{Member: "Admin", Group: "AdministratorsA"},
+			{Member: "Admin", Group: "AdministratorsB"},
+			{Member: "Admin", Group: "AdministratorsC"},
+			{Member: "Admin", Group: "AdministratorsD"},
+		},
+	})
+
+	// The given bitmap must be left untouched
+	computerIDs := roaring64.BitmapOf(testContext.SpecNode("ComputerB").ID.Uint64(), testContext.SpecNode("ComputerD").ID.Uint64())
+
+	_, err := adPost.PostLocalGroupsWithOptions(context.Background(), db, adPost.LocalGroupPostProcessingOptions{
+		ComputerIDs:              computerIDs,
+		PriorityComputerCriteria: adAnalysis.TierZeroComputerCriteria(),
+	})
+	require.Nil(t, err)
+	require.Equal(t, uint64(2), computerIDs.GetCardinality())
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		computers, err := adAnalysis.FetchDirectTargets(tx, testContext.SpecNode("Admin").ID, ad.AdminTo)
+		require.Nil(t, err)
+		require.Equal(t, 2, computers.Len())
+		require.True(t, computers.Contains(testContext.SpecNode("ComputerB")))
+		require.True(t, computers.Contains(testContext.SpecNode("ComputerD")))
+
+		return nil
+	}))
+}
+
+func TestAuditURAConsistency(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Computers: []integration.ComputerSpec{
+			{Name: "Consistent", Domain: "Domain", HasURA: true},
+			{Name: "MissingFlag", Domain: "Domain"},
+			{Name: "MissingGroup", Domain: "Domain", HasURA: true},
+			{Name: "Uncollected", Domain: "Domain"},
+		},
+		Users: []integration.PrincipalSpec{
+			{Name: "RDPUser", Domain: "Domain"},
+		},
+		LocalGroups: []integration.LocalGroupSpec{
+			{Name: "RDPGroupConsistent", Computer: "Consistent", SIDSuffix: adAnalysis.RDPGroupSuffix, RemoteInteractiveLogon: true},
+			{Name: "RDPGroupMissingFlag", Computer: "MissingFlag", SIDSuffix: adAnalysis.RDPGroupSuffix},
+			{Name: "RDPGroupUncollected", Computer: "Uncollected", SIDSuffix: adAnalysis.RDPGroupSuffix},
+		},
+		Edges: []integration.EdgeSpec{
+			{From: "RDPUser", To: "MissingFlag", Kind: ad.RemoteInteractiveLogonPrivilege},
+		},
+	})
+
+	issues, err := adAnalysis.AuditURAConsistency(context.Background(), db)
+	require.Nil(t, err)
+	require.ElementsMatch(t, []adAnalysis.ComputerURAIssue{
+		{ComputerID: testContext.SpecNode("MissingFlag").ID, Issue: adAnalysis.URAIssueMissingHasURA},
+		{ComputerID: testContext.SpecNode("MissingGroup").ID, Issue: adAnalysis.URAIssueMissingRDPGroup},
+	}, issues)
+}
+
+func TestPostWithOptions_AsOf(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Users:   []integration.PrincipalSpec{{Name: "Syncer", Domain: "Domain"}},
+		Edges: []integration.EdgeSpec{
+			{From: "Syncer", To: "Domain", Kind: ad.GetChanges},
+			{From: "Syncer", To: "Domain", Kind: ad.GetChangesAll},
+		},
+	})
+
+	_, err := adPost.Post(context.Background(), db)
+	require.Nil(t, err)
+
+	_, err = adPost.PostWithOptions(context.Background(), db, adPost.PostOptions{AsOf: time.Now().Add(-24 * time.Hour)})
+	require.True(t, errors.Is(err, adPost.ErrPointInTimeUnsupported))
+	require.True(t, errors.Is(err, graph.ErrUnsupportedDatabaseOperation))
+
+	// The failed run must leave the relationships of the previous run in place
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		count, err := tx.Relationships().Filterf(func() graph.Criteria {
+			return query.Kind(query.Relationship(), ad.DCSync)
+		}).Count()
+
+		require.Nil(t, err)
+		require.Equal(t, int64(1), count)
+		return nil
+	}))
+}
+
+func TestPostGroupSyncedToEntraRole(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Groups: []integration.PrincipalSpec{
+			{Name: "SyncedGroup", Domain: "Domain"},
+			{Name: "UnsyncedGroup", Domain: "Domain"},
+		},
+	})
+
+	// Nothing to link yet
+	_, err := adAnalysis.PostGroupSyncedToEntraRole(context.Background(), db)
+	require.Nil(t, err)
+
+	var (
+		syncedGroup   = testContext.SpecNode("SyncedGroup")
+		unsyncedGroup = testContext.SpecNode("UnsyncedGroup")
+		roleIDs       []graph.ID
+	)
+
+	require.Nil(t, db.WriteTransaction(context.Background(), func(tx graph.Transaction) error {
+		newEntraGroup := func(onPremID string, syncEnabled bool) *graph.Node {
+			entraGroup, err := tx.CreateNode(graph.AsProperties(graph.PropertyMap{
+				azure.OnPremID:          onPremID,
+				azure.OnPremSyncEnabled: syncEnabled,
+			}), azure.Entity, azure.Group)
+			require.Nil(t, err)
+
+			return entraGroup
+		}
+
+		var (
+			syncedEntraGroup   = newEntraGroup(strings.ToLower(testContext.NodeObjectID(syncedGroup)), true)
+			unsyncedEntraGroup = newEntraGroup(testContext.NodeObjectID(unsyncedGroup), false)
+		)
+
+		for _, roleName := range []string{"Global Administrator", "Privileged Role Administrator"} {
+			role, err := tx.CreateNode(graph.AsProperties(graph.PropertyMap{common.Name: roleName}), azure.Entity, azure.Role)
+			require.Nil(t, err)
+
+			_, err = tx.CreateRelationshipByIDs(syncedEntraGroup.ID, role.ID, azure.HasRole, graph.NewProperties())
+			require.Nil(t, err)
+
+			_, err = tx.CreateRelationshipByIDs(unsyncedEntraGroup.ID, role.ID, azure.HasRole, graph.NewProperties())
+			require.Nil(t, err)
+
+			roleIDs = append(roleIDs, role.ID)
+		}
+
+		return nil
+	}))
+
+	_, err = adAnalysis.PostGroupSyncedToEntraRole(context.Background(), db)
+	require.Nil(t, err)
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		roles, err := adAnalysis.FetchDirectTargets(tx, syncedGroup.ID, ad.SyncedToEntraRole)
+		require.Nil(t, err)
+		require.ElementsMatch(t, roleIDs, roles.IDs())
+
+		roles, err = adAnalysis.FetchDirectTargets(tx, unsyncedGroup.ID, ad.SyncedToEntraRole)
+		require.Nil(t, err)
+		require.Equal(t, 0, roles.Len())
+
+		return nil
+	}))
+}
+
+func TestPostAddAllowedToActFromControl(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Computers: []integration.ComputerSpec{
+			{Name: "Workstation", Domain: "Domain"},
+		},
+		Users: []integration.PrincipalSpec{
+			{Name: "Writer", Domain: "Domain"},
+			{Name: "GenericAllHolder", Domain: "Domain"},
+			{Name: "AccountRestrictionsWriter", Domain: "Domain"},
+			{Name: "DACLWriter", Domain: "Domain"},
+			{Name: "MultipleRightsHolder", Domain: "Domain"},
+			{Name: "UserTarget", Domain: "Domain"},
+		},
+		Edges: []integration.EdgeSpec{
+			{From: "Writer", To: "Workstation", Kind: ad.AddAllowedToAct},
+			{From: "Writer", To: "Workstation", Kind: ad.WriteDACL},
+			{From: "GenericAllHolder", To: "Workstation", Kind: ad.GenericAll},
+			{From: "AccountRestrictionsWriter", To: "Workstation", Kind: ad.WriteAccountRestrictions},
+			{From: "DACLWriter", To: "Workstation", Kind: ad.WriteDACL},
+			{From: "MultipleRightsHolder", To: "Workstation", Kind: ad.WriteDACL},
+			{From: "MultipleRightsHolder", To: "Workstation", Kind: ad.WriteAccountRestrictions},
+			{From: "DACLWriter", To: "UserTarget", Kind: ad.WriteDACL},
+		},
+	})
+
+	fetchSources := func() map[graph.ID]string {
+		sources := map[graph.ID]string{}
+
+		require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+			relationships, err := ops.FetchRelationships(tx.Relationships().Filterf(func() graph.Criteria {
+				return query.Kind(query.Relationship(), ad.AddAllowedToAct)
+			}))
+			require.Nil(t, err)
+
+			for _, relationship := range relationships {
+				require.Equal(t, testContext.SpecNode("Workstation").ID, relationship.EndID)
+
+				if source, err := relationship.Properties.Get(adAnalysis.RBCDWriteSourceProperty).String(); err != nil {
+					require.True(t, graph.IsErrPropertyNotFound(err))
+					sources[relationship.StartID] = ""
+				} else {
+					sources[relationship.StartID] = source
+				}
+			}
+
+			return nil
+		}))
+
+		return sources
+	}
+
+	_, err := adAnalysis.PostAddAllowedToActFromControl(context.Background(), db)
+	require.Nil(t, err)
+
+	// The collected edge is left unstamped and not duplicated, and a principal holding several rights gets one edge
+	require.Equal(t, map[graph.ID]string{
+		testContext.SpecNode("Writer").ID:                    "",
+		testContext.SpecNode("GenericAllHolder").ID:          adAnalysis.RBCDWriteSourceGenericAll,
+		testContext.SpecNode("AccountRestrictionsWriter").ID: adAnalysis.RBCDWriteSourceWriteAccountRestrictions,
+		testContext.SpecNode("DACLWriter").ID:                adAnalysis.RBCDWriteSourceWriteDACL,
+		testContext.SpecNode("MultipleRightsHolder").ID:      adAnalysis.RBCDWriteSourceWriteAccountRestrictions,
+	}, fetchSources())
+
+	_, err = adAnalysis.DeleteDerivedAddAllowedToActEdges(context.Background(), db)
+	require.Nil(t, err)
+	require.Equal(t, map[graph.ID]string{
+		testContext.SpecNode("Writer").ID: "",
+	}, fetchSources())
+}
+
+func TestPostLocalGroupsWithOptions_WindowSize(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+
+	spec := integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Users: []integration.PrincipalSpec{
+			{Name: "Admin", Domain: "Domain"},
+			{Name: "Operator", Domain: "Domain"},
+		},
+		Groups: []integration.PrincipalSpec{
+			{Name: "Helpdesk", Domain: "Domain"},
+		},
+		Memberships: []integration.MembershipSpec{
+			{Member: "Operator", Group: "Helpdesk"},
+		},
+	}
+
+	for _, computerName := range []string{"ComputerA", "ComputerB", "ComputerC", "ComputerD", "ComputerE"} {
+		var (
+			adminGroup    = "Administrators" + computerName
+			rdpGroup      = "RemoteDesktopUsers" + computerName
+			psRemoteGroup = "RemoteManagementUsers" + computerName
+			dcomGroup     = "DistributedCOMUsers" + computerName
+		)
+
+		spec.Computers = append(spec.Computers, integration.ComputerSpec{Name: computerName, Domain: "Domain"})
+		spec.LocalGroups = append(spec.LocalGroups,
+			integration.LocalGroupSpec{Name: adminGroup, Computer: computerName, SIDSuffix: adAnalysis.AdminGroupSuffix},
+			integration.LocalGroupSpec{Name: rdpGroup, Computer: computerName, SIDSuffix: adAnalysis.RDPGroupSuffix},
+			integration.LocalGroupSpec{Name: psRemoteGroup, Computer: computerName, SIDSuffix: "-580"},
+			integration.LocalGroupSpec{Name: dcomGroup, Computer: computerName, SIDSuffix: "-562"},
+		)
+		spec.Memberships = append(spec.Memberships,
+			integration.MembershipSpec{Member: "Admin", Group: adminGroup},
+			integration.MembershipSpec{Member: "Helpdesk", Group: rdpGroup},
+			integration.MembershipSpec{Member: "Operator", Group: psRemoteGroup},
+			integration.MembershipSpec{Member: "Admin", Group: dcomGroup},
+		)
+	}
+
+	var (
+		db              = testContext.BuildADTestGraph(spec)
+		localGroupKinds = []graph.Kind{ad.AdminTo, ad.CanRDP, ad.CanPSRemote, ad.ExecuteDCOM}
+		fetchEdges      = func() []string {
+			var edges []string
+
+			require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+				return tx.Relationships().Filterf(func() graph.Criteria {
+					return query.KindIn(query.Relationship(), localGroupKinds...)
+				}).FetchKinds(func(cursor graph.Cursor[graph.RelationshipKindsResult]) error {
+					for result := range cursor.Chan() {
+						edges = append(edges, fmt.Sprintf("%d-%s-%d", result.StartID, result.Kind, result.EndID))
+					}
+
+					return cursor.Error()
+				})
+			}))
+
+			return edges
+		}
+	)
+
+	stats, err := adPost.PostLocalGroups(context.Background(), db)
+	require.Nil(t, err)
+
+	unwindowedEdges := fetchEdges()
+	require.NotEmpty(t, unwindowedEdges)
+
+	for _, windowSize := range []int{1, 2, 5, 10} {
+		_, err = analysis.DeleteTransitEdges(context.Background(), db, ad.Entity, ad.Entity, localGroupKinds...)
+		require.Nil(t, err)
+
+		windowedStats, err := adPost.PostLocalGroupsWithOptions(context.Background(), db, adPost.LocalGroupPostProcessingOptions{
+			WindowSize: windowSize,
+		})
+		require.Nil(t, err)
+		require.ElementsMatch(t, unwindowedEdges, fetchEdges(), "window size %d", windowSize)
+
+		for _, kind := range localGroupKinds {
+			require.Equal(t, *stats.RelationshipsCreated[kind], *windowedStats.RelationshipsCreated[kind], "window size %d", windowSize)
+		}
+	}
+}
+
+func TestPostWithOptions_DisabledLocalGroupKind(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Users: []integration.PrincipalSpec{
+			{Name: "User", Domain: "Domain"},
+		},
+		Computers: []integration.ComputerSpec{
+			{Name: "Computer", Domain: "Domain"},
+		},
+		LocalGroups: []integration.LocalGroupSpec{
+			{Name: "Administrators", Computer: "Computer", SIDSuffix: adAnalysis.AdminGroupSuffix},
+			{Name: "RemoteDesktopUsers", Computer: "Computer", SIDSuffix: adAnalysis.RDPGroupSuffix},
+			{Name: "RemoteManagementUsers", Computer: "Computer", SIDSuffix: "-580"},
+			{Name: "DistributedCOMUsers", Computer: "Computer", SIDSuffix: "-562"},
+		},
+		Memberships: []integration.MembershipSpec{
+			{Member: "User", Group: "Administrators"},
+			{Member: "User", Group: "RemoteDesktopUsers"},
+			{Member: "User", Group: "RemoteManagementUsers"},
+			{Member: "User", Group: "DistributedCOMUsers"},
+		},
+	})
+
+	require.Nil(t, db.WriteTransaction(context.Background(), func(tx graph.Transaction) error {
+		_, err := tx.CreateNode(graph.AsProperties(graph.PropertyMap{
+			ad.AdminTo: false,
+		}), common.PostProcessingConfig)
+
+		return err
+	}))
+
+	config, err := adAnalysis.LoadPostProcessingConfig(context.Background(), db)
+	require.Nil(t, err)
+
+	_, err = adPost.PostWithOptions(context.Background(), db, adPost.PostOptions{Config: config})
+	require.Nil(t, err)
+
+	var (
+		user     = testContext.SpecNode("User").ID
+		computer = testContext.SpecNode("Computer").ID
+		edges    []graph.Kind
+	)
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		return tx.Relationships().Filterf(func() graph.Criteria {
+			return query.And(
+				query.Equals(query.StartID(), user),
+				query.Equals(query.EndID(), computer),
+				query.KindIn(query.Relationship(), ad.AdminTo, ad.CanRDP, ad.CanPSRemote, ad.ExecuteDCOM),
+			)
+		}).FetchKinds(func(cursor graph.Cursor[graph.RelationshipKindsResult]) error {
+			for result := range cursor.Chan() {
+				edges = append(edges, result.Kind)
+			}
+
+			return cursor.Error()
+		})
+	}))
+
+	require.ElementsMatch(t, []graph.Kind{ad.CanRDP, ad.CanPSRemote, ad.ExecuteDCOM}, edges)
+}
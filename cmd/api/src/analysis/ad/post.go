@@ -19,14 +19,18 @@ package ad
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/RoaringBitmap/roaring/roaring64"
 	"github.com/specterops/bloodhound/src/model/appcfg"
 	"github.com/specterops/bloodhound/analysis"
 	adAnalysis "github.com/specterops/bloodhound/analysis/ad"
 	"github.com/specterops/bloodhound/analysis/impact"
+	"github.com/specterops/bloodhound/dawgs/cardinality"
 	"github.com/specterops/bloodhound/dawgs/graph"
 	"github.com/specterops/bloodhound/dawgs/util/channels"
 	"github.com/specterops/bloodhound/graphschema/ad"
+	"github.com/specterops/bloodhound/graphschema/azure"
 	"github.com/specterops/bloodhound/log"
 )
 
@@ -39,148 +43,743 @@ func PostProcessedRelationships(localGroupPostProcessingFlag appcfg.FeatureFlag)
 			ad.AdminTo,
 			ad.CanPSRemote,
 			ad.ExecuteDCOM,
+			ad.SameForestTrust,
+			ad.CrossForestTrust,
+			ad.ImpliedGenericAll,
+			ad.SyncedToEntraUser,
+			ad.SyncedToEntraRole,
+			ad.CanApplyGPO,
+			ad.HasTrustKeys,
+			ad.CanTakeOver,
+			ad.CanImpersonate,
+			ad.SharedAdminLateral,
+			ad.RDPSessionCapture,
+			ad.CanDCSyncViaReset,
+			ad.AdminToViaGMSA,
+			ad.AccountOperatorControl,
 		}
 	}
 
 	return []graph.Kind{
 		ad.SyncLAPSPassword,
 		ad.DCSync,
+		ad.SameForestTrust,
+		ad.CrossForestTrust,
+		ad.ImpliedGenericAll,
+		ad.SyncedToEntraUser,
+		ad.SyncedToEntraRole,
+		ad.CanApplyGPO,
+		ad.HasTrustKeys,
+		ad.CanTakeOver,
+		ad.CanImpersonate,
+		ad.SharedAdminLateral,
+		ad.RDPSessionCapture,
+		ad.CanDCSyncViaReset,
+		ad.AdminToViaGMSA,
+		ad.AccountOperatorControl,
 	}
 }
 
-func PostLocalGroups(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+// LocalGroupPostProcessingOptions tunes which local group derived edges PostLocalGroupsWithOptions creates
+type LocalGroupPostProcessingOptions struct {
+	// SuppressBuiltinAdmins prevents principals matching SuppressedAdminSIDSuffixes from being used as the source of
+	// AdminTo, CanRDP, CanPSRemote and ExecuteDCOM edges.
+	SuppressBuiltinAdmins bool
+
+	// SuppressedAdminSIDSuffixes overrides the RIDs filtered by SuppressBuiltinAdmins. When empty the built-in
+	// Administrator account and Domain Admins RIDs of WellKnownRIDs are used.
+	SuppressedAdminSIDSuffixes []string
+
+	// SIDResolver, when set, finds the principals filtered by SuppressBuiltinAdmins in place of a graph query
+	SIDResolver adAnalysis.SIDResolver
+
+	// Collectors attributes the emitted edges to the collectors that ingested their nodes. When nil it is loaded once
+	// before the computers are processed.
+	Collectors analysis.CollectorAttribution
+
+	// SubtractDeniedLogons removes local group members holding a collected deny network logon right on the computer
+	// before AdminTo, CanPSRemote and ExecuteDCOM edges are created. Deny interactive logon rights do not apply to these
+	// network logons and are left alone. Deny rights are not always collected, so this is off by default.
+	SubtractDeniedLogons bool
+
+	// DirectRDPLogonRight also emits CanRDP for principals granted "Allow log on through Remote Desktop Services" on a
+	// computer even when they are not members of its Remote Desktop Users local group.
+	DirectRDPLogonRight bool
+
+	// SubtractDeniedRDPLogons removes principals denied logon through Remote Desktop Services on a computer, along with
+	// the members of denied groups, from its CanRDP edges. Like SubtractDeniedLogons this depends on deny rights having
+	// been collected and is off by default.
+	SubtractDeniedRDPLogons bool
+
+	// MaxMembershipHops caps how many MemberOf and MemberOfLocalGroup relationships are followed when local group
+	// memberships are expanded. Zero leaves the expansion unbounded. Deeply nested groups beyond the budget are left
+	// out of the emitted edges, trading completeness for a predictable runtime on pathological graphs.
+	MaxMembershipHops int
+
+	// PriorityComputerCriteria, when set, has the computers it matches submitted for processing before every other
+	// computer so that their edges are written first, for example adAnalysis.TierZeroComputerCriteria. Computers are
+	// still processed in parallel, so this orders the output roughly rather than strictly. The edges created are the
+	// same with or without it; only the order in which they appear changes.
+	PriorityComputerCriteria graph.Criteria
+
+	// ComputerIDs, when set, limits processing to the given computers instead of every computer in the graph. This
+	// supports recomputing the edges of the few computers that changed, which requires the caller to have removed their
+	// previous edges. Local group memberships are still expanded across the whole graph.
+	ComputerIDs *roaring64.Bitmap
+
+	// WindowSize, when positive, processes computers in windows of at most WindowSize computers. The edges of a window
+	// are written and its readers released before the next window starts, which bounds the work held in memory at once
+	// regardless of the number of computers. Local group memberships are still expanded once for the whole graph. Zero
+	// processes every computer in a single window.
+	WindowSize int
+
+	// Kinds limits the emitted relationships to the given kinds out of CanRDP, AdminTo, CanPSRemote and ExecuteDCOM.
+	// When empty all four are emitted.
+	Kinds []graph.Kind
+
+	// WellKnownRIDs identifies the local groups and built-in principals read by local group post-processing. The zero
+	// value uses adAnalysis.DefaultWellKnownRIDs.
+	WellKnownRIDs adAnalysis.WellKnownRIDs
+}
+
+func (s LocalGroupPostProcessingOptions) emits(kind graph.Kind) bool {
+	return len(s.Kinds) == 0 || graph.Kinds(s.Kinds).ContainsOneOf(kind)
+}
+
+func (s LocalGroupPostProcessingOptions) fetchComputers(ctx context.Context, db graph.Database) ([]uint64, error) {
+	if s.ComputerIDs == nil {
+		return adAnalysis.FetchPrioritizedComputers(ctx, db, s.PriorityComputerCriteria)
+	}
+
+	return adAnalysis.PrioritizeComputers(ctx, db, s.ComputerIDs, s.PriorityComputerCriteria)
+}
+
+func (s LocalGroupPostProcessingOptions) expandLocalGroups(ctx context.Context, db graph.Database) (impact.PathAggregator, error) {
+	return adAnalysis.ExpandAllRDPLocalGroupsWithMaxHops(ctx, db, s.MaxMembershipHops, s.WellKnownRIDs.OrDefault())
+}
+
+func (s LocalGroupPostProcessingOptions) fetchRDPEntityBitmap(tx graph.Transaction, computer graph.ID, localGroupExpansions impact.PathAggregator, bitmapPool *adAnalysis.BitmapPool) (cardinality.Duplex[uint32], error) {
+	entities, err := adAnalysis.FetchRDPEntityBitmapForComputerWithUnenforcedURAAndRIDs(tx, computer, localGroupExpansions, s.WellKnownRIDs.OrDefault(), bitmapPool)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.DirectRDPLogonRight {
+		if directEntities, err := adAnalysis.FetchDirectRDPLogonRightBitmapForComputer(tx, computer, localGroupExpansions); err != nil {
+			return nil, err
+		} else {
+			entities.Or(directEntities)
+		}
+	}
+
+	// Deny rights override allow rights and are subtracted last so that they also apply to directly granted entities
+	if s.SubtractDeniedRDPLogons {
+		if err := adAnalysis.SubtractDeniedRDPEntities(tx, computer, entities, localGroupExpansions); err != nil {
+			return nil, err
+		}
+	}
+
+	return entities, nil
+}
+
+func (s LocalGroupPostProcessingOptions) fetchLocalGroupBitmap(tx graph.Transaction, computer graph.ID, suffix string, bitmapPool *adAnalysis.BitmapPool) (cardinality.Duplex[uint32], error) {
+	if s.SubtractDeniedLogons {
+		return adAnalysis.FetchLocalGroupBitmapForComputerExcludingDenied(tx, computer, suffix, bitmapPool)
+	}
+
+	return adAnalysis.FetchLocalGroupBitmapForComputerWithPool(tx, computer, suffix, bitmapPool)
+}
+
+func (s LocalGroupPostProcessingOptions) suppressedSIDSuffixes() []string {
+	if !s.SuppressBuiltinAdmins {
+		return nil
+	} else if len(s.SuppressedAdminSIDSuffixes) > 0 {
+		return s.SuppressedAdminSIDSuffixes
+	}
+
+	return s.WellKnownRIDs.OrDefault().BuiltinAdminSIDSuffixes()
+}
+
+func (s LocalGroupPostProcessingOptions) fetchSuppressedPrincipals(ctx context.Context, db graph.Database) (cardinality.Duplex[uint32], error) {
+	if s.SIDResolver != nil {
+		return s.SIDResolver.ResolveSuffixes(s.suppressedSIDSuffixes()...), nil
+	}
+
+	return adAnalysis.FetchPrincipalBitmapBySIDSuffixes(ctx, db, s.suppressedSIDSuffixes()...)
+}
+
+// loadCollectors returns Collectors, loading the attribution when it was not given
+func (s LocalGroupPostProcessingOptions) loadCollectors(ctx context.Context, db graph.Database) (analysis.CollectorAttribution, error) {
+	if s.Collectors != nil {
+		return s.Collectors, nil
+	}
+
+	return analysis.LoadCollectorAttribution(ctx, db)
+}
+
+// submitLocalGroupReaders submits the readers emitting the ExecuteDCOM, CanPSRemote, AdminTo and CanRDP edges of the
+// given computer to operation. The bitmaps read by each reader are drawn from the given pool and handed back once the
+// reader has submitted its edges.
+func (s LocalGroupPostProcessingOptions) submitLocalGroupReaders(operation *analysis.StatTrackedOperation[analysis.CreatePostRelationshipJob], computerID graph.ID, localGroupExpansions impact.PathAggregator, suppressedPrincipals cardinality.Duplex[uint32], collectors analysis.CollectorAttribution, bitmapPool *adAnalysis.BitmapPool) error {
 	var (
-		adminGroupSuffix    = "-544"
-		psRemoteGroupSuffix = "-580"
-		dcomGroupSuffix     = "-562"
+		rids                = s.WellKnownRIDs.OrDefault()
+		adminGroupSuffix    = adAnalysis.RIDSuffix(rids.Administrators)
+		psRemoteGroupSuffix = adAnalysis.RIDSuffix(rids.RemoteManagementUsers)
+		dcomGroupSuffix     = adAnalysis.RIDSuffix(rids.DistributedCOMUsers)
 	)
 
-	if localGroupExpansions, err := adAnalysis.ExpandAllRDPLocalGroups(ctx, db); err != nil {
-		return &analysis.AtomicPostProcessingStats{}, err
-	} else if computers, err := adAnalysis.FetchComputers(ctx, db); err != nil {
-		return &analysis.AtomicPostProcessingStats{}, err
-	} else {
-		var (
-			threadSafeLocalGroupExpansions = impact.NewThreadSafeAggregator(localGroupExpansions)
-			operation                      = analysis.NewPostRelationshipOperation(ctx, db, "LocalGroup Post Processing")
-		)
+	if s.emits(ad.ExecuteDCOM) {
+		if err := operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+			if entities, err := s.fetchLocalGroupBitmap(tx, computerID, dcomGroupSuffix, bitmapPool); err != nil {
+				return err
+			} else {
+				defer bitmapPool.Put(entities)
 
-		for idx, computer := range computers.ToArray() {
-			computerID := graph.ID(computer)
+				for _, admin := range entities.Slice() {
+					if suppressedPrincipals.Contains(admin) {
+						continue
+					}
 
-			if idx > 0 && idx%10000 == 0 {
-				log.Infof("Post processed %d active directory computers", idx)
+					nextJob := analysis.CreatePostRelationshipJob{
+						FromID:          graph.ID(admin),
+						ToID:            computerID,
+						Kind:            ad.ExecuteDCOM,
+						SourceCollector: collectors.Source(graph.ID(admin), computerID),
+					}
+
+					if !channels.Submit(ctx, outC, nextJob) {
+						return nil
+					}
+				}
+
+				return nil
 			}
+		}); err != nil {
+			return fmt.Errorf("failed submitting reader for operation involving computer %d: %w", computerID, err)
+		}
+	}
 
-			if err := operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
-				if entities, err := adAnalysis.FetchLocalGroupBitmapForComputer(tx, computerID, dcomGroupSuffix); err != nil {
-					return err
-				} else {
-					for _, admin := range entities.Slice() {
-						nextJob := analysis.CreatePostRelationshipJob{
-							FromID: graph.ID(admin),
-							ToID:   computerID,
-							Kind:   ad.ExecuteDCOM,
-						}
-
-						if !channels.Submit(ctx, outC, nextJob) {
-							return nil
-						}
+	if s.emits(ad.CanPSRemote) {
+		if err := operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+			if entities, err := s.fetchLocalGroupBitmap(tx, computerID, psRemoteGroupSuffix, bitmapPool); err != nil {
+				return err
+			} else {
+				defer bitmapPool.Put(entities)
+
+				for _, admin := range entities.Slice() {
+					if suppressedPrincipals.Contains(admin) {
+						continue
+					}
+
+					nextJob := analysis.CreatePostRelationshipJob{
+						FromID:          graph.ID(admin),
+						ToID:            computerID,
+						Kind:            ad.CanPSRemote,
+						SourceCollector: collectors.Source(graph.ID(admin), computerID),
 					}
 
-					return nil
+					if !channels.Submit(ctx, outC, nextJob) {
+						return nil
+					}
 				}
-			}); err != nil {
-				return &analysis.AtomicPostProcessingStats{}, fmt.Errorf("failed submitting reader for operation involving computer %d: %w", computerID, err)
+
+				return nil
 			}
+		}); err != nil {
+			return fmt.Errorf("failed submitting reader for operation involving computer %d: %w", computerID, err)
+		}
+	}
+
+	if s.emits(ad.AdminTo) {
+		if err := operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+			if entities, err := s.fetchLocalGroupBitmap(tx, computerID, adminGroupSuffix, bitmapPool); err != nil {
+				return err
+			} else {
+				defer bitmapPool.Put(entities)
+
+				for _, admin := range entities.Slice() {
+					if suppressedPrincipals.Contains(admin) {
+						continue
+					}
 
-			if err := operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
-				if entities, err := adAnalysis.FetchLocalGroupBitmapForComputer(tx, computerID, psRemoteGroupSuffix); err != nil {
-					return err
-				} else {
-					for _, admin := range entities.Slice() {
-						nextJob := analysis.CreatePostRelationshipJob{
-							FromID: graph.ID(admin),
-							ToID:   computerID,
-							Kind:   ad.CanPSRemote,
-						}
-
-						if !channels.Submit(ctx, outC, nextJob) {
-							return nil
-						}
+					nextJob := analysis.CreatePostRelationshipJob{
+						FromID:          graph.ID(admin),
+						ToID:            computerID,
+						Kind:            ad.AdminTo,
+						SourceCollector: collectors.Source(graph.ID(admin), computerID),
 					}
 
-					return nil
+					if !channels.Submit(ctx, outC, nextJob) {
+						return nil
+					}
 				}
-			}); err != nil {
-				return &analysis.AtomicPostProcessingStats{}, fmt.Errorf("failed submitting reader for operation involving computer %d: %w", computerID, err)
+
+				return nil
 			}
+		}); err != nil {
+			return fmt.Errorf("failed submitting reader for operation involving computer %d: %w", computerID, err)
+		}
+	}
 
-			if err := operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
-				if entities, err := adAnalysis.FetchLocalGroupBitmapForComputer(tx, computerID, adminGroupSuffix); err != nil {
-					return err
-				} else {
-					for _, admin := range entities.Slice() {
-						nextJob := analysis.CreatePostRelationshipJob{
-							FromID: graph.ID(admin),
-							ToID:   computerID,
-							Kind:   ad.AdminTo,
-						}
-
-						if !channels.Submit(ctx, outC, nextJob) {
-							return nil
-						}
+	if s.emits(ad.CanRDP) {
+		if err := operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+			if entities, err := s.fetchRDPEntityBitmap(tx, computerID, localGroupExpansions, bitmapPool); err != nil {
+				return err
+			} else {
+				defer bitmapPool.Put(entities)
+
+				for _, rdp := range entities.Slice() {
+					if suppressedPrincipals.Contains(rdp) {
+						continue
 					}
 
-					return nil
+					nextJob := analysis.CreatePostRelationshipJob{
+						FromID:          graph.ID(rdp),
+						ToID:            computerID,
+						Kind:            ad.CanRDP,
+						SourceCollector: collectors.Source(graph.ID(rdp), computerID),
+					}
+
+					if !channels.Submit(ctx, outC, nextJob) {
+						return nil
+					}
 				}
-			}); err != nil {
-				return &analysis.AtomicPostProcessingStats{}, fmt.Errorf("failed submitting reader for operation involving computer %d: %w", computerID, err)
 			}
 
-			if err := operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
-				if entities, err := adAnalysis.FetchRDPEntityBitmapForComputerWithUnenforcedURA(tx, computerID, threadSafeLocalGroupExpansions); err != nil {
-					return err
-				} else {
-					for _, rdp := range entities.Slice() {
-						nextJob := analysis.CreatePostRelationshipJob{
-							FromID: graph.ID(rdp),
-							ToID:   computerID,
-							Kind:   ad.CanRDP,
-						}
-
-						if !channels.Submit(ctx, outC, nextJob) {
-							return nil
-						}
-					}
+			return nil
+		}); err != nil {
+			return fmt.Errorf("failed submitting reader for operation involving computer %d: %w", computerID, err)
+		}
+	}
+
+	return nil
+}
+
+func PostLocalGroups(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+	return PostLocalGroupsWithOptions(ctx, db, LocalGroupPostProcessingOptions{})
+}
+
+func PostLocalGroupsWithOptions(ctx context.Context, db graph.Database, options LocalGroupPostProcessingOptions) (*analysis.AtomicPostProcessingStats, error) {
+	if localGroupExpansions, err := options.expandLocalGroups(ctx, db); err != nil {
+		return &analysis.AtomicPostProcessingStats{}, err
+	} else if computers, err := options.fetchComputers(ctx, db); err != nil {
+		return &analysis.AtomicPostProcessingStats{}, err
+	} else if suppressedPrincipals, err := options.fetchSuppressedPrincipals(ctx, db); err != nil {
+		return &analysis.AtomicPostProcessingStats{}, err
+	} else if collectors, err := options.loadCollectors(ctx, db); err != nil {
+		return &analysis.AtomicPostProcessingStats{}, err
+	} else {
+		var (
+			threadSafeLocalGroupExpansions = impact.NewThreadSafeAggregator(localGroupExpansions)
+			bitmapPool                     = adAnalysis.NewBitmapPool()
+			aggregateStats                 = analysis.NewAtomicPostProcessingStats()
+			windowSize                     = options.WindowSize
+		)
+
+		if windowSize <= 0 || windowSize > len(computers) {
+			windowSize = len(computers)
+		}
+
+		for windowStart := 0; windowStart < len(computers); windowStart += windowSize {
+			var (
+				windowEnd = windowStart + windowSize
+				operation = analysis.NewPostRelationshipOperation(ctx, db, "LocalGroup Post Processing")
+			)
+
+			if windowEnd > len(computers) {
+				windowEnd = len(computers)
+			}
+
+			for idx := windowStart; idx < windowEnd; idx++ {
+				if idx > 0 && idx%10000 == 0 {
+					log.Infof("Post processed %d active directory computers", idx)
 				}
 
-				return nil
-			}); err != nil {
-				return &analysis.AtomicPostProcessingStats{}, fmt.Errorf("failed submitting reader for operation involving computer %d: %w", computerID, err)
+				if err := options.submitLocalGroupReaders(&operation, graph.ID(computers[idx]), threadSafeLocalGroupExpansions, suppressedPrincipals, collectors, bitmapPool); err != nil {
+					return &analysis.AtomicPostProcessingStats{}, err
+				}
 			}
+
+			// Every edge of the window is written before the next window's readers are submitted
+			if err := operation.Done(); err != nil {
+				aggregateStats.Merge(&operation.Stats)
+				return &aggregateStats, err
+			}
+
+			aggregateStats.Merge(&operation.Stats)
+		}
+
+		log.Infof("Finished post-processing %d active directory computers", len(computers))
+		return &aggregateStats, nil
+	}
+}
+
+// ErrPointInTimeUnsupported is returned by PostWithOptions when PostOptions.AsOf is set
+var ErrPointInTimeUnsupported = fmt.Errorf("%w: post-processing against a past graph state", graph.ErrUnsupportedDatabaseOperation)
+
+// PostOptions gates the optional, derived edge computations performed by PostWithOptions
+type PostOptions struct {
+	LocalGroups LocalGroupPostProcessingOptions
+
+	// WellKnownRIDs identifies the built-in groups and principals that post-processing looks up by RID, for
+	// environments that renumber them. The zero value uses adAnalysis.DefaultWellKnownRIDs. It is also used for
+	// LocalGroups when LocalGroups.WellKnownRIDs is not set.
+	WellKnownRIDs adAnalysis.WellKnownRIDs
+
+	// DeriveLAPSReadFromGenericAll emits ReadLAPSPassword edges from principals holding GenericAll over LAPS enabled
+	// computers. These edges describe a derived rather than a collected capability and are therefore opt-in.
+	DeriveLAPSReadFromGenericAll bool
+
+	// PrivilegedGroupCapabilities is the table of built-in groups, and the relationships emitted from them, used by
+	// privileged built-in group post-processing. When empty the capabilities of WellKnownRIDs are used. Entries
+	// emitting a disabled kind are left out.
+	PrivilegedGroupCapabilities []adAnalysis.PrivilegedGroupCapability
+
+	// DomainControllers is a precomputed domain controller set, typically from adAnalysis.FetchDomainControllerSet,
+	// shared by the post-processors that single out domain controllers. When nil PostWithOptions detects domain
+	// controllers once with WellKnownRIDs and shares the result across the run.
+	DomainControllers *adAnalysis.DomainControllerSet
+
+	// SIDResolver maps SIDs to the principals carrying them for the post-processors that look principals up by SID.
+	// When nil PostWithOptions fetches an adAnalysis.CachedSIDResolver once and shares it across the run.
+	SIDResolver adAnalysis.SIDResolver
+
+	// Collectors attributes computed edges to the collectors that ingested their nodes. When nil PostWithOptions loads
+	// it once and shares it across the run.
+	Collectors analysis.CollectorAttribution
+
+	// DeriveSharedAdminLateral emits SharedAdminLateral edges between computers sharing an AdminTo holder. The number of
+	// edges grows with the square of the computers each holder administers, so this is opt-in.
+	DeriveSharedAdminLateral bool
+
+	// DeriveCanTakeOver emits CanTakeOver edges summarizing the full control primitives each principal holds over an
+	// object. Every GenericAll, Owns, WriteDACL and WriteOwner edge is read, so this is opt-in.
+	DeriveCanTakeOver bool
+
+	// DeriveEnterpriseAdminsAdminTo emits AdminTo from each forest's Enterprise Admins group to every computer in the
+	// forest. These edges describe the group's implied membership in every domain's Administrators group rather than a
+	// collected local group membership, and number the computers of each forest, so this is opt-in.
+	DeriveEnterpriseAdminsAdminTo bool
+
+	// DeriveDCSyncFromDACLControl also emits DCSync from principals holding WriteDacl or GenericAll over a domain, as
+	// they can grant themselves the replication rights. These edges are stamped as derived from DACL modification.
+	DeriveDCSyncFromDACLControl bool
+
+	// AdminEscalationPrivileges lists the user rights assignment kinds treated as equivalent to local admin on the
+	// computer they are assigned on. adAnalysis.EscalationPrivilegeRelationships is used when empty.
+	AdminEscalationPrivileges []graph.Kind
+
+	// ExchangeDCSyncGroups, when set, also emits DCSync from the Exchange security groups it matches that hold
+	// GetChangesAll over a domain, and from their members. These edges are stamped as derived from Exchange replication.
+	// adAnalysis.DefaultExchangeGroupHeuristic matches the groups created by Exchange setup.
+	ExchangeDCSyncGroups *adAnalysis.ExchangeGroupHeuristic
+
+	// NoDelete skips the deletion of relationships left by earlier runs so that they remain next to the relationships
+	// created by this run, for instance to compare the output of changed analysis logic against the previous output.
+	// Relationships from both runs can be told apart by their lastseen and analysis version properties. Processors that
+	// skip pairs already joined by the kind they emit will not recreate those relationships.
+	NoDelete bool
+
+	// Config disables post-processors by the kinds they emit. Relationships of a disabled kind are still purged, unless
+	// NoDelete is set, so that edges from earlier runs do not linger.
+	Config adAnalysis.PostProcessingConfig
+
+	// Metrics, when set, is sent the number of edges deleted and created by each post-processing stage along with the
+	// time each stage took. Stage names match the names given to the stage's post-processing operation.
+	Metrics analysis.MetricsRecorder
+
+	// AsOf, when set, requests that relationships be computed from the graph as it was at the given time. This needs a
+	// backend that retains superseded and deleted nodes and relationships along with the period each was valid for, so
+	// that every fetch can be scoped to the objects valid at AsOf. None of the current graph drivers do: they keep only
+	// the latest version of each object, and lastseen records when an object was last collected rather than when it
+	// stopped being valid. PostWithOptions fails with ErrPointInTimeUnsupported, before deleting anything, when this
+	// is set.
+	AsOf time.Time
+}
+
+func (s PostOptions) enabledPrivilegedGroupCapabilities() []adAnalysis.PrivilegedGroupCapability {
+	capabilities := s.PrivilegedGroupCapabilities
+
+	if len(capabilities) == 0 {
+		capabilities = s.WellKnownRIDs.OrDefault().PrivilegedGroupCapabilities()
+	}
+
+	enabled := make([]adAnalysis.PrivilegedGroupCapability, 0, len(capabilities))
+
+	for _, capability := range capabilities {
+		if s.Config.Enabled(capability.Kind) {
+			enabled = append(enabled, capability)
 		}
+	}
 
-		log.Infof("Finished post-processing %d active directory computers", computers.GetCardinality())
-		return &operation.Stats, operation.Done()
+	return enabled
+}
+
+// privilegedGroupKinds returns the distinct kinds emitted by the given capabilities
+func privilegedGroupKinds(capabilities []adAnalysis.PrivilegedGroupCapability) []graph.Kind {
+	var kinds graph.Kinds
+
+	for _, capability := range capabilities {
+		if !kinds.ContainsOneOf(capability.Kind) {
+			kinds = append(kinds, capability.Kind)
+		}
+	}
+
+	return kinds
+}
+
+func (s PostOptions) enabledLocalGroupOptions() LocalGroupPostProcessingOptions {
+	var (
+		options = s.LocalGroups
+		kinds   = options.Kinds
+	)
+
+	if len(kinds) == 0 {
+		kinds = []graph.Kind{ad.CanRDP, ad.AdminTo, ad.CanPSRemote, ad.ExecuteDCOM}
+	}
+
+	options.Kinds = make([]graph.Kind, 0, len(kinds))
+
+	for _, kind := range kinds {
+		if s.Config.Enabled(kind) {
+			options.Kinds = append(options.Kinds, kind)
+		}
 	}
+
+	return options
+}
+
+type postStep struct {
+	name  string
+	emits []graph.Kind
+	post  func(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error)
 }
 
 func Post(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
-	aggregateStats := analysis.NewAtomicPostProcessingStats()
+	return PostWithOptions(ctx, db, PostOptions{})
+}
+
+// RunPostProcessors verifies that every post-processed kind is registered in the graph schema, loads the post-processing
+// configuration stored in the graph and runs every post-processor that has not been disabled by it
+func RunPostProcessors(ctx context.Context, db graph.Database, options PostOptions) (*analysis.AtomicPostProcessingStats, error) {
+	if err := analysis.EnsureKindsRegistered(adAnalysis.PostProcessedRelationships()); err != nil {
+		stats := analysis.NewAtomicPostProcessingStats()
+		return &stats, err
+	} else if config, err := adAnalysis.LoadPostProcessingConfig(ctx, db); err != nil {
+		stats := analysis.NewAtomicPostProcessingStats()
+		return &stats, fmt.Errorf("failed loading post-processing configuration: %w", err)
+	} else {
+		if disabledKinds := config.DisabledKinds(); len(disabledKinds) > 0 {
+			log.Infof("Post-processing is disabled for kinds: %s", disabledKinds.Strings())
+		}
+
+		options.Config = config
+		return PostWithOptions(ctx, db, options)
+	}
+}
+
+// deletePostProcessedRelationships removes the relationships created by earlier post-processing runs, including the
+// derived relationships of self cleaning processors
+func deletePostProcessedRelationships(ctx context.Context, db graph.Database) (analysis.AtomicPostProcessingStats, error) {
+	deleteStats := analysis.NewAtomicPostProcessingStats()
+
 	if stats, err := analysis.DeleteTransitEdges(ctx, db, ad.Entity, ad.Entity, adAnalysis.PostProcessedRelationships()...); err != nil {
-		return &aggregateStats, err
-	} else if dcSyncStats, err := adAnalysis.PostDCSync(ctx, db); err != nil {
-		return &aggregateStats, err
-	} else if syncLAPSStats, err := adAnalysis.PostSyncLAPSPassword(ctx, db); err != nil {
-		return &aggregateStats, err
-	} else if localGroupStats, err := PostLocalGroups(ctx, db); err != nil {
-		return &aggregateStats, err
+		return deleteStats, err
+	} else if hybridDeleteStats, err := analysis.DeleteTransitEdges(ctx, db, ad.Entity, azure.Entity, ad.SyncedToEntraUser, ad.SyncedToEntraRole); err != nil {
+		return deleteStats, err
+	} else if lapsReadDeleteStats, err := adAnalysis.DeleteDerivedLAPSReadEdges(ctx, db); err != nil {
+		return deleteStats, err
+	} else if forceChangePasswordDeleteStats, err := adAnalysis.DeleteDerivedForceChangePasswordEdges(ctx, db); err != nil {
+		return deleteStats, err
+	} else if gmsaReadDeleteStats, err := adAnalysis.DeleteDerivedGMSAReadEdges(ctx, db); err != nil {
+		return deleteStats, err
+	} else if keyCredentialLinkDeleteStats, err := adAnalysis.DeleteDerivedKeyCredentialLinkEdges(ctx, db); err != nil {
+		return deleteStats, err
+	} else if addAllowedToActDeleteStats, err := adAnalysis.DeleteDerivedAddAllowedToActEdges(ctx, db); err != nil {
+		return deleteStats, err
 	} else {
-		aggregateStats.Merge(stats)
-		aggregateStats.Merge(syncLAPSStats)
-		aggregateStats.Merge(dcSyncStats)
-		aggregateStats.Merge(localGroupStats)
-		return &aggregateStats, nil
+		deleteStats.Merge(stats)
+		deleteStats.Merge(hybridDeleteStats)
+		deleteStats.Merge(lapsReadDeleteStats)
+		deleteStats.Merge(forceChangePasswordDeleteStats)
+		deleteStats.Merge(gmsaReadDeleteStats)
+		deleteStats.Merge(keyCredentialLinkDeleteStats)
+		deleteStats.Merge(addAllowedToActDeleteStats)
+	}
+
+	return deleteStats, nil
+}
+
+func PostWithOptions(ctx context.Context, db graph.Database, options PostOptions) (*analysis.AtomicPostProcessingStats, error) {
+	aggregateStats := analysis.NewAtomicPostProcessingStats()
+
+	if !options.AsOf.IsZero() {
+		return &aggregateStats, fmt.Errorf("%w: requested graph state as of %s", ErrPointInTimeUnsupported, options.AsOf.Format(time.RFC3339))
+	}
+
+	if options.NoDelete {
+		log.Infof("Post-processing without deleting previously computed relationships")
+	} else {
+		measureDelete := analysis.MeasureStage(options.Metrics, "Delete Post Processed Edges")
+
+		if deleteStats, err := deletePostProcessedRelationships(ctx, db); err != nil {
+			return &aggregateStats, err
+		} else {
+			measureDelete()
+			deleteStats.Record(options.Metrics)
+			aggregateStats.Merge(&deleteStats)
+		}
+	}
+
+	// Authenticated Users meta-nodes are created once, ahead of every step, so that edges derived from Everyone are
+	// reachable from them through MemberOf
+	if err := adAnalysis.EnsureBroadPrincipalMetaNodes(ctx, db); err != nil {
+		return &aggregateStats, fmt.Errorf("failed creating broad principal meta-nodes: %w", err)
+	}
+
+	if options.SIDResolver == nil {
+		if sidResolver, err := adAnalysis.FetchSIDResolver(ctx, db); err != nil {
+			return &aggregateStats, fmt.Errorf("failed building SID resolver: %w", err)
+		} else {
+			options.SIDResolver = sidResolver
+		}
+	}
+
+	if options.LocalGroups.SIDResolver == nil {
+		options.LocalGroups.SIDResolver = options.SIDResolver
+	}
+
+	if options.Collectors == nil {
+		if collectors, err := analysis.LoadCollectorAttribution(ctx, db); err != nil {
+			return &aggregateStats, fmt.Errorf("failed loading collector attribution: %w", err)
+		} else {
+			options.Collectors = collectors
+		}
+	}
+
+	if options.LocalGroups.Collectors == nil {
+		options.LocalGroups.Collectors = options.Collectors
+	}
+
+	if options.LocalGroups.WellKnownRIDs == (adAnalysis.WellKnownRIDs{}) {
+		options.LocalGroups.WellKnownRIDs = options.WellKnownRIDs
+	}
+
+	if options.DomainControllers == nil {
+		if domainControllers, err := adAnalysis.FetchDomainControllerSetWithRIDs(ctx, db, options.WellKnownRIDs.OrDefault()); err != nil {
+			return &aggregateStats, fmt.Errorf("failed detecting domain controllers: %w", err)
+		} else {
+			options.DomainControllers = domainControllers
+		}
+	}
+
+	var (
+		localGroups            = options.enabledLocalGroupOptions()
+		privilegedCapabilities = options.enabledPrivilegedGroupCapabilities()
+		derivedEdges           = adAnalysis.DerivedEdgeOptions{
+			Collectors:        options.Collectors,
+			DomainControllers: options.DomainControllers,
+		}
+	)
+
+	steps := []postStep{
+		{name: "DCSync Post Processing", emits: []graph.Kind{ad.DCSync}, post: func(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+			return adAnalysis.PostDCSyncWithOptions(ctx, db, adAnalysis.DCSyncOptions{
+				IncludeDACLControl: options.DeriveDCSyncFromDACLControl,
+				ExchangeGroups:     options.ExchangeDCSyncGroups,
+				SIDResolver:        options.SIDResolver,
+				Collectors:         options.Collectors,
+			})
+		}},
+		{name: "SyncLAPSPassword Post Processing", emits: []graph.Kind{ad.SyncLAPSPassword}, post: adAnalysis.PostSyncLAPSPassword},
+		{name: "Domain Trusts Post Processing", emits: []graph.Kind{ad.SameForestTrust, ad.CrossForestTrust}, post: adAnalysis.PostDomainTrusts},
+		{name: "Owns Implies Control Post Processing", emits: []graph.Kind{ad.ImpliedGenericAll}, post: adAnalysis.PostOwnsImpliesControl},
+		{name: "Hybrid Identity Link Post Processing", emits: []graph.Kind{ad.SyncedToEntraUser}, post: adAnalysis.PostHybridIdentityLink},
+		{name: "Group Synced To Entra Role Post Processing", emits: []graph.Kind{ad.SyncedToEntraRole}, post: adAnalysis.PostGroupSyncedToEntraRole},
+		{name: "ForceChangePassword From GenericAll Post Processing", emits: []graph.Kind{ad.ForceChangePassword}, post: func(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+			return adAnalysis.PostForceChangePasswordFromGenericAllWithOptions(ctx, db, derivedEdges)
+		}},
+
+		// Chains ForceChangePassword into DCSync and must follow both the DCSync step and derived ForceChangePassword
+		{name: "CanDCSyncViaReset Post Processing", emits: []graph.Kind{ad.CanDCSyncViaReset}, post: adAnalysis.PostCanDCSyncViaReset},
+		{name: "ReadGMSAPassword From Control Post Processing", emits: []graph.Kind{ad.ReadGMSAPassword}, post: func(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+			return adAnalysis.PostReadGMSAPasswordFromControlWithOptions(ctx, db, derivedEdges)
+		}},
+		{name: "AddKeyCredentialLink From Control Post Processing", emits: []graph.Kind{ad.AddKeyCredentialLink}, post: func(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+			return adAnalysis.PostAddKeyCredentialLinkFromControlWithOptions(ctx, db, derivedEdges)
+		}},
+		{name: "AddAllowedToAct From Control Post Processing", emits: []graph.Kind{ad.AddAllowedToAct}, post: func(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+			return adAnalysis.PostAddAllowedToActFromControlWithOptions(ctx, db, derivedEdges)
+		}},
+		{name: "WriteGPLink Post Processing", emits: []graph.Kind{ad.CanApplyGPO}, post: adAnalysis.PostWriteGPLink},
+		{name: "Trust Account Compromise Post Processing", emits: []graph.Kind{ad.HasTrustKeys}, post: adAnalysis.PostTrustAccountCompromise},
+		{name: "LocalGroup Post Processing", emits: localGroups.Kinds, post: func(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+			return PostLocalGroupsWithOptions(ctx, db, localGroups)
+		}},
+		{name: "Privileged Builtin Groups Post Processing", emits: privilegedGroupKinds(privilegedCapabilities), post: func(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+			return adAnalysis.PostPrivilegedBuiltinGroupsWithDomainControllers(ctx, db, privilegedCapabilities, options.DomainControllers, options.WellKnownRIDs)
+		}},
+	}
+
+	if options.DeriveCanTakeOver {
+		steps = append(steps, postStep{name: "CanTakeOver Post Processing", emits: []graph.Kind{ad.CanTakeOver}, post: adAnalysis.PostCanTakeOver})
+	}
+
+	if options.DeriveLAPSReadFromGenericAll {
+		steps = append(steps, postStep{name: "ReadLAPSPassword From GenericAll Post Processing", emits: []graph.Kind{ad.ReadLAPSPassword}, post: func(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+			return adAnalysis.PostReadLAPSPasswordFromGenericAllWithOptions(ctx, db, derivedEdges)
+		}})
+	}
+
+	// AdminTo from LAPS password reads must follow ReadLAPSPassword derivation to see derived reads, and follow local
+	// group post-processing so that pairs already joined by AdminTo are skipped
+	steps = append(steps, postStep{name: "AdminTo From ReadLAPSPassword Post Processing", emits: []graph.Kind{ad.AdminTo}, post: func(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+		return adAnalysis.PostAdminToFromLAPSReadWithOptions(ctx, db, derivedEdges)
+	}})
+
+	// AdminTo from escalation privileges follows local group post-processing for the same reason
+	steps = append(steps, postStep{name: "AdminTo From Privileges Post Processing", emits: []graph.Kind{ad.AdminTo}, post: func(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+		return adAnalysis.PostAdminToFromPrivilegesWithOptions(ctx, db, derivedEdges, options.AdminEscalationPrivileges...)
+	}})
+
+	// AdminTo from Enterprise Admins membership follows local group post-processing for the same reason
+	if options.DeriveEnterpriseAdminsAdminTo {
+		steps = append(steps, postStep{name: "Enterprise Admins AdminTo Post Processing", emits: []graph.Kind{ad.AdminTo}, post: func(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+			return adAnalysis.PostEnterpriseAdminsAdminToWithRIDs(ctx, db, options.WellKnownRIDs.OrDefault())
+		}})
+	}
+
+	// Shared admin lateral movement and cross session impersonation are derived from AdminTo and must follow every step
+	// emitting it
+	if options.DeriveSharedAdminLateral {
+		steps = append(steps, postStep{name: "Shared Admin Lateral Movement Post Processing", emits: []graph.Kind{ad.SharedAdminLateral}, post: adAnalysis.PostSharedAdminLateral})
+	}
+
+	steps = append(steps,
+		postStep{name: "AdminToViaGMSA Post Processing", emits: []graph.Kind{ad.AdminToViaGMSA}, post: adAnalysis.PostAdminToViaGMSA},
+		postStep{name: "Cross Session Post Processing", emits: []graph.Kind{ad.CanImpersonate}, post: adAnalysis.PostCrossSession},
+
+		// RDP session capture is derived from CanRDP and must follow local group post-processing
+		postStep{name: "RDP Session Capture Post Processing", emits: []graph.Kind{ad.RDPSessionCapture}, post: adAnalysis.PostRDPSessionCapture},
+	)
+
+	for _, step := range steps {
+		if !options.Config.Enabled(step.emits...) {
+			continue
+		}
+
+		measureStep := analysis.MeasureStage(options.Metrics, step.name)
+
+		if stats, err := step.post(ctx, db); err != nil {
+			return &aggregateStats, err
+		} else if err := adAnalysis.ValidateWrittenKinds(step.name, step.emits, stats); err != nil {
+			return &aggregateStats, err
+		} else {
+			measureStep()
+			stats.Record(options.Metrics)
+			aggregateStats.Merge(stats)
+		}
 	}
+
+	return &aggregateStats, nil
 }
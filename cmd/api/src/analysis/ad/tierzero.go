@@ -26,18 +26,7 @@ import (
 )
 
 func TierZeroWellKnownSIDSuffixes() []string {
-	return []string{
-		analysis.EnterpriseDomainControllersGroupSIDSuffix,
-		analysis.AdministratorAccountSIDSuffix,
-		analysis.DomainAdminsGroupSIDSuffix,
-		analysis.DomainControllersGroupSIDSuffix,
-		analysis.SchemaAdminsGroupSIDSuffix,
-		analysis.EnterpriseAdminsGroupSIDSuffix,
-		analysis.KeyAdminsGroupSIDSuffix,
-		analysis.EnterpriseKeyAdminsGroupSIDSuffix,
-		analysis.BackupOperatorsGroupSIDSuffix,
-		analysis.AdministratorsGroupSIDSuffix,
-	}
+	return analysis.DefaultWellKnownRIDs().TierZeroSIDSuffixes()
 }
 
 func FetchWellKnownTierZeroEntities(tx graph.Transaction, domainSID string) (graph.NodeSet, error) {
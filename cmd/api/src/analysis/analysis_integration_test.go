@@ -24,6 +24,7 @@ import (
 	"testing"
 
 	analysis "github.com/specterops/bloodhound/analysis/ad"
+	"github.com/specterops/bloodhound/dawgs/cardinality"
 	"github.com/specterops/bloodhound/graphschema/ad"
 
 	"github.com/specterops/bloodhound/src/test/integration"
@@ -107,3 +108,247 @@ func TestFetchRDPEntityBitmapForComputer(t *testing.T) {
 		})
 	})
 }
+
+func TestFetchLocalGroupMembershipExcludingDenied(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	testContext.DatabaseTestWithSetup(func(harness *integration.HarnessDetails) {
+		harness.RDP.Setup(testContext)
+	}, func(harness integration.HarnessDetails, db graph.Database) error {
+		require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+			// Without any collected deny rights the result must match FetchLocalGroupMembership
+			members, err := analysis.FetchLocalGroupMembershipExcludingDenied(tx, harness.RDP.Computer.ID, analysis.RDPGroupSuffix)
+			require.Nil(t, err)
+			require.Equal(t, 6, members.Len())
+			require.True(t, members.Contains(harness.RDP.EliUser))
+
+			return nil
+		}))
+
+		require.Nil(t, db.WriteTransaction(context.Background(), func(tx graph.Transaction) error {
+			if _, err := tx.CreateRelationship(harness.RDP.EliUser, harness.RDP.Computer, ad.DenyNetworkLogonPrivilege, graph.NewProperties()); err != nil {
+				return err
+			}
+
+			// A denied interactive logon does not block network logons and must not be subtracted
+			_, err := tx.CreateRelationship(harness.RDP.UliUser, harness.RDP.Computer, ad.DenyInteractiveLogonPrivilege, graph.NewProperties())
+			return err
+		}))
+
+		return db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+			members, err := analysis.FetchLocalGroupMembershipExcludingDenied(tx, harness.RDP.Computer.ID, analysis.RDPGroupSuffix)
+			require.Nil(t, err)
+			require.Equal(t, 5, members.Len())
+			require.False(t, members.Contains(harness.RDP.EliUser))
+			require.True(t, members.Contains(harness.RDP.UliUser))
+
+			// The unfiltered membership is unaffected by the deny right
+			members, err = analysis.FetchLocalGroupMembership(tx, harness.RDP.Computer.ID, analysis.RDPGroupSuffix)
+			require.Nil(t, err)
+			require.Equal(t, 6, members.Len())
+
+			return nil
+		})
+	})
+}
+
+func TestFetchRDPEntityBitmapForComputer_FromGraphSpec(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains:   []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Computers: []integration.ComputerSpec{{Name: "Computer", Domain: "Domain", HasURA: true}},
+		Users: []integration.PrincipalSpec{
+			{Name: "RDPUser", Domain: "Domain"},
+			{Name: "OtherUser", Domain: "Domain"},
+		},
+		LocalGroups: []integration.LocalGroupSpec{
+			{Name: "RemoteDesktopUsers", Computer: "Computer", SIDSuffix: analysis.RDPGroupSuffix, RemoteInteractiveLogon: true},
+		},
+		Memberships: []integration.MembershipSpec{
+			{Member: "RDPUser", Group: "RemoteDesktopUsers"},
+		},
+	})
+
+	groupExpansions, err := analysis.ExpandAllRDPLocalGroups(context.Background(), db)
+	require.Nil(t, err)
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		rdpEnabledEntityIDBitmap, err := analysis.FetchRDPEntityBitmapForComputer(tx, testContext.SpecNode("Computer").ID, groupExpansions)
+		require.Nil(t, err)
+
+		require.True(t, rdpEnabledEntityIDBitmap.Contains(testContext.SpecNode("RDPUser").ID.Uint32()))
+		require.False(t, rdpEnabledEntityIDBitmap.Contains(testContext.SpecNode("OtherUser").ID.Uint32()))
+
+		return nil
+	}))
+}
+
+func TestFetchDirectRDPLogonRightBitmapForComputer(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Computers: []integration.ComputerSpec{
+			{Name: "Computer", Domain: "Domain", HasURA: true},
+			{Name: "ComputerWithoutURA", Domain: "Domain"},
+		},
+		Users: []integration.PrincipalSpec{
+			{Name: "DirectURAUser", Domain: "Domain"},
+			{Name: "OtherUser", Domain: "Domain"},
+		},
+		LocalGroups: []integration.LocalGroupSpec{
+			{Name: "RemoteDesktopUsers", Computer: "Computer", SIDSuffix: analysis.RDPGroupSuffix},
+			{Name: "OtherRemoteDesktopUsers", Computer: "ComputerWithoutURA", SIDSuffix: analysis.RDPGroupSuffix},
+		},
+		Edges: []integration.EdgeSpec{
+			{From: "DirectURAUser", To: "Computer", Kind: ad.RemoteInteractiveLogonPrivilege},
+			{From: "DirectURAUser", To: "ComputerWithoutURA", Kind: ad.RemoteInteractiveLogonPrivilege},
+		},
+	})
+
+	groupExpansions, err := analysis.ExpandAllRDPLocalGroups(context.Background(), db)
+	require.Nil(t, err)
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		// The user holds the right but is not a member of the RDP group, so the membership based logic excludes them
+		rdpEnabledEntityIDBitmap, err := analysis.FetchRDPEntityBitmapForComputer(tx, testContext.SpecNode("Computer").ID, groupExpansions)
+		require.Nil(t, err)
+		require.False(t, rdpEnabledEntityIDBitmap.Contains(testContext.SpecNode("DirectURAUser").ID.Uint32()))
+
+		directEntityIDBitmap, err := analysis.FetchDirectRDPLogonRightBitmapForComputer(tx, testContext.SpecNode("Computer").ID, groupExpansions)
+		require.Nil(t, err)
+		require.True(t, directEntityIDBitmap.Contains(testContext.SpecNode("DirectURAUser").ID.Uint32()))
+		require.False(t, directEntityIDBitmap.Contains(testContext.SpecNode("OtherUser").ID.Uint32()))
+
+		directEntityIDBitmap, err = analysis.FetchDirectRDPLogonRightBitmapForComputer(tx, testContext.SpecNode("ComputerWithoutURA").ID, groupExpansions)
+		require.Nil(t, err)
+		require.Equal(t, uint64(0), directEntityIDBitmap.Cardinality())
+
+		return nil
+	}))
+}
+
+func TestSubtractDeniedRDPEntities(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains:   []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Computers: []integration.ComputerSpec{{Name: "Computer", Domain: "Domain", HasURA: true}},
+		Users: []integration.PrincipalSpec{
+			{Name: "AllowedUser", Domain: "Domain"},
+			{Name: "DeniedUser", Domain: "Domain"},
+			{Name: "DeniedGroupMember", Domain: "Domain"},
+		},
+		Groups: []integration.PrincipalSpec{{Name: "DeniedGroup", Domain: "Domain"}},
+		LocalGroups: []integration.LocalGroupSpec{
+			{Name: "RemoteDesktopUsers", Computer: "Computer", SIDSuffix: analysis.RDPGroupSuffix},
+		},
+		Edges: []integration.EdgeSpec{
+			{From: "DeniedGroupMember", To: "DeniedGroup", Kind: ad.MemberOf},
+			{From: "AllowedUser", To: "RemoteDesktopUsers", Kind: ad.MemberOfLocalGroup},
+			{From: "DeniedUser", To: "RemoteDesktopUsers", Kind: ad.MemberOfLocalGroup},
+			{From: "DeniedGroupMember", To: "RemoteDesktopUsers", Kind: ad.MemberOfLocalGroup},
+			{From: "RemoteDesktopUsers", To: "Computer", Kind: ad.RemoteInteractiveLogonPrivilege},
+			{From: "DeniedUser", To: "Computer", Kind: ad.DenyRemoteInteractiveLogonPrivilege},
+			{From: "DeniedGroup", To: "Computer", Kind: ad.DenyRemoteInteractiveLogonPrivilege},
+		},
+	})
+
+	groupExpansions, err := analysis.ExpandAllRDPLocalGroups(context.Background(), db)
+	require.Nil(t, err)
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		computerID := testContext.SpecNode("Computer").ID
+
+		// Every member of the RDP group is allowed before deny rights are considered
+		rdpEnabledEntityIDBitmap, err := analysis.FetchRDPEntityBitmapForComputer(tx, computerID, groupExpansions)
+		require.Nil(t, err)
+		require.True(t, rdpEnabledEntityIDBitmap.Contains(testContext.SpecNode("AllowedUser").ID.Uint32()))
+		require.True(t, rdpEnabledEntityIDBitmap.Contains(testContext.SpecNode("DeniedUser").ID.Uint32()))
+		require.True(t, rdpEnabledEntityIDBitmap.Contains(testContext.SpecNode("DeniedGroupMember").ID.Uint32()))
+
+		require.Nil(t, analysis.SubtractDeniedRDPEntities(tx, computerID, rdpEnabledEntityIDBitmap, groupExpansions))
+		require.True(t, rdpEnabledEntityIDBitmap.Contains(testContext.SpecNode("AllowedUser").ID.Uint32()))
+		require.False(t, rdpEnabledEntityIDBitmap.Contains(testContext.SpecNode("DeniedUser").ID.Uint32()))
+		require.False(t, rdpEnabledEntityIDBitmap.Contains(testContext.SpecNode("DeniedGroupMember").ID.Uint32()))
+
+		return nil
+	}))
+}
+
+func TestExpandRDPLocalGroupsForDomain(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{
+			{Name: "Domain", Collected: true},
+			{Name: "OtherDomain", Collected: true},
+		},
+		Computers: []integration.ComputerSpec{
+			{Name: "Computer", Domain: "Domain"},
+			{Name: "OtherComputer", Domain: "OtherDomain"},
+		},
+		Users: []integration.PrincipalSpec{
+			{Name: "User", Domain: "Domain"},
+			{Name: "OtherUser", Domain: "OtherDomain"},
+		},
+		LocalGroups: []integration.LocalGroupSpec{
+			{Name: "RemoteDesktopUsers", Computer: "Computer", SIDSuffix: analysis.RDPGroupSuffix},
+			{Name: "OtherRemoteDesktopUsers", Computer: "OtherComputer", SIDSuffix: analysis.RDPGroupSuffix},
+		},
+		Memberships: []integration.MembershipSpec{
+			{Member: "User", Group: "RemoteDesktopUsers"},
+			{Member: "OtherUser", Group: "OtherRemoteDesktopUsers"},
+		},
+	})
+
+	domainSID, err := testContext.SpecNode("Domain").Properties.Get(ad.DomainSID.String()).String()
+	require.Nil(t, err)
+
+	groupExpansions, err := analysis.ExpandRDPLocalGroupsForDomain(context.Background(), db, domainSID)
+	require.Nil(t, err)
+
+	members := groupExpansions.Cardinality(testContext.SpecNode("RemoteDesktopUsers").ID.Uint32())
+	require.True(t, members.(cardinality.Duplex[uint32]).Contains(testContext.SpecNode("User").ID.Uint32()))
+
+	otherMembers := groupExpansions.Cardinality(testContext.SpecNode("OtherRemoteDesktopUsers").ID.Uint32())
+	require.Equal(t, uint64(0), otherMembers.Cardinality())
+}
+
+func TestFetchFirstDegreeLocalGroupMembers(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Computers: []integration.ComputerSpec{
+			{Name: "Computer", Domain: "Domain"},
+			{Name: "MemberComputer", Domain: "Domain"},
+		},
+		Users: []integration.PrincipalSpec{
+			{Name: "DirectUser", Domain: "Domain"},
+			{Name: "NestedUser", Domain: "Domain"},
+		},
+		Groups: []integration.PrincipalSpec{{Name: "Group", Domain: "Domain"}},
+		LocalGroups: []integration.LocalGroupSpec{
+			{Name: "DistributedCOMUsers", Computer: "Computer", SIDSuffix: "-562"},
+		},
+		Memberships: []integration.MembershipSpec{
+			{Member: "DirectUser", Group: "DistributedCOMUsers"},
+			{Member: "Group", Group: "DistributedCOMUsers"},
+			{Member: "MemberComputer", Group: "DistributedCOMUsers"},
+			{Member: "NestedUser", Group: "Group"},
+		},
+	})
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		members, err := analysis.FetchFirstDegreeLocalGroupMembers(tx, testContext.SpecNode("DistributedCOMUsers").ID)
+		require.Nil(t, err)
+		require.Equal(t, uint64(3), members.Cardinality())
+
+		for _, member := range []string{"DirectUser", "Group", "MemberComputer"} {
+			require.Truef(t, members.Contains(testContext.SpecNode(member).ID.Uint32()), "%s is missing from the first degree members", member)
+		}
+
+		// The computer level helper resolves the same local group by its SID suffix
+		computerMembers, err := analysis.FetchLocalGroupBitmapForComputer(tx, testContext.SpecNode("Computer").ID, "-562")
+		require.Nil(t, err)
+		require.Equal(t, members.Slice(), computerMembers.Slice())
+
+		return nil
+	}))
+}
@@ -26,9 +26,12 @@ import (
 	"github.com/specterops/bloodhound/src/test/integration"
 	"github.com/stretchr/testify/require"
 	analysis "github.com/specterops/bloodhound/analysis/ad"
+	"github.com/specterops/bloodhound/dawgs/cardinality"
 	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/dawgs/ops"
 	"github.com/specterops/bloodhound/dawgs/query"
 	"github.com/specterops/bloodhound/graphschema/ad"
+	adPost "github.com/specterops/bloodhound/src/analysis/ad"
 )
 
 func TestRealizeNodeKindDuplexMap(t *testing.T) {
@@ -90,3 +93,262 @@ func TestResolveAllGroupMemberships(t *testing.T) {
 		return err
 	})
 }
+
+func TestResolveAllGroupMemberships_DomainGroupInLocalGroup(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains:   []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Computers: []integration.ComputerSpec{{Name: "Computer", Domain: "Domain"}},
+		Users:     []integration.PrincipalSpec{{Name: "User", Domain: "Domain"}},
+		Groups: []integration.PrincipalSpec{
+			{Name: "Group", Domain: "Domain"},
+			{Name: "NestedGroup", Domain: "Domain"},
+		},
+		LocalGroups: []integration.LocalGroupSpec{
+			{Name: "Administrators", Computer: "Computer", SIDSuffix: analysis.AdminGroupSuffix},
+		},
+		Memberships: []integration.MembershipSpec{
+			{Member: "Group", Group: "Administrators"},
+			{Member: "NestedGroup", Group: "Group"},
+			{Member: "User", Group: "NestedGroup"},
+		},
+	})
+
+	memberships, err := analysis.ResolveAllGroupMemberships(context.Background(), db)
+	require.Nil(t, err)
+
+	// The expansion must cross from MemberOfLocalGroup into MemberOf and keep descending through nested domain groups
+	administrators := memberships.Cardinality(testContext.SpecNode("Administrators").ID.Uint32())
+	require.Equal(t, uint64(3), administrators.Cardinality())
+
+	for _, member := range []string{"Group", "NestedGroup", "User"} {
+		require.Truef(t, administrators.(cardinality.Duplex[uint32]).Contains(testContext.SpecNode(member).ID.Uint32()), "%s is missing from the local Administrators expansion", member)
+	}
+
+	_, err = adPost.PostLocalGroups(context.Background(), db)
+	require.Nil(t, err)
+
+	// AdminTo is emitted from the first degree member and the user reaches the computer through its group memberships
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		admins, err := ops.FetchStartNodes(tx.Relationships().Filterf(func() graph.Criteria {
+			return query.And(
+				query.Kind(query.Relationship(), ad.AdminTo),
+				query.Equals(query.EndID(), testContext.SpecNode("Computer").ID),
+			)
+		}))
+		require.Nil(t, err)
+		require.Equal(t, 1, admins.Len())
+		require.True(t, admins.Contains(testContext.SpecNode("Group")))
+
+		groupMembers, err := analysis.ExpandGroupMembershipIDBitmap(tx, testContext.SpecNode("Group"))
+		require.Nil(t, err)
+		require.True(t, groupMembers.Contains(testContext.SpecNode("User").ID.Uint64()))
+
+		return nil
+	}))
+}
+
+func TestResolveAllGroupMembershipsWithMaxHops(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Users:   []integration.PrincipalSpec{{Name: "User", Domain: "Domain"}},
+		Groups: []integration.PrincipalSpec{
+			{Name: "GroupA", Domain: "Domain"},
+			{Name: "GroupB", Domain: "Domain"},
+			{Name: "GroupC", Domain: "Domain"},
+		},
+		Memberships: []integration.MembershipSpec{
+			{Member: "GroupB", Group: "GroupA"},
+			{Member: "GroupC", Group: "GroupB"},
+			{Member: "User", Group: "GroupC"},
+		},
+	})
+
+	groupA := testContext.SpecNode("GroupA").ID.Uint32()
+
+	unbounded, err := analysis.ResolveAllGroupMembershipsWithMaxHops(context.Background(), db, 0)
+	require.Nil(t, err)
+	require.Equal(t, uint64(3), unbounded.Cardinality(groupA).Cardinality())
+
+	// Two hops reach GroupB and GroupC but leave the user nested under GroupC out
+	bounded, err := analysis.ResolveAllGroupMembershipsWithMaxHops(context.Background(), db, 2)
+	require.Nil(t, err)
+
+	groupAMembers := bounded.Cardinality(groupA).(cardinality.Duplex[uint32])
+	require.Equal(t, uint64(2), groupAMembers.Cardinality())
+	require.True(t, groupAMembers.Contains(testContext.SpecNode("GroupC").ID.Uint32()))
+	require.False(t, groupAMembers.Contains(testContext.SpecNode("User").ID.Uint32()))
+}
+
+func TestResolveAllGroupMembershipsWithMaxHops_SharedChain(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Users:   []integration.PrincipalSpec{{Name: "User", Domain: "Domain"}},
+		Groups: []integration.PrincipalSpec{
+			{Name: "RootA", Domain: "Domain"},
+			{Name: "RootB", Domain: "Domain"},
+			{Name: "GroupA", Domain: "Domain"},
+			{Name: "GroupB", Domain: "Domain"},
+			{Name: "GroupC", Domain: "Domain"},
+		},
+		Memberships: []integration.MembershipSpec{
+			{Member: "GroupA", Group: "RootA"},
+			{Member: "GroupB", Group: "GroupA"},
+			{Member: "GroupC", Group: "GroupB"},
+			{Member: "User", Group: "GroupC"},
+			{Member: "GroupB", Group: "RootB"},
+		},
+	})
+
+	memberIDs := func(names ...string) []uint32 {
+		ids := make([]uint32, 0, len(names))
+
+		for _, name := range names {
+			ids = append(ids, testContext.SpecNode(name).ID.Uint32())
+		}
+
+		return ids
+	}
+
+	// The chain below GroupB is reached in one hop from RootB and in two from RootA. Each root keeps to the budget no
+	// matter which of them reaches the chain first.
+	for run := 0; run < 5; run++ {
+		bounded, err := analysis.ResolveAllGroupMembershipsWithMaxHops(context.Background(), db, 2)
+		require.Nil(t, err)
+
+		require.ElementsMatch(t, memberIDs("GroupA", "GroupB"), bounded.Cardinality(testContext.SpecNode("RootA").ID.Uint32()).(cardinality.Duplex[uint32]).Slice())
+		require.ElementsMatch(t, memberIDs("GroupB", "GroupC"), bounded.Cardinality(testContext.SpecNode("RootB").ID.Uint32()).(cardinality.Duplex[uint32]).Slice())
+		require.ElementsMatch(t, memberIDs("GroupB", "GroupC"), bounded.Cardinality(testContext.SpecNode("GroupA").ID.Uint32()).(cardinality.Duplex[uint32]).Slice())
+		require.ElementsMatch(t, memberIDs("GroupC", "User"), bounded.Cardinality(testContext.SpecNode("GroupB").ID.Uint32()).(cardinality.Duplex[uint32]).Slice())
+	}
+}
+
+func TestResolveChangedGroupMemberships(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains: []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Users: []integration.PrincipalSpec{
+			{Name: "User", Domain: "Domain"},
+			{Name: "AddedUser", Domain: "Domain"},
+			{Name: "NestedUser", Domain: "Domain"},
+		},
+		Groups: []integration.PrincipalSpec{
+			{Name: "GroupA", Domain: "Domain"},
+			{Name: "GroupB", Domain: "Domain"},
+			{Name: "GroupC", Domain: "Domain"},
+			{Name: "GroupD", Domain: "Domain"},
+			{Name: "GroupE", Domain: "Domain"},
+		},
+		Memberships: []integration.MembershipSpec{
+			{Member: "GroupB", Group: "GroupA"},
+			{Member: "GroupC", Group: "GroupB"},
+			{Member: "User", Group: "GroupC"},
+			{Member: "GroupC", Group: "GroupE"},
+			{Member: "NestedUser", Group: "GroupD"},
+		},
+	})
+
+	groupIDs := func(names ...string) []graph.ID {
+		ids := make([]graph.ID, len(names))
+
+		for idx, name := range names {
+			ids[idx] = testContext.SpecNode(name).ID
+		}
+
+		return ids
+	}
+
+	memberships, err := analysis.ResolveAllGroupMemberships(context.Background(), db)
+	require.Nil(t, err)
+
+	// Resolve everything once so that the update starts from resolved memberships
+	for _, groupID := range groupIDs("GroupA", "GroupB", "GroupC", "GroupD", "GroupE") {
+		memberships.Cardinality(groupID.Uint32())
+	}
+
+	// GroupA loses GroupB, GroupB gains the already populated GroupD and GroupC gains a user
+	require.Nil(t, db.WriteTransaction(context.Background(), func(tx graph.Transaction) error {
+		if err := tx.Relationships().Filterf(func() graph.Criteria {
+			return query.And(
+				query.Kind(query.Relationship(), ad.MemberOf),
+				query.Equals(query.StartID(), testContext.SpecNode("GroupB").ID),
+				query.Equals(query.EndID(), testContext.SpecNode("GroupA").ID),
+			)
+		}).Delete(); err != nil {
+			return err
+		} else if _, err := tx.CreateRelationshipByIDs(testContext.SpecNode("GroupD").ID, testContext.SpecNode("GroupB").ID, ad.MemberOf, graph.NewProperties()); err != nil {
+			return err
+		} else {
+			_, err := tx.CreateRelationshipByIDs(testContext.SpecNode("AddedUser").ID, testContext.SpecNode("GroupC").ID, ad.MemberOf, graph.NewProperties())
+			return err
+		}
+	}))
+
+	incremental, err := analysis.ResolveChangedGroupMemberships(context.Background(), db, memberships, groupIDs("GroupA", "GroupB", "GroupC"))
+	require.Nil(t, err)
+
+	rebuilt, err := analysis.ResolveAllGroupMemberships(context.Background(), db)
+	require.Nil(t, err)
+
+	for _, name := range []string{"GroupA", "GroupB", "GroupC", "GroupD", "GroupE"} {
+		var (
+			groupID            = testContext.SpecNode(name).ID.Uint32()
+			incrementalMembers = incremental.Cardinality(groupID).(cardinality.Duplex[uint32])
+			rebuiltMembers     = rebuilt.Cardinality(groupID).(cardinality.Duplex[uint32])
+		)
+
+		require.ElementsMatchf(t, rebuiltMembers.Slice(), incrementalMembers.Slice(), "memberships of %s differ", name)
+	}
+
+	groupBMembers := incremental.Cardinality(testContext.SpecNode("GroupB").ID.Uint32()).(cardinality.Duplex[uint32])
+	require.True(t, groupBMembers.Contains(testContext.SpecNode("NestedUser").ID.Uint32()))
+	require.True(t, groupBMembers.Contains(testContext.SpecNode("AddedUser").ID.Uint32()))
+	require.Equal(t, uint64(0), incremental.Cardinality(testContext.SpecNode("GroupA").ID.Uint32()).Cardinality())
+}
+
+func TestExpandLocalGroupMembership_Cycle(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+	db := testContext.BuildADTestGraph(integration.GraphSpec{
+		Domains:   []integration.DomainSpec{{Name: "Domain", Collected: true}},
+		Computers: []integration.ComputerSpec{{Name: "Computer", Domain: "Domain"}},
+		Users:     []integration.PrincipalSpec{{Name: "User", Domain: "Domain"}},
+		Groups: []integration.PrincipalSpec{
+			{Name: "GroupA", Domain: "Domain"},
+			{Name: "GroupB", Domain: "Domain"},
+		},
+		LocalGroups: []integration.LocalGroupSpec{
+			{Name: "Administrators", Computer: "Computer", SIDSuffix: analysis.AdminGroupSuffix},
+		},
+		Memberships: []integration.MembershipSpec{
+			{Member: "GroupA", Group: "Administrators"},
+			{Member: "GroupB", Group: "GroupA"},
+			{Member: "User", Group: "GroupB"},
+
+			// Deliberate cycles: the local group is nested back into its own member and the domain groups contain
+			// each other
+			{Member: "Administrators", Group: "GroupB"},
+			{Member: "GroupA", Group: "GroupB"},
+		},
+	})
+
+	require.Nil(t, db.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+		administrators := testContext.SpecNode("Administrators")
+
+		paths, err := analysis.ExpandLocalGroupMembershipPaths(tx, graph.NewNodeSet(administrators))
+		require.Nil(t, err)
+
+		// Neither cycle is followed, which leaves the single path from the user up to the local group
+		require.Equal(t, 1, paths.Len())
+
+		members := paths.AllNodes()
+		require.Equal(t, 4, members.Len())
+
+		for _, name := range []string{"Administrators", "GroupA", "GroupB", "User"} {
+			require.Truef(t, members.Contains(testContext.SpecNode(name)), "%s is missing from the expansion", name)
+		}
+
+		return nil
+	}))
+}
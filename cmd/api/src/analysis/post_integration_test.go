@@ -21,13 +21,18 @@ package analysis_test
 
 import (
 	"context"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/specterops/bloodhound/src/test/integration"
 	"github.com/stretchr/testify/require"
 	"github.com/specterops/bloodhound/analysis"
 	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/dawgs/query"
+	"github.com/specterops/bloodhound/dawgs/util/channels"
 	"github.com/specterops/bloodhound/graphschema/ad"
+	"github.com/specterops/bloodhound/graphschema/common"
 )
 
 func FetchNumHarnessNodes(db graph.Database) (int64, error) {
@@ -82,3 +87,146 @@ func TestClearOrphanedNodes(t *testing.T) {
 		return nil
 	})
 }
+
+func BenchmarkPostRelationshipOperation_WriteBatchSize(b *testing.B) {
+	const (
+		numNodes = 1_000
+		numJobs  = 20_000
+	)
+
+	var (
+		testContext = integration.NewGraphTestContext(b)
+		nodeIDs     = make([]graph.ID, 0, numNodes)
+	)
+
+	require.Nil(b, testContext.GraphDB.WriteTransaction(context.Background(), func(tx graph.Transaction) error {
+		for numCreated := 0; numCreated < numNodes; numCreated++ {
+			if node, err := tx.CreateNode(graph.NewProperties(), ad.Entity); err != nil {
+				return err
+			} else {
+				nodeIDs = append(nodeIDs, node.ID)
+			}
+		}
+
+		return nil
+	}))
+
+	b.Cleanup(func() {
+		require.Nil(b, testContext.GraphDB.BatchOperation(context.Background(), func(batch graph.Batch) error {
+			for _, nodeID := range nodeIDs {
+				if err := batch.DeleteNode(nodeID); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}))
+	})
+
+	// Zero leaves flushing to the driver and serves as the baseline for the explicit sizes
+	for _, writeBatchSize := range []int{0, 100, 1_000, 10_000} {
+		b.Run(fmt.Sprintf("WriteBatchSize=%d", writeBatchSize), func(b *testing.B) {
+			for iteration := 0; iteration < b.N; iteration++ {
+				operation := analysis.NewPostRelationshipOperationWithOptions(context.Background(), testContext.GraphDB, "Write Batch Size Benchmark", analysis.PostRelationshipOperationOptions{
+					WriteBatchSize: writeBatchSize,
+				})
+
+				require.Nil(b, operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+					for jobIdx := 0; jobIdx < numJobs; jobIdx++ {
+						if !channels.Submit(ctx, outC, analysis.CreatePostRelationshipJob{
+							FromID: nodeIDs[jobIdx%numNodes],
+							ToID:   nodeIDs[(jobIdx/numNodes+jobIdx+1)%numNodes],
+							Kind:   ad.AdminTo,
+						}) {
+							return nil
+						}
+					}
+
+					return nil
+				}))
+
+				require.Nil(b, operation.Done())
+
+				b.StopTimer()
+				_, err := analysis.DeleteTransitEdges(context.Background(), testContext.GraphDB, ad.Entity, ad.Entity, ad.AdminTo)
+				require.Nil(b, err)
+				b.StartTimer()
+			}
+		})
+	}
+}
+
+func TestWriteRunSummary(t *testing.T) {
+	testContext := integration.NewGraphTestContext(t)
+
+	fetchSummaries := func() []*graph.Node {
+		var summaries []*graph.Node
+
+		require.Nil(t, testContext.GraphDB.ReadTransaction(context.Background(), func(tx graph.Transaction) error {
+			return tx.Nodes().Filterf(func() graph.Criteria {
+				return query.Kind(query.Node(), common.PostProcessingRunSummary)
+			}).Fetch(func(cursor graph.Cursor[*graph.Node]) error {
+				for node := range cursor.Chan() {
+					summaries = append(summaries, node)
+				}
+
+				return cursor.Error()
+			})
+		}))
+
+		return summaries
+	}
+
+	t.Cleanup(func() {
+		require.Nil(t, testContext.GraphDB.WriteTransaction(context.Background(), func(tx graph.Transaction) error {
+			return tx.Nodes().Filterf(func() graph.Criteria {
+				return query.Kind(query.Node(), common.PostProcessingRunSummary)
+			}).Delete()
+		}))
+	})
+
+	firstRunStats := analysis.NewAtomicPostProcessingStats()
+	firstRunStats.AddRelationshipsCreated(ad.DCSync, 3)
+	firstRunStats.AddRelationshipsCreated(ad.AdminTo, 4)
+	firstRunStats.AddRelationshipsDeleted(ad.DCSync, 2)
+
+	require.Nil(t, analysis.WriteRunSummary(context.Background(), testContext.GraphDB, &firstRunStats, 1500*time.Millisecond))
+
+	summaries := fetchSummaries()
+	require.Len(t, summaries, 1)
+
+	properties := summaries[0].Properties
+
+	for property, expected := range map[string]int64{
+		analysis.RunSummaryRelationshipsCreatedProperty:        7,
+		analysis.RunSummaryRelationshipsDeletedProperty:        2,
+		analysis.RunSummaryReaderErrorsProperty:                0,
+		analysis.RunSummaryDurationProperty:                    1500,
+		analysis.RunSummaryCreatedPrefix + ad.DCSync.String():  3,
+		analysis.RunSummaryCreatedPrefix + ad.AdminTo.String(): 4,
+		analysis.RunSummaryDeletedPrefix + ad.DCSync.String():  2,
+	} {
+		actual, err := properties.Get(property).Int64()
+		require.Nil(t, err, property)
+		require.Equal(t, expected, actual, property)
+	}
+
+	_, err := properties.Get(analysis.RunSummaryCompletedAtProperty).Time()
+	require.Nil(t, err)
+
+	// A later run replaces the summary, dropping the per-kind counts of kinds it did not touch
+	secondRunStats := analysis.NewAtomicPostProcessingStats()
+	secondRunStats.AddRelationshipsCreated(ad.CanRDP, 1)
+
+	require.Nil(t, analysis.WriteRunSummary(context.Background(), testContext.GraphDB, &secondRunStats, time.Second))
+
+	summaries = fetchSummaries()
+	require.Len(t, summaries, 1)
+
+	properties = summaries[0].Properties
+
+	numCreated, err := properties.Get(analysis.RunSummaryRelationshipsCreatedProperty).Int64()
+	require.Nil(t, err)
+	require.Equal(t, int64(1), numCreated)
+	require.False(t, properties.Exists(analysis.RunSummaryCreatedPrefix+ad.DCSync.String()))
+}
@@ -163,6 +163,7 @@ type Configuration struct {
 	DisableCypherQC        bool                      `json:"disable_cypher_qc"`
 	DisableMigrations      bool                      `json:"disable_migrations"`
 	TraversalMemoryLimit   uint16                    `json:"traversal_memory_limit"`
+	ExpireComputedEdges    bool                      `json:"expire_computed_edges"`
 }
 
 func (s Configuration) TempDirectory() string {
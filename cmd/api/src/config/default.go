@@ -50,6 +50,7 @@ func NewDefaultConfiguration() (Configuration, error) {
 			DisableCypherQC:        false,
 			DisableMigrations:      false,
 			TraversalMemoryLimit:   2, // 2 GiB by default
+			ExpireComputedEdges:    false,
 			TLS:                    TLSConfiguration{},
 			SAML:                   SAMLConfiguration{},
 			Database: DatabaseConfiguration{
@@ -19,6 +19,7 @@ package datapipe
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/specterops/bloodhound/analysis"
 	adAnalysis "github.com/specterops/bloodhound/analysis/ad"
@@ -32,7 +33,7 @@ import (
 	"github.com/specterops/bloodhound/src/services/dataquality"
 )
 
-func RunAnalysisOperations(ctx context.Context, db database.Database, graphDB graph.Database, _ config.Configuration) error {
+func RunAnalysisOperations(ctx context.Context, db database.Database, graphDB graph.Database, cfg config.Configuration) error {
 	var (
 		collector = &errors.ErrorCollector{}
 	)
@@ -61,16 +62,42 @@ func RunAnalysisOperations(ctx context.Context, db database.Database, graphDB gr
 		collector.Collect(fmt.Errorf("azure tier zero tagging failed: %w", err))
 	}
 
-	if stats, err := ad.Post(ctx, graphDB); err != nil {
+	// Expiry deletes relationships outright, so it only runs when explicitly enabled
+	if cfg.ExpireComputedEdges {
+		if stats, err := analysis.ExpireStaleComputedEdges(ctx, graphDB, adAnalysis.PostProcessedRelationships()...); err != nil {
+			collector.Collect(fmt.Errorf("expiring stale computed edges failed: %w", err))
+		} else {
+			stats.LogStats()
+		}
+	}
+
+	var (
+		postStats  = analysis.NewAtomicPostProcessingStats()
+		postStart  = time.Now()
+		postFailed = false
+	)
+
+	if stats, err := ad.RunPostProcessors(ctx, graphDB, ad.PostOptions{}); err != nil {
 		collector.Collect(fmt.Errorf("error during ad post: %w", err))
+		postFailed = true
 	} else {
 		stats.LogStats()
+		postStats.Merge(stats)
 	}
 
 	if stats, err := azure.Post(ctx, graphDB); err != nil {
 		collector.Collect(fmt.Errorf("error during azure post: %w", err))
+		postFailed = true
 	} else {
 		stats.LogStats()
+		postStats.Merge(stats)
+	}
+
+	// A failed run's stats are partial, so the summary of the last complete run is kept in place of them
+	if !postFailed {
+		if err := analysis.WriteRunSummary(ctx, graphDB, &postStats, time.Since(postStart)); err != nil {
+			collector.Collect(fmt.Errorf("writing post-processing run summary failed: %w", err))
+		}
 	}
 
 	if err := agi.RunAssetGroupIsolationCollections(ctx, db, graphDB, analysis.GetNodeKindDisplayLabel); err != nil {
@@ -54,6 +54,18 @@ func convertComputerData(data []ein.Computer) ConvertedData {
 			if userRight.Privilege == ein.UserRightRemoteInteractiveLogon {
 				converted.RelProps = append(converted.RelProps, ein.ParseUserRightData(userRight, computer, ad.RemoteInteractiveLogonPrivilege)...)
 				baseNodeProp.PropertyMap[ad.HasURA.String()] = true
+			} else if userRight.Privilege == ein.UserRightDenyInteractiveLogon {
+				converted.RelProps = append(converted.RelProps, ein.ParseUserRightData(userRight, computer, ad.DenyInteractiveLogonPrivilege)...)
+			} else if userRight.Privilege == ein.UserRightDenyNetworkLogon {
+				converted.RelProps = append(converted.RelProps, ein.ParseUserRightData(userRight, computer, ad.DenyNetworkLogonPrivilege)...)
+			} else if userRight.Privilege == ein.UserRightDenyRemoteInteractive {
+				converted.RelProps = append(converted.RelProps, ein.ParseUserRightData(userRight, computer, ad.DenyRemoteInteractiveLogonPrivilege)...)
+			} else if userRight.Privilege == ein.UserRightDebug {
+				converted.RelProps = append(converted.RelProps, ein.ParseUserRightData(userRight, computer, ad.DebugPrivilege)...)
+			} else if userRight.Privilege == ein.UserRightTakeOwnership {
+				converted.RelProps = append(converted.RelProps, ein.ParseUserRightData(userRight, computer, ad.TakeOwnershipPrivilege)...)
+			} else if userRight.Privilege == ein.UserRightImpersonate {
+				converted.RelProps = append(converted.RelProps, ein.ParseUserRightData(userRight, computer, ad.ImpersonatePrivilege)...)
 			}
 		}
 
@@ -54,6 +54,7 @@ type GraphTestContext struct {
 	testCtrl     test.Controller
 	tx           graph.Transaction
 	nodesCreated cardinality.Duplex[uint32]
+	specNodes    map[string]*graph.Node
 	Harness      HarnessDetails
 	GraphDB      graph.Database
 }
@@ -0,0 +1,259 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package integration
+
+import (
+	"context"
+	"strings"
+
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/graphschema/ad"
+	"github.com/specterops/bloodhound/graphschema/common"
+	"github.com/specterops/bloodhound/src/test/must"
+	"github.com/stretchr/testify/require"
+)
+
+// GraphSpec declaratively describes an Active Directory graph for post-processing tests. Every node is referred to by
+// the name given to it in the spec and can be fetched after the build with GraphTestContext.SpecNode.
+type GraphSpec struct {
+	Domains     []DomainSpec
+	Computers   []ComputerSpec
+	Users       []PrincipalSpec
+	Groups      []PrincipalSpec
+	LocalGroups []LocalGroupSpec
+	OUs         []OUSpec
+	GPOs        []PrincipalSpec
+
+	// Memberships nests principals into groups. The relationship kind follows the kind of the group: MemberOfLocalGroup
+	// for local groups and MemberOf for everything else.
+	Memberships []MembershipSpec
+
+	// Edges adds arbitrary relationships between named nodes such as GetChanges, ReadLAPSPassword, Contains or
+	// RemoteInteractiveLogonPrivilege.
+	Edges []EdgeSpec
+}
+
+type DomainSpec struct {
+	Name string
+
+	// SID is generated when left empty
+	SID       string
+	Collected bool
+}
+
+type PrincipalSpec struct {
+	Name   string
+	Domain string
+
+	// RID, when set, produces an object ID of the domain SID followed by the RID. This allows well-known principals such
+	// as Domain Admins (-512) to be expressed. A random object ID is generated otherwise.
+	RID string
+
+	// SamAccountName is only set on the node when given
+	SamAccountName string
+}
+
+type ComputerSpec struct {
+	Name    string
+	Domain  string
+	HasLAPS bool
+	HasURA  bool
+}
+
+type LocalGroupSpec struct {
+	Name     string
+	Computer string
+
+	// SIDSuffix is appended to the computer's object ID, for example "-544" for Administrators or "-555" for Remote
+	// Desktop Users
+	SIDSuffix string
+
+	// RemoteInteractiveLogon grants the local group the RemoteInteractiveLogonPrivilege on its computer
+	RemoteInteractiveLogon bool
+}
+
+type OUSpec struct {
+	Name              string
+	Domain            string
+	BlocksInheritance bool
+}
+
+type MembershipSpec struct {
+	Member string
+	Group  string
+}
+
+type EdgeSpec struct {
+	From string
+	To   string
+	Kind graph.Kind
+
+	// Properties are set on the relationship in addition to DefaultRelProperties
+	Properties map[string]any
+}
+
+// BuildADTestGraph clears the graph and creates every node and relationship described by the given spec
+func (s *GraphTestContext) BuildADTestGraph(spec GraphSpec) graph.Database {
+	require.Nil(s.testCtrl, s.GraphDB.WriteTransaction(context.Background(), func(tx graph.Transaction) error {
+		s.tx = tx
+
+		defer func() {
+			s.tx = nil
+		}()
+
+		if err := tx.Nodes().Delete(); err != nil {
+			return err
+		}
+
+		s.buildADTestGraph(spec)
+		return nil
+	}))
+
+	return s.GraphDB
+}
+
+// SpecNode returns the node created for the given name by the last call to BuildADTestGraph
+func (s *GraphTestContext) SpecNode(name string) *graph.Node {
+	node, found := s.specNodes[name]
+	require.Truef(s.testCtrl, found, "No node named %s was created from the test graph spec", name)
+
+	return node
+}
+
+func (s *GraphTestContext) addSpecNode(name string, node *graph.Node) {
+	_, exists := s.specNodes[name]
+	require.Falsef(s.testCtrl, exists, "Duplicate node name %s in the test graph spec", name)
+
+	s.specNodes[name] = node
+}
+
+func (s *GraphTestContext) specDomainSID(domainName string) string {
+	domainSID, err := s.SpecNode(domainName).Properties.Get(ad.DomainSID.String()).String()
+	require.Nilf(s.testCtrl, err, "Domain %s has no domain SID: %v", domainName, err)
+
+	return domainSID
+}
+
+func (s *GraphTestContext) newSpecPrincipal(principal PrincipalSpec, kind graph.Kind) *graph.Node {
+	var (
+		domainSID = s.specDomainSID(principal.Domain)
+		objectID  = strings.ToUpper(must.NewUUIDv4().String())
+	)
+
+	if principal.RID != "" {
+		objectID = domainSID + principal.RID
+	}
+
+	properties := graph.AsProperties(graph.PropertyMap{
+		common.Name:     principal.Name,
+		common.ObjectID: objectID,
+		ad.DomainSID:    domainSID,
+	})
+
+	if principal.SamAccountName != "" {
+		properties.Set(ad.SamAccountName.String(), principal.SamAccountName)
+	}
+
+	return s.NewNode(properties, ad.Entity, kind)
+}
+
+func (s *GraphTestContext) buildADTestGraph(spec GraphSpec) {
+	s.specNodes = map[string]*graph.Node{}
+
+	for _, domain := range spec.Domains {
+		domainSID := domain.SID
+
+		if domainSID == "" {
+			domainSID = RandomDomainSID()
+		}
+
+		s.addSpecNode(domain.Name, s.NewActiveDirectoryDomain(domain.Name, domainSID, false, domain.Collected))
+	}
+
+	for _, computer := range spec.Computers {
+		s.addSpecNode(computer.Name, s.NewNode(graph.AsProperties(graph.PropertyMap{
+			common.Name:     computer.Name,
+			common.ObjectID: must.NewUUIDv4().String(),
+			ad.DomainSID:    s.specDomainSID(computer.Domain),
+			ad.HasLAPS:      computer.HasLAPS,
+			ad.HasURA:       computer.HasURA,
+		}), ad.Entity, ad.Computer))
+	}
+
+	for _, user := range spec.Users {
+		s.addSpecNode(user.Name, s.newSpecPrincipal(user, ad.User))
+	}
+
+	for _, group := range spec.Groups {
+		s.addSpecNode(group.Name, s.newSpecPrincipal(group, ad.Group))
+	}
+
+	for _, gpo := range spec.GPOs {
+		s.addSpecNode(gpo.Name, s.newSpecPrincipal(gpo, ad.GPO))
+	}
+
+	for _, ou := range spec.OUs {
+		s.addSpecNode(ou.Name, s.NewNode(graph.AsProperties(graph.PropertyMap{
+			common.Name:          ou.Name,
+			common.ObjectID:      must.NewUUIDv4().String(),
+			ad.DomainSID:         s.specDomainSID(ou.Domain),
+			ad.BlocksInheritance: ou.BlocksInheritance,
+		}), ad.Entity, ad.OU))
+	}
+
+	for _, localGroup := range spec.LocalGroups {
+		var (
+			computer    = s.SpecNode(localGroup.Computer)
+			domainSID   = computer.Properties.Get(ad.DomainSID.String()).Any()
+			localGroupN = s.NewNode(graph.AsProperties(graph.PropertyMap{
+				common.Name:     localGroup.Name,
+				common.ObjectID: s.NodeObjectID(computer) + localGroup.SIDSuffix,
+				ad.DomainSID:    domainSID,
+			}), ad.Entity, ad.LocalGroup)
+		)
+
+		s.addSpecNode(localGroup.Name, localGroupN)
+		s.NewRelationship(localGroupN, computer, ad.LocalToComputer, DefaultRelProperties)
+
+		if localGroup.RemoteInteractiveLogon {
+			s.NewRelationship(localGroupN, computer, ad.RemoteInteractiveLogonPrivilege, DefaultRelProperties)
+		}
+	}
+
+	for _, membership := range spec.Memberships {
+		var (
+			member = s.SpecNode(membership.Member)
+			group  = s.SpecNode(membership.Group)
+		)
+
+		if group.Kinds.ContainsOneOf(ad.LocalGroup) {
+			s.NewRelationship(member, group, ad.MemberOfLocalGroup, DefaultRelProperties)
+		} else {
+			s.NewRelationship(member, group, ad.MemberOf, DefaultRelProperties)
+		}
+	}
+
+	for _, edge := range spec.Edges {
+		properties := DefaultRelProperties
+
+		if len(edge.Properties) > 0 {
+			properties = DefaultRelProperties.Clone().SetAll(edge.Properties)
+		}
+
+		s.NewRelationship(s.SpecNode(edge.From), s.SpecNode(edge.To), edge.Kind, properties)
+	}
+}
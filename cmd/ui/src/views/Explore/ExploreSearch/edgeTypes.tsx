@@ -34,9 +34,13 @@ export const AllEdgeTypes: Category[] = [
                 name: 'Active Directory Structure',
                 edgeTypes: [
                     ActiveDirectoryRelationshipKind.Contains,
+                    ActiveDirectoryRelationshipKind.CrossForestTrust,
                     ActiveDirectoryRelationshipKind.GPLink,
                     ActiveDirectoryRelationshipKind.HasSIDHistory,
                     ActiveDirectoryRelationshipKind.MemberOf,
+                    ActiveDirectoryRelationshipKind.SameForestTrust,
+                    ActiveDirectoryRelationshipKind.SyncedToEntraRole,
+                    ActiveDirectoryRelationshipKind.SyncedToEntraUser,
                     ActiveDirectoryRelationshipKind.TrustedBy,
                 ],
             },
@@ -44,33 +48,42 @@ export const AllEdgeTypes: Category[] = [
                 name: 'Lateral Movement',
                 edgeTypes: [
                     ActiveDirectoryRelationshipKind.AdminTo,
+                    ActiveDirectoryRelationshipKind.AdminToViaGMSA,
                     ActiveDirectoryRelationshipKind.AllowedToAct,
                     ActiveDirectoryRelationshipKind.AllowedToDelegate,
                     ActiveDirectoryRelationshipKind.CanPSRemote,
                     ActiveDirectoryRelationshipKind.CanRDP,
                     ActiveDirectoryRelationshipKind.ExecuteDCOM,
+                    ActiveDirectoryRelationshipKind.SharedAdminLateral,
                     ActiveDirectoryRelationshipKind.SQLAdmin,
                 ],
             },
             {
                 name: 'Credential Access',
                 edgeTypes: [
+                    ActiveDirectoryRelationshipKind.CanDCSyncViaReset,
+                    ActiveDirectoryRelationshipKind.CanImpersonate,
                     ActiveDirectoryRelationshipKind.DCSync,
                     ActiveDirectoryRelationshipKind.DumpSMSAPassword,
                     ActiveDirectoryRelationshipKind.HasSession,
+                    ActiveDirectoryRelationshipKind.HasTrustKeys,
                     ActiveDirectoryRelationshipKind.ReadGMSAPassword,
                     ActiveDirectoryRelationshipKind.ReadLAPSPassword,
+                    ActiveDirectoryRelationshipKind.RDPSessionCapture,
                     ActiveDirectoryRelationshipKind.SyncLAPSPassword,
                 ],
             },
             {
                 name: 'Basic Object Manipulation',
                 edgeTypes: [
+                    ActiveDirectoryRelationshipKind.AccountOperatorControl,
                     ActiveDirectoryRelationshipKind.AddMember,
                     ActiveDirectoryRelationshipKind.AddSelf,
                     ActiveDirectoryRelationshipKind.AllExtendedRights,
+                    ActiveDirectoryRelationshipKind.CanTakeOver,
                     ActiveDirectoryRelationshipKind.ForceChangePassword,
                     ActiveDirectoryRelationshipKind.GenericAll,
+                    ActiveDirectoryRelationshipKind.ImpliedGenericAll,
                     ActiveDirectoryRelationshipKind.Owns,
                     ActiveDirectoryRelationshipKind.GenericWrite,
                     ActiveDirectoryRelationshipKind.WriteDACL,
@@ -82,7 +95,9 @@ export const AllEdgeTypes: Category[] = [
                 edgeTypes: [
                     ActiveDirectoryRelationshipKind.AddAllowedToAct,
                     ActiveDirectoryRelationshipKind.AddKeyCredentialLink,
+                    ActiveDirectoryRelationshipKind.CanApplyGPO,
                     ActiveDirectoryRelationshipKind.WriteAccountRestrictions,
+                    ActiveDirectoryRelationshipKind.WriteGPLink,
                     ActiveDirectoryRelationshipKind.WriteSPN,
                 ],
             },
@@ -498,6 +498,111 @@ GetChangesInFilteredSet: types.#Kind & {
 	schema: "active_directory"
 }
 
+SameForestTrust: types.#Kind & {
+	symbol: "SameForestTrust"
+	schema: "active_directory"
+}
+
+CrossForestTrust: types.#Kind & {
+	symbol: "CrossForestTrust"
+	schema: "active_directory"
+}
+
+ImpliedGenericAll: types.#Kind & {
+	symbol: "ImpliedGenericAll"
+	schema: "active_directory"
+}
+
+DenyInteractiveLogonPrivilege: types.#Kind & {
+	symbol: "DenyInteractiveLogonPrivilege"
+	schema: "active_directory"
+}
+
+DenyNetworkLogonPrivilege: types.#Kind & {
+	symbol: "DenyNetworkLogonPrivilege"
+	schema: "active_directory"
+}
+
+SyncedToEntraUser: types.#Kind & {
+	symbol: "SyncedToEntraUser"
+	schema: "active_directory"
+}
+
+WriteGPLink: types.#Kind & {
+	symbol: "WriteGPLink"
+	schema: "active_directory"
+}
+
+CanApplyGPO: types.#Kind & {
+	symbol: "CanApplyGPO"
+	schema: "active_directory"
+}
+
+DenyRemoteInteractiveLogonPrivilege: types.#Kind & {
+	symbol: "DenyRemoteInteractiveLogonPrivilege"
+	schema: "active_directory"
+}
+
+HasTrustKeys: types.#Kind & {
+	symbol: "HasTrustKeys"
+	schema: "active_directory"
+}
+
+CanTakeOver: types.#Kind & {
+	symbol: "CanTakeOver"
+	schema: "active_directory"
+}
+
+CanImpersonate: types.#Kind & {
+	symbol: "CanImpersonate"
+	schema: "active_directory"
+}
+
+SharedAdminLateral: types.#Kind & {
+	symbol: "SharedAdminLateral"
+	schema: "active_directory"
+}
+
+RDPSessionCapture: types.#Kind & {
+	symbol: "RDPSessionCapture"
+	schema: "active_directory"
+}
+
+SyncedToEntraRole: types.#Kind & {
+	symbol: "SyncedToEntraRole"
+	schema: "active_directory"
+}
+
+DebugPrivilege: types.#Kind & {
+	symbol: "DebugPrivilege"
+	schema: "active_directory"
+}
+
+TakeOwnershipPrivilege: types.#Kind & {
+	symbol: "TakeOwnershipPrivilege"
+	schema: "active_directory"
+}
+
+ImpersonatePrivilege: types.#Kind & {
+	symbol: "ImpersonatePrivilege"
+	schema: "active_directory"
+}
+
+CanDCSyncViaReset: types.#Kind & {
+	symbol: "CanDCSyncViaReset"
+	schema: "active_directory"
+}
+
+AdminToViaGMSA: types.#Kind & {
+	symbol: "AdminToViaGMSA"
+	schema: "active_directory"
+}
+
+AccountOperatorControl: types.#Kind & {
+	symbol: "AccountOperatorControl"
+	schema: "active_directory"
+}
+
 // Relationship Kinds
 RelationshipKinds: [
 	Owns,
@@ -536,7 +641,28 @@ RelationshipKinds: [
 	MemberOfLocalGroup,
 	RemoteInteractiveLogonPrivilege,
 	SyncLAPSPassword,
-	WriteAccountRestrictions
+	WriteAccountRestrictions,
+	SameForestTrust,
+	CrossForestTrust,
+	ImpliedGenericAll,
+	DenyInteractiveLogonPrivilege,
+	DenyNetworkLogonPrivilege,
+	SyncedToEntraUser,
+	WriteGPLink,
+	CanApplyGPO,
+	DenyRemoteInteractiveLogonPrivilege,
+	HasTrustKeys,
+	CanTakeOver,
+	CanImpersonate,
+	SharedAdminLateral,
+	RDPSessionCapture,
+	SyncedToEntraRole,
+	DebugPrivilege,
+	TakeOwnershipPrivilege,
+	ImpersonatePrivilege,
+	CanDCSyncViaReset,
+	AdminToViaGMSA,
+	AccountOperatorControl
 ]
 
 // ACL Relationships
@@ -561,6 +687,7 @@ ACLRelationships: [
 	WriteAccountRestrictions,
 	SyncLAPSPassword,
 	DCSync,
+	WriteGPLink,
 ]
 
 // Edges that are used in pathfinding
@@ -595,5 +722,20 @@ PathfindingRelationships: [
 	WriteSPN,
 	AddKeyCredentialLink,
 	SyncLAPSPassword,
-	WriteAccountRestrictions
+	WriteAccountRestrictions,
+	SameForestTrust,
+	CrossForestTrust,
+	ImpliedGenericAll,
+	SyncedToEntraUser,
+	WriteGPLink,
+	CanApplyGPO,
+	HasTrustKeys,
+	CanTakeOver,
+	CanImpersonate,
+	SharedAdminLateral,
+	RDPSessionCapture,
+	SyncedToEntraRole,
+	CanDCSyncViaReset,
+	AdminToViaGMSA,
+	AccountOperatorControl
 ]
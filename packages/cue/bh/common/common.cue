@@ -162,8 +162,22 @@ MigrationData: types.#Kind & {
 	representation: "MigrationData"
 }
 
+PostProcessingRunSummary: types.#Kind & {
+	symbol:         "PostProcessingRunSummary"
+	schema:         "common"
+	representation: "PostProcessingRunSummary"
+}
+
+PostProcessingConfig: types.#Kind & {
+	symbol:         "PostProcessingConfig"
+	schema:         "common"
+	representation: "PostProcessingConfig"
+}
+
 NodeKinds: [
 	MigrationData,
+	PostProcessingRunSummary,
+	PostProcessingConfig,
 ]
 
 RelationshipKinds: [
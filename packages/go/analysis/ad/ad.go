@@ -32,12 +32,16 @@ import (
 	"github.com/specterops/bloodhound/log"
 )
 
+// AdminGroupSuffix and RDPGroupSuffix are the default SID suffixes of the local Administrators and Remote Desktop Users
+// groups and match DefaultWellKnownRIDs
 var (
 	ErrNoSuchGroup   = errors.New("no group found")
 	AdminGroupSuffix = "-544"
 	RDPGroupSuffix   = "-555"
 )
 
+// The SID suffixes below are the Windows defaults and match DefaultWellKnownRIDs. Post-processing derives domain
+// relative suffixes from a WellKnownRIDs table instead so that an overridden table is honored.
 const (
 	EnterpriseDomainControllersGroupSIDSuffix = "1-5-9"
 	AdministratorAccountSIDSuffix             = "-500"
@@ -48,6 +52,9 @@ const (
 	KeyAdminsGroupSIDSuffix                   = "-526"
 	EnterpriseKeyAdminsGroupSIDSuffix         = "-527"
 	AdministratorsGroupSIDSuffix              = "-544"
+	AccountOperatorsGroupSIDSuffix            = "-548"
+	ServerOperatorsGroupSIDSuffix             = "-549"
+	PrintOperatorsGroupSIDSuffix              = "-550"
 	BackupOperatorsGroupSIDSuffix             = "-551"
 	DomainUsersSuffix                         = "-513"
 	AuthenticatedUsersSuffix                  = "-S-1-5-11"
@@ -56,18 +63,7 @@ const (
 )
 
 func TierZeroWellKnownSIDSuffixes() []string {
-	return []string{
-		EnterpriseDomainControllersGroupSIDSuffix,
-		AdministratorAccountSIDSuffix,
-		DomainAdminsGroupSIDSuffix,
-		DomainControllersGroupSIDSuffix,
-		SchemaAdminsGroupSIDSuffix,
-		EnterpriseAdminsGroupSIDSuffix,
-		KeyAdminsGroupSIDSuffix,
-		EnterpriseKeyAdminsGroupSIDSuffix,
-		BackupOperatorsGroupSIDSuffix,
-		AdministratorsGroupSIDSuffix,
-	}
+	return DefaultWellKnownRIDs().TierZeroSIDSuffixes()
 }
 func FetchWellKnownTierZeroEntities(tx graph.Transaction, domainSID string) (graph.NodeSet, error) {
 	nodes := graph.NewNodeSet()
@@ -96,15 +92,16 @@ func FetchWellKnownTierZeroEntities(tx graph.Transaction, domainSID string) (gra
 
 func FixWellKnownNodeTypes(ctx context.Context, db graph.Database) error {
 	defer log.Measure(log.LevelInfo, "Fix well known node types")()
-	groupSuffixes := []string{EnterpriseKeyAdminsGroupSIDSuffix,
-		KeyAdminsGroupSIDSuffix,
+	rids := DefaultWellKnownRIDs()
+	groupSuffixes := []string{RIDSuffix(rids.EnterpriseKeyAdmins),
+		RIDSuffix(rids.KeyAdmins),
 		EnterpriseDomainControllersGroupSIDSuffix,
-		DomainAdminsGroupSIDSuffix,
-		DomainControllersGroupSIDSuffix,
-		SchemaAdminsGroupSIDSuffix,
-		EnterpriseAdminsGroupSIDSuffix,
-		AdministratorsGroupSIDSuffix,
-		BackupOperatorsGroupSIDSuffix,
+		RIDSuffix(rids.DomainAdmins),
+		RIDSuffix(rids.DomainControllers),
+		RIDSuffix(rids.SchemaAdmins),
+		RIDSuffix(rids.EnterpriseAdmins),
+		RIDSuffix(rids.Administrators),
+		RIDSuffix(rids.BackupOperators),
 	}
 
 	return db.WriteTransaction(ctx, func(tx graph.Transaction) error {
@@ -197,6 +194,7 @@ func LinkWellKnownGroups(ctx context.Context, db graph.Database) error {
 	var (
 		errors        = util.NewErrorCollector()
 		newProperties = graph.NewProperties()
+		rids          = DefaultWellKnownRIDs()
 	)
 
 	if domains, err := GetCollectedDomains(ctx, db); err != nil {
@@ -212,10 +210,10 @@ func LinkWellKnownGroups(ctx context.Context, db graph.Database) error {
 			} else {
 				var (
 					domainId         = domain.ID
-					domainUsersId    = fmt.Sprintf("%s%s", domainSid, DomainUsersSuffix)
+					domainUsersId    = fmt.Sprintf("%s%s", domainSid, RIDSuffix(rids.DomainUsers))
 					authUsersId      = fmt.Sprintf("%s%s", domainName, AuthenticatedUsersSuffix)
 					everyoneId       = fmt.Sprintf("%s%s", domainName, EveryoneSuffix)
-					domainComputerId = fmt.Sprintf("%s%s", domainSid, DomainComputersSuffix)
+					domainComputerId = fmt.Sprintf("%s%s", domainSid, RIDSuffix(rids.DomainComputers))
 				)
 
 				if err := db.WriteTransaction(ctx, func(tx graph.Transaction) error {
@@ -0,0 +1,76 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ad
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/dawgs/query"
+	"github.com/specterops/bloodhound/graphschema/ad"
+	"github.com/specterops/bloodhound/graphschema/common"
+	"github.com/specterops/bloodhound/log"
+)
+
+// EnsureBroadPrincipalMetaNodes creates the Authenticated Users meta-node for every domain that has an Everyone
+// principal in the graph and links it to Everyone through MemberOf. ACE derived edges that originate from Everyone are
+// then reachable from the meta-node, which lets pathfinding start from "any authenticated user" of the domain.
+// Collected domains already have their meta-node created by LinkWellKnownGroups; this covers domains that were only
+// referenced by ACEs. It is a write transaction and is run once before post-processing starts.
+func EnsureBroadPrincipalMetaNodes(ctx context.Context, db graph.Database) error {
+	return db.WriteTransaction(ctx, func(tx graph.Transaction) error {
+		var everyoneNodes []*graph.Node
+
+		if err := tx.Nodes().Filterf(func() graph.Criteria {
+			return query.And(
+				query.Kind(query.Node(), ad.Group),
+				query.StringEndsWith(query.NodeProperty(common.ObjectID.String()), EveryoneSuffix),
+			)
+		}).Fetch(func(cursor graph.Cursor[*graph.Node]) error {
+			for node := range cursor.Chan() {
+				everyoneNodes = append(everyoneNodes, node)
+			}
+
+			return cursor.Error()
+		}); err != nil {
+			return err
+		}
+
+		linkProperties := graph.NewProperties().Set(common.LastSeen.String(), time.Now().UTC())
+
+		for _, everyoneNode := range everyoneNodes {
+			if objectID, err := everyoneNode.Properties.Get(common.ObjectID.String()).String(); err != nil {
+				log.Errorf("Everyone node %d does not have a valid object ID: %v", everyoneNode.ID, err)
+			} else if domainName := strings.TrimSuffix(objectID, EveryoneSuffix); domainName == "" {
+				log.Debugf("Skipping Everyone node %d as it is not scoped to a domain", everyoneNode.ID)
+			} else {
+				domainSid, _ := everyoneNode.Properties.Get(ad.DomainSID.String()).String()
+
+				if authUsersNode, err := getOrCreateWellKnownGroup(tx, domainName+AuthenticatedUsersSuffix, domainSid, domainName, fmt.Sprintf("AUTHENTICATED USERS@%s", domainName)); err != nil {
+					return fmt.Errorf("error getting auth users node for everyone node %d: %w", everyoneNode.ID, err)
+				} else if err := createOrUpdateWellKnownLink(tx, authUsersNode, everyoneNode, linkProperties); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,109 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ad
+
+import (
+	"context"
+	"sort"
+
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/dawgs/query"
+	"github.com/specterops/bloodhound/graphschema/common"
+	"github.com/specterops/bloodhound/log"
+)
+
+// PostProcessingConfig records which post-processed relationship kinds have been disabled. It is read from the
+// common.PostProcessingConfig node operators use to toggle post-processed relationship kinds. Each property of the node
+// is keyed by the name of a post-processed kind and holds a boolean stating whether the kind is produced. The zero
+// value enables every kind.
+type PostProcessingConfig struct {
+	disabled map[graph.Kind]struct{}
+}
+
+// Enabled returns true if any of the given kinds is enabled. Post-processors emitting several kinds are only skipped
+// once all of their kinds have been disabled.
+func (s PostProcessingConfig) Enabled(kinds ...graph.Kind) bool {
+	for _, kind := range kinds {
+		if _, disabled := s.disabled[kind]; !disabled {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DisabledKinds returns the disabled kinds sorted by name
+func (s PostProcessingConfig) DisabledKinds() graph.Kinds {
+	disabledKinds := make(graph.Kinds, 0, len(s.disabled))
+
+	for kind := range s.disabled {
+		disabledKinds = append(disabledKinds, kind)
+	}
+
+	sort.Slice(disabledKinds, func(i, j int) bool {
+		return disabledKinds[i].String() < disabledKinds[j].String()
+	})
+
+	return disabledKinds
+}
+
+func postProcessorKindsByName() map[string]graph.Kind {
+	kindsByName := map[string]graph.Kind{}
+
+	for _, processor := range PostProcessors() {
+		for _, kind := range processor.Emits {
+			kindsByName[kind.String()] = kind
+		}
+	}
+
+	return kindsByName
+}
+
+// LoadPostProcessingConfig reads the common.PostProcessingConfig node from the graph. Every kind is enabled when no such
+// node exists. Properties naming a kind that no post-processor emits, or holding a non-boolean value, are logged and
+// ignored so that a stale configuration can not prevent analysis from running.
+func LoadPostProcessingConfig(ctx context.Context, db graph.Database) (PostProcessingConfig, error) {
+	config := PostProcessingConfig{
+		disabled: map[graph.Kind]struct{}{},
+	}
+
+	return config, db.ReadTransaction(ctx, func(tx graph.Transaction) error {
+		if configNode, err := tx.Nodes().Filterf(func() graph.Criteria {
+			return query.Kind(query.Node(), common.PostProcessingConfig)
+		}).First(); err != nil {
+			if graph.IsErrNotFound(err) {
+				return nil
+			}
+
+			return err
+		} else {
+			kindsByName := postProcessorKindsByName()
+
+			for name, value := range configNode.Properties.Map {
+				if kind, known := kindsByName[name]; !known {
+					log.Warnf("Ignoring post-processing configuration for unknown kind %s", name)
+				} else if enabled, isBool := value.(bool); !isBool {
+					log.Warnf("Ignoring post-processing configuration for kind %s: expected a boolean but found %T", name, value)
+				} else if !enabled {
+					config.disabled[kind] = struct{}{}
+				}
+			}
+
+			return nil
+		}
+	})
+}
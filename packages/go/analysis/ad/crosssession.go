@@ -0,0 +1,192 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ad
+
+import (
+	"context"
+	"time"
+
+	"github.com/specterops/bloodhound/analysis"
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/dawgs/query"
+	"github.com/specterops/bloodhound/dawgs/util/channels"
+	"github.com/specterops/bloodhound/graphschema/ad"
+	"github.com/specterops/bloodhound/graphschema/common"
+	"github.com/specterops/bloodhound/log"
+)
+
+// CrossSessionPairLimit caps the number of holder and session user pairs PostCrossSession and PostRDPSessionCapture will
+// emit for a single computer. Every admin of a computer can impersonate every user with a session on it, so hosts such
+// as terminal servers would otherwise contribute edges in proportion to the product of both counts.
+const CrossSessionPairLimit = 10_000
+
+// SessionEdgeTTL is how long relationships derived from sessions by PostCrossSession and PostRDPSessionCapture stay
+// valid once written. Sessions are transient, so when post-processing stops refreshing these relationships they are
+// left for ExpireStaleComputedEdges to remove rather than persisting indefinitely.
+const SessionEdgeTTL = 7 * 24 * time.Hour
+
+// fetchComputerEndpoints maps each computer to the IDs of the nodes on the other end of the relationships of the given
+// kind. When fromComputer is set the computer is the start node of the relationship, otherwise it is the end node.
+func fetchComputerEndpoints(tx graph.Transaction, kind graph.Kind, fromComputer bool) (map[graph.ID][]graph.ID, error) {
+	var (
+		endpoints       = map[graph.ID][]graph.ID{}
+		computerBoundTo = query.End()
+	)
+
+	if fromComputer {
+		computerBoundTo = query.Start()
+	}
+
+	return endpoints, tx.Relationships().Filterf(func() graph.Criteria {
+		return query.And(
+			query.Kind(query.Relationship(), kind),
+			query.Kind(computerBoundTo, ad.Computer),
+		)
+	}).FetchTriples(func(cursor graph.Cursor[graph.RelationshipTripleResult]) error {
+		for result := range cursor.Chan() {
+			if fromComputer {
+				endpoints[result.StartID] = append(endpoints[result.StartID], result.EndID)
+			} else {
+				endpoints[result.EndID] = append(endpoints[result.EndID], result.StartID)
+			}
+		}
+
+		return cursor.Error()
+	})
+}
+
+// fetchComputerSessions maps each computer to the users with a session on it, as fetchComputerEndpoints does for
+// HasSession, and also returns the lastseen time of each session keyed by computer and user. Sessions without a
+// readable lastseen are given the zero time.
+func fetchComputerSessions(tx graph.Transaction) (map[graph.ID][]graph.ID, map[endpointPair]time.Time, error) {
+	var (
+		sessionUsers      = map[graph.ID][]graph.ID{}
+		sessionCollection = map[endpointPair]time.Time{}
+	)
+
+	return sessionUsers, sessionCollection, tx.Relationships().Filterf(func() graph.Criteria {
+		return query.And(
+			query.Kind(query.Relationship(), ad.HasSession),
+			query.Kind(query.Start(), ad.Computer),
+		)
+	}).Fetch(func(cursor graph.Cursor[*graph.Relationship]) error {
+		for session := range cursor.Chan() {
+			sessionUsers[session.StartID] = append(sessionUsers[session.StartID], session.EndID)
+
+			if lastSeen, err := session.Properties.Get(common.LastSeen.String()).Time(); err == nil {
+				sessionCollection[endpointPair{From: session.StartID, To: session.EndID}] = lastSeen
+			}
+		}
+
+		return cursor.Error()
+	})
+}
+
+// postSessionPairs emits a relationship of the given kind from every holder of holderKind over a computer to every
+// user with a session on that computer. Holders are used as they are, without expanding group membership. Pairs are
+// emitted once regardless of how many computers they share and computers with no sessions contribute nothing.
+// Computers that would produce more than CrossSessionPairLimit pairs are skipped with a warning. Each relationship
+// carries the lastseen time of the most recently collected session it was derived from as its source collection time
+// and is given a TTL of SessionEdgeTTL.
+func postSessionPairs(ctx context.Context, db graph.Database, operationName string, holderKind, emittedKind graph.Kind) (*analysis.AtomicPostProcessingStats, error) {
+	operation := analysis.NewPostRelationshipOperation(ctx, db, operationName)
+
+	if err := operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		if sessionUsers, sessionCollection, err := fetchComputerSessions(tx); err != nil {
+			return err
+		} else if holders, err := fetchComputerEndpoints(tx, holderKind, false); err != nil {
+			return err
+		} else {
+			collectedAt := map[endpointPair]time.Time{}
+
+			for computer, users := range sessionUsers {
+				computerHolders, hasHolders := holders[computer]
+
+				if !hasHolders {
+					continue
+				}
+
+				if numPairs := len(computerHolders) * len(users); numPairs > CrossSessionPairLimit {
+					log.Warnf("Skipping %s for computer %d: %d %s holders and %d session users exceed the limit of %d pairs", operationName, computer, len(computerHolders), holderKind, len(users), CrossSessionPairLimit)
+					continue
+				}
+
+				for _, holder := range computerHolders {
+					for _, user := range users {
+						if holder == user {
+							continue
+						}
+
+						var (
+							pair           = endpointPair{From: holder, To: user}
+							sessionSeen    = sessionCollection[endpointPair{From: computer, To: user}]
+							pairSeen, seen = collectedAt[pair]
+						)
+
+						if !seen || sessionSeen.After(pairSeen) {
+							collectedAt[pair] = sessionSeen
+						}
+					}
+				}
+			}
+
+			for pair, sessionSeen := range collectedAt {
+				if !channels.Submit(ctx, outC, analysis.CreatePostRelationshipJob{
+					FromID:            pair.From,
+					ToID:              pair.To,
+					Kind:              emittedKind,
+					TTL:               SessionEdgeTTL,
+					SourceCollectedAt: sessionSeen,
+				}) {
+					return nil
+				}
+			}
+
+			return nil
+		}
+	}); err != nil {
+		return &operation.Stats, err
+	}
+
+	return &operation.Stats, operation.Done()
+}
+
+// PostCrossSession emits a CanImpersonate edge from every principal holding AdminTo over a computer to every user with
+// a session on that computer. An administrator can steal the tokens of any logged on user, which carries the user's
+// privileges over to the administrator. AdminTo holders are used as they are, so a group holding AdminTo receives the
+// edge and its members reach the session user through MemberOf.
+//
+// This must run after AdminTo edges exist, which for post-processed AdminTo means after PostLocalGroups, and after
+// sessions have been ingested. Pairs are emitted once regardless of how many computers they share, and computers that
+// would produce more than CrossSessionPairLimit pairs are skipped with a warning. Each edge carries the lastseen time
+// of the most recently collected session it was derived from as its source collection time and expires after
+// SessionEdgeTTL.
+func PostCrossSession(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+	return postSessionPairs(ctx, db, "Cross Session Post Processing", ad.AdminTo, ad.CanImpersonate)
+}
+
+// PostRDPSessionCapture emits an RDPSessionCapture edge from every principal holding CanRDP over a computer to every
+// user with a session on that computer. A principal logged on over RDP shares the host with the session user and has
+// an opportunity to capture the user's credentials, for example by keylogging or by dumping them once local privileges
+// have been escalated.
+//
+// This must run after CanRDP edges exist, which means after PostLocalGroups, and after sessions have been ingested.
+// Computers with no sessions produce nothing. Pairs are limited and carry their source collection time and TTL as they
+// are for PostCrossSession.
+func PostRDPSessionCapture(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+	return postSessionPairs(ctx, db, "RDP Session Capture Post Processing", ad.CanRDP, ad.RDPSessionCapture)
+}
@@ -0,0 +1,202 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ad
+
+import (
+	"context"
+
+	"github.com/specterops/bloodhound/analysis"
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/dawgs/ops"
+	"github.com/specterops/bloodhound/dawgs/query"
+	"github.com/specterops/bloodhound/dawgs/util/channels"
+)
+
+// Some kinds are both collected at ingest time and derived by post-processing. Derived relationships carry a source
+// property naming the right they were derived from, which is what separates them from collected relationships of the
+// same kind during cleanup.
+
+func deleteDerivedEdges(ctx context.Context, db graph.Database, kind graph.Kind, sourceProperty string) (*analysis.AtomicPostProcessingStats, error) {
+	var (
+		relationshipIDs []graph.ID
+		stats           = analysis.NewAtomicPostProcessingStats()
+	)
+
+	if err := db.ReadTransaction(ctx, func(tx graph.Transaction) error {
+		fetchedRelationshipIDs, err := ops.FetchRelationshipIDs(tx.Relationships().Filterf(func() graph.Criteria {
+			return query.And(
+				query.Kind(query.Relationship(), kind),
+				query.Exists(query.RelationshipProperty(sourceProperty)),
+			)
+		}))
+
+		relationshipIDs = fetchedRelationshipIDs
+		return err
+	}); err != nil {
+		return &stats, err
+	}
+
+	stats.AddRelationshipsDeleted(kind, int32(len(relationshipIDs)))
+
+	return &stats, db.BatchOperation(ctx, func(batch graph.Batch) error {
+		for _, relationshipID := range relationshipIDs {
+			if err := batch.DeleteRelationship(relationshipID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// DerivedEdgeOptions carries state shared by the post-processors deriving relationships from the rights held over
+// their targets. Its zero value has each post-processor load what it needs itself.
+type DerivedEdgeOptions struct {
+	// Collectors attributes derived relationships to the collectors that ingested their nodes. When nil it is loaded by
+	// the post-processor.
+	Collectors analysis.CollectorAttribution
+
+	// DomainControllers grades the severity of AddKeyCredentialLink relationships. When nil it is detected by the
+	// post-processor.
+	DomainControllers *DomainControllerSet
+}
+
+// postDerivedFromRights emits a relationship of the derived kind for every relationship of one of the given rights
+// matching the given end node criteria. The derived relationship's source property is set to the name of the right it
+// was derived from. A pair joined by several of the rights receives one relationship sourced from the right listed
+// first. Pairs already joined by a collected relationship of the derived kind are skipped so that no duplicate is
+// created when both sources apply.
+func postDerivedFromRights(ctx context.Context, db graph.Database, collectors analysis.CollectorAttribution, operationName string, derivedKind graph.Kind, sourceProperty string, endCriteria graph.Criteria, rights ...graph.Kind) (*analysis.AtomicPostProcessingStats, error) {
+	return postAnnotatedDerivedFromRights(ctx, db, collectors, operationName, derivedKind, sourceProperty, endCriteria, nil, rights...)
+}
+
+// derivedEdgeAnnotator is run once within the reader transaction of postAnnotatedDerivedFromRights. The returned
+// function is then called with the target and relationship properties of every derived relationship so that target
+// specific properties can be added.
+type derivedEdgeAnnotator func(tx graph.Transaction) (func(target graph.ID, properties map[string]any), error)
+
+// postAnnotatedDerivedFromRights behaves like postDerivedFromRights but lets the given annotator, when not nil, add
+// properties to each derived relationship before it is submitted. A nil collectors is loaded before the operation
+// starts.
+func postAnnotatedDerivedFromRights(ctx context.Context, db graph.Database, collectors analysis.CollectorAttribution, operationName string, derivedKind graph.Kind, sourceProperty string, endCriteria graph.Criteria, annotator derivedEdgeAnnotator, rights ...graph.Kind) (*analysis.AtomicPostProcessingStats, error) {
+	if collectors == nil {
+		if loadedCollectors, err := analysis.LoadCollectorAttribution(ctx, db); err != nil {
+			return &analysis.AtomicPostProcessingStats{}, err
+		} else {
+			collectors = loadedCollectors
+		}
+	}
+
+	operation := analysis.NewPostRelationshipOperation(ctx, db, operationName)
+
+	if err := operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		var (
+			rightHolders    = map[endpointPair]int{}
+			rightPrecedence = make(map[graph.Kind]int, len(rights))
+			collected       = map[endpointPair]struct{}{}
+		)
+
+		for idx, right := range rights {
+			rightPrecedence[right] = idx
+		}
+
+		if err := tx.Relationships().Filterf(func() graph.Criteria {
+			return query.And(
+				query.KindIn(query.Relationship(), rights...),
+				endCriteria,
+			)
+		}).FetchKinds(func(cursor graph.Cursor[graph.RelationshipKindsResult]) error {
+			for result := range cursor.Chan() {
+				if result.StartID == result.EndID {
+					continue
+				}
+
+				var (
+					pair       = endpointPair{From: result.StartID, To: result.EndID}
+					rightIndex = rightPrecedence[result.Kind]
+				)
+
+				if heldIndex, held := rightHolders[pair]; !held || rightIndex < heldIndex {
+					rightHolders[pair] = rightIndex
+				}
+			}
+
+			return cursor.Error()
+		}); err != nil {
+			return err
+		}
+
+		if len(rightHolders) == 0 {
+			return nil
+		}
+
+		if err := tx.Relationships().Filterf(func() graph.Criteria {
+			return query.And(
+				query.Kind(query.Relationship(), derivedKind),
+				query.Not(query.Exists(query.RelationshipProperty(sourceProperty))),
+			)
+		}).FetchTriples(func(cursor graph.Cursor[graph.RelationshipTripleResult]) error {
+			for result := range cursor.Chan() {
+				collected[endpointPair{From: result.StartID, To: result.EndID}] = struct{}{}
+			}
+
+			return cursor.Error()
+		}); err != nil {
+			return err
+		}
+
+		var annotate func(target graph.ID, properties map[string]any)
+
+		if annotator != nil {
+			if fetchedAnnotate, err := annotator(tx); err != nil {
+				return err
+			} else {
+				annotate = fetchedAnnotate
+			}
+		}
+
+		for holder, rightIndex := range rightHolders {
+			relProperties := map[string]any{
+				sourceProperty: rights[rightIndex].String(),
+			}
+
+			if annotate != nil {
+				annotate(holder.To, relProperties)
+			}
+
+			if _, isCollected := collected[holder]; isCollected {
+				continue
+			}
+
+			if !channels.Submit(ctx, outC, analysis.CreatePostRelationshipJob{
+				FromID:          holder.From,
+				ToID:            holder.To,
+				Kind:            derivedKind,
+				RelProperties:   relProperties,
+				SourceCollector: collectors.Source(holder.From, holder.To),
+			}) {
+				return nil
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return &operation.Stats, err
+	}
+
+	return &operation.Stats, operation.Done()
+}
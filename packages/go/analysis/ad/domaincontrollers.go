@@ -0,0 +1,152 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ad
+
+import (
+	"context"
+
+	"github.com/specterops/bloodhound/dawgs/cardinality"
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/dawgs/ops"
+	"github.com/specterops/bloodhound/dawgs/query"
+	"github.com/specterops/bloodhound/graphschema/ad"
+	"github.com/specterops/bloodhound/graphschema/common"
+)
+
+// domainControllerMembershipCriteria matches MemberOf relationships from a computer into the Domain Controllers group
+// or the Enterprise Domain Controllers group. The graph carries neither a dedicated kind nor the user account control
+// flags for domain controllers, so membership of these groups, including primary group membership where it was
+// ingested, is the signal used to identify them.
+func (s WellKnownRIDs) domainControllerMembershipCriteria() graph.Criteria {
+	return query.And(
+		query.Kind(query.Relationship(), ad.MemberOf),
+		query.Kind(query.Start(), ad.Computer),
+		query.Kind(query.End(), ad.Group),
+		query.Or(
+			query.StringEndsWith(query.EndProperty(common.ObjectID.String()), RIDSuffix(s.DomainControllers)),
+			query.StringEndsWith(query.EndProperty(common.ObjectID.String()), EnterpriseDomainControllersGroupSIDSuffix),
+		),
+	)
+}
+
+// IsDomainController reports whether the given computer is a domain controller. This is the canonical check that edge
+// computations should use rather than inspecting group membership themselves.
+func IsDomainController(tx graph.Transaction, computer graph.ID) (bool, error) {
+	if count, err := tx.Relationships().Filterf(func() graph.Criteria {
+		return query.And(
+			query.Equals(query.StartID(), computer),
+			DefaultWellKnownRIDs().domainControllerMembershipCriteria(),
+		)
+	}).Count(); err != nil {
+		return false, err
+	} else {
+		return count > 0, nil
+	}
+}
+
+// FetchDomainControllers returns every domain controller of the domain with the given domain SID
+func FetchDomainControllers(ctx context.Context, db graph.Database, domainSID string) (graph.NodeSet, error) {
+	domainControllers := graph.NewNodeSet()
+
+	return domainControllers, db.ReadTransaction(ctx, func(tx graph.Transaction) error {
+		if nodes, err := ops.FetchStartNodes(tx.Relationships().Filterf(func() graph.Criteria {
+			return query.And(
+				query.Equals(query.StartProperty(ad.DomainSID.String()), domainSID),
+				DefaultWellKnownRIDs().domainControllerMembershipCriteria(),
+			)
+		})); err != nil {
+			return err
+		} else {
+			domainControllers.AddSet(nodes)
+			return nil
+		}
+	})
+}
+
+// FetchDomainControllerIDs returns the IDs of every domain controller in the graph regardless of domain
+func FetchDomainControllerIDs(tx graph.Transaction) (cardinality.Duplex[uint32], error) {
+	domainControllers := cardinality.NewBitmap32()
+
+	return domainControllers, tx.Relationships().Filter(DefaultWellKnownRIDs().domainControllerMembershipCriteria()).FetchTriples(func(cursor graph.Cursor[graph.RelationshipTripleResult]) error {
+		for result := range cursor.Chan() {
+			domainControllers.Add(result.StartID.Uint32())
+		}
+
+		return cursor.Error()
+	})
+}
+
+// DomainControllerSet holds every domain controller in the graph, detected once and shared by the post-processors of
+// an analysis run that need to single out domain controllers. Processors given a nil set detect domain controllers
+// themselves when they first need them.
+type DomainControllerSet struct {
+	ids      cardinality.Duplex[uint32]
+	idsBySID map[string][]graph.ID
+}
+
+// Contains reports whether the node with the given ID is a domain controller
+func (s *DomainControllerSet) Contains(id graph.ID) bool {
+	return s.ids.Contains(id.Uint32())
+}
+
+// InDomain returns the IDs of the domain controllers of the domain with the given domain SID
+func (s *DomainControllerSet) InDomain(domainSID string) []graph.ID {
+	return s.idsBySID[domainSID]
+}
+
+func fetchDomainControllerSet(tx graph.Transaction, rids WellKnownRIDs) (*DomainControllerSet, error) {
+	domainControllers := &DomainControllerSet{
+		ids:      cardinality.NewBitmap32(),
+		idsBySID: map[string][]graph.ID{},
+	}
+
+	if nodes, err := ops.FetchStartNodes(tx.Relationships().Filter(rids.domainControllerMembershipCriteria())); err != nil {
+		return nil, err
+	} else {
+		for _, node := range nodes {
+			domainControllers.ids.Add(node.ID.Uint32())
+
+			// Domain controllers without a domain SID are still recognized by Contains
+			if domainSID, err := node.Properties.Get(ad.DomainSID.String()).String(); err == nil {
+				domainControllers.idsBySID[domainSID] = append(domainControllers.idsBySID[domainSID], node.ID)
+			}
+		}
+
+		return domainControllers, nil
+	}
+}
+
+// FetchDomainControllerSet detects every domain controller in the graph in a single query so that the result can be
+// passed to the post-processors of an analysis run in place of each running its own detection
+func FetchDomainControllerSet(ctx context.Context, db graph.Database) (*DomainControllerSet, error) {
+	return FetchDomainControllerSetWithRIDs(ctx, db, DefaultWellKnownRIDs())
+}
+
+// FetchDomainControllerSetWithRIDs behaves like FetchDomainControllerSet but finds the Domain Controllers groups with
+// the given RID table
+func FetchDomainControllerSetWithRIDs(ctx context.Context, db graph.Database, rids WellKnownRIDs) (*DomainControllerSet, error) {
+	var domainControllers *DomainControllerSet
+
+	return domainControllers, db.ReadTransaction(ctx, func(tx graph.Transaction) error {
+		if fetchedDomainControllers, err := fetchDomainControllerSet(tx, rids); err != nil {
+			return err
+		} else {
+			domainControllers = fetchedDomainControllers
+			return nil
+		}
+	})
+}
@@ -0,0 +1,232 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ad
+
+import (
+	"context"
+
+	"github.com/specterops/bloodhound/analysis"
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/dawgs/ops"
+	"github.com/specterops/bloodhound/dawgs/query"
+	"github.com/specterops/bloodhound/dawgs/util/channels"
+	"github.com/specterops/bloodhound/graphschema/ad"
+	"github.com/specterops/bloodhound/graphschema/common"
+)
+
+// EnterpriseAdminsSourceProperty is set on AdminTo edges emitted by PostEnterpriseAdminsAdminTo to the object ID of the
+// Enterprise Admins group the edge starts from. AdminTo is purged wholesale before each analysis run, so the property
+// is not needed for cleanup; it tells these edges apart from AdminTo derived from local group membership.
+const EnterpriseAdminsSourceProperty = "enterpriseadminssource"
+
+// EnterpriseAdminsWriteBatchSize is the number of AdminTo edges PostEnterpriseAdminsAdminTo writes per batch. Every
+// Enterprise Admins group is paired with every computer of its forest, so batches are kept bounded regardless of the
+// graph driver's configured batch size.
+const EnterpriseAdminsWriteBatchSize = 10_000
+
+// fetchForests groups every domain in the graph by forest. Domains joined by a TrustedBy edge whose trust type keeps
+// it within a forest, in either direction, share a forest. A domain without such a trust is a forest of its own.
+func fetchForests(tx graph.Transaction) ([][]*graph.Node, error) {
+	var (
+		domains = map[graph.ID]*graph.Node{}
+		parents = map[graph.ID]graph.ID{}
+	)
+
+	findRoot := func(domain graph.ID) graph.ID {
+		for parents[domain] != domain {
+			parents[domain] = parents[parents[domain]]
+			domain = parents[domain]
+		}
+
+		return domain
+	}
+
+	if err := tx.Nodes().Filterf(func() graph.Criteria {
+		return query.Kind(query.Node(), ad.Domain)
+	}).Fetch(func(cursor graph.Cursor[*graph.Node]) error {
+		for domain := range cursor.Chan() {
+			domains[domain.ID] = domain
+			parents[domain.ID] = domain.ID
+		}
+
+		return cursor.Error()
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Relationships().Filterf(func() graph.Criteria {
+		return query.And(
+			query.Kind(query.Relationship(), ad.TrustedBy),
+			query.Kind(query.Start(), ad.Domain),
+			query.Kind(query.End(), ad.Domain),
+		)
+	}).Fetch(func(cursor graph.Cursor[*graph.Relationship]) error {
+		for trust := range cursor.Chan() {
+			trustType, _ := trust.Properties.Get(ad.TrustType.String()).String()
+
+			if trustKind, ok := TrustKindForType(trustType); ok && trustKind == ad.SameForestTrust {
+				parents[findRoot(trust.StartID)] = findRoot(trust.EndID)
+			}
+		}
+
+		return cursor.Error()
+	}); err != nil {
+		return nil, err
+	}
+
+	var (
+		forests       [][]*graph.Node
+		forestsByRoot = map[graph.ID]int{}
+	)
+
+	for domainID, domain := range domains {
+		root := findRoot(domainID)
+
+		if forestIdx, seen := forestsByRoot[root]; seen {
+			forests[forestIdx] = append(forests[forestIdx], domain)
+		} else {
+			forestsByRoot[root] = len(forests)
+			forests = append(forests, []*graph.Node{domain})
+		}
+	}
+
+	return forests, nil
+}
+
+// fetchEnterpriseAdminsGroups returns the Enterprise Admins groups of the domains with the given SIDs
+func fetchEnterpriseAdminsGroups(tx graph.Transaction, domainSIDs []string, rids WellKnownRIDs) ([]*graph.Node, error) {
+	var (
+		enterpriseAdminsSuffix = RIDSuffix(rids.EnterpriseAdmins)
+		enterpriseAdminsSIDs   = make([]string, len(domainSIDs))
+	)
+
+	for idx, domainSID := range domainSIDs {
+		enterpriseAdminsSIDs[idx] = domainSID + enterpriseAdminsSuffix
+	}
+
+	return ops.FetchNodes(tx.Nodes().Filterf(func() graph.Criteria {
+		return query.And(
+			query.Kind(query.Node(), ad.Group),
+			query.In(query.NodeProperty(common.ObjectID.String()), enterpriseAdminsSIDs),
+		)
+	}))
+}
+
+// PostEnterpriseAdminsAdminTo emits an AdminTo edge from a forest's Enterprise Admins group to every computer in that
+// forest. Enterprise Admins is added to the Administrators group of every domain in its forest, and through it
+// administers every computer, which local group post-processing only sees where the collector recorded it. Members of
+// the group, including principals from other domains, reach the computers through their MemberOf edges, so no edge is
+// emitted per member. Forests are found with fetchForests. Pairs already joined by an AdminTo edge are skipped and
+// every emitted edge is stamped with EnterpriseAdminsSourceProperty.
+//
+// The number of edges is the number of computers in each forest, so writes are committed every
+// EnterpriseAdminsWriteBatchSize edges. This must run after local group post-processing.
+func PostEnterpriseAdminsAdminTo(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+	return PostEnterpriseAdminsAdminToWithRIDs(ctx, db, DefaultWellKnownRIDs())
+}
+
+// PostEnterpriseAdminsAdminToWithRIDs behaves like PostEnterpriseAdminsAdminTo but finds the Enterprise Admins groups
+// with the given RID table
+func PostEnterpriseAdminsAdminToWithRIDs(ctx context.Context, db graph.Database, rids WellKnownRIDs) (*analysis.AtomicPostProcessingStats, error) {
+	operation := analysis.NewPostRelationshipOperationWithOptions(ctx, db, "Enterprise Admins AdminTo Post Processing", analysis.PostRelationshipOperationOptions{
+		WriteBatchSize: EnterpriseAdminsWriteBatchSize,
+	})
+
+	if err := operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		forests, err := fetchForests(tx)
+		if err != nil {
+			return err
+		}
+
+		for _, forest := range forests {
+			var domainSIDs []string
+
+			for _, domain := range forest {
+				if domainSID, err := domain.Properties.Get(ad.DomainSID.String()).String(); err == nil && domainSID != "" {
+					domainSIDs = append(domainSIDs, domainSID)
+				}
+			}
+
+			if len(domainSIDs) == 0 {
+				continue
+			}
+
+			groups, err := fetchEnterpriseAdminsGroups(tx, domainSIDs, rids)
+			if err != nil {
+				return err
+			} else if len(groups) == 0 {
+				continue
+			}
+
+			computers, err := ops.FetchNodeIDs(tx.Nodes().Filterf(func() graph.Criteria {
+				return query.And(
+					query.Kind(query.Node(), ad.Computer),
+					query.In(query.NodeProperty(ad.DomainSID.String()), domainSIDs),
+				)
+			}))
+			if err != nil {
+				return err
+			} else if len(computers) == 0 {
+				continue
+			}
+
+			existing := map[endpointPair]struct{}{}
+
+			if err := tx.Relationships().Filterf(func() graph.Criteria {
+				return query.And(
+					query.Kind(query.Relationship(), ad.AdminTo),
+					query.InIDs(query.StartID(), graph.NewNodeSet(groups...).IDs()...),
+				)
+			}).FetchTriples(func(cursor graph.Cursor[graph.RelationshipTripleResult]) error {
+				for result := range cursor.Chan() {
+					existing[endpointPair{From: result.StartID, To: result.EndID}] = struct{}{}
+				}
+
+				return cursor.Error()
+			}); err != nil {
+				return err
+			}
+
+			for _, group := range groups {
+				groupObjectID, _ := group.Properties.Get(common.ObjectID.String()).String()
+
+				for _, computer := range computers {
+					if _, joined := existing[endpointPair{From: group.ID, To: computer}]; joined {
+						continue
+					}
+
+					if !channels.Submit(ctx, outC, analysis.CreatePostRelationshipJob{
+						FromID: group.ID,
+						ToID:   computer,
+						Kind:   ad.AdminTo,
+						RelProperties: map[string]any{
+							EnterpriseAdminsSourceProperty: groupObjectID,
+						},
+					}) {
+						return nil
+					}
+				}
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return &operation.Stats, err
+	}
+
+	return &operation.Stats, operation.Done()
+}
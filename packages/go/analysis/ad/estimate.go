@@ -0,0 +1,299 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ad
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/specterops/bloodhound/dawgs/cardinality"
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/dawgs/query"
+	"github.com/specterops/bloodhound/graphschema/ad"
+	"github.com/specterops/bloodhound/graphschema/azure"
+	"github.com/specterops/bloodhound/graphschema/common"
+)
+
+type volumeEstimator func(tx graph.Transaction) (int, error)
+
+func countRelationships(tx graph.Transaction, criteria graph.Criteria) (int, error) {
+	count, err := tx.Relationships().Filter(criteria).Count()
+	return int(count), err
+}
+
+func countNodes(tx graph.Transaction, criteria graph.Criteria) (int, error) {
+	count, err := tx.Nodes().Filter(criteria).Count()
+	return int(count), err
+}
+
+func countDistinctStartNodes(tx graph.Transaction, criteria graph.Criteria) (int, error) {
+	startNodes := cardinality.NewBitmap32()
+
+	if err := tx.Relationships().Filter(criteria).FetchTriples(func(cursor graph.Cursor[graph.RelationshipTripleResult]) error {
+		for result := range cursor.Chan() {
+			startNodes.Add(result.StartID.Uint32())
+		}
+
+		return cursor.Error()
+	}); err != nil {
+		return 0, err
+	}
+
+	return int(startNodes.Cardinality()), nil
+}
+
+func collectedDomainsCriteria() graph.Criteria {
+	return query.And(
+		query.Kind(query.Node(), ad.Domain),
+		query.Equals(query.NodeProperty(common.Collected.String()), true),
+	)
+}
+
+// estimateSyncersTimesTargets multiplies the number of distinct principals holding the given replication right over a
+// domain by the number of nodes matching the given target criteria. Group membership is not expanded.
+func estimateSyncersTimesTargets(replicationRight graph.Kind, targetCriteria graph.Criteria) volumeEstimator {
+	return func(tx graph.Transaction) (int, error) {
+		if numSyncers, err := countDistinctStartNodes(tx, query.And(
+			query.Kind(query.Relationship(), replicationRight),
+			query.Kind(query.End(), ad.Domain),
+		)); err != nil {
+			return 0, err
+		} else if numTargets, err := countNodes(tx, targetCriteria); err != nil {
+			return 0, err
+		} else {
+			return numSyncers * numTargets, nil
+		}
+	}
+}
+
+// estimateLocalGroupMembers counts the first degree members of every local group with the given SID suffix
+func estimateLocalGroupMembers(groupSuffix string) volumeEstimator {
+	return func(tx graph.Transaction) (int, error) {
+		return countRelationships(tx, query.And(
+			query.Kind(query.Relationship(), ad.MemberOfLocalGroup),
+			query.StringEndsWith(query.EndProperty(common.ObjectID.String()), groupSuffix),
+		))
+	}
+}
+
+// estimateSharedAdminPairs sums, over every AdminTo holder within SharedAdminLateralComputerLimit, the number of ordered
+// pairs of computers it administers. Pairs shared by several holders are counted once per holder.
+func estimateSharedAdminPairs(tx graph.Transaction) (int, error) {
+	if administeredComputers, err := fetchAdministeredComputers(tx); err != nil {
+		return 0, err
+	} else {
+		numPairs := 0
+
+		for _, computers := range administeredComputers {
+			if numComputers := len(computers); numComputers <= SharedAdminLateralComputerLimit {
+				numPairs += numComputers * (numComputers - 1)
+			}
+		}
+
+		return numPairs, nil
+	}
+}
+
+// estimateSessionPairs sums, over every computer, the product of its holders of the given kind and its session users.
+// Computers exceeding CrossSessionPairLimit are left out as postSessionPairs skips them.
+func estimateSessionPairs(holderKind graph.Kind) volumeEstimator {
+	return func(tx graph.Transaction) (int, error) {
+		if sessionUsers, err := fetchComputerEndpoints(tx, ad.HasSession, true); err != nil {
+			return 0, err
+		} else if holders, err := fetchComputerEndpoints(tx, holderKind, false); err != nil {
+			return 0, err
+		} else {
+			numPairs := 0
+
+			for computer, users := range sessionUsers {
+				if computerPairs := len(holders[computer]) * len(users); computerPairs <= CrossSessionPairLimit {
+					numPairs += computerPairs
+				}
+			}
+
+			return numPairs, nil
+		}
+	}
+}
+
+func volumeEstimators() map[graph.Kind]volumeEstimator {
+	rids := DefaultWellKnownRIDs()
+
+	return map[graph.Kind]volumeEstimator{
+		ad.DCSync: estimateSyncersTimesTargets(ad.GetChangesAll, collectedDomainsCriteria()),
+		ad.SyncLAPSPassword: estimateSyncersTimesTargets(ad.GetChangesInFilteredSet, query.And(
+			query.Kind(query.Node(), ad.Computer),
+			query.Equals(query.NodeProperty(ad.HasLAPS.String()), true),
+		)),
+		ad.CanRDP: func(tx graph.Transaction) (int, error) {
+			return countRelationships(tx, query.And(
+				query.Kind(query.Relationship(), ad.LocalToComputer),
+				query.StringEndsWith(query.StartProperty(common.ObjectID.String()), RIDSuffix(rids.RemoteDesktopUsers)),
+			))
+		},
+		ad.AdminTo:     estimateLocalGroupMembers(RIDSuffix(rids.Administrators)),
+		ad.CanPSRemote: estimateLocalGroupMembers(RIDSuffix(rids.RemoteManagementUsers)),
+		ad.ExecuteDCOM: estimateLocalGroupMembers(RIDSuffix(rids.DistributedCOMUsers)),
+		ad.SameForestTrust: func(tx graph.Transaction) (int, error) {
+			return countRelationships(tx, query.And(
+				query.Kind(query.Relationship(), ad.TrustedBy),
+				query.Kind(query.Start(), ad.Domain),
+				query.Kind(query.End(), ad.Domain),
+			))
+		},
+		ad.AccountOperatorControl: func(tx graph.Transaction) (int, error) {
+			return countNodes(tx, query.And(
+				query.KindIn(query.Node(), ad.User, ad.Group),
+				query.Not(query.Equals(query.NodeProperty(ad.AdminCount.String()), true)),
+			))
+		},
+		ad.ImpliedGenericAll: func(tx graph.Transaction) (int, error) {
+			return countRelationships(tx, query.Kind(query.Relationship(), ad.Owns))
+		},
+		ad.SyncedToEntraUser: func(tx graph.Transaction) (int, error) {
+			return countNodes(tx, query.And(
+				query.Kind(query.Node(), azure.User),
+				query.Equals(query.NodeProperty(azure.OnPremSyncEnabled.String()), true),
+			))
+		},
+		ad.SyncedToEntraRole: func(tx graph.Transaction) (int, error) {
+			return countRelationships(tx, query.And(
+				query.Kind(query.Relationship(), azure.HasRole),
+				query.Kind(query.Start(), azure.Group),
+				query.Equals(query.StartProperty(azure.OnPremSyncEnabled.String()), true),
+			))
+		},
+		ad.ReadLAPSPassword: func(tx graph.Transaction) (int, error) {
+			return countRelationships(tx, query.And(
+				query.Kind(query.Relationship(), ad.GenericAll),
+				query.Kind(query.End(), ad.Computer),
+				query.Equals(query.EndProperty(ad.HasLAPS.String()), true),
+			))
+		},
+		ad.CanApplyGPO: func(tx graph.Transaction) (int, error) {
+			return countRelationships(tx, query.And(
+				query.Kind(query.Relationship(), ad.WriteGPLink),
+				query.Kind(query.End(), ad.OU),
+			))
+		},
+		ad.HasTrustKeys: func(tx graph.Transaction) (int, error) {
+			return countRelationships(tx, query.And(
+				query.KindIn(query.Relationship(), TrustAccountControlRelationships()...),
+				query.Kind(query.End(), ad.User),
+				query.StringEndsWith(query.EndProperty(ad.SamAccountName.String()), "$"),
+			))
+		},
+		ad.CanImpersonate:     estimateSessionPairs(ad.AdminTo),
+		ad.RDPSessionCapture:  estimateSessionPairs(ad.CanRDP),
+		ad.SharedAdminLateral: estimateSharedAdminPairs,
+		ad.AdminToViaGMSA: func(tx graph.Transaction) (int, error) {
+			if gmsaAccounts, err := FetchGMSAAccountIDs(tx); err != nil || len(gmsaAccounts) == 0 {
+				return 0, err
+			} else if numReaders, err := countRelationships(tx, query.And(
+				query.Kind(query.Relationship(), ad.ReadGMSAPassword),
+				query.Not(query.Exists(query.RelationshipProperty(GMSAReadSourceProperty))),
+			)); err != nil {
+				return 0, err
+			} else if numAdminTo, err := countRelationships(tx, query.And(
+				query.Kind(query.Relationship(), ad.AdminTo),
+				query.InIDs(query.StartID(), gmsaAccounts...),
+			)); err != nil {
+				return 0, err
+			} else {
+				return numReaders * numAdminTo / len(gmsaAccounts), nil
+			}
+		},
+		ad.CanTakeOver: func(tx graph.Transaction) (int, error) {
+			return countRelationships(tx, query.KindIn(query.Relationship(), ad.GenericAll, ad.Owns))
+		},
+		ad.ReadGMSAPassword: func(tx graph.Transaction) (int, error) {
+			if gmsaAccounts, err := FetchGMSAAccountIDs(tx); err != nil || len(gmsaAccounts) == 0 {
+				return 0, err
+			} else {
+				return countRelationships(tx, query.And(
+					query.KindIn(query.Relationship(), ad.GenericAll, ad.GenericWrite),
+					query.InIDs(query.EndID(), gmsaAccounts...),
+				))
+			}
+		},
+		ad.AddKeyCredentialLink: func(tx graph.Transaction) (int, error) {
+			return countRelationships(tx, query.And(
+				query.KindIn(query.Relationship(), ad.GenericAll, ad.GenericWrite),
+				query.KindIn(query.End(), ad.User, ad.Computer),
+			))
+		},
+		ad.AddAllowedToAct: func(tx graph.Transaction) (int, error) {
+			return countRelationships(tx, query.And(
+				query.KindIn(query.Relationship(), RBCDWriteRelationships()...),
+				query.Kind(query.End(), ad.Computer),
+			))
+		},
+		ad.CanDCSyncViaReset: func(tx graph.Transaction) (int, error) {
+			if syncTargets, err := fetchDCSyncTargets(tx); err != nil || len(syncTargets) == 0 {
+				return 0, err
+			} else {
+				syncers := make([]graph.ID, 0, len(syncTargets))
+
+				for syncer := range syncTargets {
+					syncers = append(syncers, syncer)
+				}
+
+				return countRelationships(tx, query.And(
+					query.Kind(query.Relationship(), ad.ForceChangePassword),
+					query.InIDs(query.EndID(), syncers...),
+				))
+			}
+		},
+		ad.ForceChangePassword: func(tx graph.Transaction) (int, error) {
+			return countRelationships(tx, query.And(
+				query.Kind(query.Relationship(), ad.GenericAll),
+				query.Kind(query.End(), ad.User),
+			))
+		},
+	}
+}
+
+// EstimatePostProcessingVolume returns an order-of-magnitude estimate of the number of edges post-processing would
+// create for each of the given kinds. Only the fetch and count portions of each computation are run; group membership
+// is never expanded, so the estimate is meant for capacity planning rather than as a prediction. CrossForestTrust shares
+// its estimate with SameForestTrust as both are derived from the same TrustedBy edges.
+func EstimatePostProcessingVolume(ctx context.Context, db graph.Database, kinds []graph.Kind) (map[graph.Kind]int, error) {
+	var (
+		estimators = volumeEstimators()
+		estimates  = make(map[graph.Kind]int, len(kinds))
+	)
+
+	estimators[ad.CrossForestTrust] = estimators[ad.SameForestTrust]
+
+	for _, kind := range kinds {
+		if _, hasEstimator := estimators[kind]; !hasEstimator {
+			return nil, fmt.Errorf("no volume estimate is available for kind %s", kind)
+		}
+	}
+
+	return estimates, db.ReadTransaction(ctx, func(tx graph.Transaction) error {
+		for _, kind := range kinds {
+			if estimate, err := estimators[kind](tx); err != nil {
+				return fmt.Errorf("failed estimating volume for kind %s: %w", kind, err)
+			} else {
+				estimates[kind] = estimate
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,92 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ad
+
+import (
+	"strings"
+
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/graphschema/common"
+)
+
+// ExchangeGroupHeuristic recognizes the security groups created by an Exchange installation. A group matches when the
+// part of its name before the domain suffix equals one of GroupNames, or when its object ID ends with one of
+// SIDSuffixes. Names are compared case-insensitively. Exchange groups are created with domain relative RIDs that differ
+// between installations, so SIDSuffixes is empty by default and meant for environments with known RIDs.
+type ExchangeGroupHeuristic struct {
+	GroupNames  []string
+	SIDSuffixes []string
+}
+
+// DefaultExchangeGroupHeuristic returns a heuristic matching the groups that Exchange setup grants replication rights to,
+// either directly or through the split permissions model.
+func DefaultExchangeGroupHeuristic() ExchangeGroupHeuristic {
+	return ExchangeGroupHeuristic{
+		GroupNames: []string{
+			"Exchange Trusted Subsystem",
+			"Exchange Windows Permissions",
+			"Exchange Servers",
+			"Exchange Enterprise Servers",
+			"Organization Management",
+		},
+	}
+}
+
+// Matches returns true if the given group node is recognized as an Exchange security group
+func (s ExchangeGroupHeuristic) Matches(group *graph.Node) bool {
+	if s.matchesName(group) {
+		return true
+	}
+
+	if objectID, err := group.Properties.Get(common.ObjectID.String()).String(); err == nil {
+		for _, sidSuffix := range s.SIDSuffixes {
+			if strings.HasSuffix(strings.ToUpper(objectID), strings.ToUpper(sidSuffix)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (s ExchangeGroupHeuristic) matchesName(group *graph.Node) bool {
+	if name, err := group.Properties.Get(common.Name.String()).String(); err == nil {
+		name, _, _ = strings.Cut(name, "@")
+
+		for _, groupName := range s.GroupNames {
+			if strings.EqualFold(name, groupName) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// MatcherWithResolver returns a function equivalent to Matches that finds the groups matching SIDSuffixes with the given
+// resolver up front instead of reading the object ID of every group it is given. A nil resolver returns Matches.
+func (s ExchangeGroupHeuristic) MatcherWithResolver(resolver SIDResolver) func(group *graph.Node) bool {
+	if resolver == nil {
+		return s.Matches
+	}
+
+	suffixMatches := resolver.ResolveSuffixes(s.SIDSuffixes...)
+
+	return func(group *graph.Node) bool {
+		return suffixMatches.Contains(group.ID.Uint32()) || s.matchesName(group)
+	}
+}
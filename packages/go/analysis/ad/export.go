@@ -0,0 +1,231 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ad
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/specterops/bloodhound/analysis"
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/dawgs/query"
+	"github.com/specterops/bloodhound/graphschema/common"
+)
+
+// ExportFormat selects the encoding used by ExportComputedEdges
+type ExportFormat int
+
+const (
+	// ExportFormatCSV writes a header row followed by one row per edge
+	ExportFormatCSV ExportFormat = iota
+
+	// ExportFormatNDJSON writes one JSON object per line per edge
+	ExportFormatNDJSON
+)
+
+func (s ExportFormat) String() string {
+	switch s {
+	case ExportFormatCSV:
+		return "csv"
+	case ExportFormatNDJSON:
+		return "ndjson"
+	default:
+		return fmt.Sprintf("ExportFormat(%d)", int(s))
+	}
+}
+
+// ExportedEdgeProvenanceProperties returns the relationship properties describing when and how a computed edge was
+// produced. These are the only relationship properties included in an export.
+func ExportedEdgeProvenanceProperties() []string {
+	return []string{
+		common.LastSeen.String(),
+		analysis.AnalysisVersionProperty,
+		analysis.ValidUntilProperty,
+		analysis.SourceCollectedAtProperty,
+		analysis.SourceCollectorProperty,
+		LAPSReadSourceProperty,
+		ForceChangePasswordSourceProperty,
+		GMSAReadSourceProperty,
+		KeyCredentialLinkSourceProperty,
+		KeyCredentialLinkSeverityProperty,
+		RBCDWriteSourceProperty,
+		PrivilegedGroupSourceProperty,
+		DCSyncSourceProperty,
+		LAPSAdminSourceProperty,
+		PrivilegeAdminSourceProperty,
+	}
+}
+
+// ExportedEdge is a single computed edge as written by ExportComputedEdges
+type ExportedEdge struct {
+	SourceSID  string         `json:"source_sid"`
+	TargetSID  string         `json:"target_sid"`
+	Kind       string         `json:"kind"`
+	Provenance map[string]any `json:"provenance"`
+}
+
+type edgeExportWriter interface {
+	Write(edge ExportedEdge) error
+	Close() error
+}
+
+type csvEdgeExportWriter struct {
+	writer            *csv.Writer
+	provenanceColumns []string
+	headerWritten     bool
+}
+
+func (s *csvEdgeExportWriter) writeHeader() error {
+	return s.writer.Write(append([]string{"source_sid", "target_sid", "kind"}, s.provenanceColumns...))
+}
+
+func (s *csvEdgeExportWriter) Write(edge ExportedEdge) error {
+	if !s.headerWritten {
+		if err := s.writeHeader(); err != nil {
+			return err
+		}
+
+		s.headerWritten = true
+	}
+
+	record := []string{edge.SourceSID, edge.TargetSID, edge.Kind}
+
+	for _, column := range s.provenanceColumns {
+		if value, hasValue := edge.Provenance[column]; hasValue {
+			record = append(record, fmt.Sprint(value))
+		} else {
+			record = append(record, "")
+		}
+	}
+
+	return s.writer.Write(record)
+}
+
+func (s *csvEdgeExportWriter) Close() error {
+	// An export without edges still carries its header so that consumers can rely on the column layout
+	if !s.headerWritten {
+		if err := s.writeHeader(); err != nil {
+			return err
+		}
+	}
+
+	s.writer.Flush()
+	return s.writer.Error()
+}
+
+type ndjsonEdgeExportWriter struct {
+	encoder *json.Encoder
+}
+
+func (s ndjsonEdgeExportWriter) Write(edge ExportedEdge) error {
+	return s.encoder.Encode(edge)
+}
+
+func (s ndjsonEdgeExportWriter) Close() error {
+	return nil
+}
+
+func newEdgeExportWriter(w io.Writer, format ExportFormat) (edgeExportWriter, error) {
+	switch format {
+	case ExportFormatCSV:
+		return &csvEdgeExportWriter{
+			writer:            csv.NewWriter(w),
+			provenanceColumns: ExportedEdgeProvenanceProperties(),
+		}, nil
+
+	case ExportFormatNDJSON:
+		return ndjsonEdgeExportWriter{
+			encoder: json.NewEncoder(w),
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+func nodeObjectID(node graph.Node) string {
+	if objectID, err := node.Properties.Get(common.ObjectID.String()).String(); err != nil {
+		return ""
+	} else {
+		return objectID
+	}
+}
+
+func newExportedEdge(start graph.Node, relationship graph.Relationship, end graph.Node) ExportedEdge {
+	provenance := map[string]any{}
+
+	for _, propertyName := range ExportedEdgeProvenanceProperties() {
+		if relationship.Properties != nil && relationship.Properties.Exists(propertyName) {
+			provenance[propertyName] = relationship.Properties.Get(propertyName).Any()
+		}
+	}
+
+	return ExportedEdge{
+		SourceSID:  nodeObjectID(start),
+		TargetSID:  nodeObjectID(end),
+		Kind:       relationship.Kind.String(),
+		Provenance: provenance,
+	}
+}
+
+// ExportComputedEdges writes every relationship of the given kinds to w in the given format. Each edge is written with
+// the object IDs of its start and end nodes, its kind and the provenance properties listed by
+// ExportedEdgeProvenanceProperties. Edges are streamed from the database cursor as they are read rather than collected
+// first, so the export holds at most one edge in memory at a time.
+func ExportComputedEdges(ctx context.Context, db graph.Database, kinds []graph.Kind, w io.Writer, format ExportFormat) error {
+	if len(kinds) == 0 {
+		return fmt.Errorf("no kinds were given to export")
+	}
+
+	exportWriter, err := newEdgeExportWriter(w, format)
+	if err != nil {
+		return err
+	}
+
+	if err := db.ReadTransaction(ctx, func(tx graph.Transaction) error {
+		return tx.Relationships().Filterf(func() graph.Criteria {
+			return query.KindIn(query.Relationship(), kinds...)
+		}).Execute(func(results graph.Result) error {
+			for results.Next() {
+				var (
+					start        graph.Node
+					relationship graph.Relationship
+					end          graph.Node
+				)
+
+				if err := results.Scan(&start, &relationship, &end); err != nil {
+					return err
+				} else if err := exportWriter.Write(newExportedEdge(start, relationship, end)); err != nil {
+					return fmt.Errorf("failed writing exported edge %d: %w", relationship.ID, err)
+				}
+			}
+
+			return results.Error()
+		}, query.Returning(
+			query.Start(),
+			query.Relationship(),
+			query.End(),
+		))
+	}); err != nil {
+		return err
+	}
+
+	return exportWriter.Close()
+}
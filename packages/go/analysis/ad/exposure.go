@@ -0,0 +1,100 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ad
+
+import (
+	"context"
+
+	"github.com/specterops/bloodhound/analysis"
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/dawgs/query"
+	"github.com/specterops/bloodhound/graphschema/ad"
+)
+
+// ComputerExposureRelationships are the computed access relationships counted towards a computer's exposure score
+func ComputerExposureRelationships() []graph.Kind {
+	return []graph.Kind{ad.AdminTo, ad.CanRDP, ad.ExecuteDCOM, ad.CanPSRemote}
+}
+
+// ComputerExposureOptions tunes ComputeComputerExposureWithOptions
+type ComputerExposureOptions struct {
+	// Kinds overrides the relationships counted towards exposure. When empty ComputerExposureRelationships is used.
+	Kinds []graph.Kind
+
+	// WeightByCost weighs each relationship by how easy it is to exploit according to its analysis.EdgeCostProperty:
+	// EdgeCostLow counts 3, EdgeCostMedium 2 and EdgeCostHigh 1. Unscored relationships count 1 as they would unweighted.
+	WeightByCost bool
+}
+
+// exposureWeight returns the weight of a relationship with the given cost. Lower costs are easier to exploit and so
+// weigh more.
+func exposureWeight(cost analysis.EdgeCost) int {
+	if cost < analysis.EdgeCostLow || cost > analysis.EdgeCostHigh {
+		return 1
+	}
+
+	return int(analysis.EdgeCostHigh-cost) + 1
+}
+
+// ComputeComputerExposure returns an exposure score for every computer with at least one inbound relationship of
+// ComputerExposureRelationships. The score is the number of such relationships, so it is only meaningful once
+// post-processing has run.
+func ComputeComputerExposure(ctx context.Context, db graph.Database) (map[graph.ID]int, error) {
+	return ComputeComputerExposureWithOptions(ctx, db, ComputerExposureOptions{})
+}
+
+// ComputeComputerExposureWithOptions runs ComputeComputerExposure with the given options. Relationships are streamed and
+// only the running score of each computer is kept, so memory grows with the number of exposed computers rather than
+// the number of relationships.
+func ComputeComputerExposureWithOptions(ctx context.Context, db graph.Database, options ComputerExposureOptions) (map[graph.ID]int, error) {
+	var (
+		exposure = map[graph.ID]int{}
+		kinds    = options.Kinds
+	)
+
+	if len(kinds) == 0 {
+		kinds = ComputerExposureRelationships()
+	}
+
+	return exposure, db.ReadTransaction(ctx, func(tx graph.Transaction) error {
+		exposureQuery := tx.Relationships().Filterf(func() graph.Criteria {
+			return query.And(
+				query.KindIn(query.Relationship(), kinds...),
+				query.Kind(query.End(), ad.Computer),
+			)
+		})
+
+		if !options.WeightByCost {
+			return exposureQuery.FetchTriples(func(cursor graph.Cursor[graph.RelationshipTripleResult]) error {
+				for result := range cursor.Chan() {
+					exposure[result.EndID]++
+				}
+
+				return cursor.Error()
+			})
+		}
+
+		return exposureQuery.Fetch(func(cursor graph.Cursor[*graph.Relationship]) error {
+			for relationship := range cursor.Chan() {
+				cost, _ := relationship.Properties.Get(analysis.EdgeCostProperty).Int()
+				exposure[relationship.EndID] += exposureWeight(analysis.EdgeCost(cost))
+			}
+
+			return cursor.Error()
+		})
+	})
+}
@@ -139,3 +139,7 @@ func SelectGPOTierZeroCandidateFilter(node *graph.Node) bool {
 func SelectGPOContainerCandidateFilter(node *graph.Node) bool {
 	return node.Kinds.ContainsOneOf(ad.OU, ad.Domain)
 }
+
+func SelectGPOApplicableCandidateFilter(node *graph.Node) bool {
+	return node.Kinds.ContainsOneOf(ad.User, ad.Computer)
+}
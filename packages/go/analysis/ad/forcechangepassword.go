@@ -0,0 +1,141 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ad
+
+import (
+	"context"
+
+	"github.com/specterops/bloodhound/analysis"
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/dawgs/query"
+	"github.com/specterops/bloodhound/dawgs/util/channels"
+	"github.com/specterops/bloodhound/graphschema/ad"
+)
+
+// ForceChangePasswordSourceProperty records which right a post-processed ForceChangePassword edge was derived from. A
+// ForceChangePassword edge without it was collected from the User-Force-Change-Password extended right.
+const (
+	ForceChangePasswordSourceProperty   = "forcechangepasswordsource"
+	ForceChangePasswordSourceGenericAll = "GenericAll"
+)
+
+// DeleteDerivedForceChangePasswordEdges removes every ForceChangePassword edge created by post-processing while leaving
+// collected ForceChangePassword edges in place
+func DeleteDerivedForceChangePasswordEdges(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+	return deleteDerivedEdges(ctx, db, ad.ForceChangePassword, ForceChangePasswordSourceProperty)
+}
+
+// PostForceChangePasswordFromGenericAll emits a ForceChangePassword edge from every principal holding GenericAll over a
+// user, as GenericAll includes the right to reset the user's password. Pairs already joined by a collected
+// ForceChangePassword edge are skipped and every emitted edge is stamped with ForceChangePasswordSourceProperty.
+func PostForceChangePasswordFromGenericAll(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+	return PostForceChangePasswordFromGenericAllWithOptions(ctx, db, DerivedEdgeOptions{})
+}
+
+// PostForceChangePasswordFromGenericAllWithOptions runs PostForceChangePasswordFromGenericAll with the given options
+func PostForceChangePasswordFromGenericAllWithOptions(ctx context.Context, db graph.Database, options DerivedEdgeOptions) (*analysis.AtomicPostProcessingStats, error) {
+	return postDerivedFromRights(ctx, db, options.Collectors, "ForceChangePassword From GenericAll Post Processing", ad.ForceChangePassword, ForceChangePasswordSourceProperty, query.Kind(query.End(), ad.User), ad.GenericAll)
+}
+
+// fetchDCSyncTargets returns the domains every principal holding DCSync can replicate from
+func fetchDCSyncTargets(tx graph.Transaction) (map[graph.ID][]graph.ID, error) {
+	syncTargets := map[graph.ID][]graph.ID{}
+
+	return syncTargets, tx.Relationships().Filterf(func() graph.Criteria {
+		return query.Kind(query.Relationship(), ad.DCSync)
+	}).FetchTriples(func(cursor graph.Cursor[graph.RelationshipTripleResult]) error {
+		for result := range cursor.Chan() {
+			syncTargets[result.StartID] = append(syncTargets[result.StartID], result.EndID)
+		}
+
+		return cursor.Error()
+	})
+}
+
+// PostCanDCSyncViaReset emits a CanDCSyncViaReset edge from every principal holding ForceChangePassword over a principal
+// with DCSync to each domain that principal can DCSync. Resetting the password hands over the account and with it the
+// replication rights, so the edge collapses the two hops into one for prioritization. Principals already holding DCSync
+// over the domain themselves are skipped.
+//
+// Both DCSync and ForceChangePassword are read as they are when this runs, so it must follow DCSync post-processing and
+// PostForceChangePasswordFromGenericAll for derived edges of either kind to be chained.
+func PostCanDCSyncViaReset(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+	operation := analysis.NewPostRelationshipOperation(ctx, db, "CanDCSyncViaReset Post Processing")
+
+	if err := operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		syncTargets, err := fetchDCSyncTargets(tx)
+		if err != nil || len(syncTargets) == 0 {
+			return err
+		}
+
+		var (
+			syncers = make([]graph.ID, 0, len(syncTargets))
+			skipped = map[endpointPair]struct{}{}
+			resets  []graph.RelationshipTripleResult
+		)
+
+		for syncer, domains := range syncTargets {
+			syncers = append(syncers, syncer)
+
+			for _, domain := range domains {
+				skipped[endpointPair{From: syncer, To: domain}] = struct{}{}
+			}
+		}
+
+		if err := tx.Relationships().Filterf(func() graph.Criteria {
+			return query.And(
+				query.Kind(query.Relationship(), ad.ForceChangePassword),
+				query.InIDs(query.EndID(), syncers...),
+			)
+		}).FetchTriples(func(cursor graph.Cursor[graph.RelationshipTripleResult]) error {
+			for result := range cursor.Chan() {
+				resets = append(resets, result)
+			}
+
+			return cursor.Error()
+		}); err != nil {
+			return err
+		}
+
+		for _, reset := range resets {
+			for _, domain := range syncTargets[reset.EndID] {
+				pair := endpointPair{From: reset.StartID, To: domain}
+
+				// Skips direct DCSync holders as well as pairs reachable through several reset targets
+				if _, isSkipped := skipped[pair]; isSkipped {
+					continue
+				}
+
+				skipped[pair] = struct{}{}
+
+				if !channels.Submit(ctx, outC, analysis.CreatePostRelationshipJob{
+					FromID: reset.StartID,
+					ToID:   domain,
+					Kind:   ad.CanDCSyncViaReset,
+				}) {
+					return nil
+				}
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return &operation.Stats, err
+	}
+
+	return &operation.Stats, operation.Done()
+}
@@ -0,0 +1,190 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ad
+
+import (
+	"context"
+
+	"github.com/specterops/bloodhound/analysis"
+	"github.com/specterops/bloodhound/dawgs/cardinality"
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/dawgs/ops"
+	"github.com/specterops/bloodhound/dawgs/query"
+	"github.com/specterops/bloodhound/dawgs/util/channels"
+	"github.com/specterops/bloodhound/graphschema/ad"
+)
+
+// GMSAReadSourceProperty records the control right a post-processed ReadGMSAPassword edge was derived from. Collected
+// ReadGMSAPassword edges come from the GMSA's msDS-GroupMSAMembership and never carry it.
+const (
+	GMSAReadSourceProperty     = "gmsareadsource"
+	GMSAReadSourceGenericAll   = "GenericAll"
+	GMSAReadSourceGenericWrite = "GenericWrite"
+)
+
+// DeleteDerivedGMSAReadEdges removes every ReadGMSAPassword edge created by post-processing while leaving collected
+// ReadGMSAPassword edges in place
+func DeleteDerivedGMSAReadEdges(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+	return deleteDerivedEdges(ctx, db, ad.ReadGMSAPassword, GMSAReadSourceProperty)
+}
+
+// FetchGMSAAccountIDs returns the IDs of the group managed service accounts in the graph. Whether an account is a GMSA
+// is not collected as a property, so accounts are recognized by having at least one collected ReadGMSAPassword edge. A
+// GMSA that no principal is allowed to read is therefore not found.
+func FetchGMSAAccountIDs(tx graph.Transaction) ([]graph.ID, error) {
+	gmsaAccounts := cardinality.NewBitmap32()
+
+	if err := tx.Relationships().Filterf(func() graph.Criteria {
+		return query.And(
+			query.Kind(query.Relationship(), ad.ReadGMSAPassword),
+			query.Not(query.Exists(query.RelationshipProperty(GMSAReadSourceProperty))),
+		)
+	}).FetchTriples(func(cursor graph.Cursor[graph.RelationshipTripleResult]) error {
+		for result := range cursor.Chan() {
+			gmsaAccounts.Add(result.EndID.Uint32())
+		}
+
+		return cursor.Error()
+	}); err != nil {
+		return nil, err
+	}
+
+	return cardinality.DuplexToGraphIDs(gmsaAccounts), nil
+}
+
+// PostReadGMSAPasswordFromControl emits a ReadGMSAPassword edge from every principal holding GenericAll or GenericWrite
+// over a GMSA. Either right allows the principal to add itself to msDS-GroupMSAMembership and then retrieve the managed
+// password. Pairs already joined by a collected ReadGMSAPassword edge are skipped. Every emitted edge is stamped with
+// GMSAReadSourceProperty, preferring GenericAll when both rights are held.
+func PostReadGMSAPasswordFromControl(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+	return PostReadGMSAPasswordFromControlWithOptions(ctx, db, DerivedEdgeOptions{})
+}
+
+// PostReadGMSAPasswordFromControlWithOptions runs PostReadGMSAPasswordFromControl with the given options
+func PostReadGMSAPasswordFromControlWithOptions(ctx context.Context, db graph.Database, options DerivedEdgeOptions) (*analysis.AtomicPostProcessingStats, error) {
+	var gmsaAccounts []graph.ID
+
+	if err := db.ReadTransaction(ctx, func(tx graph.Transaction) error {
+		if fetchedAccounts, err := FetchGMSAAccountIDs(tx); err != nil {
+			return err
+		} else {
+			gmsaAccounts = fetchedAccounts
+			return nil
+		}
+	}); err != nil {
+		return &analysis.AtomicPostProcessingStats{}, err
+	} else if len(gmsaAccounts) == 0 {
+		stats := analysis.NewAtomicPostProcessingStats()
+		return &stats, nil
+	}
+
+	return postDerivedFromRights(ctx, db, options.Collectors, "ReadGMSAPassword From Control Post Processing", ad.ReadGMSAPassword, GMSAReadSourceProperty, query.InIDs(query.EndID(), gmsaAccounts...), ad.GenericAll, ad.GenericWrite)
+}
+
+// fetchGMSAHosts returns the computers allowed to retrieve the managed password of the given GMSA. The hosts are read
+// from the collected ReadGMSAPassword edges, which mirror the GMSA's PrincipalsAllowedToRetrieveManagedPassword.
+// Groups allowed to retrieve the password are expanded to their member computers.
+func fetchGMSAHosts(tx graph.Transaction, gmsaAccount graph.ID) ([]graph.ID, error) {
+	if readers, err := ops.FetchStartNodes(tx.Relationships().Filterf(func() graph.Criteria {
+		return query.And(
+			query.Kind(query.Relationship(), ad.ReadGMSAPassword),
+			query.Equals(query.EndID(), gmsaAccount),
+			query.Not(query.Exists(query.RelationshipProperty(GMSAReadSourceProperty))),
+		)
+	})); err != nil {
+		return nil, err
+	} else if readerMembers, err := analysis.ExpandGroupMembership(tx, readers); err != nil {
+		return nil, err
+	} else {
+		readers.AddSet(readerMembers)
+
+		var hosts []graph.ID
+
+		for _, reader := range readers {
+			if reader.Kinds.ContainsOneOf(ad.Computer) {
+				hosts = append(hosts, reader.ID)
+			}
+		}
+
+		return hosts, nil
+	}
+}
+
+// PostAdminToViaGMSA emits an AdminToViaGMSA edge from every computer allowed to retrieve a GMSA's managed password to
+// every computer the GMSA holds AdminTo over. A host runs the GMSA's services and can retrieve its password at will,
+// so compromising the host yields the GMSA's local admin rights. The password retrieval itself is the collected
+// ReadGMSAPassword edge from the host, or from a group the host belongs to. GMSAs without authorized hosts or without
+// AdminTo produce no edges, and pairs reachable through several GMSAs are emitted once.
+//
+// This must run after every step emitting AdminTo.
+func PostAdminToViaGMSA(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+	operation := analysis.NewPostRelationshipOperation(ctx, db, "AdminToViaGMSA Post Processing")
+
+	if err := operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		gmsaAccounts, err := FetchGMSAAccountIDs(tx)
+		if err != nil || len(gmsaAccounts) == 0 {
+			return err
+		}
+
+		administeredComputers, err := fetchAdministeredComputers(tx)
+		if err != nil {
+			return err
+		}
+
+		emitted := map[endpointPair]struct{}{}
+
+		for _, gmsaAccount := range gmsaAccounts {
+			computers := administeredComputers[gmsaAccount]
+
+			if len(computers) == 0 {
+				continue
+			}
+
+			if hosts, err := fetchGMSAHosts(tx, gmsaAccount); err != nil {
+				return err
+			} else {
+				for _, host := range hosts {
+					for _, computer := range computers {
+						pair := endpointPair{From: host, To: computer}
+
+						if host == computer {
+							continue
+						} else if _, seen := emitted[pair]; seen {
+							continue
+						}
+
+						emitted[pair] = struct{}{}
+
+						if !channels.Submit(ctx, outC, analysis.CreatePostRelationshipJob{
+							FromID: host,
+							ToID:   computer,
+							Kind:   ad.AdminToViaGMSA,
+						}) {
+							return nil
+						}
+					}
+				}
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return &operation.Stats, err
+	}
+
+	return &operation.Stats, operation.Done()
+}
@@ -0,0 +1,132 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ad
+
+import (
+	"context"
+
+	"github.com/specterops/bloodhound/analysis"
+	"github.com/specterops/bloodhound/dawgs/cardinality"
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/dawgs/ops"
+	"github.com/specterops/bloodhound/dawgs/query"
+	"github.com/specterops/bloodhound/dawgs/util/channels"
+	"github.com/specterops/bloodhound/graphschema/ad"
+)
+
+// GPOControlRelationships are the rights over a GPO that allow a principal to rewrite its settings
+func GPOControlRelationships() []graph.Kind {
+	return []graph.Kind{ad.GenericAll, ad.GenericWrite, ad.WriteDACL, ad.WriteOwner, ad.Owns}
+}
+
+// fetchGPOControllers returns the IDs of every principal that directly holds one of GPOControlRelationships over a GPO
+func fetchGPOControllers(tx graph.Transaction) (cardinality.Duplex[uint32], error) {
+	controllers := cardinality.NewBitmap32()
+
+	return controllers, tx.Relationships().Filterf(func() graph.Criteria {
+		return query.And(
+			query.KindIn(query.Relationship(), GPOControlRelationships()...),
+			query.Kind(query.End(), ad.GPO),
+		)
+	}).FetchTriples(func(cursor graph.Cursor[graph.RelationshipTripleResult]) error {
+		for result := range cursor.Chan() {
+			controllers.Add(result.StartID.Uint32())
+		}
+
+		return cursor.Error()
+	})
+}
+
+// FetchGPOApplicableObjects returns the users and computers that a GPO linked to the given OU would apply to. The link
+// is treated as not enforced, so descent stops below any child OU that blocks inheritance.
+func FetchGPOApplicableObjects(tx graph.Transaction, ou *graph.Node) (graph.NodeSet, error) {
+	return ops.AcyclicTraverseNodes(tx, ops.TraversalPlan{
+		Root:          ou,
+		Direction:     graph.DirectionOutbound,
+		BranchQuery:   FilterContainsRelationship,
+		DescentFilter: BlocksInheritanceDescentFilter,
+	}, SelectGPOApplicableCandidateFilter)
+}
+
+// PostWriteGPLink emits a CanApplyGPO edge from every principal holding WriteGPLink over an OU to each user and computer
+// under that OU. Linking only leads to takeover when there is a malicious GPO to link, so a principal must also directly
+// control at least one GPO for its WriteGPLink rights to be considered.
+func PostWriteGPLink(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+	operation := analysis.NewPostRelationshipOperation(ctx, db, "WriteGPLink Post Processing")
+
+	if err := operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		var (
+			linkersByOU    = map[graph.ID][]graph.ID{}
+			gpoControllers cardinality.Duplex[uint32]
+		)
+
+		if controllers, err := fetchGPOControllers(tx); err != nil {
+			return err
+		} else if controllers.Cardinality() == 0 {
+			return nil
+		} else {
+			gpoControllers = controllers
+		}
+
+		if err := tx.Relationships().Filterf(func() graph.Criteria {
+			return query.And(
+				query.Kind(query.Relationship(), ad.WriteGPLink),
+				query.Kind(query.End(), ad.OU),
+			)
+		}).FetchTriples(func(cursor graph.Cursor[graph.RelationshipTripleResult]) error {
+			for result := range cursor.Chan() {
+				if gpoControllers.Contains(result.StartID.Uint32()) {
+					linkersByOU[result.EndID] = append(linkersByOU[result.EndID], result.StartID)
+				}
+			}
+
+			return cursor.Error()
+		}); err != nil {
+			return err
+		}
+
+		for ouID, linkers := range linkersByOU {
+			if ou, err := ops.FetchNode(tx, ouID); err != nil {
+				return err
+			} else if applicableObjects, err := FetchGPOApplicableObjects(tx, ou); err != nil {
+				return err
+			} else {
+				for _, linker := range linkers {
+					for _, applicableObject := range applicableObjects {
+						if linker == applicableObject.ID {
+							continue
+						}
+
+						if !channels.Submit(ctx, outC, analysis.CreatePostRelationshipJob{
+							FromID: linker,
+							ToID:   applicableObject.ID,
+							Kind:   ad.CanApplyGPO,
+						}) {
+							return nil
+						}
+					}
+				}
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return &operation.Stats, err
+	}
+
+	return &operation.Stats, operation.Done()
+}
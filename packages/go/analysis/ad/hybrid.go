@@ -0,0 +1,217 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ad
+
+import (
+	"context"
+	"strings"
+
+	"github.com/specterops/bloodhound/analysis"
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/dawgs/query"
+	"github.com/specterops/bloodhound/dawgs/util/channels"
+	"github.com/specterops/bloodhound/graphschema/ad"
+	"github.com/specterops/bloodhound/graphschema/azure"
+	"github.com/specterops/bloodhound/graphschema/common"
+	"github.com/specterops/bloodhound/log"
+)
+
+// PostHybridIdentityLink emits a SyncedToEntraUser edge from every on-prem AD user to the Entra user it is synced to.
+// The linkage comes from the on-prem security identifier collected for Entra users with on-prem sync enabled. Entra
+// users without linkage, or whose on-prem user is not in the graph, are skipped.
+func PostHybridIdentityLink(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+	operation := analysis.NewPostRelationshipOperation(ctx, db, "Hybrid Identity Link Post Processing")
+
+	if err := operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		entraUsersByOnPremID := map[string][]graph.ID{}
+
+		if err := tx.Nodes().Filterf(func() graph.Criteria {
+			return query.And(
+				query.Kind(query.Node(), azure.User),
+				query.Equals(query.NodeProperty(azure.OnPremSyncEnabled.String()), true),
+				query.Exists(query.NodeProperty(azure.OnPremID.String())),
+			)
+		}).Fetch(func(cursor graph.Cursor[*graph.Node]) error {
+			for entraUser := range cursor.Chan() {
+				if onPremID, err := entraUser.Properties.Get(azure.OnPremID.String()).String(); err != nil {
+					log.Debugf("Skipping Entra user %d with invalid on-prem ID: %v", entraUser.ID, err)
+				} else if onPremID != "" {
+					onPremID = strings.ToUpper(onPremID)
+					entraUsersByOnPremID[onPremID] = append(entraUsersByOnPremID[onPremID], entraUser.ID)
+				}
+			}
+
+			return cursor.Error()
+		}); err != nil {
+			return err
+		}
+
+		if len(entraUsersByOnPremID) == 0 {
+			return nil
+		}
+
+		onPremIDs := make([]string, 0, len(entraUsersByOnPremID))
+		for onPremID := range entraUsersByOnPremID {
+			onPremIDs = append(onPremIDs, onPremID)
+		}
+
+		return tx.Nodes().Filterf(func() graph.Criteria {
+			return query.And(
+				query.Kind(query.Node(), ad.User),
+				query.In(query.NodeProperty(common.ObjectID.String()), onPremIDs),
+			)
+		}).Fetch(func(cursor graph.Cursor[*graph.Node]) error {
+			for adUser := range cursor.Chan() {
+				objectID, err := adUser.Properties.Get(common.ObjectID.String()).String()
+				if err != nil {
+					continue
+				}
+
+				for _, entraUserID := range entraUsersByOnPremID[strings.ToUpper(objectID)] {
+					nextJob := analysis.CreatePostRelationshipJob{
+						FromID: adUser.ID,
+						ToID:   entraUserID,
+						Kind:   ad.SyncedToEntraUser,
+					}
+
+					if !channels.Submit(ctx, outC, nextJob) {
+						return nil
+					}
+				}
+			}
+
+			return cursor.Error()
+		})
+	}); err != nil {
+		return &operation.Stats, err
+	}
+
+	return &operation.Stats, operation.Done()
+}
+
+// fetchSyncedEntraGroupRoles returns the roles directly assigned to each Entra group synced from on-prem, keyed by the
+// upper cased on-prem security identifier of the group. Synced groups without role assignments are left out.
+func fetchSyncedEntraGroupRoles(tx graph.Transaction) (map[string][]graph.ID, error) {
+	var (
+		onPremIDsByEntraGroup = map[graph.ID]string{}
+		rolesByOnPremID       = map[string][]graph.ID{}
+	)
+
+	if err := tx.Nodes().Filterf(func() graph.Criteria {
+		return query.And(
+			query.Kind(query.Node(), azure.Group),
+			query.Equals(query.NodeProperty(azure.OnPremSyncEnabled.String()), true),
+			query.Exists(query.NodeProperty(azure.OnPremID.String())),
+		)
+	}).Fetch(func(cursor graph.Cursor[*graph.Node]) error {
+		for entraGroup := range cursor.Chan() {
+			if onPremID, err := entraGroup.Properties.Get(azure.OnPremID.String()).String(); err != nil {
+				log.Debugf("Skipping Entra group %d with invalid on-prem ID: %v", entraGroup.ID, err)
+			} else if onPremID != "" {
+				onPremIDsByEntraGroup[entraGroup.ID] = strings.ToUpper(onPremID)
+			}
+		}
+
+		return cursor.Error()
+	}); err != nil || len(onPremIDsByEntraGroup) == 0 {
+		return rolesByOnPremID, err
+	}
+
+	entraGroupIDs := make([]graph.ID, 0, len(onPremIDsByEntraGroup))
+	for entraGroupID := range onPremIDsByEntraGroup {
+		entraGroupIDs = append(entraGroupIDs, entraGroupID)
+	}
+
+	return rolesByOnPremID, tx.Relationships().Filterf(func() graph.Criteria {
+		return query.And(
+			query.Kind(query.Relationship(), azure.HasRole),
+			query.InIDs(query.StartID(), entraGroupIDs...),
+			query.Kind(query.End(), azure.Role),
+		)
+	}).FetchTriples(func(cursor graph.Cursor[graph.RelationshipTripleResult]) error {
+		for result := range cursor.Chan() {
+			onPremID := onPremIDsByEntraGroup[result.StartID]
+			rolesByOnPremID[onPremID] = append(rolesByOnPremID[onPremID], result.EndID)
+		}
+
+		return cursor.Error()
+	})
+}
+
+// PostGroupSyncedToEntraRole emits a SyncedToEntraRole edge from every on-prem AD group to each Entra role directly
+// assigned to the Entra group it is synced to. Members of the on-prem group are synced into the Entra group and so hold
+// its roles, which bridges AD membership to cloud privilege. Linkage uses the on-prem security identifier collected for
+// synced Entra groups. Graphs without synced Entra groups, role assignments or the matching on-prem groups produce no
+// edges.
+func PostGroupSyncedToEntraRole(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+	operation := analysis.NewPostRelationshipOperation(ctx, db, "Group Synced To Entra Role Post Processing")
+
+	if err := operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		if rolesByOnPremID, err := fetchSyncedEntraGroupRoles(tx); err != nil {
+			return err
+		} else if len(rolesByOnPremID) == 0 {
+			log.Debugf("No synced Entra groups with role assignments found; skipping group to Entra role linkage")
+			return nil
+		} else {
+			onPremIDs := make([]string, 0, len(rolesByOnPremID))
+			for onPremID := range rolesByOnPremID {
+				onPremIDs = append(onPremIDs, onPremID)
+			}
+
+			return tx.Nodes().Filterf(func() graph.Criteria {
+				return query.And(
+					query.Kind(query.Node(), ad.Group),
+					query.In(query.NodeProperty(common.ObjectID.String()), onPremIDs),
+				)
+			}).Fetch(func(cursor graph.Cursor[*graph.Node]) error {
+				for adGroup := range cursor.Chan() {
+					objectID, err := adGroup.Properties.Get(common.ObjectID.String()).String()
+					if err != nil {
+						continue
+					}
+
+					// Several synced Entra groups sharing an on-prem ID may hold the same role
+					emitted := map[graph.ID]struct{}{}
+
+					for _, roleID := range rolesByOnPremID[strings.ToUpper(objectID)] {
+						if _, seen := emitted[roleID]; seen {
+							continue
+						}
+
+						emitted[roleID] = struct{}{}
+
+						nextJob := analysis.CreatePostRelationshipJob{
+							FromID: adGroup.ID,
+							ToID:   roleID,
+							Kind:   ad.SyncedToEntraRole,
+						}
+
+						if !channels.Submit(ctx, outC, nextJob) {
+							return nil
+						}
+					}
+				}
+
+				return cursor.Error()
+			})
+		}
+	}); err != nil {
+		return &operation.Stats, err
+	}
+
+	return &operation.Stats, operation.Done()
+}
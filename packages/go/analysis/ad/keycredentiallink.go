@@ -0,0 +1,93 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ad
+
+import (
+	"context"
+
+	"github.com/specterops/bloodhound/analysis"
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/dawgs/query"
+	"github.com/specterops/bloodhound/graphschema/ad"
+)
+
+// KeyCredentialLinkSourceProperty records the control right a post-processed AddKeyCredentialLink edge was derived from.
+// Collected AddKeyCredentialLink edges come from an explicit write grant on msDS-KeyCredentialLink and never carry it.
+const (
+	KeyCredentialLinkSourceProperty     = "keycredentiallinksource"
+	KeyCredentialLinkSourceGenericAll   = "GenericAll"
+	KeyCredentialLinkSourceGenericWrite = "GenericWrite"
+)
+
+// KeyCredentialLinkSeverityProperty grades a post-processed AddKeyCredentialLink edge. Shadow credentials on a domain
+// controller's computer account yield a ticket for the DC itself and with it the ability to replicate the domain, so
+// those edges are graded critical while every other target is graded high.
+const (
+	KeyCredentialLinkSeverityProperty = "keycredentiallinkseverity"
+	KeyCredentialLinkSeverityHigh     = "High"
+	KeyCredentialLinkSeverityCritical = "Critical"
+)
+
+// DeleteDerivedKeyCredentialLinkEdges removes every AddKeyCredentialLink edge created by post-processing while leaving
+// collected AddKeyCredentialLink edges in place
+func DeleteDerivedKeyCredentialLinkEdges(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+	return deleteDerivedEdges(ctx, db, ad.AddKeyCredentialLink, KeyCredentialLinkSourceProperty)
+}
+
+// keyCredentialLinkSeverityAnnotator grades each derived AddKeyCredentialLink edge by whether its target is one of the
+// given domain controllers. A nil set is detected within the reader transaction.
+func keyCredentialLinkSeverityAnnotator(domainControllers *DomainControllerSet) derivedEdgeAnnotator {
+	return func(tx graph.Transaction) (func(target graph.ID, properties map[string]any), error) {
+		if domainControllers == nil {
+			if fetchedDomainControllers, err := fetchDomainControllerSet(tx, DefaultWellKnownRIDs()); err != nil {
+				return nil, err
+			} else {
+				domainControllers = fetchedDomainControllers
+			}
+		}
+
+		return func(target graph.ID, properties map[string]any) {
+			if domainControllers.Contains(target) {
+				properties[KeyCredentialLinkSeverityProperty] = KeyCredentialLinkSeverityCritical
+			} else {
+				properties[KeyCredentialLinkSeverityProperty] = KeyCredentialLinkSeverityHigh
+			}
+		}, nil
+	}
+}
+
+// PostAddKeyCredentialLinkFromControl emits an AddKeyCredentialLink edge from every principal holding GenericAll or
+// GenericWrite over a user or computer. Either right covers writing msDS-KeyCredentialLink, which allows a shadow
+// credential to be planted and used to authenticate as the target. Computer targets are included as this takes over
+// the computer account without resource-based constrained delegation or a silver ticket. Pairs already joined by a
+// collected AddKeyCredentialLink edge are skipped. Every emitted edge is stamped with KeyCredentialLinkSourceProperty,
+// preferring GenericAll when both rights are held, and with KeyCredentialLinkSeverityProperty.
+func PostAddKeyCredentialLinkFromControl(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+	return PostAddKeyCredentialLinkFromControlWithDomainControllers(ctx, db, nil)
+}
+
+// PostAddKeyCredentialLinkFromControlWithDomainControllers runs PostAddKeyCredentialLinkFromControl with a precomputed
+// domain controller set used to grade severity. A nil set is detected by the processor.
+func PostAddKeyCredentialLinkFromControlWithDomainControllers(ctx context.Context, db graph.Database, domainControllers *DomainControllerSet) (*analysis.AtomicPostProcessingStats, error) {
+	return PostAddKeyCredentialLinkFromControlWithOptions(ctx, db, DerivedEdgeOptions{DomainControllers: domainControllers})
+}
+
+// PostAddKeyCredentialLinkFromControlWithOptions runs PostAddKeyCredentialLinkFromControl with the given options
+func PostAddKeyCredentialLinkFromControlWithOptions(ctx context.Context, db graph.Database, options DerivedEdgeOptions) (*analysis.AtomicPostProcessingStats, error) {
+	return postAnnotatedDerivedFromRights(ctx, db, options.Collectors, "AddKeyCredentialLink From Control Post Processing", ad.AddKeyCredentialLink, KeyCredentialLinkSourceProperty,
+		query.KindIn(query.End(), ad.User, ad.Computer), keyCredentialLinkSeverityAnnotator(options.DomainControllers), ad.GenericAll, ad.GenericWrite)
+}
@@ -0,0 +1,76 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ad
+
+import (
+	"context"
+
+	"github.com/specterops/bloodhound/analysis"
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/dawgs/query"
+	"github.com/specterops/bloodhound/graphschema/ad"
+)
+
+// LAPSReadSourceProperty records which right a post-processed ReadLAPSPassword edge was derived from. Collected
+// ReadLAPSPassword edges never carry this property, which is what separates them from derived edges during cleanup.
+const (
+	LAPSReadSourceProperty   = "lapsreadsource"
+	LAPSReadSourceGenericAll = "GenericAll"
+)
+
+// DeleteDerivedLAPSReadEdges removes every ReadLAPSPassword edge created by post-processing while leaving collected
+// ReadLAPSPassword edges in place. ReadLAPSPassword can not be listed in PostProcessedRelationships as that would also
+// delete the collected edges.
+func DeleteDerivedLAPSReadEdges(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+	return deleteDerivedEdges(ctx, db, ad.ReadLAPSPassword, LAPSReadSourceProperty)
+}
+
+// PostReadLAPSPasswordFromGenericAll emits a ReadLAPSPassword edge from every principal holding GenericAll over a
+// computer with HasLAPS=true. GenericAll includes the control access right needed to read ms-Mcs-AdmPwd, so these
+// principals can read the password even without an explicit read grant. Pairs already joined by a collected
+// ReadLAPSPassword edge are skipped and every emitted edge is stamped with LAPSReadSourceProperty.
+func PostReadLAPSPasswordFromGenericAll(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+	return PostReadLAPSPasswordFromGenericAllWithOptions(ctx, db, DerivedEdgeOptions{})
+}
+
+// PostReadLAPSPasswordFromGenericAllWithOptions runs PostReadLAPSPasswordFromGenericAll with the given options
+func PostReadLAPSPasswordFromGenericAllWithOptions(ctx context.Context, db graph.Database, options DerivedEdgeOptions) (*analysis.AtomicPostProcessingStats, error) {
+	return postDerivedFromRights(ctx, db, options.Collectors, "ReadLAPSPassword From GenericAll Post Processing", ad.ReadLAPSPassword, LAPSReadSourceProperty, query.And(
+		query.Kind(query.End(), ad.Computer),
+		query.Equals(query.EndProperty(ad.HasLAPS.String()), true),
+	), ad.GenericAll)
+}
+
+// LAPSAdminSourceProperty is set on AdminTo edges derived from ReadLAPSPassword by PostAdminToFromLAPSRead. AdminTo is
+// purged wholesale before each analysis run, so unlike LAPSReadSourceProperty it is not needed for cleanup and only
+// records provenance.
+const LAPSAdminSourceProperty = "lapsadminsource"
+
+// PostAdminToFromLAPSRead emits an AdminTo edge from every principal holding ReadLAPSPassword over a computer. The LAPS
+// managed password belongs to the computer's local administrator account, so reading it is equivalent to local admin.
+// Both collected and derived ReadLAPSPassword edges are used, which means this must run after
+// PostReadLAPSPasswordFromGenericAll to chain GenericAll through the password read into AdminTo. Pairs already joined by
+// an AdminTo edge, such as one from local group membership, are skipped and every emitted edge is stamped with
+// LAPSAdminSourceProperty.
+func PostAdminToFromLAPSRead(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+	return PostAdminToFromLAPSReadWithOptions(ctx, db, DerivedEdgeOptions{})
+}
+
+// PostAdminToFromLAPSReadWithOptions runs PostAdminToFromLAPSRead with the given options
+func PostAdminToFromLAPSReadWithOptions(ctx context.Context, db graph.Database, options DerivedEdgeOptions) (*analysis.AtomicPostProcessingStats, error) {
+	return postDerivedFromRights(ctx, db, options.Collectors, "AdminTo From ReadLAPSPassword Post Processing", ad.AdminTo, LAPSAdminSourceProperty, query.Kind(query.End(), ad.Computer), ad.ReadLAPSPassword)
+}
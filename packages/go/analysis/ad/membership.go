@@ -19,6 +19,8 @@ package ad
 import (
 	"context"
 	"fmt"
+	"sync"
+	"sync/atomic"
 
 	"github.com/specterops/bloodhound/analysis"
 	"github.com/specterops/bloodhound/analysis/impact"
@@ -31,28 +33,99 @@ import (
 	"github.com/specterops/bloodhound/log"
 )
 
+// ResolveAllGroupMemberships expands the membership of every group and local group in the graph. MemberOf and
+// MemberOfLocalGroup are followed interchangeably, so a local group's expansion includes the transitive members of any
+// domain group nested in it.
 func ResolveAllGroupMemberships(ctx context.Context, db graph.Database, additionalCriteria ...graph.Criteria) (impact.PathAggregator, error) {
 	defer log.Measure(log.LevelInfo, "ResolveAllGroupMemberships")()
 
-	var (
-		adGroupIDs []graph.ID
+	return resolveGroupMemberships(ctx, db, nil, 0, additionalCriteria...)
+}
 
-		searchCriteria = []graph.Criteria{query.KindIn(query.Relationship(), ad.MemberOf, ad.MemberOfLocalGroup)}
-		coordC         = make(chan struct{}, analysis.MaximumDatabaseParallelWorkers)
-		traversalMap   = cardinality.ThreadSafeDuplex(cardinality.NewBitmap32())
-		memberships    = impact.NewThreadSafeAggregator(impact.NewIDA(func() cardinality.Provider[uint32] {
+// ResolveAllGroupMembershipsWithMaxHops behaves like ResolveAllGroupMemberships but stops descending once a membership
+// path is maxHops relationships long. A maxHops of zero or less leaves the traversal unbounded. Memberships beyond the
+// budget are left out of the result and a warning is logged whenever the budget cuts a traversal short.
+func ResolveAllGroupMembershipsWithMaxHops(ctx context.Context, db graph.Database, maxHops int, additionalCriteria ...graph.Criteria) (impact.PathAggregator, error) {
+	defer log.Measure(log.LevelInfo, "ResolveAllGroupMembershipsWithMaxHops %d", maxHops)()
+
+	return resolveGroupMemberships(ctx, db, nil, maxHops, additionalCriteria...)
+}
+
+// ResolveDomainGroupMemberships behaves like ResolveAllGroupMemberships but only resolves the groups and local groups
+// whose domain SID matches the given domain SID. Members are still followed into other domains.
+func ResolveDomainGroupMemberships(ctx context.Context, db graph.Database, domainSID string, additionalCriteria ...graph.Criteria) (impact.PathAggregator, error) {
+	defer log.Measure(log.LevelInfo, "ResolveDomainGroupMemberships %s", domainSID)()
+
+	return resolveGroupMemberships(ctx, db, query.Equals(query.NodeProperty(ad.DomainSID.String()), domainSID), 0, additionalCriteria...)
+}
+
+// ResolveDomainGroupMembershipsWithMaxHops is the hop bounded form of ResolveDomainGroupMemberships. See
+// ResolveAllGroupMembershipsWithMaxHops for how maxHops is applied.
+func ResolveDomainGroupMembershipsWithMaxHops(ctx context.Context, db graph.Database, domainSID string, maxHops int, additionalCriteria ...graph.Criteria) (impact.PathAggregator, error) {
+	defer log.Measure(log.LevelInfo, "ResolveDomainGroupMembershipsWithMaxHops %s %d", domainSID, maxHops)()
+
+	return resolveGroupMemberships(ctx, db, query.Equals(query.NodeProperty(ad.DomainSID.String()), domainSID), maxHops, additionalCriteria...)
+}
+
+// ResolveChangedGroupMemberships updates memberships, as returned by ResolveAllGroupMemberships, after the direct
+// members of the given groups changed in the graph. Every changed group is invalidated along with the groups it is a
+// transitive member of, and only those groups are traversed again. Memberships of groups below them are reused rather
+// than traversed. Groups that gained or lost a member, including those that gained a new nested group, must all be
+// listed; a group created or deleted since memberships were resolved is listed like any other changed group.
+//
+// The additional criteria must match those memberships were resolved with. The updated aggregator is returned and
+// matches what a full resolution of the current graph would produce.
+func ResolveChangedGroupMemberships(ctx context.Context, db graph.Database, memberships impact.PathAggregator, changedGroups []graph.ID, additionalCriteria ...graph.Criteria) (impact.PathAggregator, error) {
+	defer log.Measure(log.LevelInfo, "ResolveChangedGroupMemberships %d", len(changedGroups))()
+
+	resolveChangedGroupMemberships(ctx, db, memberships, changedGroups, 0, additionalCriteria...)
+	return memberships, nil
+}
+
+// ResolveChangedGroupMembershipsWithMaxHops is the hop bounded form of ResolveChangedGroupMemberships. The given
+// maxHops must match the one memberships were resolved with.
+func ResolveChangedGroupMembershipsWithMaxHops(ctx context.Context, db graph.Database, memberships impact.PathAggregator, changedGroups []graph.ID, maxHops int, additionalCriteria ...graph.Criteria) (impact.PathAggregator, error) {
+	defer log.Measure(log.LevelInfo, "ResolveChangedGroupMembershipsWithMaxHops %d %d", len(changedGroups), maxHops)()
+
+	resolveChangedGroupMemberships(ctx, db, memberships, changedGroups, maxHops, additionalCriteria...)
+	return memberships, nil
+}
+
+func resolveChangedGroupMemberships(ctx context.Context, db graph.Database, memberships impact.PathAggregator, changedGroups []graph.ID, maxHops int, additionalCriteria ...graph.Criteria) {
+	invalidated := cardinality.NewBitmap32()
+
+	for _, changedGroup := range changedGroups {
+		invalidated.Or(memberships.InvalidateGroup(changedGroup.Uint32()))
+	}
+
+	log.Infof("Invalidated %d groups for %d changed groups", invalidated.Cardinality(), len(changedGroups))
+
+	// Nodes outside the invalidated set that the aggregator already holds keep valid memberships and are joined to
+	// the traversals as shortcuts. Nodes it does not hold, such as groups nested since the last resolution, are
+	// traversed.
+	isSettled := func(node uint32) bool {
+		return !invalidated.Contains(node) && memberships.Contains(node)
+	}
+
+	traverseGroupMemberships(ctx, db, memberships, cardinality.DuplexToGraphIDs(invalidated), isSettled, maxHops, additionalCriteria...)
+}
+
+func resolveGroupMemberships(ctx context.Context, db graph.Database, groupCriteria graph.Criteria, maxHops int, additionalCriteria ...graph.Criteria) (impact.PathAggregator, error) {
+	var (
+		adGroupIDs  []graph.ID
+		memberships = impact.NewThreadSafeAggregator(impact.NewIDA(func() cardinality.Provider[uint32] {
 			return cardinality.NewBitmap32()
 		}))
 	)
 
-	if len(additionalCriteria) > 0 {
-		searchCriteria = append(searchCriteria, additionalCriteria...)
-	}
-
 	if err := db.ReadTransaction(ctx, func(tx graph.Transaction) error {
-		if fetchedGroups, err := ops.FetchNodeIDs(tx.Nodes().Filter(
-			query.KindIn(query.Node(), ad.Group, ad.LocalGroup),
-		)); err != nil {
+		var groupFilter graph.Criteria = query.KindIn(query.Node(), ad.Group, ad.LocalGroup)
+
+		if groupCriteria != nil {
+			groupFilter = query.And(groupFilter, groupCriteria)
+		}
+
+		if fetchedGroups, err := ops.FetchNodeIDs(tx.Nodes().Filter(groupFilter)); err != nil {
 			return err
 		} else {
 			adGroupIDs = fetchedGroups
@@ -64,29 +137,89 @@ func ResolveAllGroupMemberships(ctx context.Context, db graph.Database, addition
 
 	log.Infof("Collected %d groups to resolve", len(adGroupIDs))
 
+	traverseGroupMemberships(ctx, db, memberships, adGroupIDs, nil, maxHops, additionalCriteria...)
+	return memberships, nil
+}
+
+// traverseGroupMemberships traverses the membership of each of the given groups into memberships. Members for which
+// isSettled, when not nil, returns true are added as shortcuts instead of being traversed. Traversal errors are logged
+// rather than returned.
+//
+// A shortcut joins the full membership of its target, however deep, so shortcuts are only taken when maxHops leaves
+// the traversal unbounded. With a hop budget every group is traversed from its own root and a member is descended into
+// again whenever it is reached in fewer hops than before, which keeps the result independent of traversal order.
+func traverseGroupMemberships(ctx context.Context, db graph.Database, memberships impact.PathAggregator, adGroupIDs []graph.ID, isSettled func(node uint32) bool, maxHops int, additionalCriteria ...graph.Criteria) {
+	var (
+		numTruncatedPaths = &atomic.Int64{}
+
+		searchCriteria = []graph.Criteria{query.KindIn(query.Relationship(), ad.MemberOf, ad.MemberOfLocalGroup)}
+		coordC         = make(chan struct{}, analysis.MaximumDatabaseParallelWorkers)
+		traversalMap   = cardinality.ThreadSafeDuplex(cardinality.NewBitmap32())
+	)
+
+	if len(additionalCriteria) > 0 {
+		searchCriteria = append(searchCriteria, additionalCriteria...)
+	}
+
 	for i := 0; i < analysis.MaximumDatabaseParallelWorkers; i++ {
 		coordC <- struct{}{}
 	}
 
 	for _, adGroupID := range adGroupIDs {
-		if traversalMap.Contains(adGroupID.Uint32()) {
+		if maxHops <= 0 && traversalMap.Contains(adGroupID.Uint32()) {
 			continue
 		}
 
 		<-coordC
 
 		go func(adGroupID graph.ID) {
+			var (
+				reachedLock  = &sync.Mutex{}
+				reachedDepth = map[graph.ID]int{adGroupID: 0}
+			)
+
+			// descend reports whether a member reached by the given segment should be traversed from. Only used with a
+			// hop budget.
+			descend := func(segment *graph.IDSegment) bool {
+				reachedLock.Lock()
+				defer reachedLock.Unlock()
+
+				if depth, reached := reachedDepth[segment.Node]; reached && depth <= segment.Depth() {
+					return false
+				}
+
+				reachedDepth[segment.Node] = segment.Depth()
+				return true
+			}
+
 			if err := traversal.NewIDTraversal(db, analysis.MaximumDatabaseParallelWorkers).BreadthFirst(ctx, traversal.IDPlan{
 				Root: adGroupID,
 				Delegate: func(ctx context.Context, tx graph.Transaction, segment *graph.IDSegment) ([]*graph.IDSegment, error) {
 					if nextQuery, err := newTraversalQuery(tx, segment, graph.DirectionInbound, searchCriteria...); err != nil {
 						return nil, err
+					} else if maxHops > 0 && segment.Depth() >= maxHops {
+						// The budget is spent. Only check whether there was anything further to find so that the
+						// truncation can be reported.
+						if numMembers, err := nextQuery.Count(); err != nil {
+							return nil, err
+						} else if numMembers > 0 {
+							numTruncatedPaths.Add(1)
+						}
+
+						memberships.AddPath(segment)
+						return nil, nil
 					} else {
 						var nextSegments []*graph.IDSegment
 
 						if err := nextQuery.FetchTriples(func(cursor graph.Cursor[graph.RelationshipTripleResult]) error {
 							for nextTriple := range cursor.Chan() {
-								if traversalMap.CheckedAdd(nextTriple.StartID.Uint32()) {
+								if maxHops > 0 {
+									if nextSegment := segment.Descend(nextTriple.StartID, nextTriple.ID); descend(nextSegment) {
+										nextSegments = append(nextSegments, nextSegment)
+									}
+								} else if isSettled != nil && isSettled(nextTriple.StartID.Uint32()) {
+									memberships.AddShortcut(segment.Descend(nextTriple.StartID, nextTriple.ID))
+								} else if traversalMap.CheckedAdd(nextTriple.StartID.Uint32()) {
 									nextSegments = append(nextSegments, segment.Descend(nextTriple.StartID, nextTriple.ID))
 								} else {
 									memberships.AddShortcut(segment.Descend(nextTriple.StartID, nextTriple.ID))
@@ -122,7 +255,10 @@ func ResolveAllGroupMemberships(ctx context.Context, db graph.Database, addition
 	}
 
 	close(coordC)
-	return memberships, nil
+
+	if numTruncated := numTruncatedPaths.Load(); numTruncated > 0 {
+		log.Warnf("Group membership resolution stopped %d paths at the budget of %d hops; resolved memberships may be incomplete", numTruncated, maxHops)
+	}
 }
 
 func newTraversalQuery(tx graph.Transaction, segment *graph.IDSegment, direction graph.Direction, queryCriteria ...graph.Criteria) (graph.RelationshipQuery, error) {
@@ -0,0 +1,105 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ad
+
+import (
+	"context"
+
+	"github.com/specterops/bloodhound/analysis"
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/dawgs/query"
+	"github.com/specterops/bloodhound/dawgs/util/channels"
+	"github.com/specterops/bloodhound/graphschema/ad"
+)
+
+// ownershipConfersControlFilter excludes owned objects where ownership does not translate into lasting control. Objects
+// protected by AdminSDHolder (admincount=true) have both their owner and their DACL restored by SDProp, so any control
+// gained through ownership is undone within the hour.
+func ownershipConfersControlFilter() graph.Criteria {
+	return query.Or(
+		query.Not(query.Exists(query.EndProperty(ad.AdminCount.String()))),
+		query.Equals(query.EndProperty(ad.AdminCount.String()), false),
+	)
+}
+
+// PostOwnsImpliesControl emits an ImpliedGenericAll edge for every Owns edge. An object's owner is implicitly granted
+// READ_CONTROL and WRITE_DAC, which is enough to grant itself full control regardless of what the DACL currently says.
+// No edge is emitted for self-ownership, for AdminSDHolder protected objects, or where the owner already holds an
+// explicit GenericAll over the object.
+func PostOwnsImpliesControl(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+	operation := analysis.NewPostRelationshipOperation(ctx, db, "Owns Implies Control Post Processing")
+
+	if err := operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		var (
+			ownership       []endpointPair
+			explicitControl = map[endpointPair]struct{}{}
+		)
+
+		if err := tx.Relationships().Filterf(func() graph.Criteria {
+			return query.And(
+				query.Kind(query.Relationship(), ad.Owns),
+				ownershipConfersControlFilter(),
+			)
+		}).FetchTriples(func(cursor graph.Cursor[graph.RelationshipTripleResult]) error {
+			for result := range cursor.Chan() {
+				if result.StartID != result.EndID {
+					ownership = append(ownership, endpointPair{From: result.StartID, To: result.EndID})
+				}
+			}
+
+			return cursor.Error()
+		}); err != nil {
+			return err
+		}
+
+		if len(ownership) == 0 {
+			return nil
+		}
+
+		if err := tx.Relationships().Filterf(func() graph.Criteria {
+			return query.Kind(query.Relationship(), ad.GenericAll)
+		}).FetchTriples(func(cursor graph.Cursor[graph.RelationshipTripleResult]) error {
+			for result := range cursor.Chan() {
+				explicitControl[endpointPair{From: result.StartID, To: result.EndID}] = struct{}{}
+			}
+
+			return cursor.Error()
+		}); err != nil {
+			return err
+		}
+
+		for _, owned := range ownership {
+			if _, hasExplicitControl := explicitControl[owned]; hasExplicitControl {
+				continue
+			}
+
+			if !channels.Submit(ctx, outC, analysis.CreatePostRelationshipJob{
+				FromID: owned.From,
+				ToID:   owned.To,
+				Kind:   ad.ImpliedGenericAll,
+			}) {
+				return nil
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return &operation.Stats, err
+	}
+
+	return &operation.Stats, operation.Done()
+}
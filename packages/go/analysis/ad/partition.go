@@ -0,0 +1,64 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ad
+
+import (
+	"context"
+
+	"github.com/specterops/bloodhound/analysis"
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/dawgs/query"
+	"github.com/specterops/bloodhound/graphschema/ad"
+)
+
+// PartitionSelector scopes post-processing to a single tenant when several environments share one database. A node
+// belongs to the partition when its Property holds Value. The zero value selects every node.
+//
+// Only the domain rooted computations, PostDCSyncForPartition and PostSyncLAPSPasswordForPartition, accept a partition.
+// Relationships are assumed not to cross partitions, so the principals found by expanding from a partition's domains
+// belong to the same partition.
+type PartitionSelector struct {
+	Property string
+	Value    any
+}
+
+func (s PartitionSelector) IsZero() bool {
+	return s.Property == ""
+}
+
+// Scope joins the given criteria and, unless the selector is the zero value, a predicate matching the node reference
+// to the partition
+func (s PartitionSelector) Scope(reference graph.Criteria, criteria ...graph.Criteria) graph.Criteria {
+	if !s.IsZero() {
+		criteria = append(criteria, query.Equals(query.Property(reference, s.Property), s.Value))
+	}
+
+	return query.And(criteria...)
+}
+
+// DeletePartitionEdges deletes the post-processed relationships of the given kinds whose end node belongs to the
+// partition. This replaces the global purge when partitions are post-processed independently, so that running one
+// partition leaves the relationships of every other partition in place.
+func DeletePartitionEdges(ctx context.Context, db graph.Database, partition PartitionSelector, kinds ...graph.Kind) (*analysis.AtomicPostProcessingStats, error) {
+	var partitionCriteria graph.Criteria
+
+	if !partition.IsZero() {
+		partitionCriteria = partition.Scope(query.End())
+	}
+
+	return analysis.DeleteTransitEdgesMatching(ctx, db, ad.Entity, ad.Entity, partitionCriteria, kinds...)
+}
@@ -18,6 +18,9 @@ package ad
 
 import (
 	"context"
+	"errors"
+	"strconv"
+	"sync"
 
 	"github.com/RoaringBitmap/roaring/roaring64"
 	"github.com/specterops/bloodhound/analysis"
@@ -32,6 +35,12 @@ import (
 	"github.com/specterops/bloodhound/log"
 )
 
+// endpointPair identifies a directed relationship by its start and end node
+type endpointPair struct {
+	From graph.ID
+	To   graph.ID
+}
+
 func PostProcessedRelationships() []graph.Kind {
 	return []graph.Kind{
 		ad.DCSync,
@@ -40,79 +49,214 @@ func PostProcessedRelationships() []graph.Kind {
 		ad.AdminTo,
 		ad.CanPSRemote,
 		ad.ExecuteDCOM,
+		ad.SameForestTrust,
+		ad.CrossForestTrust,
+		ad.ImpliedGenericAll,
+		ad.SyncedToEntraUser,
+		ad.SyncedToEntraRole,
+		ad.CanApplyGPO,
+		ad.HasTrustKeys,
+		ad.CanTakeOver,
+		ad.CanImpersonate,
+		ad.SharedAdminLateral,
+		ad.RDPSessionCapture,
+		ad.CanDCSyncViaReset,
+		ad.AdminToViaGMSA,
+		ad.AccountOperatorControl,
 	}
 }
 
 func PostSyncLAPSPassword(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
-	if domainNodes, err := fetchCollectedDomainNodes(ctx, db); err != nil {
-		return &analysis.AtomicPostProcessingStats{}, err
-	} else {
-		operation := analysis.NewPostRelationshipOperation(ctx, db, "SyncLAPSPassword Post Processing")
-		for _, domain := range domainNodes {
-			innerDomain := domain
-			operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
-				if lapsSyncers, err := analysis.GetLAPSSyncers(tx, innerDomain); err != nil {
-					return err
-				} else if len(lapsSyncers) == 0 {
-					return nil
-				} else if computers, err := getLAPSComputersForDomain(tx, innerDomain); err != nil {
-					return err
-				} else {
-					for _, computer := range computers {
-						for _, lapsSyncer := range lapsSyncers {
-							nextJob := analysis.CreatePostRelationshipJob{
-								FromID: lapsSyncer.ID,
-								ToID:   computer,
-								Kind:   ad.SyncLAPSPassword,
-							}
-
-							if !channels.Submit(ctx, outC, nextJob) {
-								return nil
-							}
-						}
+	return PostSyncLAPSPasswordForPartition(ctx, db, PartitionSelector{})
+}
+
+// PostSyncLAPSPasswordForPartition runs PostSyncLAPSPassword against the collected domains of the given partition only
+func PostSyncLAPSPasswordForPartition(ctx context.Context, db graph.Database, partition PartitionSelector) (*analysis.AtomicPostProcessingStats, error) {
+	operation := analysis.NewPostRelationshipOperation(ctx, db, "SyncLAPSPassword Post Processing")
+
+	if err := forEachCollectedDomain(ctx, db, &operation, partition, func(ctx context.Context, tx graph.Transaction, domain *graph.Node, outC chan<- analysis.CreatePostRelationshipJob) error {
+		if lapsSyncers, err := analysis.GetLAPSSyncers(tx, domain); err != nil {
+			return err
+		} else if len(lapsSyncers) == 0 {
+			return nil
+		} else if computers, err := getLAPSComputersForDomain(tx, domain); err != nil {
+			return err
+		} else {
+			for _, computer := range computers {
+				for _, lapsSyncer := range lapsSyncers {
+					nextJob := analysis.CreatePostRelationshipJob{
+						FromID: lapsSyncer.ID,
+						ToID:   computer,
+						Kind:   ad.SyncLAPSPassword,
 					}
 
-					return nil
+					if !channels.Submit(ctx, outC, nextJob) {
+						return nil
+					}
 				}
-			})
-		}
+			}
 
-		return &operation.Stats, operation.Done()
+			return nil
+		}
+	}); err != nil {
+		return &operation.Stats, errors.Join(err, operation.Done())
 	}
+
+	return &operation.Stats, operation.Done()
+}
+
+// DCSyncSourceProperty records how the principal of a DCSync edge obtains the replication rights it needs: either by
+// holding them, by being able to modify the domain's DACL and grant them to itself, or by being a member of an Exchange
+// security group that was granted replication rights by Exchange setup.
+const (
+	DCSyncSourceProperty            = "dcsyncsource"
+	DCSyncSourceReplicationRights   = "ReplicationRights"
+	DCSyncSourceDACLModification    = "DACLModification"
+	DCSyncSourceExchangeReplication = "ExchangeReplication"
+)
+
+// DCSyncOptions selects the additional sources PostDCSyncWithOptions derives DCSync from. The zero value derives DCSync
+// only from principals holding both replication rights, as PostDCSync does.
+type DCSyncOptions struct {
+	// IncludeDACLControl also emits DCSync from the principals returned by analysis.GetDACLDCSyncers
+	IncludeDACLControl bool
+
+	// ExchangeGroups, when set, also emits DCSync from the groups it matches that hold GetChangesAll over a domain,
+	// along with their members. Exchange setup grants these groups GetChangesAll, which combined with the rights
+	// Exchange servers hold is enough to replicate secrets. A nil value disables Exchange detection.
+	ExchangeGroups *ExchangeGroupHeuristic
+
+	// SIDResolver, when set, is used to match ExchangeGroups by SID suffix in place of reading the object ID of every
+	// group holding GetChangesAll
+	SIDResolver SIDResolver
+
+	// Collectors attributes DCSync edges to the collectors that ingested their nodes. When nil it is loaded once before
+	// the domains are processed.
+	Collectors analysis.CollectorAttribution
 }
 
 func PostDCSync(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
-	if domainNodes, err := fetchCollectedDomainNodes(ctx, db); err != nil {
-		return &analysis.AtomicPostProcessingStats{}, err
-	} else {
-		operation := analysis.NewPostRelationshipOperation(ctx, db, "DCSync Post Processing")
+	return PostDCSyncForPartition(ctx, db, PartitionSelector{})
+}
+
+// PostDCSyncForPartition runs PostDCSync against the collected domains of the given partition only
+func PostDCSyncForPartition(ctx context.Context, db graph.Database, partition PartitionSelector) (*analysis.AtomicPostProcessingStats, error) {
+	return postDCSync(ctx, db, partition, DCSyncOptions{})
+}
+
+// PostDCSyncIncludingDACLControl runs PostDCSync and also emits DCSync from the principals returned by
+// analysis.GetDACLDCSyncers, which can grant themselves the replication rights. Those edges are stamped with
+// DCSyncSourceDACLModification. Principals already holding the replication rights keep a single edge stamped with
+// DCSyncSourceReplicationRights.
+func PostDCSyncIncludingDACLControl(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+	return postDCSync(ctx, db, PartitionSelector{}, DCSyncOptions{IncludeDACLControl: true})
+}
+
+// PostDCSyncWithOptions runs PostDCSync with the additional sources selected by the given options. Each principal
+// receives a single DCSync edge per domain, stamped with the first source that applies in the order replication rights,
+// Exchange replication, DACL modification.
+func PostDCSyncWithOptions(ctx context.Context, db graph.Database, options DCSyncOptions) (*analysis.AtomicPostProcessingStats, error) {
+	return postDCSync(ctx, db, PartitionSelector{}, options)
+}
+
+func postDCSync(ctx context.Context, db graph.Database, partition PartitionSelector, options DCSyncOptions) (*analysis.AtomicPostProcessingStats, error) {
+	if options.Collectors == nil {
+		if collectors, err := analysis.LoadCollectorAttribution(ctx, db); err != nil {
+			return &analysis.AtomicPostProcessingStats{}, err
+		} else {
+			options.Collectors = collectors
+		}
+	}
+
+	var (
+		operation       = analysis.NewPostRelationshipOperation(ctx, db, "DCSync Post Processing")
+		isExchangeGroup func(group *graph.Node) bool
+	)
+
+	if options.ExchangeGroups != nil {
+		isExchangeGroup = options.ExchangeGroups.MatcherWithResolver(options.SIDResolver)
+	}
+
+	if err := forEachCollectedDomain(ctx, db, &operation, partition, func(ctx context.Context, tx graph.Transaction, domain *graph.Node, outC chan<- analysis.CreatePostRelationshipJob) error {
+		emitted := map[graph.ID]struct{}{}
+
+		// submitSyncers emits DCSync for every syncer without an edge to this domain yet and returns false if the
+		// context was cancelled
+		submitSyncers := func(syncers []*graph.Node, source string) bool {
+			properties := map[string]any{DCSyncSourceProperty: source}
+
+			for _, syncer := range syncers {
+				if _, alreadyEmitted := emitted[syncer.ID]; alreadyEmitted {
+					continue
+				}
+
+				emitted[syncer.ID] = struct{}{}
+
+				if !channels.Submit(ctx, outC, analysis.CreatePostRelationshipJob{
+					FromID:          syncer.ID,
+					ToID:            domain.ID,
+					Kind:            ad.DCSync,
+					RelProperties:   properties,
+					SourceCollector: options.Collectors.Source(syncer.ID, domain.ID),
+				}) {
+					return false
+				}
+			}
 
+			return true
+		}
+
+		if dcSyncers, err := analysis.GetDCSyncers(tx, domain, true); err != nil {
+			return err
+		} else if !submitSyncers(dcSyncers, DCSyncSourceReplicationRights) {
+			return nil
+		}
+
+		if options.ExchangeGroups != nil {
+			if exchangeSyncers, err := analysis.GetExchangeDCSyncers(tx, domain, true, isExchangeGroup); err != nil {
+				return err
+			} else if !submitSyncers(exchangeSyncers, DCSyncSourceExchangeReplication) {
+				return nil
+			}
+		}
+
+		if options.IncludeDACLControl {
+			if daclSyncers, err := analysis.GetDACLDCSyncers(tx, domain, true); err != nil {
+				return err
+			} else if !submitSyncers(daclSyncers, DCSyncSourceDACLModification) {
+				return nil
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return &operation.Stats, errors.Join(err, operation.Done())
+	}
+
+	return &operation.Stats, operation.Done()
+}
+
+// collectedDomainReader is the body of a reader submitted by forEachCollectedDomain for a single domain
+type collectedDomainReader func(ctx context.Context, tx graph.Transaction, domain *graph.Node, outC chan<- analysis.CreatePostRelationshipJob) error
+
+// forEachCollectedDomain fetches the collected domains of the given partition and submits one reader per domain to the
+// given operation, each running reader against its domain. On error the caller remains responsible for calling Done on
+// the operation.
+func forEachCollectedDomain(ctx context.Context, db graph.Database, operation *analysis.StatTrackedOperation[analysis.CreatePostRelationshipJob], partition PartitionSelector, reader collectedDomainReader) error {
+	if domainNodes, err := fetchCollectedDomainNodes(ctx, db, partition); err != nil {
+		return err
+	} else {
 		for _, domain := range domainNodes {
 			innerDomain := domain
-			operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
-				if dcSyncers, err := analysis.GetDCSyncers(tx, innerDomain, true); err != nil {
-					return err
-				} else if len(dcSyncers) == 0 {
-					return nil
-				} else {
-					for _, dcSyncer := range dcSyncers {
-						nextJob := analysis.CreatePostRelationshipJob{
-							FromID: dcSyncer.ID,
-							ToID:   innerDomain.ID,
-							Kind:   ad.DCSync,
-						}
-
-						if !channels.Submit(ctx, outC, nextJob) {
-							return nil
-						}
-					}
 
-					return nil
-				}
-			})
+			if err := operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+				return reader(ctx, tx, innerDomain, outC)
+			}); err != nil {
+				return err
+			}
 		}
 
-		return &operation.Stats, operation.Done()
+		return nil
 	}
 }
 
@@ -132,12 +276,176 @@ func FetchComputers(ctx context.Context, db graph.Database) (*roaring64.Bitmap,
 	})
 }
 
-func fetchCollectedDomainNodes(ctx context.Context, db graph.Database) ([]*graph.Node, error) {
+// TierZeroComputerCriteria matches nodes tagged as tier zero assets. It is meant to be given to
+// FetchPrioritizedComputers so that tier zero computers are processed first.
+func TierZeroComputerCriteria() graph.Criteria {
+	return query.StringContains(query.NodeProperty(common.SystemTags.String()), ad.AdminTierZero)
+}
+
+// FetchPrioritizedComputers returns the IDs of every computer, with the computers matching priorityCriteria ahead of
+// the rest. Each of the two groups is in ascending ID order, the order of FetchComputers, which is also the order
+// returned when priorityCriteria is nil.
+func FetchPrioritizedComputers(ctx context.Context, db graph.Database, priorityCriteria graph.Criteria) ([]uint64, error) {
+	if computers, err := FetchComputers(ctx, db); err != nil {
+		return nil, err
+	} else {
+		return PrioritizeComputers(ctx, db, computers, priorityCriteria)
+	}
+}
+
+// PrioritizeComputers orders the given computer IDs as FetchPrioritizedComputers does. The given bitmap is not
+// modified.
+func PrioritizeComputers(ctx context.Context, db graph.Database, computers *roaring64.Bitmap, priorityCriteria graph.Criteria) ([]uint64, error) {
+	if priorityCriteria == nil || computers.IsEmpty() {
+		return computers.ToArray(), nil
+	}
+
+	priorityComputers := roaring64.NewBitmap()
+
+	if err := db.ReadTransaction(ctx, func(tx graph.Transaction) error {
+		return tx.Nodes().Filterf(func() graph.Criteria {
+			return query.And(
+				query.Kind(query.Node(), ad.Computer),
+				priorityCriteria,
+			)
+		}).FetchIDs(func(cursor graph.Cursor[graph.ID]) error {
+			for id := range cursor.Chan() {
+				if computers.Contains(id.Uint64()) {
+					priorityComputers.Add(id.Uint64())
+				}
+			}
+
+			return cursor.Error()
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	return append(priorityComputers.ToArray(), roaring64.AndNot(computers, priorityComputers).ToArray()...), nil
+}
+
+// FetchComputersWithoutURACollection returns the IDs of every computer whose HasURA property is false or absent. These
+// are the computers where CanRDP falls back to Remote Desktop Users membership alone, making the result a measure of
+// user rights assignment collection coverage.
+func FetchComputersWithoutURACollection(ctx context.Context, db graph.Database) (*roaring64.Bitmap, error) {
+	computerNodeIds := roaring64.NewBitmap()
+
+	return computerNodeIds, db.ReadTransaction(ctx, func(tx graph.Transaction) error {
+		return tx.Nodes().Filterf(func() graph.Criteria {
+			return query.And(
+				query.Kind(query.Node(), ad.Computer),
+				query.Or(
+					query.Not(query.Exists(query.NodeProperty(ad.HasURA.String()))),
+					query.Not(query.Equals(query.NodeProperty(ad.HasURA.String()), true)),
+				),
+			)
+		}).FetchIDs(func(cursor graph.Cursor[graph.ID]) error {
+			for id := range cursor.Chan() {
+				computerNodeIds.Add(id.Uint64())
+			}
+
+			return cursor.Error()
+		})
+	})
+}
+
+// BuiltinAdminSIDSuffixes returns the well-known RIDs of the built-in Administrator account and the Domain Admins group.
+// These principals hold local admin rights nearly everywhere and tend to drown out less obvious edges.
+func BuiltinAdminSIDSuffixes() []string {
+	return DefaultWellKnownRIDs().BuiltinAdminSIDSuffixes()
+}
+
+// FetchPrincipalBitmapBySIDSuffixes returns the IDs of every user, group and computer whose object identifier ends with
+// one of the given SID suffixes.
+func FetchPrincipalBitmapBySIDSuffixes(ctx context.Context, db graph.Database, sidSuffixes ...string) (cardinality.Duplex[uint32], error) {
+	principals := cardinality.NewBitmap32()
+
+	if len(sidSuffixes) == 0 {
+		return principals, nil
+	}
+
+	return principals, db.ReadTransaction(ctx, func(tx graph.Transaction) error {
+		suffixCriteria := make([]graph.Criteria, 0, len(sidSuffixes))
+
+		for _, sidSuffix := range sidSuffixes {
+			suffixCriteria = append(suffixCriteria, query.StringEndsWith(query.NodeProperty(common.ObjectID.String()), sidSuffix))
+		}
+
+		return tx.Nodes().Filterf(func() graph.Criteria {
+			return query.And(
+				query.KindIn(query.Node(), ad.User, ad.Group, ad.Computer),
+				query.Or(suffixCriteria...),
+			)
+		}).FetchIDs(func(cursor graph.Cursor[graph.ID]) error {
+			for id := range cursor.Chan() {
+				principals.Add(id.Uint32())
+			}
+
+			return cursor.Error()
+		})
+	})
+}
+
+// FetchDomainAdminsBitmap returns the effective members of the given domain's Domain Admins group, including members of
+// nested groups. The group itself is not part of the result. An empty bitmap is returned when the group is not in the
+// graph.
+func FetchDomainAdminsBitmap(tx graph.Transaction, domain *graph.Node) (cardinality.Duplex[uint32], error) {
+	return FetchDomainAdminsBitmapWithResolver(tx, domain, nil)
+}
+
+// FetchDomainAdminsBitmapWithResolver runs FetchDomainAdminsBitmap, finding the Domain Admins group with the given
+// resolver. A nil resolver looks it up in the graph instead.
+func FetchDomainAdminsBitmapWithResolver(tx graph.Transaction, domain *graph.Node, resolver SIDResolver) (cardinality.Duplex[uint32], error) {
+	domainAdmins := cardinality.NewBitmap32()
+
+	if domainSID, err := domain.Properties.Get(ad.DomainSID.String()).String(); err != nil {
+		return nil, err
+	} else if domainAdminsGroup, err := fetchDomainAdminsGroup(tx, domainSID, resolver); err != nil {
+		if graph.IsErrNotFound(err) {
+			return domainAdmins, nil
+		}
+
+		return nil, err
+	} else if members, err := ExpandGroupMembershipIDBitmap(tx, domainAdminsGroup); err != nil {
+		return nil, err
+	} else {
+		members.Remove(domainAdminsGroup.ID.Uint64())
+
+		for _, member := range members.ToArray() {
+			domainAdmins.Add(uint32(member))
+		}
+
+		return domainAdmins, nil
+	}
+}
+
+func fetchDomainAdminsGroup(tx graph.Transaction, domainSID string, resolver SIDResolver) (*graph.Node, error) {
+	domainAdminsSID := domainSID + RIDSuffix(DefaultWellKnownRIDs().DomainAdmins)
+
+	if resolver == nil {
+		return tx.Nodes().Filterf(func() graph.Criteria {
+			return query.And(
+				query.Kind(query.Node(), ad.Group),
+				query.Equals(query.NodeProperty(common.ObjectID.String()), domainAdminsSID),
+			)
+		}).First()
+	} else if groupID, found := resolver.Resolve(domainAdminsSID); !found {
+		return nil, graph.ErrNoResultsFound
+	} else if group, err := ops.FetchNode(tx, groupID); err != nil {
+		return nil, err
+	} else if !group.Kinds.ContainsOneOf(ad.Group) {
+		return nil, graph.ErrNoResultsFound
+	} else {
+		return group, nil
+	}
+}
+
+func fetchCollectedDomainNodes(ctx context.Context, db graph.Database, partition PartitionSelector) ([]*graph.Node, error) {
 	var nodes []*graph.Node
 	return nodes, db.ReadTransaction(ctx, func(tx graph.Transaction) error {
 		var err error
 		if nodes, err = ops.FetchNodes(tx.Nodes().Filterf(func() graph.Criteria {
-			return query.And(
+			return partition.Scope(query.Node(),
 				query.Kind(query.Node(), ad.Domain),
 				query.Equals(query.NodeProperty(common.Collected.String()), true),
 			)
@@ -172,17 +480,27 @@ func ExpandLocalGroupMembership(tx graph.Transaction, candidates graph.NodeSet)
 	}
 }
 
+// ExpandLocalGroupMembershipPaths returns the MemberOf and MemberOfLocalGroup paths leading into every group and local
+// group in candidates. Each node is descended into at most once per candidate, so a membership cycle in malformed
+// collection data, such as a local group that is transitively a member of itself, neither loops nor multiplies the
+// returned paths. Members reachable along several routes appear on only one of them.
 func ExpandLocalGroupMembershipPaths(tx graph.Transaction, candidates graph.NodeSet) (graph.PathSet, error) {
 	groupMemberPaths := graph.NewPathSet()
 
 	for _, candidate := range candidates {
-		if candidate.Kinds.ContainsOneOf(ad.Group) {
+		if candidate.Kinds.ContainsOneOf(ad.Group, ad.LocalGroup) {
+			visited := cardinality.NewBitmap32()
+			visited.Add(candidate.ID.Uint32())
+
 			if membershipPaths, err := ops.TraversePaths(tx, ops.TraversalPlan{
 				Root:      candidate,
 				Direction: graph.DirectionInbound,
 				BranchQuery: func() graph.Criteria {
 					return query.KindIn(query.Relationship(), ad.MemberOf, ad.MemberOfLocalGroup)
 				},
+				DescentFilter: func(ctx *ops.TraversalContext, segment *graph.PathSegment) bool {
+					return visited.CheckedAdd(segment.Node.ID.Uint32())
+				},
 			}); err != nil {
 				return nil, err
 			} else {
@@ -247,6 +565,38 @@ func FetchLocalGroupMembership(tx graph.Transaction, computer graph.ID, groupSuf
 	}
 }
 
+// FetchDeniedNetworkLogonPrincipals returns the principals that hold a collected "Deny access to this computer from the
+// network" right on the given computer
+func FetchDeniedNetworkLogonPrincipals(tx graph.Transaction, computer graph.ID) (graph.NodeSet, error) {
+	return ops.FetchStartNodes(tx.Relationships().Filterf(func() graph.Criteria {
+		return query.And(
+			query.Kind(query.Relationship(), ad.DenyNetworkLogonPrivilege),
+			query.Equals(query.EndID(), computer),
+		)
+	}))
+}
+
+// FetchLocalGroupMembershipExcludingDenied behaves like FetchLocalGroupMembership but removes any member that holds a
+// collected deny network logon right on the computer. Only the network right is considered as the edges built from
+// local group membership (AdminTo, CanPSRemote and ExecuteDCOM) all authenticate with a network logon; a denied
+// interactive logon does not block them. Where deny rights were not collected the result is identical to
+// FetchLocalGroupMembership.
+func FetchLocalGroupMembershipExcludingDenied(tx graph.Transaction, computer graph.ID, groupSuffix string) (graph.NodeSet, error) {
+	if members, err := FetchLocalGroupMembership(tx, computer, groupSuffix); err != nil {
+		return nil, err
+	} else if len(members) == 0 {
+		return members, nil
+	} else if deniedPrincipals, err := FetchDeniedNetworkLogonPrincipals(tx, computer); err != nil {
+		return nil, err
+	} else {
+		for _, deniedPrincipal := range deniedPrincipals {
+			members.Remove(deniedPrincipal.ID)
+		}
+
+		return members, nil
+	}
+}
+
 func FetchRemoteInteractiveLogonPrivilegedEntities(tx graph.Transaction, computerId graph.ID) (graph.NodeSet, error) {
 	return ops.FetchStartNodes(tx.Relationships().Filterf(func() graph.Criteria {
 		return query.And(
@@ -270,115 +620,313 @@ func HasRemoteInteractiveLogonPrivilege(tx graph.Transaction, groupId, computerI
 	return true
 }
 
+// FetchFirstDegreeLocalGroupMembers returns the IDs of the users, groups and computers that are direct members of the
+// given local group. Membership is not expanded.
+func FetchFirstDegreeLocalGroupMembers(tx graph.Transaction, localGroup graph.ID) (cardinality.Duplex[uint32], error) {
+	return fetchFirstDegreeLocalGroupMembers(tx, localGroup, nil, ad.User, ad.Group, ad.Computer)
+}
+
+func fetchFirstDegreeLocalGroupMembers(tx graph.Transaction, localGroup graph.ID, bitmapPool *BitmapPool, memberKinds ...graph.Kind) (cardinality.Duplex[uint32], error) {
+	members := bitmapPool.Get()
+
+	return members, tx.Relationships().Filterf(func() graph.Criteria {
+		return query.And(
+			query.KindIn(query.Start(), memberKinds...),
+			query.Kind(query.Relationship(), ad.MemberOfLocalGroup),
+			query.Equals(query.EndID(), localGroup),
+		)
+	}).FetchTriples(func(cursor graph.Cursor[graph.RelationshipTripleResult]) error {
+		for result := range cursor.Chan() {
+			members.Add(result.StartID.Uint32())
+		}
+
+		return cursor.Error()
+	})
+}
+
+// FetchLocalGroupBitmapForComputer returns the first degree members of the computer's local group with the given SID
+// suffix. A computer without such a local group yields an empty bitmap.
 func FetchLocalGroupBitmapForComputer(tx graph.Transaction, computer graph.ID, suffix string) (cardinality.Duplex[uint32], error) {
-	if members, err := FetchLocalGroupMembership(tx, computer, suffix); err != nil {
+	return FetchLocalGroupBitmapForComputerWithPool(tx, computer, suffix, nil)
+}
+
+// FetchLocalGroupBitmapForComputerWithPool behaves like FetchLocalGroupBitmapForComputer but draws the returned bitmap
+// from the given pool. The returned bitmap is owned by the caller and may be handed back to the pool with Put.
+func FetchLocalGroupBitmapForComputerWithPool(tx graph.Transaction, computer graph.ID, suffix string, bitmapPool *BitmapPool) (cardinality.Duplex[uint32], error) {
+	if localGroup, err := FetchComputerLocalGroupBySIDSuffix(tx, computer, suffix); err != nil {
 		if graph.IsErrNotFound(err) {
-			return cardinality.NewBitmap32(), nil
+			return bitmapPool.Get(), nil
 		}
 
 		return nil, err
 	} else {
-		return cardinality.NodeSetToDuplex(members), nil
+		return fetchFirstDegreeLocalGroupMembers(tx, localGroup.ID, bitmapPool, ad.User, ad.Group, ad.Computer)
 	}
 }
 
+// FetchLocalGroupBitmapForComputerExcludingDenied is the bitmap form of FetchLocalGroupMembershipExcludingDenied. The
+// returned bitmap is drawn from the given pool, which may be nil.
+func FetchLocalGroupBitmapForComputerExcludingDenied(tx graph.Transaction, computer graph.ID, suffix string, bitmapPool *BitmapPool) (cardinality.Duplex[uint32], error) {
+	if members, err := FetchLocalGroupBitmapForComputerWithPool(tx, computer, suffix, bitmapPool); err != nil {
+		return nil, err
+	} else if members.Cardinality() == 0 {
+		return members, nil
+	} else if deniedPrincipals, err := FetchDeniedNetworkLogonPrincipals(tx, computer); err != nil {
+		return nil, err
+	} else {
+		for _, deniedPrincipal := range deniedPrincipals {
+			members.Remove(deniedPrincipal.ID.Uint32())
+		}
+
+		return members, nil
+	}
+}
+
+// ExpandAllRDPLocalGroups resolves every group in the graph in a single pass. This is cheaper than iterating
+// ExpandRDPLocalGroupsForDomain over each domain as groups reached from several domains are only traversed once.
 func ExpandAllRDPLocalGroups(ctx context.Context, db graph.Database) (impact.PathAggregator, error) {
 	log.Infof("Expanding all AD group and local group memberships")
 
-	return ResolveAllGroupMemberships(ctx, db, query.Not(
-		query.Or(
-			query.StringEndsWith(query.StartProperty(common.ObjectID.String()), AdminGroupSuffix),
-			query.StringEndsWith(query.EndProperty(common.ObjectID.String()), AdminGroupSuffix),
-		),
-	))
+	return ResolveAllGroupMemberships(ctx, db, DefaultWellKnownRIDs().rdpExpansionCriteria())
+}
+
+// ExpandAllRDPLocalGroupsWithMaxHops is the hop bounded form of ExpandAllRDPLocalGroups. A maxHops of zero or less
+// leaves the expansion unbounded. The local Administrators group left out of the expansion is found with the given
+// RID table.
+func ExpandAllRDPLocalGroupsWithMaxHops(ctx context.Context, db graph.Database, maxHops int, rids WellKnownRIDs) (impact.PathAggregator, error) {
+	log.Infof("Expanding all AD group and local group memberships with a budget of %d hops", maxHops)
+
+	return ResolveAllGroupMembershipsWithMaxHops(ctx, db, maxHops, rids.rdpExpansionCriteria())
+}
+
+// ExpandRDPLocalGroupsForDomain is the single domain form of ExpandAllRDPLocalGroups for use when only one domain is
+// being reprocessed. Only groups and local groups carrying the given domain SID are resolved.
+func ExpandRDPLocalGroupsForDomain(ctx context.Context, db graph.Database, domainSID string) (impact.PathAggregator, error) {
+	log.Infof("Expanding AD group and local group memberships for domain %s", domainSID)
+
+	return ResolveDomainGroupMemberships(ctx, db, domainSID, DefaultWellKnownRIDs().rdpExpansionCriteria())
+}
+
+// BitmapPool recycles cardinality.Duplex[uint32] instances across calls to reduce allocation pressure when iterating
+// large numbers of computers. A bitmap returned by Get belongs to the caller until it is handed back with Put. A nil
+// *BitmapPool is valid and allocates a fresh bitmap for every call to Get.
+type BitmapPool struct {
+	pool sync.Pool
+}
+
+func NewBitmapPool() *BitmapPool {
+	return &BitmapPool{
+		pool: sync.Pool{
+			New: func() any {
+				return cardinality.NewBitmap32()
+			},
+		},
+	}
+}
+
+// Get returns an empty bitmap that is not shared with any other caller.
+func (s *BitmapPool) Get() cardinality.Duplex[uint32] {
+	if s == nil {
+		return cardinality.NewBitmap32()
+	}
+
+	return s.pool.Get().(cardinality.Duplex[uint32])
+}
+
+// Put clears the given bitmap and returns it to the pool. The bitmap must not be used by the caller afterward.
+func (s *BitmapPool) Put(bitmap cardinality.Duplex[uint32]) {
+	if s != nil && bitmap != nil {
+		bitmap.Clear()
+		s.pool.Put(bitmap)
+	}
 }
 
 func FetchRDPEntityBitmapForComputer(tx graph.Transaction, computer graph.ID, localGroupExpansions impact.PathAggregator) (cardinality.Duplex[uint32], error) {
+	return FetchRDPEntityBitmapForComputerWithPool(tx, computer, localGroupExpansions, nil)
+}
+
+// FetchRDPEntityBitmapForComputerWithPool behaves like FetchRDPEntityBitmapForComputer but draws both the returned
+// bitmap and any intermediate scratch bitmaps from the given pool. The returned bitmap is owned by the caller and may be
+// handed back to the pool with Put once the caller is done with it.
+func FetchRDPEntityBitmapForComputerWithPool(tx graph.Transaction, computer graph.ID, localGroupExpansions impact.PathAggregator, bitmapPool *BitmapPool) (cardinality.Duplex[uint32], error) {
 	if rdpLocalGroup, err := FetchComputerLocalGroupBySIDSuffix(tx, computer, RDPGroupSuffix); err != nil {
 		if graph.IsErrNotFound(err) {
-			return cardinality.NewBitmap32(), nil
+			return bitmapPool.Get(), nil
 		}
 
 		return nil, err
 	} else {
-		return ProcessRDPWithUra(tx, rdpLocalGroup, computer, localGroupExpansions)
+		return processRDPWithUra(tx, rdpLocalGroup, computer, localGroupExpansions, bitmapPool)
 	}
 }
 
 func FetchRDPEntityBitmapForComputerWithUnenforcedURA(tx graph.Transaction, computer graph.ID, localGroupExpansions impact.PathAggregator) (cardinality.Duplex[uint32], error) {
-	if rdpLocalGroup, err := FetchComputerLocalGroupBySIDSuffix(tx, computer, RDPGroupSuffix); err != nil {
+	return FetchRDPEntityBitmapForComputerWithUnenforcedURAAndRIDs(tx, computer, localGroupExpansions, DefaultWellKnownRIDs(), nil)
+}
+
+// FetchRDPEntityBitmapForComputerWithUnenforcedURAAndRIDs behaves like FetchRDPEntityBitmapForComputerWithUnenforcedURA
+// but finds the computer's Remote Desktop Users local group with the given RID table. The returned bitmap and any
+// scratch bitmaps are drawn from the given pool, which may be nil.
+func FetchRDPEntityBitmapForComputerWithUnenforcedURAAndRIDs(tx graph.Transaction, computer graph.ID, localGroupExpansions impact.PathAggregator, rids WellKnownRIDs, bitmapPool *BitmapPool) (cardinality.Duplex[uint32], error) {
+	rdpGroupSuffix := RIDSuffix(rids.RemoteDesktopUsers)
+
+	if rdpLocalGroup, err := FetchComputerLocalGroupBySIDSuffix(tx, computer, rdpGroupSuffix); err != nil {
 		if graph.IsErrNotFound(err) {
-			return cardinality.NewBitmap32(), nil
+			return bitmapPool.Get(), nil
 		}
 
 		return nil, err
 	} else if ComputerHasURACollection(tx, computer) {
-		return ProcessRDPWithUra(tx, rdpLocalGroup, computer, localGroupExpansions)
-	} else if bitmap, err := FetchLocalGroupBitmapForComputer(tx, computer, RDPGroupSuffix); err != nil {
+		return processRDPWithUra(tx, rdpLocalGroup, computer, localGroupExpansions, bitmapPool)
+	} else if bitmap, err := FetchLocalGroupBitmapForComputerWithPool(tx, computer, rdpGroupSuffix, bitmapPool); err != nil {
 		return nil, err
 	} else {
 		return bitmap, nil
 	}
 }
 
+// FetchDirectRDPLogonRightBitmapForComputer returns the principals granted "Allow log on through Remote Desktop
+// Services" on the computer regardless of their membership to its Remote Desktop Users local group. The right is
+// collected as a RemoteInteractiveLogonPrivilege edge; local groups holding it are replaced by their expanded
+// membership. Computers without URA collection yield an empty bitmap since the absence of the edge means nothing there.
+func FetchDirectRDPLogonRightBitmapForComputer(tx graph.Transaction, computer graph.ID, localGroupExpansions impact.PathAggregator) (cardinality.Duplex[uint32], error) {
+	rdpEntities := cardinality.NewBitmap32()
+
+	if !ComputerHasURACollection(tx, computer) {
+		return rdpEntities, nil
+	} else if rilEntities, err := FetchRemoteInteractiveLogonPrivilegedEntities(tx, computer); err != nil {
+		return nil, err
+	} else {
+		for _, entity := range rilEntities {
+			if entity.Kinds.ContainsOneOf(ad.LocalGroup) {
+				rdpEntities.Or(localGroupExpansions.Cardinality(entity.ID.Uint32()).(cardinality.Duplex[uint32]))
+			} else {
+				rdpEntities.Add(entity.ID.Uint32())
+			}
+		}
+
+		return rdpEntities, nil
+	}
+}
+
+// FetchDeniedRDPLogonPrincipals returns the principals that hold a collected "Deny log on through Remote Desktop
+// Services" right on the given computer
+func FetchDeniedRDPLogonPrincipals(tx graph.Transaction, computer graph.ID) (graph.NodeSet, error) {
+	return ops.FetchStartNodes(tx.Relationships().Filterf(func() graph.Criteria {
+		return query.And(
+			query.Kind(query.Relationship(), ad.DenyRemoteInteractiveLogonPrivilege),
+			query.Equals(query.EndID(), computer),
+		)
+	}))
+}
+
+// SubtractDeniedRDPEntities removes every principal denied logon through Remote Desktop Services on the computer from
+// the given CanRDP entities. Windows evaluates deny rights before allow rights, so members of a denied group lose
+// access even when they are granted it through another path; groups are therefore replaced by their expanded
+// membership before subtraction. The entities are left untouched where the deny right was not collected.
+func SubtractDeniedRDPEntities(tx graph.Transaction, computer graph.ID, rdpEntities cardinality.Duplex[uint32], localGroupExpansions impact.PathAggregator) error {
+	if rdpEntities.Cardinality() == 0 {
+		return nil
+	} else if deniedPrincipals, err := FetchDeniedRDPLogonPrincipals(tx, computer); err != nil {
+		return err
+	} else {
+		deniedEntities := cardinality.NewBitmap32()
+
+		for _, deniedPrincipal := range deniedPrincipals {
+			deniedEntities.Add(deniedPrincipal.ID.Uint32())
+
+			if deniedPrincipal.Kinds.ContainsOneOf(ad.Group, ad.LocalGroup) {
+				deniedEntities.Or(localGroupExpansions.Cardinality(deniedPrincipal.ID.Uint32()).(cardinality.Duplex[uint32]))
+			}
+		}
+
+		for _, deniedEntity := range deniedEntities.Slice() {
+			rdpEntities.Remove(deniedEntity)
+		}
+
+		return nil
+	}
+}
+
+// ComputerHasURACollection returns true when user rights assignments were collected for the given computer. A HasURA
+// value stored as a string encoded boolean is coerced, with a warning, rather than treated as missing collection.
 func ComputerHasURACollection(tx graph.Transaction, computerID graph.ID) bool {
 	if computer, err := tx.Nodes().Filterf(func() graph.Criteria {
 		return query.Equals(query.NodeID(), computerID)
 	}).First(); err != nil {
 		return false
 	} else {
-		if ura, err := computer.Properties.Get(ad.HasURA.String()).Bool(); err != nil {
-			return false
-		} else {
-			return ura
-		}
+		return nodeHasURACollection(computer)
+	}
+}
+
+func nodeHasURACollection(computer *graph.Node) bool {
+	hasURA := computer.Properties.Get(ad.HasURA.String())
+
+	if ura, err := hasURA.Bool(); err == nil {
+		return ura
+	} else if rawURA, err := hasURA.String(); err != nil {
+		return false
+	} else if ura, err := strconv.ParseBool(rawURA); err != nil {
+		log.Warnf("Computer %d has an unparsable %s value %q; treating user rights assignments as not collected", computer.ID, ad.HasURA, rawURA)
+		return false
+	} else {
+		log.Warnf("Computer %d has %s stored as the string %q; coercing it to a boolean", computer.ID, ad.HasURA, rawURA)
+		return ura
 	}
 }
 
 func ProcessRDPWithUra(tx graph.Transaction, rdpLocalGroup *graph.Node, computer graph.ID, localGroupExpansions impact.PathAggregator) (cardinality.Duplex[uint32], error) {
+	return processRDPWithUra(tx, rdpLocalGroup, computer, localGroupExpansions, nil)
+}
+
+func processRDPWithUra(tx graph.Transaction, rdpLocalGroup *graph.Node, computer graph.ID, localGroupExpansions impact.PathAggregator, bitmapPool *BitmapPool) (cardinality.Duplex[uint32], error) {
 	rdpLocalGroupMembers := localGroupExpansions.Cardinality(rdpLocalGroup.ID.Uint32()).(cardinality.Duplex[uint32])
 	//Shortcut opportunity: see if the RDP group has RIL privilege. If it does, get the first degree members and return those ids, since everything in RDP group has CanRDP privs. No reason to look any further
 	if HasRemoteInteractiveLogonPrivilege(tx, rdpLocalGroup.ID, computer) {
-		firstDegreeMembers := cardinality.NewBitmap32()
-
-		return firstDegreeMembers, tx.Relationships().Filter(
-			query.And(
-				query.Kind(query.Relationship(), ad.MemberOfLocalGroup),
-				query.KindIn(query.Start(), ad.Group, ad.User),
-				query.Equals(query.EndID(), rdpLocalGroup.ID),
-			),
-		).FetchTriples(func(cursor graph.Cursor[graph.RelationshipTripleResult]) error {
-			for result := range cursor.Chan() {
-				firstDegreeMembers.Add(result.StartID.Uint32())
-			}
-			return cursor.Error()
-		})
+		return fetchFirstDegreeLocalGroupMembers(tx, rdpLocalGroup.ID, bitmapPool, ad.Group, ad.User)
 	} else if baseRilEntities, err := FetchRemoteInteractiveLogonPrivilegedEntities(tx, computer); err != nil {
 		return nil, err
 	} else {
-		var (
-			rdpEntities      = cardinality.NewBitmap32()
-			secondaryTargets = cardinality.NewBitmap32()
-		)
+		return FilterRDPEntitiesByMembership(baseRilEntities, rdpLocalGroupMembers, localGroupExpansions, bitmapPool), nil
+	}
+}
 
-		// Attempt 2: look at each RIL entity directly and see if it has membership to the RDP group. If not, and it's a group, expand its membership for further processing
-		for _, entity := range baseRilEntities {
-			if rdpLocalGroupMembers.Contains(entity.ID.Uint32()) {
-				// If we have membership to the RDP group, then this is a valid CanRDP entity
-				rdpEntities.Add(entity.ID.Uint32())
-			} else if entity.Kinds.ContainsOneOf(ad.Group, ad.LocalGroup) {
-				secondaryTargets.Or(localGroupExpansions.Cardinality(entity.ID.Uint32()).(cardinality.Duplex[uint32]))
-			}
+// FilterRDPEntitiesByMembership returns the entities holding RemoteInteractiveLogonPrivilege that are also members of
+// the computer's RDP local group. RIL entities that are RDP members are kept as-is, while RIL groups that are not
+// members have their expanded membership intersected with the RDP membership instead. Membership is checked in bulk
+// with bitmap operations so the cost does not grow with a lookup per entity.
+func FilterRDPEntitiesByMembership(baseRilEntities graph.NodeSet, rdpLocalGroupMembers cardinality.Duplex[uint32], localGroupExpansions impact.PathAggregator, bitmapPool *BitmapPool) cardinality.Duplex[uint32] {
+	var (
+		rdpEntities      = bitmapPool.Get()
+		secondaryTargets = bitmapPool.Get()
+		rilGroups        []uint32
+	)
+
+	// The secondary targets bitmap is scratch space only and never escapes this function
+	defer bitmapPool.Put(secondaryTargets)
+
+	for _, entity := range baseRilEntities {
+		rdpEntities.Add(entity.ID.Uint32())
+
+		if entity.Kinds.ContainsOneOf(ad.Group, ad.LocalGroup) {
+			rilGroups = append(rilGroups, entity.ID.Uint32())
 		}
+	}
 
-		// Attempt 3: Look at each member of expanded groups and see if they have the correct permissions
-		for _, entity := range secondaryTargets.Slice() {
-			// If we have membership to the RDP group then this is a valid CanRDP entity
-			if rdpLocalGroupMembers.Contains(entity) {
-				rdpEntities.Add(entity)
-			}
-		}
+	// Attempt 2: RIL entities with membership to the RDP group are valid CanRDP entities
+	rdpEntities.And(rdpLocalGroupMembers)
 
-		return rdpEntities, nil
+	// RIL groups without membership to the RDP group have their own membership expanded for further processing
+	for _, rilGroup := range rilGroups {
+		if !rdpEntities.Contains(rilGroup) {
+			secondaryTargets.Or(localGroupExpansions.Cardinality(rilGroup).(cardinality.Duplex[uint32]))
+		}
 	}
+
+	// Attempt 3: members of expanded groups that have membership to the RDP group are valid CanRDP entities
+	secondaryTargets.And(rdpLocalGroupMembers)
+	rdpEntities.Or(secondaryTargets)
+
+	return rdpEntities
 }
@@ -0,0 +1,379 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ad_test
+
+import (
+	"testing"
+
+	"github.com/specterops/bloodhound/analysis"
+	"github.com/specterops/bloodhound/analysis/ad"
+	"github.com/specterops/bloodhound/analysis/impact"
+	"github.com/specterops/bloodhound/dawgs/cardinality"
+	"github.com/specterops/bloodhound/dawgs/graph"
+	graph_mocks "github.com/specterops/bloodhound/dawgs/graph/mocks"
+	adSchema "github.com/specterops/bloodhound/graphschema/ad"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// newNoRDPGroupTransaction returns a mock transaction where every computer lookup misses its RDP local group
+func newNoRDPGroupTransaction(ctrl *gomock.Controller) graph.Transaction {
+	var (
+		mockTx       = graph_mocks.NewMockTransaction(ctrl)
+		mockRelQuery = graph_mocks.NewMockRelationshipQuery(ctrl)
+	)
+
+	mockTx.EXPECT().Relationships().Return(mockRelQuery).AnyTimes()
+	mockRelQuery.EXPECT().Filter(gomock.Any()).Return(mockRelQuery).AnyTimes()
+	mockRelQuery.EXPECT().First().Return(nil, graph.ErrNoResultsFound).AnyTimes()
+
+	return mockTx
+}
+
+// newRDPGroupTransaction returns a mock transaction where every computer has an RDP local group holding
+// RemoteInteractiveLogonPrivilege with the given number of first degree members
+func newRDPGroupTransaction(ctrl *gomock.Controller, numMembers int) graph.Transaction {
+	var (
+		mockTx        = graph_mocks.NewMockTransaction(ctrl)
+		mockRelQuery  = graph_mocks.NewMockRelationshipQuery(ctrl)
+		mockNodeQuery = graph_mocks.NewMockNodeQuery(ctrl)
+		rdpLocalGroup = graph.NewNode(graph.ID(numMembers), graph.NewProperties(), adSchema.Entity, adSchema.LocalGroup)
+	)
+
+	mockTx.EXPECT().Relationships().Return(mockRelQuery).AnyTimes()
+	mockRelQuery.EXPECT().Filter(gomock.Any()).Return(mockRelQuery).AnyTimes()
+	mockRelQuery.EXPECT().Filterf(gomock.Any()).Return(mockRelQuery).AnyTimes()
+	mockRelQuery.EXPECT().First().Return(&graph.Relationship{StartID: rdpLocalGroup.ID}, nil).AnyTimes()
+	mockRelQuery.EXPECT().FetchTriples(gomock.Any()).DoAndReturn(func(delegate func(cursor graph.Cursor[graph.RelationshipTripleResult]) error) error {
+		cursor := graph_mocks.NewMockCursor[graph.RelationshipTripleResult](ctrl)
+		cursor.EXPECT().Error().Return(nil).AnyTimes()
+		cursor.EXPECT().Chan().DoAndReturn(func() chan graph.RelationshipTripleResult {
+			triples := make(chan graph.RelationshipTripleResult, numMembers)
+
+			for member := 0; member < numMembers; member++ {
+				triples <- graph.RelationshipTripleResult{StartID: graph.ID(member), EndID: rdpLocalGroup.ID}
+			}
+
+			close(triples)
+			return triples
+		}).AnyTimes()
+
+		return delegate(cursor)
+	}).AnyTimes()
+
+	mockTx.EXPECT().Nodes().Return(mockNodeQuery).AnyTimes()
+	mockNodeQuery.EXPECT().Filterf(gomock.Any()).Return(mockNodeQuery).AnyTimes()
+	mockNodeQuery.EXPECT().First().Return(rdpLocalGroup, nil).AnyTimes()
+
+	return mockTx
+}
+
+func TestBitmapPool(t *testing.T) {
+	var (
+		bitmapPool = ad.NewBitmapPool()
+		first      = bitmapPool.Get()
+		second     = bitmapPool.Get()
+	)
+
+	first.Add(1, 2, 3)
+	require.Equal(t, uint64(0), second.Cardinality())
+
+	bitmapPool.Put(first)
+	require.Equal(t, uint64(0), bitmapPool.Get().Cardinality())
+
+	var nilPool *ad.BitmapPool
+	require.NotNil(t, nilPool.Get())
+	nilPool.Put(cardinality.NewBitmap32())
+}
+
+func TestFetchRDPEntityBitmapForComputerWithPool_ReturnsIndependentBitmaps(t *testing.T) {
+	var (
+		ctrl       = gomock.NewController(t)
+		tx         = newNoRDPGroupTransaction(ctrl)
+		expansions = impact.NewIDA(func() cardinality.Provider[uint32] {
+			return cardinality.NewBitmap32()
+		})
+		bitmapPool = ad.NewBitmapPool()
+	)
+
+	first, err := ad.FetchRDPEntityBitmapForComputerWithPool(tx, 1, expansions, bitmapPool)
+	require.Nil(t, err)
+
+	second, err := ad.FetchRDPEntityBitmapForComputerWithPool(tx, 2, expansions, bitmapPool)
+	require.Nil(t, err)
+
+	first.Add(10)
+	require.False(t, second.Contains(10))
+}
+
+func BenchmarkFetchRDPEntityBitmapForComputer_NoPool(b *testing.B) {
+	var (
+		ctrl       = gomock.NewController(b)
+		tx         = newNoRDPGroupTransaction(ctrl)
+		expansions = impact.NewIDA(func() cardinality.Provider[uint32] {
+			return cardinality.NewBitmap32()
+		})
+	)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		if _, err := ad.FetchRDPEntityBitmapForComputer(tx, graph.ID(n), expansions); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFetchRDPEntityBitmapForComputer_Pool(b *testing.B) {
+	var (
+		ctrl       = gomock.NewController(b)
+		tx         = newNoRDPGroupTransaction(ctrl)
+		expansions = impact.NewIDA(func() cardinality.Provider[uint32] {
+			return cardinality.NewBitmap32()
+		})
+		bitmapPool = ad.NewBitmapPool()
+	)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		if bitmap, err := ad.FetchRDPEntityBitmapForComputerWithPool(tx, graph.ID(n), expansions, bitmapPool); err != nil {
+			b.Fatal(err)
+		} else {
+			bitmapPool.Put(bitmap)
+		}
+	}
+}
+
+func TestFetchRDPEntityBitmapForComputerWithPool_Found(t *testing.T) {
+	var (
+		ctrl       = gomock.NewController(t)
+		tx         = newRDPGroupTransaction(ctrl, 50)
+		expansions = impact.NewIDA(func() cardinality.Provider[uint32] {
+			return cardinality.NewBitmap32()
+		})
+		bitmapPool = ad.NewBitmapPool()
+	)
+
+	// A recycled bitmap must not carry the members of the previous computer over
+	for n := 0; n < 3; n++ {
+		bitmap, err := ad.FetchRDPEntityBitmapForComputerWithPool(tx, graph.ID(n), expansions, bitmapPool)
+		require.Nil(t, err)
+		require.Equal(t, uint64(50), bitmap.Cardinality())
+
+		bitmapPool.Put(bitmap)
+	}
+}
+
+func BenchmarkFetchRDPEntityBitmapForComputer_FoundNoPool(b *testing.B) {
+	var (
+		ctrl       = gomock.NewController(b)
+		tx         = newRDPGroupTransaction(ctrl, 1000)
+		expansions = impact.NewIDA(func() cardinality.Provider[uint32] {
+			return cardinality.NewBitmap32()
+		})
+	)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		if _, err := ad.FetchRDPEntityBitmapForComputer(tx, graph.ID(n), expansions); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFetchRDPEntityBitmapForComputer_FoundPool(b *testing.B) {
+	var (
+		ctrl       = gomock.NewController(b)
+		tx         = newRDPGroupTransaction(ctrl, 1000)
+		expansions = impact.NewIDA(func() cardinality.Provider[uint32] {
+			return cardinality.NewBitmap32()
+		})
+		bitmapPool = ad.NewBitmapPool()
+	)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		if bitmap, err := ad.FetchRDPEntityBitmapForComputerWithPool(tx, graph.ID(n), expansions, bitmapPool); err != nil {
+			b.Fatal(err)
+		} else {
+			bitmapPool.Put(bitmap)
+		}
+	}
+}
+
+func TestValidateWrittenKinds(t *testing.T) {
+	stats := analysis.NewAtomicPostProcessingStats()
+	stats.AddRelationshipsCreated(adSchema.DCSync, 2)
+
+	// Kinds with no created relationships are not considered written
+	stats.AddRelationshipsCreated(adSchema.AdminTo, 0)
+
+	require.Nil(t, ad.ValidateWrittenKinds("Declared", []graph.Kind{adSchema.DCSync}, &stats))
+	require.ErrorContains(t, ad.ValidateWrittenKinds("Undeclared", []graph.Kind{adSchema.AdminTo}, &stats), "Undeclared")
+}
+
+// newRDPMembershipFixture builds numEntities RIL entities where every tenth entity is a group with ten members. Every
+// other entity and every other group member also belongs to the RDP local group.
+func newRDPMembershipFixture(numEntities int) (graph.NodeSet, cardinality.Duplex[uint32], impact.PathAggregator) {
+	var (
+		rilEntities          = graph.NewNodeSet()
+		rdpLocalGroupMembers = cardinality.NewBitmap32()
+		expansions           = impact.NewIDA(func() cardinality.Provider[uint32] {
+			return cardinality.NewBitmap32()
+		})
+		nextMemberID = uint32(numEntities)
+	)
+
+	for idx := 0; idx < numEntities; idx++ {
+		entityID := graph.ID(idx)
+
+		if idx%10 == 0 {
+			rilEntities.Add(graph.NewNode(entityID, graph.NewProperties(), adSchema.Entity, adSchema.Group))
+
+			for member := 0; member < 10; member++ {
+				expansions.AddPath(graph.NewRootIDSegment(entityID).Descend(graph.ID(nextMemberID), 0))
+
+				if member%2 == 0 {
+					rdpLocalGroupMembers.Add(nextMemberID)
+				}
+
+				nextMemberID++
+			}
+		} else {
+			rilEntities.Add(graph.NewNode(entityID, graph.NewProperties(), adSchema.Entity, adSchema.User))
+		}
+
+		if idx%2 == 0 {
+			rdpLocalGroupMembers.Add(entityID.Uint32())
+		}
+	}
+
+	return rilEntities, rdpLocalGroupMembers, expansions
+}
+
+func TestFilterRDPEntitiesByMembership_MatchesPerEntityChecks(t *testing.T) {
+	var (
+		rilEntities, rdpLocalGroupMembers, expansions = newRDPMembershipFixture(1000)
+		expected                                      = cardinality.NewBitmap32()
+		secondaryTargets                              = cardinality.NewBitmap32()
+	)
+
+	// Reference implementation using a membership check per entity
+	for _, entity := range rilEntities {
+		if rdpLocalGroupMembers.Contains(entity.ID.Uint32()) {
+			expected.Add(entity.ID.Uint32())
+		} else if entity.Kinds.ContainsOneOf(adSchema.Group, adSchema.LocalGroup) {
+			secondaryTargets.Or(expansions.Cardinality(entity.ID.Uint32()).(cardinality.Duplex[uint32]))
+		}
+	}
+
+	for _, entity := range secondaryTargets.Slice() {
+		if rdpLocalGroupMembers.Contains(entity) {
+			expected.Add(entity)
+		}
+	}
+
+	actual := ad.FilterRDPEntitiesByMembership(rilEntities, rdpLocalGroupMembers, expansions, nil)
+	require.Equal(t, expected.Slice(), actual.Slice())
+}
+
+func BenchmarkFilterRDPEntitiesByMembership(b *testing.B) {
+	rilEntities, rdpLocalGroupMembers, expansions := newRDPMembershipFixture(10_000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		ad.FilterRDPEntitiesByMembership(rilEntities, rdpLocalGroupMembers, expansions, nil)
+	}
+}
+
+func TestComputerHasURACollection(t *testing.T) {
+	testCases := []struct {
+		name     string
+		hasURA   any
+		expected bool
+	}{
+		{name: "bool true", hasURA: true, expected: true},
+		{name: "bool false", hasURA: false, expected: false},
+		{name: "string true", hasURA: "true", expected: true},
+		{name: "string false", hasURA: "false", expected: false},
+		{name: "unparsable string", hasURA: "yes", expected: false},
+		{name: "missing", hasURA: nil, expected: false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var (
+				ctrl          = gomock.NewController(t)
+				mockTx        = graph_mocks.NewMockTransaction(ctrl)
+				mockNodeQuery = graph_mocks.NewMockNodeQuery(ctrl)
+				properties    = graph.NewProperties()
+			)
+
+			if testCase.hasURA != nil {
+				properties.Set(adSchema.HasURA.String(), testCase.hasURA)
+			}
+
+			mockTx.EXPECT().Nodes().Return(mockNodeQuery)
+			mockNodeQuery.EXPECT().Filterf(gomock.Any()).Return(mockNodeQuery)
+			mockNodeQuery.EXPECT().First().Return(graph.NewNode(1, properties, adSchema.Entity, adSchema.Computer), nil)
+
+			require.Equal(t, testCase.expected, ad.ComputerHasURACollection(mockTx, 1))
+		})
+	}
+}
+
+func TestWellKnownRIDs(t *testing.T) {
+	defaults := ad.DefaultWellKnownRIDs()
+
+	require.Equal(t, ad.AdministratorsGroupSIDSuffix, ad.RIDSuffix(defaults.Administrators))
+	require.Equal(t, ad.DomainAdminsGroupSIDSuffix, ad.RIDSuffix(defaults.DomainAdmins))
+	require.Equal(t, ad.RDPGroupSuffix, ad.RIDSuffix(defaults.RemoteDesktopUsers))
+	require.Equal(t, ad.BackupOperatorsGroupSIDSuffix, ad.RIDSuffix(defaults.BackupOperators))
+
+	require.Equal(t, defaults, ad.WellKnownRIDs{}.OrDefault())
+
+	t.Run("Overrides Are Honored", func(t *testing.T) {
+		overridden := defaults
+		overridden.Administrators = 1544
+		overridden.AccountOperators = 1548
+
+		require.Equal(t, overridden, overridden.OrDefault())
+		require.Contains(t, overridden.TierZeroSIDSuffixes(), "-1544")
+		require.NotContains(t, overridden.TierZeroSIDSuffixes(), ad.AdministratorsGroupSIDSuffix)
+
+		var capabilitySuffixes []string
+
+		for _, capability := range overridden.PrivilegedGroupCapabilities() {
+			capabilitySuffixes = append(capabilitySuffixes, capability.SIDSuffix)
+		}
+
+		require.Contains(t, capabilitySuffixes, "-1548")
+
+		// Overriding a table leaves the defaults and the default suffixes untouched
+		require.Equal(t, ad.AdminGroupSuffix, ad.RIDSuffix(ad.DefaultWellKnownRIDs().Administrators))
+		require.Contains(t, ad.TierZeroWellKnownSIDSuffixes(), ad.AdministratorsGroupSIDSuffix)
+	})
+}
@@ -0,0 +1,56 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ad
+
+import (
+	"context"
+
+	"github.com/specterops/bloodhound/analysis"
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/dawgs/query"
+	"github.com/specterops/bloodhound/graphschema/ad"
+)
+
+// PrivilegeAdminSourceProperty is set on AdminTo edges derived from a user rights assignment by
+// PostAdminToFromPrivileges and holds the name of the privilege kind the edge was derived from. Like
+// LAPSAdminSourceProperty it only records provenance.
+const PrivilegeAdminSourceProperty = "privilegeadminsource"
+
+// EscalationPrivilegeRelationships returns the user rights assignment kinds that grant a principal effective SYSTEM
+// access on the computer they are assigned on. SeDebugPrivilege allows injecting into SYSTEM processes,
+// SeTakeOwnershipPrivilege allows taking ownership of and rewriting the ACL of any securable object and
+// SeImpersonatePrivilege allows impersonating SYSTEM tokens obtained through coerced local authentication.
+func EscalationPrivilegeRelationships() []graph.Kind {
+	return []graph.Kind{ad.DebugPrivilege, ad.TakeOwnershipPrivilege, ad.ImpersonatePrivilege}
+}
+
+// PostAdminToFromPrivileges emits an AdminTo edge from every principal assigned one of the given privileges on a
+// computer. When no privileges are given EscalationPrivilegeRelationships is used. Pairs already joined by an AdminTo
+// edge, such as one from local group membership, are skipped, so this must follow local group post-processing. Every
+// emitted edge is stamped with PrivilegeAdminSourceProperty, preferring the privilege listed first when several apply.
+func PostAdminToFromPrivileges(ctx context.Context, db graph.Database, privileges ...graph.Kind) (*analysis.AtomicPostProcessingStats, error) {
+	return PostAdminToFromPrivilegesWithOptions(ctx, db, DerivedEdgeOptions{}, privileges...)
+}
+
+// PostAdminToFromPrivilegesWithOptions runs PostAdminToFromPrivileges with the given options
+func PostAdminToFromPrivilegesWithOptions(ctx context.Context, db graph.Database, options DerivedEdgeOptions, privileges ...graph.Kind) (*analysis.AtomicPostProcessingStats, error) {
+	if len(privileges) == 0 {
+		privileges = EscalationPrivilegeRelationships()
+	}
+
+	return postDerivedFromRights(ctx, db, options.Collectors, "AdminTo From Privileges Post Processing", ad.AdminTo, PrivilegeAdminSourceProperty, query.Kind(query.End(), ad.Computer), privileges...)
+}
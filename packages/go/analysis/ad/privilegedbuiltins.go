@@ -0,0 +1,267 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ad
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/specterops/bloodhound/analysis"
+	"github.com/specterops/bloodhound/dawgs/cardinality"
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/dawgs/ops"
+	"github.com/specterops/bloodhound/dawgs/query"
+	"github.com/specterops/bloodhound/dawgs/util/channels"
+	"github.com/specterops/bloodhound/graphschema/ad"
+	"github.com/specterops/bloodhound/graphschema/common"
+	"github.com/specterops/bloodhound/log"
+)
+
+// PrivilegedGroupScope selects the objects a privileged built-in group's capability applies to. Built-in groups are
+// local to the domain controllers of their domain, so every scope is limited to the group's own domain.
+type PrivilegedGroupScope int
+
+const (
+	// PrivilegedGroupScopeDomainControllers targets the domain controllers of the group's domain
+	PrivilegedGroupScopeDomainControllers PrivilegedGroupScope = iota
+
+	// PrivilegedGroupScopeUnprotectedAccounts targets the users and groups of the group's domain that are not protected
+	// by AdminSDHolder, which is recognized by admincount not being set to true
+	PrivilegedGroupScopeUnprotectedAccounts
+)
+
+func (s PrivilegedGroupScope) String() string {
+	switch s {
+	case PrivilegedGroupScopeDomainControllers:
+		return "DomainControllers"
+	case PrivilegedGroupScopeUnprotectedAccounts:
+		return "UnprotectedAccounts"
+	default:
+		return fmt.Sprintf("PrivilegedGroupScope(%d)", int(s))
+	}
+}
+
+// PrivilegedGroupCapability maps a built-in group, identified by the suffix of its object ID, to the relationship kind
+// emitted from the group and the scope of objects it is emitted to
+type PrivilegedGroupCapability struct {
+	SIDSuffix string
+	Kind      graph.Kind
+	Scope     PrivilegedGroupScope
+}
+
+// PrivilegedGroupSourceProperty records the SID suffix of the capability a relationship was emitted for by
+// PostPrivilegedBuiltinGroups, which tells emitted relationships apart from collected relationships of the same kind.
+const PrivilegedGroupSourceProperty = "privilegedgroupsource"
+
+// PrivilegedGroupCapabilities returns the default capability table used by PostPrivilegedBuiltinGroups. Entries may be
+// appended to or removed from the returned slice to change which built-in groups are considered.
+func PrivilegedGroupCapabilities() []PrivilegedGroupCapability {
+	return DefaultWellKnownRIDs().PrivilegedGroupCapabilities()
+}
+
+// PrivilegedGroupCapabilities returns the capability table of PostPrivilegedBuiltinGroups with the built-in groups
+// identified by the RIDs of this table
+func (s WellKnownRIDs) PrivilegedGroupCapabilities() []PrivilegedGroupCapability {
+	return []PrivilegedGroupCapability{
+		// Server Operators can reconfigure services on domain controllers and so run code as SYSTEM
+		{SIDSuffix: RIDSuffix(s.ServerOperators), Kind: ad.AdminTo, Scope: PrivilegedGroupScopeDomainControllers},
+
+		// Backup Operators can overwrite any file on domain controllers, service binaries included
+		{SIDSuffix: RIDSuffix(s.BackupOperators), Kind: ad.AdminTo, Scope: PrivilegedGroupScopeDomainControllers},
+
+		// Print Operators can load printer drivers, which run in the kernel, on domain controllers
+		{SIDSuffix: RIDSuffix(s.PrintOperators), Kind: ad.AdminTo, Scope: PrivilegedGroupScopeDomainControllers},
+
+		// Account Operators can reset and modify every user and group that AdminSDHolder does not protect. The
+		// relationship has a kind of its own so that it is never mistaken for a collected GenericAll.
+		{SIDSuffix: RIDSuffix(s.AccountOperators), Kind: ad.AccountOperatorControl, Scope: PrivilegedGroupScopeUnprotectedAccounts},
+	}
+}
+
+// PrivilegedBuiltinGroupSIDSuffixes returns the RIDs of the built-in groups whose members administer domain controllers
+// without being members of their Administrators group, as listed in PrivilegedGroupCapabilities
+func PrivilegedBuiltinGroupSIDSuffixes() []string {
+	var suffixes []string
+
+	for _, capability := range PrivilegedGroupCapabilities() {
+		if capability.Kind == ad.AdminTo && capability.Scope == PrivilegedGroupScopeDomainControllers {
+			suffixes = append(suffixes, capability.SIDSuffix)
+		}
+	}
+
+	return suffixes
+}
+
+func fetchPrivilegedGroupTargets(tx graph.Transaction, scope PrivilegedGroupScope, domainSID string, domainControllers *DomainControllerSet) ([]graph.ID, error) {
+	switch scope {
+	case PrivilegedGroupScopeDomainControllers:
+		return domainControllers.InDomain(domainSID), nil
+
+	case PrivilegedGroupScopeUnprotectedAccounts:
+		return ops.FetchNodeIDs(tx.Nodes().Filterf(func() graph.Criteria {
+			return query.And(
+				query.KindIn(query.Node(), ad.User, ad.Group),
+				query.Equals(query.NodeProperty(ad.DomainSID.String()), domainSID),
+				query.Or(
+					query.Not(query.Exists(query.NodeProperty(ad.AdminCount.String()))),
+					query.Equals(query.NodeProperty(ad.AdminCount.String()), false),
+				),
+			)
+		}))
+
+	default:
+		return nil, fmt.Errorf("unsupported privileged group scope %s", scope)
+	}
+}
+
+type privilegedGroupTargetKey struct {
+	scope     PrivilegedGroupScope
+	domainSID string
+}
+
+// PostPrivilegedBuiltinGroups emits, for each of the given capabilities, a relationship of the capability's kind from
+// every built-in group whose object ID ends with the capability's SID suffix to every object in the capability's scope.
+// Relationships are emitted from the group itself and left to pathfinding to extend to its members. Pairs already
+// joined by a relationship of the capability's kind are skipped and every emitted relationship is stamped with
+// PrivilegedGroupSourceProperty. See PrivilegedGroupCapabilities for the default table.
+func PostPrivilegedBuiltinGroups(ctx context.Context, db graph.Database, capabilities []PrivilegedGroupCapability) (*analysis.AtomicPostProcessingStats, error) {
+	return PostPrivilegedBuiltinGroupsWithDomainControllers(ctx, db, capabilities, nil, DefaultWellKnownRIDs())
+}
+
+// PostPrivilegedBuiltinGroupsWithDomainControllers runs PostPrivilegedBuiltinGroups with a precomputed domain
+// controller set. A nil set is detected with the given RID table when a capability scoped to domain controllers is
+// first processed. A zero table uses DefaultWellKnownRIDs.
+func PostPrivilegedBuiltinGroupsWithDomainControllers(ctx context.Context, db graph.Database, capabilities []PrivilegedGroupCapability, domainControllers *DomainControllerSet, rids WellKnownRIDs) (*analysis.AtomicPostProcessingStats, error) {
+	operation := analysis.NewPostRelationshipOperation(ctx, db, "Privileged Builtin Groups Post Processing")
+
+	if err := operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		var (
+			targetsByDomain         = map[privilegedGroupTargetKey][]graph.ID{}
+			readerDomainControllers = domainControllers
+		)
+
+		for _, capability := range capabilities {
+			if capability.Scope == PrivilegedGroupScopeDomainControllers && readerDomainControllers == nil {
+				if fetchedDomainControllers, err := fetchDomainControllerSet(tx, rids.OrDefault()); err != nil {
+					return err
+				} else {
+					readerDomainControllers = fetchedDomainControllers
+				}
+			}
+		}
+
+		for _, capability := range capabilities {
+			groups, err := ops.FetchNodes(tx.Nodes().Filterf(func() graph.Criteria {
+				return query.And(
+					query.Kind(query.Node(), ad.Group),
+					query.StringEndsWith(query.NodeProperty(common.ObjectID.String()), capability.SIDSuffix),
+				)
+			}))
+
+			if err != nil {
+				return err
+			}
+
+			for _, group := range groups {
+				domainSID, err := group.Properties.Get(ad.DomainSID.String()).String()
+
+				if err != nil {
+					log.Warnf("Skipping privileged built-in group %d without a domain SID: %v", group.ID, err)
+					continue
+				}
+
+				var (
+					targetKey        = privilegedGroupTargetKey{scope: capability.Scope, domainSID: domainSID}
+					targets, fetched = targetsByDomain[targetKey]
+				)
+
+				if !fetched {
+					if targets, err = fetchPrivilegedGroupTargets(tx, capability.Scope, domainSID, readerDomainControllers); err != nil {
+						return err
+					}
+
+					targetsByDomain[targetKey] = targets
+				}
+
+				if len(targets) == 0 {
+					continue
+				}
+
+				existingTargets := cardinality.NewBitmap32()
+
+				if err := tx.Relationships().Filterf(func() graph.Criteria {
+					return query.And(
+						query.Kind(query.Relationship(), capability.Kind),
+						query.Equals(query.StartID(), group.ID),
+					)
+				}).FetchTriples(func(cursor graph.Cursor[graph.RelationshipTripleResult]) error {
+					for result := range cursor.Chan() {
+						existingTargets.Add(result.EndID.Uint32())
+					}
+
+					return cursor.Error()
+				}); err != nil {
+					return err
+				}
+
+				for _, target := range targets {
+					if target == group.ID || existingTargets.Contains(target.Uint32()) {
+						continue
+					}
+
+					if !channels.Submit(ctx, outC, analysis.CreatePostRelationshipJob{
+						FromID: group.ID,
+						ToID:   target,
+						Kind:   capability.Kind,
+						RelProperties: map[string]any{
+							PrivilegedGroupSourceProperty: capability.SIDSuffix,
+						},
+					}) {
+						return nil
+					}
+				}
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return &operation.Stats, err
+	}
+
+	return &operation.Stats, operation.Done()
+}
+
+// PostPrivilegedBuiltinAdminTo emits AdminTo from every built-in group whose object ID ends with one of the given SID
+// suffixes to each domain controller of the group's domain. PrivilegedBuiltinGroupSIDSuffixes is used when no suffixes
+// are given. This is PostPrivilegedBuiltinGroups restricted to AdminTo capabilities.
+func PostPrivilegedBuiltinAdminTo(ctx context.Context, db graph.Database, groupSIDSuffixes ...string) (*analysis.AtomicPostProcessingStats, error) {
+	if len(groupSIDSuffixes) == 0 {
+		groupSIDSuffixes = PrivilegedBuiltinGroupSIDSuffixes()
+	}
+
+	capabilities := make([]PrivilegedGroupCapability, 0, len(groupSIDSuffixes))
+
+	for _, suffix := range groupSIDSuffixes {
+		capabilities = append(capabilities, PrivilegedGroupCapability{
+			SIDSuffix: suffix,
+			Kind:      ad.AdminTo,
+			Scope:     PrivilegedGroupScopeDomainControllers,
+		})
+	}
+
+	return PostPrivilegedBuiltinGroups(ctx, db, capabilities)
+}
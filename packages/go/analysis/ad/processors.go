@@ -0,0 +1,91 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ad
+
+import (
+	"fmt"
+
+	"github.com/specterops/bloodhound/analysis"
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/graphschema/ad"
+)
+
+// PostProcessor describes an Active Directory post-processing function and the relationship kinds it emits
+type PostProcessor struct {
+	Name  string
+	Emits []graph.Kind
+
+	// SelfCleaning is set for processors that emit a kind which is also collected at ingest time. These processors are
+	// responsible for removing their own relationships, for example through DeleteDerivedLAPSReadEdges, as listing the
+	// kind in PostProcessedRelationships would also purge the collected relationships.
+	SelfCleaning bool
+
+	// Expansion documents how the processor resolves the members of groups that hold the right it derives edges from.
+	// Processors that emit edges from the right holder itself, leaving membership to pathfinding, use ExpansionNone.
+	Expansion analysis.ExpansionStrategy
+}
+
+// PostProcessors returns every Active Directory post-processor. A new post-processing function must be added here so
+// that PostProcessingConfig recognizes the kinds it emits.
+func PostProcessors() []PostProcessor {
+	return []PostProcessor{
+		// DCSync and SyncLAPSPassword require two rights that may be granted to different groups, so the holders of both
+		// are only found by intersecting fully expanded memberships
+		{Name: "PostDCSync", Emits: []graph.Kind{ad.DCSync}, Expansion: analysis.ExpansionTransitive},
+		{Name: "PostSyncLAPSPassword", Emits: []graph.Kind{ad.SyncLAPSPassword}, Expansion: analysis.ExpansionTransitive},
+		{Name: "PostDomainTrusts", Emits: []graph.Kind{ad.SameForestTrust, ad.CrossForestTrust}, Expansion: analysis.ExpansionNone},
+		{Name: "PostOwnsImpliesControl", Emits: []graph.Kind{ad.ImpliedGenericAll}, Expansion: analysis.ExpansionNone},
+		{Name: "PostHybridIdentityLink", Emits: []graph.Kind{ad.SyncedToEntraUser}, Expansion: analysis.ExpansionNone},
+		{Name: "PostGroupSyncedToEntraRole", Emits: []graph.Kind{ad.SyncedToEntraRole}, Expansion: analysis.ExpansionNone},
+		{Name: "PostWriteGPLink", Emits: []graph.Kind{ad.CanApplyGPO}, Expansion: analysis.ExpansionNone},
+		{Name: "PostTrustAccountCompromise", Emits: []graph.Kind{ad.HasTrustKeys}, Expansion: analysis.ExpansionNone},
+		{Name: "PostCanTakeOver", Emits: []graph.Kind{ad.CanTakeOver}, Expansion: analysis.ExpansionNone},
+
+		// AdminTo, CanPSRemote and ExecuteDCOM are emitted from the direct members of each local group. CanRDP expands
+		// transitively, which is handled by ExpandAllRDPLocalGroups rather than the strategy aware helpers.
+		{Name: "PostLocalGroups", Emits: []graph.Kind{ad.CanRDP, ad.AdminTo, ad.CanPSRemote, ad.ExecuteDCOM}, Expansion: analysis.ExpansionFirstDegreeOnly},
+		{Name: "PostPrivilegedBuiltinGroups", Emits: []graph.Kind{ad.AdminTo, ad.AccountOperatorControl}, Expansion: analysis.ExpansionNone},
+		{Name: "PostReadLAPSPasswordFromGenericAll", Emits: []graph.Kind{ad.ReadLAPSPassword}, SelfCleaning: true, Expansion: analysis.ExpansionNone},
+		{Name: "PostAdminToFromLAPSRead", Emits: []graph.Kind{ad.AdminTo}, Expansion: analysis.ExpansionNone},
+		{Name: "PostAdminToFromPrivileges", Emits: []graph.Kind{ad.AdminTo}, Expansion: analysis.ExpansionNone},
+		{Name: "PostEnterpriseAdminsAdminTo", Emits: []graph.Kind{ad.AdminTo}, Expansion: analysis.ExpansionNone},
+		{Name: "PostCrossSession", Emits: []graph.Kind{ad.CanImpersonate}, Expansion: analysis.ExpansionNone},
+		{Name: "PostRDPSessionCapture", Emits: []graph.Kind{ad.RDPSessionCapture}, Expansion: analysis.ExpansionNone},
+		{Name: "PostSharedAdminLateral", Emits: []graph.Kind{ad.SharedAdminLateral}, Expansion: analysis.ExpansionNone},
+		{Name: "PostAdminToViaGMSA", Emits: []graph.Kind{ad.AdminToViaGMSA}, Expansion: analysis.ExpansionTransitive},
+		{Name: "PostForceChangePasswordFromGenericAll", Emits: []graph.Kind{ad.ForceChangePassword}, SelfCleaning: true, Expansion: analysis.ExpansionNone},
+		{Name: "PostCanDCSyncViaReset", Emits: []graph.Kind{ad.CanDCSyncViaReset}, Expansion: analysis.ExpansionNone},
+		{Name: "PostReadGMSAPasswordFromControl", Emits: []graph.Kind{ad.ReadGMSAPassword}, SelfCleaning: true, Expansion: analysis.ExpansionNone},
+		{Name: "PostAddKeyCredentialLinkFromControl", Emits: []graph.Kind{ad.AddKeyCredentialLink}, SelfCleaning: true, Expansion: analysis.ExpansionNone},
+		{Name: "PostAddAllowedToActFromControl", Emits: []graph.Kind{ad.AddAllowedToAct}, SelfCleaning: true, Expansion: analysis.ExpansionNone},
+	}
+}
+
+// ValidateWrittenKinds returns an error naming the given post-processing step when its stats record created
+// relationships of a kind missing from emits. The declared kinds decide whether PostProcessingConfig skips a step, so a
+// step writing an undeclared kind could not be disabled.
+func ValidateWrittenKinds(step string, emits []graph.Kind, stats *analysis.AtomicPostProcessingStats) error {
+	declared := graph.Kinds(emits)
+
+	for _, kind := range stats.CreatedKinds() {
+		if !declared.ContainsOneOf(kind) {
+			return fmt.Errorf("post-processing step %s wrote %s relationships which it does not declare", step, kind)
+		}
+	}
+
+	return nil
+}
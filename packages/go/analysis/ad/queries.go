@@ -18,6 +18,7 @@ package ad
 
 import (
 	"context"
+	"fmt"
 	"github.com/specterops/bloodhound/dawgs/graphcache"
 	"github.com/specterops/bloodhound/log"
 	"strings"
@@ -144,6 +145,82 @@ func GetCollectedDomains(ctx context.Context, db graph.Database) (graph.NodeSet,
 	})
 }
 
+// FetchAllDCSyncers maps the ID of every collected domain to the principals able to DCSync it, as resolved by
+// analysis.GetDCSyncers. Tier zero principals are included so the result is the complete forest wide list. Only the
+// IDs of the collected domains are held up front; each domain node is loaded when its turn comes.
+func FetchAllDCSyncers(ctx context.Context, db graph.Database) (map[graph.ID]graph.NodeSet, error) {
+	dcSyncersByDomain := map[graph.ID]graph.NodeSet{}
+
+	return dcSyncersByDomain, db.ReadTransaction(ctx, func(tx graph.Transaction) error {
+		domainIDs, err := ops.FetchNodeIDs(tx.Nodes().Filterf(func() graph.Criteria {
+			return query.And(
+				query.Kind(query.Node(), ad.Domain),
+				query.Equals(query.NodeProperty(common.Collected.String()), true),
+			)
+		}))
+
+		if err != nil {
+			return err
+		}
+
+		for _, domainID := range domainIDs {
+			if domain, err := ops.FetchNode(tx, domainID); err != nil {
+				return err
+			} else if dcSyncers, err := analysis.GetDCSyncers(tx, domain, false); err != nil {
+				return fmt.Errorf("failed fetching DCSyncers for domain %d: %w", domainID, err)
+			} else {
+				dcSyncersByDomain[domainID] = graph.NewNodeSet(dcSyncers...)
+			}
+		}
+
+		return nil
+	})
+}
+
+// FetchDomainsPrincipalCanDCSync returns the domains the given principal can DCSync, directly or through group
+// membership. Candidate domains are found by walking from the principal through MemberOf to a replication right, and
+// each candidate is confirmed against analysis.GetDCSyncers so that both rights must be held. Tier zero principals are
+// not filtered out.
+func FetchDomainsPrincipalCanDCSync(tx graph.Transaction, principal graph.ID) (graph.NodeSet, error) {
+	principalNode, err := ops.FetchNode(tx, principal)
+
+	if err != nil {
+		return nil, err
+	}
+
+	candidateDomains, err := ops.AcyclicTraverseTerminals(tx, ops.TraversalPlan{
+		Root:      principalNode,
+		Direction: graph.DirectionOutbound,
+		BranchQuery: func() graph.Criteria {
+			return query.KindIn(query.Relationship(), ad.MemberOf, ad.GetChanges, ad.GetChangesAll)
+		},
+		PathFilter: func(ctx *ops.TraversalContext, segment *graph.PathSegment) bool {
+			return segment.Node.Kinds.ContainsOneOf(ad.Domain)
+		},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	domains := graph.NewNodeSet()
+
+	for _, domain := range candidateDomains {
+		if dcSyncers, err := analysis.GetDCSyncers(tx, domain, false); err != nil {
+			return nil, fmt.Errorf("failed fetching DCSyncers for domain %d: %w", domain.ID, err)
+		} else {
+			for _, dcSyncer := range dcSyncers {
+				if dcSyncer.ID == principal {
+					domains.Add(domain)
+					break
+				}
+			}
+		}
+	}
+
+	return domains, nil
+}
+
 func getGPOLinks(tx graph.Transaction, node *graph.Node) ([]*graph.Relationship, error) {
 	if gpLinks, err := ops.FetchRelationships(tx.Relationships().Filterf(func() graph.Criteria {
 		return query.And(
@@ -1080,6 +1157,86 @@ func FetchComputerSessions(tx graph.Transaction, node *graph.Node, skip, limit i
 	})
 }
 
+// FetchDirectTargets returns the end nodes of every relationship of the given kind starting at principal. Only
+// relationships held by the principal itself are followed; targets reachable through the principal's group memberships
+// are not included.
+func FetchDirectTargets(tx graph.Transaction, principal graph.ID, kind graph.Kind) (graph.NodeSet, error) {
+	return ops.FetchEndNodes(tx.Relationships().Filterf(func() graph.Criteria {
+		return query.And(
+			query.Equals(query.StartID(), principal),
+			query.Kind(query.Relationship(), kind),
+		)
+	}))
+}
+
+// FetchTransitiveControl returns every node reachable from principal by following outbound relationships of the given
+// control kinds, expanding breadth first for at most maxDepth hops. A maxDepth of zero or less does not limit the
+// expansion. Each node is expanded once, so cycles in the control graph are not revisited, and the principal itself is
+// never part of the result. As with FetchDirectTargets, group memberships of the principal are not followed unless
+// MemberOf is among the control kinds.
+func FetchTransitiveControl(tx graph.Transaction, principal graph.ID, controlKinds []graph.Kind, maxDepth int) (graph.NodeSet, error) {
+	var (
+		controlled = graph.NewNodeSet()
+		visited    = map[graph.ID]struct{}{principal: {}}
+		frontier   = []graph.ID{principal}
+	)
+
+	if len(controlKinds) == 0 {
+		return controlled, nil
+	}
+
+	for depth := 0; len(frontier) > 0 && (maxDepth <= 0 || depth < maxDepth); depth++ {
+		if targets, err := ops.FetchEndNodes(tx.Relationships().Filterf(func() graph.Criteria {
+			return query.And(
+				query.InIDs(query.StartID(), frontier...),
+				query.KindIn(query.Relationship(), controlKinds...),
+			)
+		})); err != nil {
+			return nil, err
+		} else {
+			var nextFrontier []graph.ID
+
+			for _, target := range targets {
+				if _, seen := visited[target.ID]; !seen {
+					visited[target.ID] = struct{}{}
+					controlled.Add(target)
+					nextFrontier = append(nextFrontier, target.ID)
+				}
+			}
+
+			frontier = nextFrontier
+		}
+	}
+
+	return controlled, nil
+}
+
+// FetchComputedEdgesForNode returns every computed relationship of the given kinds that starts or ends at node.
+// Relationships are recognized as computed by the analysis.AnalysisVersionProperty stamped on them by post-processing,
+// so collected relationships sharing a kind with derived ones, such as ReadLAPSPassword, are left out. Passing
+// PostProcessedRelationships covers every kind that is only ever computed. No relationships are returned when kinds is
+// empty.
+func FetchComputedEdgesForNode(tx graph.Transaction, node graph.ID, kinds []graph.Kind) (graph.RelationshipSet, error) {
+	if len(kinds) == 0 {
+		return graph.NewRelationshipSet(), nil
+	}
+
+	if relationships, err := ops.FetchRelationships(tx.Relationships().Filterf(func() graph.Criteria {
+		return query.And(
+			query.KindIn(query.Relationship(), kinds...),
+			query.Or(
+				query.Equals(query.StartID(), node),
+				query.Equals(query.EndID(), node),
+			),
+			query.Exists(query.RelationshipProperty(analysis.AnalysisVersionProperty)),
+		)
+	})); err != nil {
+		return nil, err
+	} else {
+		return graph.NewRelationshipSet(relationships...), nil
+	}
+}
+
 func FetchEntityGroupMembershipPaths(tx graph.Transaction, node *graph.Node) (graph.PathSet, error) {
 	return ops.TraversePaths(tx, ops.TraversalPlan{
 		Root:        node,
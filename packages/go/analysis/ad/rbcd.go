@@ -0,0 +1,64 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ad
+
+import (
+	"context"
+
+	"github.com/specterops/bloodhound/analysis"
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/dawgs/query"
+	"github.com/specterops/bloodhound/graphschema/ad"
+)
+
+// RBCDWriteSourceProperty records the right a post-processed AddAllowedToAct edge was derived from. Collected
+// AddAllowedToAct edges come from an explicit write grant on msDS-AllowedToActOnBehalfOfOtherIdentity and never carry
+// it.
+const (
+	RBCDWriteSourceProperty                 = "rbcdwritesource"
+	RBCDWriteSourceGenericAll               = "GenericAll"
+	RBCDWriteSourceWriteAccountRestrictions = "WriteAccountRestrictions"
+	RBCDWriteSourceWriteDACL                = "WriteDacl"
+)
+
+// RBCDWriteRelationships returns the rights PostAddAllowedToActFromControl derives AddAllowedToAct from, in order of
+// preference when a principal holds several of them over the same computer
+func RBCDWriteRelationships() []graph.Kind {
+	return []graph.Kind{ad.GenericAll, ad.WriteAccountRestrictions, ad.WriteDACL}
+}
+
+// DeleteDerivedAddAllowedToActEdges removes every AddAllowedToAct edge created by post-processing while leaving
+// collected AddAllowedToAct edges in place
+func DeleteDerivedAddAllowedToActEdges(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+	return deleteDerivedEdges(ctx, db, ad.AddAllowedToAct, RBCDWriteSourceProperty)
+}
+
+// PostAddAllowedToActFromControl emits an AddAllowedToAct edge from every principal able to write
+// msDS-AllowedToActOnBehalfOfOtherIdentity on a computer, which lets it configure resource-based constrained delegation
+// to the computer from an account it controls and take the computer over. GenericAll and WriteAccountRestrictions
+// cover the attribute directly while WriteDacl allows the principal to grant itself the write first. A principal
+// holding several of these rights receives a single edge stamped with RBCDWriteSourceProperty naming the first of
+// RBCDWriteRelationships it holds. Pairs already joined by a collected AddAllowedToAct edge are skipped.
+func PostAddAllowedToActFromControl(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+	return PostAddAllowedToActFromControlWithOptions(ctx, db, DerivedEdgeOptions{})
+}
+
+// PostAddAllowedToActFromControlWithOptions runs PostAddAllowedToActFromControl with the given options
+func PostAddAllowedToActFromControlWithOptions(ctx context.Context, db graph.Database, options DerivedEdgeOptions) (*analysis.AtomicPostProcessingStats, error) {
+	return postDerivedFromRights(ctx, db, options.Collectors, "AddAllowedToAct From Control Post Processing", ad.AddAllowedToAct, RBCDWriteSourceProperty,
+		query.Kind(query.End(), ad.Computer), RBCDWriteRelationships()...)
+}
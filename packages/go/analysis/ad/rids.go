@@ -0,0 +1,144 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ad
+
+import (
+	"strconv"
+
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/dawgs/query"
+	"github.com/specterops/bloodhound/graphschema/common"
+)
+
+// WellKnownRIDs holds the relative identifiers of the built-in principals that post-processing looks up by SID suffix.
+// The defaults are the RIDs Windows assigns; environments that renumber or localize these principals can pass their own
+// table to post-processing through PostOptions.WellKnownRIDs. Functions that take no table use DefaultWellKnownRIDs.
+type WellKnownRIDs struct {
+	AdministratorAccount  uint32
+	DomainAdmins          uint32
+	DomainUsers           uint32
+	DomainComputers       uint32
+	DomainControllers     uint32
+	SchemaAdmins          uint32
+	EnterpriseAdmins      uint32
+	KeyAdmins             uint32
+	EnterpriseKeyAdmins   uint32
+	Administrators        uint32
+	AccountOperators      uint32
+	ServerOperators       uint32
+	PrintOperators        uint32
+	BackupOperators       uint32
+	RemoteDesktopUsers    uint32
+	DistributedCOMUsers   uint32
+	RemoteManagementUsers uint32
+}
+
+// DefaultWellKnownRIDs returns the RIDs Windows assigns to its built-in principals
+func DefaultWellKnownRIDs() WellKnownRIDs {
+	return WellKnownRIDs{
+		AdministratorAccount:  500,
+		DomainAdmins:          512,
+		DomainUsers:           513,
+		DomainComputers:       515,
+		DomainControllers:     516,
+		SchemaAdmins:          518,
+		EnterpriseAdmins:      519,
+		KeyAdmins:             526,
+		EnterpriseKeyAdmins:   527,
+		Administrators:        544,
+		AccountOperators:      548,
+		ServerOperators:       549,
+		PrintOperators:        550,
+		BackupOperators:       551,
+		RemoteDesktopUsers:    555,
+		DistributedCOMUsers:   562,
+		RemoteManagementUsers: 580,
+	}
+}
+
+// RIDSuffix formats a RID as the suffix it forms on the end of an object ID, for example "-544"
+func RIDSuffix(rid uint32) string {
+	return "-" + strconv.FormatUint(uint64(rid), 10)
+}
+
+// named pairs the suffix of every RID in the table with the name of the principal it denotes
+func (s WellKnownRIDs) named() []wellKnownSID {
+	return []wellKnownSID{
+		{suffix: RIDSuffix(s.AdministratorAccount), name: "Administrator"},
+		{suffix: RIDSuffix(s.DomainAdmins), name: "Domain Admins"},
+		{suffix: RIDSuffix(s.DomainUsers), name: "Domain Users"},
+		{suffix: RIDSuffix(s.DomainComputers), name: "Domain Computers"},
+		{suffix: RIDSuffix(s.DomainControllers), name: "Domain Controllers"},
+		{suffix: RIDSuffix(s.SchemaAdmins), name: "Schema Admins"},
+		{suffix: RIDSuffix(s.EnterpriseAdmins), name: "Enterprise Admins"},
+		{suffix: RIDSuffix(s.KeyAdmins), name: "Key Admins"},
+		{suffix: RIDSuffix(s.EnterpriseKeyAdmins), name: "Enterprise Key Admins"},
+		{suffix: RIDSuffix(s.Administrators), name: "Administrators"},
+		{suffix: RIDSuffix(s.AccountOperators), name: "Account Operators"},
+		{suffix: RIDSuffix(s.ServerOperators), name: "Server Operators"},
+		{suffix: RIDSuffix(s.PrintOperators), name: "Print Operators"},
+		{suffix: RIDSuffix(s.BackupOperators), name: "Backup Operators"},
+		{suffix: RIDSuffix(s.RemoteDesktopUsers), name: "Remote Desktop Users"},
+		{suffix: RIDSuffix(s.DistributedCOMUsers), name: "Distributed COM Users"},
+		{suffix: RIDSuffix(s.RemoteManagementUsers), name: "Remote Management Users"},
+	}
+}
+
+// OrDefault returns DefaultWellKnownRIDs when the table is the zero value and the table itself otherwise
+func (s WellKnownRIDs) OrDefault() WellKnownRIDs {
+	if s == (WellKnownRIDs{}) {
+		return DefaultWellKnownRIDs()
+	}
+
+	return s
+}
+
+// BuiltinAdminSIDSuffixes returns the suffixes of the built-in Administrator account and the Domain Admins group
+func (s WellKnownRIDs) BuiltinAdminSIDSuffixes() []string {
+	return []string{
+		RIDSuffix(s.AdministratorAccount),
+		RIDSuffix(s.DomainAdmins),
+	}
+}
+
+// TierZeroSIDSuffixes returns the suffixes of the well-known principals that are tier zero in every domain
+func (s WellKnownRIDs) TierZeroSIDSuffixes() []string {
+	return []string{
+		EnterpriseDomainControllersGroupSIDSuffix,
+		RIDSuffix(s.AdministratorAccount),
+		RIDSuffix(s.DomainAdmins),
+		RIDSuffix(s.DomainControllers),
+		RIDSuffix(s.SchemaAdmins),
+		RIDSuffix(s.EnterpriseAdmins),
+		RIDSuffix(s.KeyAdmins),
+		RIDSuffix(s.EnterpriseKeyAdmins),
+		RIDSuffix(s.BackupOperators),
+		RIDSuffix(s.Administrators),
+	}
+}
+
+// rdpExpansionCriteria keeps the local Administrators group out of RDP membership expansion
+func (s WellKnownRIDs) rdpExpansionCriteria() graph.Criteria {
+	adminGroupSuffix := RIDSuffix(s.Administrators)
+
+	return query.Not(
+		query.Or(
+			query.StringEndsWith(query.StartProperty(common.ObjectID.String()), adminGroupSuffix),
+			query.StringEndsWith(query.EndProperty(common.ObjectID.String()), adminGroupSuffix),
+		),
+	)
+}
@@ -0,0 +1,108 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ad
+
+import (
+	"context"
+
+	"github.com/specterops/bloodhound/analysis"
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/dawgs/util/channels"
+	"github.com/specterops/bloodhound/graphschema/ad"
+	"github.com/specterops/bloodhound/log"
+)
+
+// SharedAdminLateralComputerLimit caps the number of computers a single AdminTo holder may administer before
+// PostSharedAdminLateral skips it. A principal administering n computers links every one of them to every other, so it
+// contributes n * (n - 1) edges and a broadly assigned admin group would otherwise dominate the graph.
+const SharedAdminLateralComputerLimit = 100
+
+// fetchAdministeredComputers maps each AdminTo holder to the computers it administers
+func fetchAdministeredComputers(tx graph.Transaction) (map[graph.ID][]graph.ID, error) {
+	if admins, err := fetchComputerEndpoints(tx, ad.AdminTo, false); err != nil {
+		return nil, err
+	} else {
+		administeredComputers := map[graph.ID][]graph.ID{}
+
+		for computer, computerAdmins := range admins {
+			for _, admin := range computerAdmins {
+				administeredComputers[admin] = append(administeredComputers[admin], computer)
+			}
+		}
+
+		return administeredComputers, nil
+	}
+}
+
+// PostSharedAdminLateral emits a SharedAdminLateral edge in both directions between every pair of computers sharing an
+// AdminTo holder. A credential that is local admin on both computers can be recovered on one and replayed against the
+// other, so compromising either computer yields the other.
+//
+// The number of edges grows with the square of the number of computers each holder administers. Holders administering
+// more than SharedAdminLateralComputerLimit computers are skipped with a warning, which bounds each holder's
+// contribution to SharedAdminLateralComputerLimit * (SharedAdminLateralComputerLimit - 1) edges. Pairs are emitted once
+// regardless of how many holders they share, and the pairs seen so far are held in memory for the whole run.
+//
+// This must run after every step emitting AdminTo.
+func PostSharedAdminLateral(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+	operation := analysis.NewPostRelationshipOperation(ctx, db, "Shared Admin Lateral Movement Post Processing")
+
+	if err := operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		administeredComputers, err := fetchAdministeredComputers(tx)
+
+		if err != nil {
+			return err
+		}
+
+		emitted := map[endpointPair]struct{}{}
+
+		for admin, computers := range administeredComputers {
+			if len(computers) > SharedAdminLateralComputerLimit {
+				log.Warnf("Skipping shared admin lateral movement post-processing for principal %d: it administers %d computers, exceeding the limit of %d", admin, len(computers), SharedAdminLateralComputerLimit)
+				continue
+			}
+
+			for _, fromComputer := range computers {
+				for _, toComputer := range computers {
+					pair := endpointPair{From: fromComputer, To: toComputer}
+
+					if fromComputer == toComputer {
+						continue
+					} else if _, seen := emitted[pair]; seen {
+						continue
+					}
+
+					emitted[pair] = struct{}{}
+
+					if !channels.Submit(ctx, outC, analysis.CreatePostRelationshipJob{
+						FromID: fromComputer,
+						ToID:   toComputer,
+						Kind:   ad.SharedAdminLateral,
+					}) {
+						return nil
+					}
+				}
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return &operation.Stats, err
+	}
+
+	return &operation.Stats, operation.Done()
+}
@@ -0,0 +1,162 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ad
+
+import (
+	"context"
+	"strings"
+
+	"github.com/specterops/bloodhound/dawgs/cardinality"
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/dawgs/query"
+	"github.com/specterops/bloodhound/graphschema/ad"
+	"github.com/specterops/bloodhound/graphschema/common"
+)
+
+// SIDResolver maps security identifiers to the nodes that carry them. Post-processors that look principals up by SID
+// accept a resolver so that a single lookup table can be shared by every processor of an analysis run. SIDs are
+// compared case-insensitively.
+type SIDResolver interface {
+	// Resolve returns the ID of the user, group, computer or domain whose object ID is the given SID. Foreign security
+	// principals are never returned, which makes Resolve suitable for finding the principal one stands in for.
+	Resolve(sid string) (graph.ID, bool)
+
+	// ResolveSuffixes returns the IDs of every user, group and computer whose object ID ends with one of the given SID
+	// suffixes
+	ResolveSuffixes(sidSuffixes ...string) cardinality.Duplex[uint32]
+
+	// WellKnown returns the name of the well-known principal the given SID denotes, if any
+	WellKnown(sid string) (string, bool)
+}
+
+type wellKnownSID struct {
+	suffix string
+	name   string
+}
+
+// wellKnownSIDs lists the well-known principals recognized by WellKnownSIDName. Object IDs of well-known principals
+// are prefixed with their domain at ingest, so entries are matched as suffixes. Domain relative entries follow
+// DefaultWellKnownRIDs.
+func wellKnownSIDs() []wellKnownSID {
+	return append([]wellKnownSID{
+		{suffix: EveryoneSuffix, name: "Everyone"},
+		{suffix: AuthenticatedUsersSuffix, name: "Authenticated Users"},
+		{suffix: "-S-" + EnterpriseDomainControllersGroupSIDSuffix, name: "Enterprise Domain Controllers"},
+	}, DefaultWellKnownRIDs().named()...)
+}
+
+// WellKnownSIDName returns the name of the well-known principal the given SID denotes. Domain relative SIDs are
+// recognized by their RID alone, regardless of domain.
+func WellKnownSIDName(sid string) (string, bool) {
+	normalizedSID := strings.ToUpper(sid)
+
+	for _, wellKnown := range wellKnownSIDs() {
+		if strings.HasSuffix(normalizedSID, wellKnown.suffix) {
+			return wellKnown.name, true
+		}
+	}
+
+	return "", false
+}
+
+// CachedSIDResolver is the default SIDResolver. It holds the object ID of every user, group, computer and domain in the
+// graph, fetched with a single query by FetchSIDResolver, and does not observe later changes to the graph.
+type CachedSIDResolver struct {
+	principalsBySID map[string]graph.ID
+	domainsBySID    map[string]graph.ID
+}
+
+func (s *CachedSIDResolver) Resolve(sid string) (graph.ID, bool) {
+	normalizedSID := strings.ToUpper(sid)
+
+	if id, found := s.principalsBySID[normalizedSID]; found {
+		return id, true
+	}
+
+	id, found := s.domainsBySID[normalizedSID]
+	return id, found
+}
+
+func (s *CachedSIDResolver) ResolveSuffixes(sidSuffixes ...string) cardinality.Duplex[uint32] {
+	principals := cardinality.NewBitmap32()
+
+	if len(sidSuffixes) == 0 {
+		return principals
+	}
+
+	normalizedSuffixes := make([]string, len(sidSuffixes))
+
+	for idx, sidSuffix := range sidSuffixes {
+		normalizedSuffixes[idx] = strings.ToUpper(sidSuffix)
+	}
+
+	for sid, id := range s.principalsBySID {
+		for _, sidSuffix := range normalizedSuffixes {
+			if strings.HasSuffix(sid, sidSuffix) {
+				principals.Add(id.Uint32())
+				break
+			}
+		}
+	}
+
+	return principals
+}
+
+func (s *CachedSIDResolver) WellKnown(sid string) (string, bool) {
+	return WellKnownSIDName(sid)
+}
+
+func fetchSIDResolver(tx graph.Transaction) (*CachedSIDResolver, error) {
+	resolver := &CachedSIDResolver{
+		principalsBySID: map[string]graph.ID{},
+		domainsBySID:    map[string]graph.ID{},
+	}
+
+	return resolver, tx.Nodes().Filterf(func() graph.Criteria {
+		return query.And(
+			query.KindIn(query.Node(), ad.User, ad.Group, ad.Computer, ad.Domain),
+			query.Exists(query.NodeProperty(common.ObjectID.String())),
+		)
+	}).Fetch(func(cursor graph.Cursor[*graph.Node]) error {
+		for node := range cursor.Chan() {
+			if objectID, err := node.Properties.Get(common.ObjectID.String()).String(); err != nil || objectID == "" {
+				continue
+			} else if node.Kinds.ContainsOneOf(ad.Domain) {
+				resolver.domainsBySID[strings.ToUpper(objectID)] = node.ID
+			} else {
+				resolver.principalsBySID[strings.ToUpper(objectID)] = node.ID
+			}
+		}
+
+		return cursor.Error()
+	})
+}
+
+// FetchSIDResolver builds a CachedSIDResolver with a single query so that the result can be passed to the
+// post-processors of an analysis run in place of each looking principals up by SID on its own
+func FetchSIDResolver(ctx context.Context, db graph.Database) (*CachedSIDResolver, error) {
+	var resolver *CachedSIDResolver
+
+	return resolver, db.ReadTransaction(ctx, func(tx graph.Transaction) error {
+		if fetchedResolver, err := fetchSIDResolver(tx); err != nil {
+			return err
+		} else {
+			resolver = fetchedResolver
+			return nil
+		}
+	})
+}
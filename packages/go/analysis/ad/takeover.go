@@ -0,0 +1,186 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ad
+
+import (
+	"context"
+	"sort"
+
+	"github.com/RoaringBitmap/roaring/roaring64"
+	"github.com/specterops/bloodhound/analysis"
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/dawgs/query"
+	"github.com/specterops/bloodhound/dawgs/util/channels"
+	"github.com/specterops/bloodhound/graphschema/ad"
+)
+
+// Properties written to post-processed CanTakeOver edges. The sources property lists the kinds of the underlying
+// edges that granted full control and the source IDs property lists the IDs of those edges.
+const (
+	CanTakeOverSourcesProperty   = "takeoversources"
+	CanTakeOverSourceIDsProperty = "takeoversourceids"
+)
+
+// CanTakeOverRelationships returns the relationship kinds read by PostCanTakeOver
+func CanTakeOverRelationships() []graph.Kind {
+	return []graph.Kind{ad.GenericAll, ad.Owns, ad.WriteDACL, ad.WriteOwner}
+}
+
+type takeOverCandidate struct {
+	kinds           graph.Kinds
+	relationshipIDs []graph.ID
+}
+
+func (s *takeOverCandidate) add(kind graph.Kind, relationshipID graph.ID) {
+	s.kinds = append(s.kinds, kind)
+	s.relationshipIDs = append(s.relationshipIDs, relationshipID)
+}
+
+// sources returns the kinds and relationship IDs that grant full control. WriteDACL and WriteOwner only count when
+// both are held, whereas GenericAll and Owns count on their own. Nothing is returned when no condition holds.
+func (s *takeOverCandidate) sources() ([]string, []int64) {
+	var (
+		writeDACL, writeOwner = s.kinds.ContainsOneOf(ad.WriteDACL), s.kinds.ContainsOneOf(ad.WriteOwner)
+		sourceKinds           = map[string]struct{}{}
+		sourceIDs             []int64
+	)
+
+	for idx, kind := range s.kinds {
+		if kind.Is(ad.GenericAll, ad.Owns) || (writeDACL && writeOwner) {
+			sourceKinds[kind.String()] = struct{}{}
+			sourceIDs = append(sourceIDs, int64(s.relationshipIDs[idx]))
+		}
+	}
+
+	sortedKinds := make([]string, 0, len(sourceKinds))
+
+	for kind := range sourceKinds {
+		sortedKinds = append(sortedKinds, kind)
+	}
+
+	sort.Strings(sortedKinds)
+	sort.Slice(sourceIDs, func(i, j int) bool {
+		return sourceIDs[i] < sourceIDs[j]
+	})
+
+	return sortedKinds, sourceIDs
+}
+
+// cost returns EdgeCostLow when GenericAll is held. Every other full control primitive requires the DACL to be
+// rewritten before the object can be taken over.
+func (s *takeOverCandidate) cost() analysis.EdgeCost {
+	if s.kinds.ContainsOneOf(ad.GenericAll) {
+		return analysis.EdgeCostLow
+	}
+
+	return analysis.EdgeCostMedium
+}
+
+// fetchTakeOverTargets returns the IDs of every node that is the end of a relationship read by PostCanTakeOver
+func fetchTakeOverTargets(ctx context.Context, db graph.Database) (*roaring64.Bitmap, error) {
+	targets := roaring64.NewBitmap()
+
+	return targets, db.ReadTransaction(ctx, func(tx graph.Transaction) error {
+		return tx.Relationships().Filterf(func() graph.Criteria {
+			return query.KindIn(query.Relationship(), CanTakeOverRelationships()...)
+		}).FetchTriples(func(cursor graph.Cursor[graph.RelationshipTripleResult]) error {
+			for result := range cursor.Chan() {
+				if result.StartID != result.EndID {
+					targets.Add(result.EndID.Uint64())
+				}
+			}
+
+			return cursor.Error()
+		})
+	})
+}
+
+// fetchTakeOverCandidates returns the full control primitives held over the given target, keyed by the holder
+func fetchTakeOverCandidates(tx graph.Transaction, target graph.ID) (map[graph.ID]*takeOverCandidate, error) {
+	candidates := map[graph.ID]*takeOverCandidate{}
+
+	return candidates, tx.Relationships().Filterf(func() graph.Criteria {
+		return query.And(
+			query.Equals(query.EndID(), target),
+			query.KindIn(query.Relationship(), CanTakeOverRelationships()...),
+		)
+	}).FetchKinds(func(cursor graph.Cursor[graph.RelationshipKindsResult]) error {
+		for result := range cursor.Chan() {
+			if result.StartID == target {
+				continue
+			}
+
+			candidate, found := candidates[result.StartID]
+
+			if !found {
+				candidate = &takeOverCandidate{}
+				candidates[result.StartID] = candidate
+			}
+
+			candidate.add(result.Kind, result.ID)
+		}
+
+		return cursor.Error()
+	})
+}
+
+// PostCanTakeOver emits a single CanTakeOver edge for every principal and object pair where the principal holds a full
+// control primitive over the object: GenericAll, Owns, or both WriteDACL and WriteOwner. Pairs satisfying several
+// conditions still receive one edge, which records the underlying edges through CanTakeOverSourcesProperty and
+// CanTakeOverSourceIDsProperty. Self-referencing edges are ignored. Targets are processed one at a time so that only
+// the control relationships of a single target are held in memory.
+func PostCanTakeOver(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+	targets, err := fetchTakeOverTargets(ctx, db)
+	if err != nil {
+		return &analysis.AtomicPostProcessingStats{}, err
+	}
+
+	operation := analysis.NewPostRelationshipOperation(ctx, db, "CanTakeOver Post Processing")
+
+	if err := operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		for iterator := targets.Iterator(); iterator.HasNext(); {
+			target := graph.ID(iterator.Next())
+
+			if candidates, err := fetchTakeOverCandidates(tx, target); err != nil {
+				return err
+			} else {
+				for holder, candidate := range candidates {
+					if sourceKinds, sourceIDs := candidate.sources(); len(sourceKinds) > 0 {
+						if !channels.Submit(ctx, outC, analysis.CreatePostRelationshipJob{
+							FromID: holder,
+							ToID:   target,
+							Kind:   ad.CanTakeOver,
+							Cost:   candidate.cost(),
+							RelProperties: map[string]any{
+								CanTakeOverSourcesProperty:   sourceKinds,
+								CanTakeOverSourceIDsProperty: sourceIDs,
+							},
+						}) {
+							return nil
+						}
+					}
+				}
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return &operation.Stats, err
+	}
+
+	return &operation.Stats, operation.Done()
+}
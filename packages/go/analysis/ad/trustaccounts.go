@@ -0,0 +1,139 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ad
+
+import (
+	"context"
+	"strings"
+
+	"github.com/specterops/bloodhound/analysis"
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/dawgs/ops"
+	"github.com/specterops/bloodhound/dawgs/query"
+	"github.com/specterops/bloodhound/dawgs/util/channels"
+	"github.com/specterops/bloodhound/graphschema/ad"
+	"github.com/specterops/bloodhound/graphschema/common"
+)
+
+// TrustAccountControlRelationships are the rights over a trust account that allow a principal to reset its password
+// and with it the trust key
+func TrustAccountControlRelationships() []graph.Kind {
+	return []graph.Kind{ad.GenericAll, ad.GenericWrite, ad.WriteDACL, ad.WriteOwner, ad.Owns, ad.AllExtendedRights, ad.ForceChangePassword}
+}
+
+// TrustAccountName returns the sAMAccountName of the trust account that represents the domain with the given name. The
+// flat (NetBIOS) name of a domain is not collected, so the first label of the domain's DNS name is used in its place.
+// Domains whose flat name was chosen independently of their DNS name will not have their trust accounts recognized.
+func TrustAccountName(domainName string) string {
+	flatName, _, _ := strings.Cut(domainName, ".")
+	return strings.ToUpper(flatName) + "$"
+}
+
+// FetchTrustAccounts returns the trust accounts found in the graph mapped to the domain whose trust key each holds.
+// When domain A trusts domain B, domain B holds a user account named after A with the TRUST_ACCOUNT account type.
+// The account type is not collected either, so a user of B is recognized as a trust account when its sAMAccountName
+// matches TrustAccountName for a domain that B is TrustedBy.
+func FetchTrustAccounts(tx graph.Transaction) (map[graph.ID]graph.ID, error) {
+	trustAccounts := map[graph.ID]graph.ID{}
+
+	if trusts, err := ops.FetchRelationships(tx.Relationships().Filterf(func() graph.Criteria {
+		return query.And(
+			query.Kind(query.Relationship(), ad.TrustedBy),
+			query.Kind(query.Start(), ad.Domain),
+			query.Kind(query.End(), ad.Domain),
+		)
+	})); err != nil {
+		return nil, err
+	} else {
+		for _, trust := range trusts {
+			if trust.StartID == trust.EndID {
+				continue
+			}
+
+			if trustedDomain, trustingDomain, err := ops.FetchRelationshipNodes(tx, trust); err != nil {
+				return nil, err
+			} else if trustedDomainSID, err := trustedDomain.Properties.Get(common.ObjectID.String()).String(); err != nil {
+				continue
+			} else if trustingDomainName, err := trustingDomain.Properties.Get(common.Name.String()).String(); err != nil {
+				continue
+			} else if candidates, err := ops.FetchNodes(tx.Nodes().Filterf(func() graph.Criteria {
+				return query.And(
+					query.Kind(query.Node(), ad.User),
+					query.Equals(query.NodeProperty(ad.DomainSID.String()), trustedDomainSID),
+					query.StringEndsWith(query.NodeProperty(ad.SamAccountName.String()), "$"),
+				)
+			})); err != nil {
+				return nil, err
+			} else {
+				trustAccountName := TrustAccountName(trustingDomainName)
+
+				for _, candidate := range candidates {
+					if samAccountName, err := candidate.Properties.Get(ad.SamAccountName.String()).String(); err == nil && strings.EqualFold(samAccountName, trustAccountName) {
+						trustAccounts[candidate.ID] = trustingDomain.ID
+					}
+				}
+			}
+		}
+	}
+
+	return trustAccounts, nil
+}
+
+// PostTrustAccountCompromise emits a HasTrustKeys edge from every principal directly holding one of
+// TrustAccountControlRelationships over a trust account to the domain on the other side of the trust. Resetting the
+// trust account's password yields the trust key, which is enough to forge inter-realm tickets into that domain. Trust
+// accounts are identified by FetchTrustAccounts and carry its naming assumptions.
+func PostTrustAccountCompromise(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+	operation := analysis.NewPostRelationshipOperation(ctx, db, "Trust Account Compromise Post Processing")
+
+	if err := operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		if trustAccounts, err := FetchTrustAccounts(tx); err != nil {
+			return err
+		} else {
+			for trustAccount, trustingDomain := range trustAccounts {
+				if controllers, err := ops.FetchStartNodes(tx.Relationships().Filterf(func() graph.Criteria {
+					return query.And(
+						query.KindIn(query.Relationship(), TrustAccountControlRelationships()...),
+						query.Equals(query.EndID(), trustAccount),
+					)
+				})); err != nil {
+					return err
+				} else {
+					for _, controller := range controllers {
+						if controller.ID == trustAccount {
+							continue
+						}
+
+						if !channels.Submit(ctx, outC, analysis.CreatePostRelationshipJob{
+							FromID: controller.ID,
+							ToID:   trustingDomain,
+							Kind:   ad.HasTrustKeys,
+						}) {
+							return nil
+						}
+					}
+				}
+			}
+
+			return nil
+		}
+	}); err != nil {
+		return &operation.Stats, err
+	}
+
+	return &operation.Stats, operation.Done()
+}
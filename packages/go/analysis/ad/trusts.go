@@ -0,0 +1,137 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ad
+
+import (
+	"context"
+
+	"github.com/specterops/bloodhound/analysis"
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/dawgs/query"
+	"github.com/specterops/bloodhound/dawgs/util/channels"
+	"github.com/specterops/bloodhound/graphschema/ad"
+	"github.com/specterops/bloodhound/log"
+)
+
+// Trust types as reported by the collector on ingested TrustedBy edges
+const (
+	TrustTypeParentChild = "ParentChild"
+	TrustTypeCrossLink   = "CrossLink"
+	TrustTypeTreeRoot    = "TreeRoot"
+	TrustTypeForest      = "Forest"
+	TrustTypeExternal    = "External"
+)
+
+// Properties written to post-processed trust edges
+const (
+	TrustTransitiveProperty = "transitive"
+	TrustDirectionProperty  = "trustdirection"
+
+	TrustDirectionOneWay        = "OneWay"
+	TrustDirectionBidirectional = "Bidirectional"
+)
+
+// TrustKindForType maps a collected trust type to the post-processed trust edge kind. Trusts that stay within a forest
+// map to SameForestTrust while forest and external trusts map to CrossForestTrust. The second return value is false for
+// trust types that can not be classified.
+func TrustKindForType(trustType string) (graph.Kind, bool) {
+	switch trustType {
+	case TrustTypeParentChild, TrustTypeCrossLink, TrustTypeTreeRoot:
+		return ad.SameForestTrust, true
+
+	case TrustTypeForest, TrustTypeExternal:
+		return ad.CrossForestTrust, true
+
+	default:
+		return nil, false
+	}
+}
+
+// PostDomainTrusts reads the raw TrustedBy edges written at ingest time and materializes directional SameForestTrust
+// and CrossForestTrust edges between the same domain nodes. Each resulting edge carries the trust's transitivity, SID
+// filtering state and whether the trust is one-way or bidirectional. TrustedBy itself remains owned by ingest and is
+// not rewritten here.
+func PostDomainTrusts(ctx context.Context, db graph.Database) (*analysis.AtomicPostProcessingStats, error) {
+	operation := analysis.NewPostRelationshipOperation(ctx, db, "Domain Trusts Post Processing")
+
+	if err := operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		var (
+			trusts      []*graph.Relationship
+			knownTrusts = map[endpointPair]struct{}{}
+		)
+
+		if err := tx.Relationships().Filterf(func() graph.Criteria {
+			return query.And(
+				query.Kind(query.Relationship(), ad.TrustedBy),
+				query.Kind(query.Start(), ad.Domain),
+				query.Kind(query.End(), ad.Domain),
+			)
+		}).Fetch(func(cursor graph.Cursor[*graph.Relationship]) error {
+			for trust := range cursor.Chan() {
+				trusts = append(trusts, trust)
+				knownTrusts[endpointPair{From: trust.StartID, To: trust.EndID}] = struct{}{}
+			}
+
+			return cursor.Error()
+		}); err != nil {
+			return err
+		}
+
+		for _, trust := range trusts {
+			if trust.StartID == trust.EndID {
+				continue
+			}
+
+			trustType, _ := trust.Properties.Get(ad.TrustType.String()).String()
+
+			if trustKind, ok := TrustKindForType(trustType); !ok {
+				log.Debugf("Skipping trust %d with unclassified trust type %q", trust.ID, trustType)
+			} else {
+				var (
+					transitive, _   = trust.Properties.Get(TrustTransitiveProperty).Bool()
+					sidFiltering, _ = trust.Properties.Get(ad.SidFiltering.String()).Bool()
+					direction       = TrustDirectionOneWay
+				)
+
+				if _, isBidirectional := knownTrusts[endpointPair{From: trust.EndID, To: trust.StartID}]; isBidirectional {
+					direction = TrustDirectionBidirectional
+				}
+
+				nextJob := analysis.CreatePostRelationshipJob{
+					FromID: trust.StartID,
+					ToID:   trust.EndID,
+					Kind:   trustKind,
+					RelProperties: map[string]any{
+						TrustTransitiveProperty:  transitive,
+						ad.SidFiltering.String(): sidFiltering,
+						TrustDirectionProperty:   direction,
+					},
+				}
+
+				if !channels.Submit(ctx, outC, nextJob) {
+					return nil
+				}
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return &operation.Stats, err
+	}
+
+	return &operation.Stats, operation.Done()
+}
@@ -0,0 +1,44 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ad_test
+
+import (
+	"testing"
+
+	adAnalysis "github.com/specterops/bloodhound/analysis/ad"
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/graphschema/ad"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrustKindForType(t *testing.T) {
+	for trustType, expectedKind := range map[string]graph.Kind{
+		adAnalysis.TrustTypeParentChild: ad.SameForestTrust,
+		adAnalysis.TrustTypeCrossLink:   ad.SameForestTrust,
+		adAnalysis.TrustTypeTreeRoot:    ad.SameForestTrust,
+		adAnalysis.TrustTypeForest:      ad.CrossForestTrust,
+		adAnalysis.TrustTypeExternal:    ad.CrossForestTrust,
+	} {
+		kind, ok := adAnalysis.TrustKindForType(trustType)
+
+		require.True(t, ok)
+		require.Equal(t, expectedKind, kind)
+	}
+
+	_, ok := adAnalysis.TrustKindForType("Unknown")
+	require.False(t, ok)
+}
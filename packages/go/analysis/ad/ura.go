@@ -0,0 +1,113 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ad
+
+import (
+	"context"
+	"sort"
+
+	"github.com/RoaringBitmap/roaring/roaring64"
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/dawgs/query"
+	"github.com/specterops/bloodhound/graphschema/ad"
+	"github.com/specterops/bloodhound/graphschema/common"
+)
+
+// URAIssue describes how a computer's user rights assignment collection disagrees with the rest of its collected data
+type URAIssue string
+
+const (
+	// URAIssueMissingHasURA marks a computer with RemoteInteractiveLogonPrivilege edges but without HasURA=true. CanRDP
+	// ignores those edges and falls back to Remote Desktop Users membership alone.
+	URAIssueMissingHasURA URAIssue = "MissingHasURA"
+
+	// URAIssueMissingRDPGroup marks a computer with HasURA=true but no Remote Desktop Users local group, leaving CanRDP
+	// with nothing to intersect the collected logon right with.
+	URAIssueMissingRDPGroup URAIssue = "MissingRDPGroup"
+)
+
+// ComputerURAIssue is a single inconsistency reported by AuditURAConsistency
+type ComputerURAIssue struct {
+	ComputerID graph.ID
+	Issue      URAIssue
+}
+
+// fetchRelationshipEndIDs returns the IDs of the end nodes of every relationship matching the given criteria
+func fetchRelationshipEndIDs(tx graph.Transaction, criteria graph.Criteria) (*roaring64.Bitmap, error) {
+	endIDs := roaring64.NewBitmap()
+
+	return endIDs, tx.Relationships().Filter(criteria).FetchTriples(func(cursor graph.Cursor[graph.RelationshipTripleResult]) error {
+		for result := range cursor.Chan() {
+			endIDs.Add(result.EndID.Uint64())
+		}
+
+		return cursor.Error()
+	})
+}
+
+// AuditURAConsistency reports the computers whose user rights assignment collection is inconsistent with their other
+// collected data, ordered by computer ID. CanRDP precision depends on HasURA, RemoteInteractiveLogonPrivilege edges and
+// the Remote Desktop Users local group agreeing with each other, so operators should resolve these before trusting it.
+// HasURA is read the same way ComputerHasURACollection reads it. Nothing is written to the graph.
+func AuditURAConsistency(ctx context.Context, db graph.Database) ([]ComputerURAIssue, error) {
+	var issues []ComputerURAIssue
+
+	if err := db.ReadTransaction(ctx, func(tx graph.Transaction) error {
+		if rilComputers, err := fetchRelationshipEndIDs(tx, query.And(
+			query.Kind(query.Relationship(), ad.RemoteInteractiveLogonPrivilege),
+			query.Kind(query.End(), ad.Computer),
+		)); err != nil {
+			return err
+		} else if rdpGroupComputers, err := fetchRelationshipEndIDs(tx, query.And(
+			query.StringEndsWith(query.StartProperty(common.ObjectID.String()), RDPGroupSuffix),
+			query.Kind(query.Relationship(), ad.LocalToComputer),
+			query.Kind(query.End(), ad.Computer),
+		)); err != nil {
+			return err
+		} else {
+			return tx.Nodes().Filterf(func() graph.Criteria {
+				return query.Kind(query.Node(), ad.Computer)
+			}).Fetch(func(cursor graph.Cursor[*graph.Node]) error {
+				for computer := range cursor.Chan() {
+					hasURA := nodeHasURACollection(computer)
+
+					if !hasURA && rilComputers.Contains(computer.ID.Uint64()) {
+						issues = append(issues, ComputerURAIssue{
+							ComputerID: computer.ID,
+							Issue:      URAIssueMissingHasURA,
+						})
+					} else if hasURA && !rdpGroupComputers.Contains(computer.ID.Uint64()) {
+						issues = append(issues, ComputerURAIssue{
+							ComputerID: computer.ID,
+							Issue:      URAIssueMissingRDPGroup,
+						})
+					}
+				}
+
+				return cursor.Error()
+			})
+		}
+	}); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(issues, func(i, j int) bool {
+		return issues[i].ComputerID < issues[j].ComputerID
+	})
+
+	return issues, nil
+}
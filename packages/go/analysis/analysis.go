@@ -35,6 +35,48 @@ const (
 	MaximumDatabaseParallelWorkers = 6
 )
 
+const (
+	// AnalysisVersion identifies the revision of the post-processing logic that produced a computed relationship. It
+	// must be bumped whenever a change to post-processing alters which relationships are created or what they carry.
+	// FindEdgesFromOlderVersions compares stamped relationships against it; relationships from before version stamping
+	// carry no version and are always treated as older.
+	AnalysisVersion = 1
+
+	// AnalysisVersionProperty is the relationship property that computed relationships are stamped with
+	AnalysisVersionProperty = "analysisversion"
+)
+
+// RegisteredRelationshipKinds returns every relationship kind declared by the generated graph schema
+func RegisteredRelationshipKinds() graph.Kinds {
+	var kinds graph.Kinds
+
+	kinds = append(kinds, ad.Relationships()...)
+	kinds = append(kinds, azure.Relationships()...)
+	return append(kinds, common.Relationships()...)
+}
+
+// EnsureKindsRegistered returns an error naming every given kind that is missing from RegisteredRelationshipKinds.
+// Relationships of an unregistered kind are still written by the graph, but are left out of pathfinding and the UI, so
+// a mismatch between the schema and the post-processing code is caught before any relationships are created.
+func EnsureKindsRegistered(kinds []graph.Kind) error {
+	var (
+		registered = RegisteredRelationshipKinds()
+		missing    []string
+	)
+
+	for _, kind := range kinds {
+		if !registered.ContainsOneOf(kind) {
+			missing = append(missing, kind.String())
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("relationship kinds are not registered in the graph schema: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
 func AllTaggedNodesFilter(additionalFilter graph.Criteria) graph.Criteria {
 	var (
 		filters = []graph.Criteria{
@@ -160,9 +202,76 @@ func FetchNodeByObjectID(tx graph.Transaction, objectID string) (*graph.Node, er
 	return tx.Nodes().Filter(nodeByIndexedKindProperty(common.ObjectID.String(), objectID, azure.Entity)).First()
 }
 
+// ExpansionStrategy selects how far MemberOf relationships are followed when the members of a group are resolved
+type ExpansionStrategy int
+
+const (
+	// ExpansionNone does not resolve group members; the group itself is the only principal considered
+	ExpansionNone ExpansionStrategy = iota
+
+	// ExpansionTransitive follows MemberOf to any depth
+	ExpansionTransitive
+
+	// ExpansionFirstDegreeOnly resolves only the direct members of a group
+	ExpansionFirstDegreeOnly
+
+	// ExpansionLimitedDepth follows MemberOf up to a caller supplied maximum depth
+	ExpansionLimitedDepth
+)
+
+func (s ExpansionStrategy) String() string {
+	switch s {
+	case ExpansionNone:
+		return "None"
+	case ExpansionTransitive:
+		return "Transitive"
+	case ExpansionFirstDegreeOnly:
+		return "FirstDegreeOnly"
+	case ExpansionLimitedDepth:
+		return "LimitedDepth"
+	default:
+		return fmt.Sprintf("ExpansionStrategy(%d)", int(s))
+	}
+}
+
+// maxDepth returns the deepest MemberOf hop the strategy permits, zero meaning unbounded
+func (s ExpansionStrategy) maxDepth(limitedDepth int) (int, error) {
+	switch s {
+	case ExpansionTransitive:
+		return 0, nil
+	case ExpansionFirstDegreeOnly:
+		return 1, nil
+	case ExpansionLimitedDepth:
+		if limitedDepth <= 0 {
+			return 0, fmt.Errorf("limited depth expansion requires a positive depth, got %d", limitedDepth)
+		}
+
+		return limitedDepth, nil
+	default:
+		return 0, fmt.Errorf("unsupported expansion strategy %s", s)
+	}
+}
+
 func ExpandGroupMembershipPaths(tx graph.Transaction, candidates graph.NodeSet) (graph.PathSet, error) {
+	return ExpandGroupMembershipPathsWithStrategy(tx, candidates, ExpansionTransitive, 0)
+}
+
+// ExpandGroupMembershipPathsWithStrategy returns the MemberOf paths leading into every group in candidates as limited by
+// the given strategy. limitedDepth is only consulted for ExpansionLimitedDepth.
+func ExpandGroupMembershipPathsWithStrategy(tx graph.Transaction, candidates graph.NodeSet, strategy ExpansionStrategy, limitedDepth int) (graph.PathSet, error) {
 	groupMemberPaths := graph.NewPathSet()
 
+	if strategy == ExpansionNone {
+		return groupMemberPaths, nil
+	}
+
+	maxDepth, err := strategy.maxDepth(limitedDepth)
+	if err != nil {
+		return nil, err
+	}
+
+	numTruncated := 0
+
 	for _, candidate := range candidates {
 		if candidate.Kinds.ContainsOneOf(ad.Group) {
 			if membershipPaths, err := ops.TraversePaths(tx, ops.TraversalPlan{
@@ -171,6 +280,14 @@ func ExpandGroupMembershipPaths(tx graph.Transaction, candidates graph.NodeSet)
 				BranchQuery: func() graph.Criteria {
 					return query.Kind(query.Relationship(), ad.MemberOf)
 				},
+				DescentFilter: func(ctx *ops.TraversalContext, segment *graph.PathSegment) bool {
+					if maxDepth == 0 || segment.Depth() <= maxDepth {
+						return true
+					}
+
+					numTruncated++
+					return false
+				},
 			}); err != nil {
 				return nil, err
 			} else {
@@ -179,11 +296,21 @@ func ExpandGroupMembershipPaths(tx graph.Transaction, candidates graph.NodeSet)
 		}
 	}
 
+	// First degree expansion is a deliberate choice rather than a budget so only a caller supplied depth is reported
+	if strategy == ExpansionLimitedDepth && numTruncated > 0 {
+		log.Warnf("Group membership expansion stopped %d paths at the budget of %d hops; expanded members may be incomplete", numTruncated, maxDepth)
+	}
+
 	return groupMemberPaths, nil
 }
 
 func ExpandGroupMembership(tx graph.Transaction, candidates graph.NodeSet) (graph.NodeSet, error) {
-	if paths, err := ExpandGroupMembershipPaths(tx, candidates); err != nil {
+	return ExpandGroupMembershipWithStrategy(tx, candidates, ExpansionTransitive, 0)
+}
+
+// ExpandGroupMembershipWithStrategy is the node form of ExpandGroupMembershipPathsWithStrategy
+func ExpandGroupMembershipWithStrategy(tx graph.Transaction, candidates graph.NodeSet, strategy ExpansionStrategy, limitedDepth int) (graph.NodeSet, error) {
+	if paths, err := ExpandGroupMembershipPathsWithStrategy(tx, candidates, strategy, limitedDepth); err != nil {
 		return nil, err
 	} else {
 		return paths.AllNodes(), nil
@@ -228,6 +355,12 @@ func GetLAPSSyncers(tx graph.Transaction, domain *graph.Node) ([]*graph.Node, er
 }
 
 func GetDCSyncers(tx graph.Transaction, domain *graph.Node, filterTierZero bool) ([]*graph.Node, error) {
+	return GetDCSyncersWithStrategy(tx, domain, filterTierZero, ExpansionTransitive, 0)
+}
+
+// GetDCSyncersWithStrategy behaves like GetDCSyncers but resolves the members of groups holding replication rights
+// with the given expansion strategy. limitedDepth is only consulted for ExpansionLimitedDepth.
+func GetDCSyncersWithStrategy(tx graph.Transaction, domain *graph.Node, filterTierZero bool, strategy ExpansionStrategy, limitedDepth int) ([]*graph.Node, error) {
 	var (
 		getChangesQuery    = fromEntityToEntityWithRelationshipKind(tx, domain, ad.GetChanges, filterTierZero)
 		getChangesAllQuery = fromEntityToEntityWithRelationshipKind(tx, domain, ad.GetChangesAll, filterTierZero)
@@ -235,11 +368,11 @@ func GetDCSyncers(tx graph.Transaction, domain *graph.Node, filterTierZero bool)
 
 	if getChangesNodes, err := ops.FetchStartNodes(getChangesQuery); err != nil {
 		return nil, err
-	} else if getChangesNodeMembers, err := ExpandGroupMembership(tx, getChangesNodes); err != nil {
+	} else if getChangesNodeMembers, err := ExpandGroupMembershipWithStrategy(tx, getChangesNodes, strategy, limitedDepth); err != nil {
 		return nil, err
 	} else if getChangesAllNodes, err := ops.FetchStartNodes(getChangesAllQuery); err != nil {
 		return nil, err
-	} else if getChangesAllNodeMembers, err := ExpandGroupMembership(tx, getChangesAllNodes); err != nil {
+	} else if getChangesAllNodeMembers, err := ExpandGroupMembershipWithStrategy(tx, getChangesAllNodes, strategy, limitedDepth); err != nil {
 		return nil, err
 	} else {
 		// Collect and filter the bitmap
@@ -291,6 +424,85 @@ func GetDCSyncers(tx graph.Transaction, domain *graph.Node, filterTierZero bool)
 	}
 }
 
+// DCSyncDACLControlRights returns the rights over a domain head that allow a principal to rewrite its DACL and so grant
+// itself the replication rights DCSync requires
+func DCSyncDACLControlRights() []graph.Kind {
+	return []graph.Kind{ad.WriteDACL, ad.GenericAll}
+}
+
+// GetDACLDCSyncers returns the principals holding one of DCSyncDACLControlRights over the given domain along with the
+// expanded membership of groups holding them. These principals can not DCSync yet but can grant themselves the rights
+// to. Tier zero principals are left out when filterTierZero is set, as they are by GetDCSyncers.
+func GetDACLDCSyncers(tx graph.Transaction, domain *graph.Node, filterTierZero bool) ([]*graph.Node, error) {
+	controllers := graph.NewNodeSet()
+
+	for _, right := range DCSyncDACLControlRights() {
+		if rightHolders, err := ops.FetchStartNodes(fromEntityToEntityWithRelationshipKind(tx, domain, right, filterTierZero)); err != nil {
+			return nil, err
+		} else {
+			controllers.AddSet(rightHolders)
+		}
+	}
+
+	if controllerMembers, err := ExpandGroupMembership(tx, controllers); err != nil {
+		return nil, err
+	} else {
+		controllers.AddSet(controllerMembers)
+	}
+
+	return expandedSyncerSlice(controllers, filterTierZero)
+}
+
+// GetExchangeDCSyncers returns the groups accepted by isExchangeGroup that hold GetChangesAll over the given domain,
+// along with their expanded membership. Unlike GetDCSyncers the groups are not required to also hold GetChanges.
+// Tier zero principals are left out when filterTierZero is set, as they are by GetDCSyncers.
+func GetExchangeDCSyncers(tx graph.Transaction, domain *graph.Node, filterTierZero bool, isExchangeGroup func(group *graph.Node) bool) ([]*graph.Node, error) {
+	if getChangesAllNodes, err := ops.FetchStartNodes(fromEntityToEntityWithRelationshipKind(tx, domain, ad.GetChangesAll, filterTierZero)); err != nil {
+		return nil, err
+	} else {
+		exchangeGroups := graph.NewNodeSet()
+
+		for _, node := range getChangesAllNodes {
+			if node.Kinds.ContainsOneOf(ad.Group) && isExchangeGroup(node) {
+				exchangeGroups.Add(node)
+			}
+		}
+
+		if exchangeGroups.Len() == 0 {
+			return nil, nil
+		} else if exchangeGroupMembers, err := ExpandGroupMembership(tx, exchangeGroups); err != nil {
+			return nil, err
+		} else {
+			exchangeGroups.AddSet(exchangeGroupMembers)
+		}
+
+		return expandedSyncerSlice(exchangeGroups, filterTierZero)
+	}
+}
+
+// expandedSyncerSlice returns the given nodes as a slice, leaving out tier zero principals when filterTierZero is set.
+// Group membership expansion may reach tier zero principals that do not hold a right themselves, so these are not
+// excluded by the query that found the right holders.
+func expandedSyncerSlice(nodes graph.NodeSet, filterTierZero bool) ([]*graph.Node, error) {
+	syncers := make([]*graph.Node, 0, nodes.Len())
+
+	for _, node := range nodes {
+		if filterTierZero {
+			if systemTags, err := node.Properties.Get(common.SystemTags.String()).String(); err != nil {
+				if !graph.IsErrPropertyNotFound(err) {
+					return nil, err
+				}
+			} else if strings.Contains(systemTags, ad.AdminTierZero) {
+				continue
+			}
+		}
+
+		syncers = append(syncers, node)
+	}
+
+	return syncers, nil
+}
+
 func fromEntityToEntityWithRelationshipKind(tx graph.Transaction, target *graph.Node, relKind graph.Kind, filterTierZero bool) graph.RelationshipQuery {
 	return tx.Relationships().Filterf(func() graph.Criteria {
 		filters := []graph.Criteria{
@@ -52,3 +52,11 @@ func TestGetNodeKindDisplayLabel(t *testing.T) {
 	require.Equal(t, unsupportedKind.String(), analysis.GetNodeKindDisplayLabel(graph.PrepareNode(graph.NewProperties(), unsupportedKind)))
 	require.Equal(t, "Unknown", analysis.GetNodeKindDisplayLabel(graph.PrepareNode(graph.NewProperties())))
 }
+
+func TestEnsureKindsRegistered(t *testing.T) {
+	require.Nil(t, analysis.EnsureKindsRegistered([]graph.Kind{ad.DCSync, azure.AddSecret}))
+	require.Nil(t, analysis.EnsureKindsRegistered(nil))
+
+	err := analysis.EnsureKindsRegistered([]graph.Kind{ad.DCSync, graph.StringKind("Unregistered"), ad.User})
+	require.ErrorContains(t, err, "Unregistered, User")
+}
@@ -481,7 +481,7 @@ func createAZMGApplicationReadWriteAllEdges(ctx context.Context, db graph.Databa
 		} else {
 			targetRelationships := append(tenantContainsServicePrincipalRelationships, tenantContainsAppRelationships...)
 
-			operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+			operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
 				for _, targetRelationship := range targetRelationships {
 					for _, sourceNode := range sourceNodes {
 						AZMGAddSecretRelationship := analysis.CreatePostRelationshipJob{
@@ -521,7 +521,7 @@ func createAZMGAppRoleAssignmentReadWriteAllEdges(ctx context.Context, db graph.
 		if sourceNodes, err := aggregateSourceReadWriteServicePrincipals(tx, tenantContainsServicePrincipalRelationships, azure.AppRoleAssignmentReadWriteAll); err != nil {
 			return err
 		} else {
-			operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+			operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
 				for _, tenantContainsServicePrincipalRelationship := range tenantContainsServicePrincipalRelationships {
 					for _, sourceNode := range sourceNodes {
 						AZMGGrantAppRolesRelationship := analysis.CreatePostRelationshipJob{
@@ -553,7 +553,7 @@ func createAZMGDirectoryReadWriteAllEdges(ctx context.Context, db graph.Database
 		} else if tenantContainsGroupRelationships, err := fetchTenantContainsReadWriteAllGroupRelationships(tx, tenant); err != nil {
 			return err
 		} else {
-			operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+			operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
 				for _, tenantContainsGroupRelationship := range tenantContainsGroupRelationships {
 					for _, sourceNode := range sourceNodes {
 						AZMGAddMemberRelationship := analysis.CreatePostRelationshipJob{
@@ -595,7 +595,7 @@ func createAZMGGroupReadWriteAllEdges(ctx context.Context, db graph.Database, op
 		} else if tenantContainsGroupRelationships, err := fetchTenantContainsReadWriteAllGroupRelationships(tx, tenant); err != nil {
 			return err
 		} else {
-			operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+			operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
 				for _, tenantContainsGroupRelationship := range tenantContainsGroupRelationships {
 					for _, sourceNode := range sourceNodes {
 						AZMGAddMemberRelationship := analysis.CreatePostRelationshipJob{
@@ -637,7 +637,7 @@ func createAZMGGroupMemberReadWriteAllEdges(ctx context.Context, db graph.Databa
 		} else if tenantContainsGroupRelationships, err := fetchTenantContainsReadWriteAllGroupRelationships(tx, tenant); err != nil {
 			return err
 		} else {
-			operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+			operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
 				for _, tenantContainsGroupRelationship := range tenantContainsGroupRelationships {
 					for _, sourceNode := range sourceNodes {
 						AZMGAddMemberRelationship := analysis.CreatePostRelationshipJob{
@@ -669,7 +669,7 @@ func createAZMGRoleManagementReadWriteDirectoryEdgesPart1(ctx context.Context, d
 		} else if tenantContainsRoleRelationships, err := fetchTenantContainsRelationships(tx, tenant, azure.Role); err != nil {
 			return err
 		} else {
-			operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+			operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
 				for _, tenantContainsRoleRelationship := range tenantContainsRoleRelationships {
 					for _, sourceNode := range sourceNodes {
 						AZMGGrantAppRolesRelationship := analysis.CreatePostRelationshipJob{
@@ -701,7 +701,7 @@ func createAZMGRoleManagementReadWriteDirectoryEdgesPart2(ctx context.Context, d
 		} else if tenantContainsRoleRelationships, err := fetchTenantContainsRelationships(tx, tenant, azure.Role); err != nil {
 			return err
 		} else {
-			operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+			operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
 				for _, tenantContainsRoleRelationship := range tenantContainsRoleRelationships {
 					for _, sourceNode := range sourceNodes {
 						AZMGGrantRoleRelationship := analysis.CreatePostRelationshipJob{
@@ -731,7 +731,7 @@ func createAZMGRoleManagementReadWriteDirectoryEdgesPart3(ctx context.Context, d
 		if sourceNodes, err := aggregateSourceReadWriteServicePrincipals(tx, tenantContainsServicePrincipalRelationships, azure.RoleManagementReadWriteDirectory); err != nil {
 			return err
 		} else {
-			operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+			operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
 				for _, tenantContainsServicePrincipalRelationship := range tenantContainsServicePrincipalRelationships {
 					for _, sourceNode := range sourceNodes {
 						AZMGAddSecretRelationship := analysis.CreatePostRelationshipJob{
@@ -773,7 +773,7 @@ func createAZMGRoleManagementReadWriteDirectoryEdgesPart4(ctx context.Context, d
 		} else if tenantContainsAppRelationships, err := fetchTenantContainsRelationships(tx, tenant, azure.App); err != nil {
 			return err
 		} else {
-			operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+			operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
 				for _, tenantContainsAppRelationship := range tenantContainsAppRelationships {
 					for _, sourceNode := range sourceNodes {
 						AZMGAddSecretRelationship := analysis.CreatePostRelationshipJob{
@@ -815,7 +815,7 @@ func createAZMGRoleManagementReadWriteDirectoryEdgesPart5(ctx context.Context, d
 		} else if tenantContainsGroupRelationships, err := fetchTenantContainsRelationships(tx, tenant, azure.Group); err != nil {
 			return err
 		} else {
-			operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+			operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
 				for _, tenantContainsGroupRelationship := range tenantContainsGroupRelationships {
 					for _, sourceNode := range sourceNodes {
 						AZMGAddMemberRelationship := analysis.CreatePostRelationshipJob{
@@ -855,7 +855,7 @@ func createAZMGServicePrincipalEndpointReadWriteAllEdges(ctx context.Context, db
 		if sourceNodes, err := aggregateSourceReadWriteServicePrincipals(tx, tenantContainsServicePrincipalRelationships, azure.ServicePrincipalEndpointReadWriteAll); err != nil {
 			return err
 		} else {
-			operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+			operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
 				for _, tenantContainsServicePrincipalRelationship := range tenantContainsServicePrincipalRelationships {
 					for _, sourceNode := range sourceNodes {
 						AZMGAddOwnerRelationship := analysis.CreatePostRelationshipJob{
@@ -888,7 +888,7 @@ func AddSecret(ctx context.Context, db graph.Database) (*analysis.AtomicPostProc
 	} else {
 		operation := analysis.NewPostRelationshipOperation(ctx, db, "AZAddSecret Post Processing")
 
-		operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
 			for _, appOwner := range appOwnerRels {
 				nextJob := analysis.CreatePostRelationshipJob{
 					FromID: appOwner.StartID,
@@ -915,7 +915,7 @@ func AddSecret(ctx context.Context, db graph.Database) (*analysis.AtomicPostProc
 				} else {
 					for _, roleMember := range roleMembers {
 						innerRoleMember := roleMember
-						operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+						operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
 							for _, tenantContainsAppsRelationship := range tenantContainsAppRelationships {
 								nextJob := analysis.CreatePostRelationshipJob{
 									FromID: innerRoleMember.ID,
@@ -960,7 +960,7 @@ func ExecuteCommand(ctx context.Context, db graph.Database) (*analysis.AtomicPos
 				} else {
 					for _, tenantDevice := range tenantDevices {
 						innerTenantDevice := tenantDevice
-						operation.Operation.SubmitReader(func(ctx context.Context, _ graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+						operation.SubmitReader(func(ctx context.Context, _ graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
 							if isWindowsDevice, err := IsWindowsDevice(innerTenantDevice); err != nil {
 								return err
 							} else if isWindowsDevice {
@@ -1050,7 +1050,7 @@ func getRoleEligibleSecurityGroupUsers(ctx context.Context, db graph.Database, r
 func resetPasswordCases(roleAssignments RoleAssignments, operation analysis.StatTrackedOperation[analysis.CreatePostRelationshipJob], userID graph.ID, usersWithoutRoles graph.NodeSet, securityGroupUsers *roaring64.Bitmap) error {
 	if roleAssignments.NodeHasRole(userID, azure.CompanyAdministratorRole, azure.PrivilegedAuthenticationAdministratorRole, azure.PartnerTier2SupportRole) {
 		// GA, PAA, and PT2S roles can reset all user passwords in the tenant
-		operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
 			for targetID := range roleAssignments.Nodes.Get(azure.User) {
 				if userID == targetID {
 					continue
@@ -1070,7 +1070,7 @@ func resetPasswordCases(roleAssignments RoleAssignments, operation analysis.Stat
 			return nil
 		})
 	} else if roleAssignments.NodeHasRole(userID, azure.HelpdeskAdministratorRole) {
-		operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
 			for targetID, targetNode := range roleAssignments.NodesWithRolesExclusive(HelpdeskAdministratorPasswordResetTargetRoles()...).Get(azure.User) {
 				if userID == targetID || securityGroupUsers.Contains(targetID.Uint64()) {
 					continue
@@ -1090,7 +1090,7 @@ func resetPasswordCases(roleAssignments RoleAssignments, operation analysis.Stat
 			return nil
 		})
 
-		operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
 			for targetID := range usersWithoutRoles {
 				if userID == targetID || securityGroupUsers.Contains(targetID.Uint64()) {
 					continue
@@ -1110,7 +1110,7 @@ func resetPasswordCases(roleAssignments RoleAssignments, operation analysis.Stat
 		})
 
 	} else if roleAssignments.NodeHasRole(userID, azure.AuthenticationAdministratorRole) {
-		operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
 			for targetID, targetNode := range roleAssignments.NodesWithRolesExclusive(AuthenticationAdministratorPasswordResetTargetRoles()...).Get(azure.User) {
 				if userID == targetID || securityGroupUsers.Contains(targetID.Uint64()) {
 					continue
@@ -1130,7 +1130,7 @@ func resetPasswordCases(roleAssignments RoleAssignments, operation analysis.Stat
 			return nil
 		})
 
-		operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
 			for targetID := range usersWithoutRoles {
 				if userID == targetID || securityGroupUsers.Contains(targetID.Uint64()) {
 					continue
@@ -1150,7 +1150,7 @@ func resetPasswordCases(roleAssignments RoleAssignments, operation analysis.Stat
 		})
 
 	} else if roleAssignments.NodeHasRole(userID, azure.UserAccountAdministratorRole) {
-		operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
 			for targetID, targetNode := range roleAssignments.NodesWithRolesExclusive(UserAdministratorPasswordResetTargetRoles()...).Get(azure.User) {
 				if userID == targetID || securityGroupUsers.Contains(targetID.Uint64()) {
 					continue
@@ -1169,7 +1169,7 @@ func resetPasswordCases(roleAssignments RoleAssignments, operation analysis.Stat
 			return nil
 		})
 
-		operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
 			for targetID := range usersWithoutRoles {
 				if userID == targetID || securityGroupUsers.Contains(targetID.Uint64()) {
 					continue
@@ -1189,7 +1189,7 @@ func resetPasswordCases(roleAssignments RoleAssignments, operation analysis.Stat
 			return nil
 		})
 	} else if roleAssignments.NodeHasRole(userID, azure.PasswordAdministratorRole) {
-		operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
 			for targetID := range roleAssignments.NodesWithRolesExclusive(PasswordAdministratorPasswordResetTargetRoles()...).Get(azure.User) {
 				if userID == targetID || securityGroupUsers.Contains(targetID.Uint64()) {
 					continue
@@ -1209,7 +1209,7 @@ func resetPasswordCases(roleAssignments RoleAssignments, operation analysis.Stat
 			return nil
 		})
 
-		operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
 			for targetID := range usersWithoutRoles {
 				if userID == targetID || securityGroupUsers.Contains(targetID.Uint64()) {
 					continue
@@ -1228,7 +1228,7 @@ func resetPasswordCases(roleAssignments RoleAssignments, operation analysis.Stat
 			return nil
 		})
 	} else if roleAssignments.NodeHasRole(userID, azure.PartnerTier1SupportRole) {
-		operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
 			for targetID, targetNode := range usersWithoutRoles {
 				if userID == targetID || securityGroupUsers.Contains(targetID.Uint64()) {
 					continue
@@ -1253,7 +1253,7 @@ func resetPasswordCases(roleAssignments RoleAssignments, operation analysis.Stat
 }
 
 func globalAdmins(roleAssignments RoleAssignments, tenant *graph.Node, operation analysis.StatTrackedOperation[analysis.CreatePostRelationshipJob]) {
-	operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+	operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
 		for _, roleMember := range roleAssignments.NodesWithRole(azure.CompanyAdministratorRole).GetCombined(azure.User, azure.ServicePrincipal, azure.Group) {
 			nextJob := analysis.CreatePostRelationshipJob{
 				FromID: roleMember.ID,
@@ -1271,7 +1271,7 @@ func globalAdmins(roleAssignments RoleAssignments, tenant *graph.Node, operation
 }
 
 func privilegedRoleAdmins(roleAssignments RoleAssignments, tenant *graph.Node, operation analysis.StatTrackedOperation[analysis.CreatePostRelationshipJob]) {
-	operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+	operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
 		for _, roleMember := range roleAssignments.NodesWithRole(azure.PrivilegedRoleAdministratorRole).GetCombined(azure.User, azure.ServicePrincipal, azure.Group) {
 			nextJob := analysis.CreatePostRelationshipJob{
 				FromID: roleMember.ID,
@@ -1289,7 +1289,7 @@ func privilegedRoleAdmins(roleAssignments RoleAssignments, tenant *graph.Node, o
 }
 
 func privilegedAuthAdmins(roleAssignments RoleAssignments, tenant *graph.Node, operation analysis.StatTrackedOperation[analysis.CreatePostRelationshipJob]) {
-	operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+	operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
 		for _, roleMember := range roleAssignments.NodesWithRole(azure.PrivilegedAuthenticationAdministratorRole).GetCombined(azure.User, azure.ServicePrincipal, azure.Group) {
 			nextJob := analysis.CreatePostRelationshipJob{
 				FromID: roleMember.ID,
@@ -1312,7 +1312,7 @@ func addMembers(roleAssignments RoleAssignments, operation analysis.StatTrackedO
 	for tenantGroupID, tenantGroup := range tenantGroups {
 		innerGroupID := tenantGroupID
 		innerGroup := tenantGroup
-		operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
 			for _, tenantUser := range roleAssignments.NodesWithRole(AddMemberAllGroupsTargetRoles()...).Get(azure.User) {
 				nextJob := analysis.CreatePostRelationshipJob{
 					FromID: tenantUser.ID,
@@ -1328,7 +1328,7 @@ func addMembers(roleAssignments RoleAssignments, operation analysis.StatTrackedO
 			return nil
 		})
 
-		operation.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
 			if isRoleAssignable, err := innerGroup.Properties.Get(azure.IsAssignableToRole.String()).Bool(); err != nil {
 				if graph.IsErrPropertyNotFound(err) {
 					log.Errorf("Node %d is missing property %s", innerGroup.ID, azure.IsAssignableToRole)
@@ -0,0 +1,87 @@
+// Copyright 2023 Specter Ops, Inc.
+// 
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// 
+//     http://www.apache.org/licenses/LICENSE-2.0
+// 
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// 
+// SPDX-License-Identifier: Apache-2.0
+
+package analysis
+
+import (
+	"context"
+
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/dawgs/query"
+)
+
+const (
+	// CollectorIDProperty is the node property identifying the collector that ingested a node during federated
+	// collection
+	CollectorIDProperty = "collector_id"
+
+	// SourceCollectorProperty is the relationship property recording which collector's data produced a computed
+	// relationship. It holds SourceCollectorMultiple when the nodes involved were ingested by different collectors.
+	SourceCollectorProperty = "sourcecollector"
+	SourceCollectorMultiple = "multiple"
+)
+
+// CollectorAttribution maps the IDs of nodes carrying a CollectorIDProperty to the collector that ingested them
+type CollectorAttribution map[graph.ID]string
+
+// FetchCollectorAttribution returns the collector of every node carrying a CollectorIDProperty. The result is empty
+// when the graph was not built from federated collection.
+func FetchCollectorAttribution(tx graph.Transaction) (CollectorAttribution, error) {
+	attribution := CollectorAttribution{}
+
+	return attribution, tx.Nodes().Filterf(func() graph.Criteria {
+		return query.Exists(query.NodeProperty(CollectorIDProperty))
+	}).Fetch(func(cursor graph.Cursor[*graph.Node]) error {
+		for node := range cursor.Chan() {
+			if collectorID, err := node.Properties.Get(CollectorIDProperty).String(); err == nil && collectorID != "" {
+				attribution[node.ID] = collectorID
+			}
+		}
+
+		return cursor.Error()
+	})
+}
+
+// LoadCollectorAttribution runs FetchCollectorAttribution in a read transaction of its own
+func LoadCollectorAttribution(ctx context.Context, db graph.Database) (CollectorAttribution, error) {
+	var attribution CollectorAttribution
+
+	return attribution, db.ReadTransaction(ctx, func(tx graph.Transaction) error {
+		fetchedAttribution, err := FetchCollectorAttribution(tx)
+		attribution = fetchedAttribution
+
+		return err
+	})
+}
+
+// Source returns the collector that produced a computed relationship derived from the given nodes. Nodes without a
+// collector are ignored. The result is empty when none of the nodes has a collector and SourceCollectorMultiple when
+// they disagree.
+func (s CollectorAttribution) Source(nodeIDs ...graph.ID) string {
+	source := ""
+
+	for _, nodeID := range nodeIDs {
+		if collectorID, hasCollector := s[nodeID]; !hasCollector {
+			continue
+		} else if source == "" {
+			source = collectorID
+		} else if source != collectorID {
+			return SourceCollectorMultiple
+		}
+	}
+
+	return source
+}
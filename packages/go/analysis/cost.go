@@ -0,0 +1,129 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package analysis
+
+import (
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/graphschema/ad"
+)
+
+// EdgeCostProperty is the relationship property holding the EdgeCost of a computed relationship. Pathfinding may use it
+// as a weight to prefer paths that are easier to exploit.
+const EdgeCostProperty = "cost"
+
+// EdgeCost scores how difficult a computed relationship is to exploit. Lower costs are easier.
+type EdgeCost int
+
+const (
+	// EdgeCostUnscored leaves a relationship without an EdgeCostProperty
+	EdgeCostUnscored EdgeCost = 0
+
+	// EdgeCostLow is assigned to relationships that can be exploited directly with common tooling and immediately yield
+	// credentials or control
+	EdgeCostLow EdgeCost = 1
+
+	// EdgeCostMedium is assigned to relationships that need an additional step, a reachable service or a foothold on
+	// the target before they yield credentials or control
+	EdgeCostMedium EdgeCost = 2
+
+	// EdgeCostHigh is assigned to relationships that depend on conditions outside the attacker's control, such as a
+	// policy refresh or a trust that filters SIDs
+	EdgeCostHigh EdgeCost = 3
+)
+
+var defaultEdgeCosts = map[graph.Kind]EdgeCost{
+	// Replicating secrets from a domain controller is a single remote operation
+	ad.DCSync:           EdgeCostLow,
+	ad.SyncLAPSPassword: EdgeCostLow,
+
+	// Reading the LAPS password attribute is a single LDAP query
+	ad.ReadLAPSPassword: EdgeCostLow,
+
+	// Post-processed ReadGMSAPassword edges come from control over the GMSA, which has to be used to add the principal
+	// to msDS-GroupMSAMembership before the password can be read
+	ad.ReadGMSAPassword: EdgeCostMedium,
+
+	// Planting a shadow credential needs a PKINIT capable domain controller before it yields a ticket for the target
+	ad.AddKeyCredentialLink: EdgeCostMedium,
+
+	// Resource-based constrained delegation also needs an account with a service principal name, usually a computer
+	// account created through the machine account quota, before a ticket to the target can be requested
+	ad.AddAllowedToAct: EdgeCostMedium,
+
+	// Local administrators can execute code and dump credentials on the computer directly
+	ad.AdminTo: EdgeCostLow,
+
+	// A remote session or interactive logon still has to be escalated before it yields credentials, and DCOM execution
+	// requires a reachable and exploitable DCOM application
+	ad.CanRDP:      EdgeCostMedium,
+	ad.CanPSRemote: EdgeCostMedium,
+	ad.ExecuteDCOM: EdgeCostMedium,
+
+	// Owners hold WRITE_DAC but have to rewrite the DACL before they can exercise full control
+	ad.ImpliedGenericAll: EdgeCostMedium,
+
+	// Account Operators exercise the control they hold over unprotected accounts directly
+	ad.AccountOperatorControl: EdgeCostLow,
+
+	// Resetting a password takes one write, though it is disruptive to the account owner
+	ad.ForceChangePassword: EdgeCostLow,
+
+	// Full control through GenericAll is exercised directly. PostCanTakeOver raises the cost to EdgeCostMedium for pairs
+	// where only Owns or WriteDACL and WriteOwner apply, as the DACL has to be rewritten first.
+	ad.CanTakeOver: EdgeCostLow,
+
+	// Stealing the token of a logged on user is routine for a local administrator
+	ad.CanImpersonate: EdgeCostLow,
+
+	// Capturing a session user's credentials over RDP needs the user to log on and usually a local privilege escalation
+	ad.RDPSessionCapture: EdgeCostHigh,
+
+	// The shared admin credential has to be recovered on the source computer before it can be replayed
+	ad.SharedAdminLateral: EdgeCostMedium,
+
+	// The host retrieves the managed password as part of normal operation, so only the local admin rights of the GMSA
+	// remain to be exercised once the host is compromised
+	ad.AdminToViaGMSA: EdgeCostLow,
+
+	// Abusing a linked GPO requires authoring a malicious policy and waiting for targets to refresh it
+	ad.CanApplyGPO: EdgeCostHigh,
+
+	// The trust account's credentials have to be extracted and used to forge tickets across the trust
+	ad.HasTrustKeys: EdgeCostMedium,
+
+	// Trusts inside a forest do not filter SIDs, so SID history injection across them only needs control of the
+	// trusted domain. Trusts between forests usually filter SIDs and need an additional misconfiguration.
+	ad.SameForestTrust:  EdgeCostMedium,
+	ad.CrossForestTrust: EdgeCostHigh,
+
+	// Pivoting from an on-prem account to its synced Entra user requires the account's credentials to be usable in the
+	// cloud tenant
+	ad.SyncedToEntraUser: EdgeCostMedium,
+
+	// Existing members of the on-prem group already hold the role, but members added on-prem only gain it after the
+	// next directory sync cycle
+	ad.SyncedToEntraRole: EdgeCostMedium,
+
+	// Resetting the password locks the legitimate user out of the account, which is disruptive and likely to be noticed
+	ad.CanDCSyncViaReset: EdgeCostHigh,
+}
+
+// DefaultEdgeCost returns the EdgeCost stamped on relationships of the given kind when their job does not set one.
+// Kinds without a default are left unscored.
+func DefaultEdgeCost(kind graph.Kind) EdgeCost {
+	return defaultEdgeCosts[kind]
+}
@@ -28,6 +28,11 @@ type PathAggregator interface {
 	Contains(target uint32) bool
 	AddPath(path *graph.IDSegment)
 	AddShortcut(path *graph.IDSegment)
+
+	// InvalidateGroup discards the memberships held for the given group and for every node whose membership includes
+	// it, returning the IDs of the discarded nodes. The returned nodes must be traversed again before their memberships
+	// are asked for.
+	InvalidateGroup(groupID uint32) cardinality.Duplex[uint32]
 }
 
 type ThreadSafeAggregator struct {
@@ -63,6 +68,13 @@ func (s ThreadSafeAggregator) AddShortcut(path *graph.IDSegment) {
 	s.aggregator.AddShortcut(path)
 }
 
+func (s ThreadSafeAggregator) InvalidateGroup(groupID uint32) cardinality.Duplex[uint32] {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.aggregator.InvalidateGroup(groupID)
+}
+
 func NewThreadSafeAggregator(aggregator PathAggregator) PathAggregator {
 	return &ThreadSafeAggregator{
 		aggregator: aggregator,
@@ -257,6 +269,57 @@ func (s IDA) AddShortcut(path *graph.IDSegment) {
 	}
 }
 
+// includesAny reports whether the given membership contains one of the given targets. Memberships that can not answer
+// exactly, such as those of an approximate cardinality provider, are assumed to include them.
+func includesAny(membership cardinality.Provider[uint32], targets cardinality.Duplex[uint32]) bool {
+	if exactMembership, isExact := membership.(cardinality.Duplex[uint32]); !isExact {
+		return true
+	} else {
+		included := false
+
+		targets.Each(func(target uint32) (bool, error) {
+			included = exactMembership.Contains(target)
+			return !included, nil
+		})
+
+		return included
+	}
+}
+
+// InvalidateGroup discards the memberships held for the given group and for every node whose membership includes the
+// group, directly or through a dependency, and returns the IDs of the discarded nodes. Memberships of the group's
+// members are left in place as a change to the group can not alter them. Ancestors are found from the memberships
+// held by the aggregator, so a group gaining a new parent is only handled when the parent is invalidated as well.
+func (s IDA) InvalidateGroup(groupID uint32) cardinality.Duplex[uint32] {
+	invalidated := cardinality.NewBitmap32()
+	invalidated.Add(groupID)
+
+	// Dependencies are always rolled into the membership of the node holding them, so repeating the scan until no
+	// node is added reaches ancestors whose memberships have not been resolved yet
+	for numInvalidated := uint64(0); numInvalidated != invalidated.Cardinality(); {
+		numInvalidated = invalidated.Cardinality()
+
+		s.cardinalities.Each(func(target uint32, membership cardinality.Provider[uint32]) bool {
+			if !invalidated.Contains(target) && includesAny(membership, invalidated) {
+				invalidated.Add(target)
+			}
+
+			return true
+		})
+	}
+
+	for _, target := range invalidated.Slice() {
+		if s.cardinalities.Has(target) {
+			s.cardinalities.Put(target, s.newCardinalityProvider())
+		}
+
+		delete(s.dependencies, target)
+		s.resolved.Remove(target)
+	}
+
+	return invalidated
+}
+
 func (s IDA) Resolved() cardinality.Duplex[uint32] {
 	return s.resolved
 }
@@ -161,3 +161,70 @@ func TestAggregator_Cardinality(t *testing.T) {
 	require.True(t, nodeImpact.Contains(6))
 	require.True(t, nodeImpact.Contains(8))
 }
+
+func TestIDA_InvalidateGroup(t *testing.T) {
+	resetNextID()
+
+	var (
+		newIDA = func() impact.IDA {
+			return impact.NewIDA(func() cardinality.Provider[uint32] {
+				return cardinality.NewBitmap32()
+			})
+		}
+
+		// Group 0 contains group 1 and user 4, group 1 contains group 2, group 2 contains user 3 and group 5 contains
+		// group 2, which was already traversed from group 0. Group 7 contains user 8 and is not related to group 2.
+		root0Segment = graph.NewRootIDSegment(0)
+		node1Segment = idDescend(root0Segment, 1)
+		node2Segment = idDescend(node1Segment, 2)
+		root5Segment = graph.NewRootIDSegment(5)
+		root7Segment = graph.NewRootIDSegment(7)
+
+		node3Terminal    = idDescend(node2Segment, 3)
+		node4Terminal    = idDescend(root0Segment, 4)
+		node5to2Shortcut = idDescend(root5Segment, 2)
+		node8Terminal    = idDescend(root7Segment, 8)
+
+		// User 6 is then added to group 2
+		node6Terminal = idDescend(node2Segment, 6)
+
+		incremental = newIDA()
+		rebuilt     = newIDA()
+	)
+
+	incremental.AddPath(node3Terminal)
+	incremental.AddPath(node4Terminal)
+	incremental.AddShortcut(node5to2Shortcut)
+	incremental.AddPath(node8Terminal)
+
+	// Resolve part of the aggregator so that both resolved and unresolved memberships are invalidated
+	require.Equal(t, 2, int(incremental.Cardinality(5).Cardinality()))
+
+	invalidated := incremental.InvalidateGroup(2)
+	require.ElementsMatch(t, []uint32{0, 1, 2, 5}, invalidated.Slice())
+	require.False(t, incremental.Resolved().Contains(5))
+
+	// Traverse the invalidated groups again the way membership resolution would and compare the result against an
+	// aggregator built from scratch
+	incremental.AddPath(node3Terminal)
+	incremental.AddPath(node6Terminal)
+	incremental.AddPath(node4Terminal)
+	incremental.AddShortcut(node5to2Shortcut)
+
+	rebuilt.AddPath(node3Terminal)
+	rebuilt.AddPath(node6Terminal)
+	rebuilt.AddPath(node4Terminal)
+	rebuilt.AddShortcut(node5to2Shortcut)
+	rebuilt.AddPath(node8Terminal)
+
+	for _, group := range []uint32{0, 1, 2, 5, 7} {
+		var (
+			incrementalMembers = incremental.Cardinality(group).(cardinality.Duplex[uint32])
+			rebuiltMembers     = rebuilt.Cardinality(group).(cardinality.Duplex[uint32])
+		)
+
+		require.ElementsMatchf(t, rebuiltMembers.Slice(), incrementalMembers.Slice(), "memberships of group %d differ", group)
+	}
+
+	require.ElementsMatch(t, []uint32{2, 3, 6}, incremental.Cardinality(5).(cardinality.Duplex[uint32]).Slice())
+}
@@ -0,0 +1,257 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package impact
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/specterops/bloodhound/dawgs/cardinality"
+)
+
+// pathAggregatorFormatVersion is written at the start of every serialized aggregator and must be bumped whenever the
+// layout written by SerializePathAggregatorWithToken changes
+const pathAggregatorFormatVersion uint32 = 1
+
+// maxSerializedBitmapLength bounds the length prefix of a serialized bitmap. A roaring bitmap holding every 32-bit
+// value serializes to 65536 full 8KiB containers plus their headers, so a longer prefix can only come from a corrupt or
+// foreign stream and is rejected before anything is allocated for it.
+const maxSerializedBitmapLength = (1<<16)*(8<<10) + (1 << 20)
+
+var (
+	pathAggregatorMagic = [4]byte{'B', 'H', 'P', 'A'}
+
+	ErrUnsupportedPathAggregator = errors.New("path aggregator can not be serialized")
+	ErrPathAggregatorFormat      = errors.New("invalid serialized path aggregator")
+	ErrStalePathAggregator       = errors.New("serialized path aggregator validity token does not match")
+)
+
+// SerializePathAggregator writes the given aggregator to w. See SerializePathAggregatorWithToken.
+func SerializePathAggregator(w io.Writer, agg PathAggregator) error {
+	return SerializePathAggregatorWithToken(w, agg, "")
+}
+
+// SerializePathAggregatorWithToken writes the given aggregator to w along with a caller chosen validity token. The token
+// should identify the state of the graph the aggregator was computed from, for example the ID of the last completed
+// ingest, so that LoadPathAggregatorIfValid can refuse an aggregator computed from a different graph.
+//
+// Only IDA aggregators backed by exact cardinality providers (roaring bitmaps) are supported, either directly or
+// wrapped by NewThreadSafeAggregator. Both resolved and unresolved memberships are written so that the loaded
+// aggregator answers Cardinality exactly as the original would have.
+func SerializePathAggregatorWithToken(w io.Writer, agg PathAggregator, validityToken string) error {
+	switch typedAggregator := agg.(type) {
+	case *ThreadSafeAggregator:
+		typedAggregator.lock.Lock()
+		defer typedAggregator.lock.Unlock()
+
+		return SerializePathAggregatorWithToken(w, typedAggregator.aggregator, validityToken)
+
+	case IDA:
+		return typedAggregator.writeTo(w, validityToken)
+
+	case *IDA:
+		return typedAggregator.writeTo(w, validityToken)
+
+	default:
+		return fmt.Errorf("%w: unsupported aggregator type %T", ErrUnsupportedPathAggregator, agg)
+	}
+}
+
+// LoadPathAggregator reads an aggregator written by SerializePathAggregator from r, ignoring its validity token
+func LoadPathAggregator(r io.Reader) (PathAggregator, error) {
+	if aggregator, _, err := readIDA(r); err != nil {
+		return nil, err
+	} else {
+		return aggregator, nil
+	}
+}
+
+// LoadPathAggregatorIfValid reads an aggregator written by SerializePathAggregatorWithToken from r. If the serialized
+// validity token does not match the given token ErrStalePathAggregator is returned and the aggregator should be
+// recomputed.
+func LoadPathAggregatorIfValid(r io.Reader, validityToken string) (PathAggregator, error) {
+	if aggregator, serializedToken, err := readIDA(r); err != nil {
+		return nil, err
+	} else if serializedToken != validityToken {
+		return nil, ErrStalePathAggregator
+	} else {
+		return aggregator, nil
+	}
+}
+
+func writeUint32(w io.Writer, value uint32) error {
+	return binary.Write(w, binary.LittleEndian, value)
+}
+
+func readUint32(r io.Reader) (uint32, error) {
+	var value uint32
+	return value, binary.Read(r, binary.LittleEndian, &value)
+}
+
+func writeBitmap(w io.Writer, provider cardinality.Provider[uint32]) error {
+	if duplex, isDuplex := provider.(cardinality.Duplex[uint32]); !isDuplex {
+		return fmt.Errorf("%w: cardinality provider %T is not exact", ErrUnsupportedPathAggregator, provider)
+	} else if serialized, err := roaring.BitmapOf(duplex.Slice()...).ToBytes(); err != nil {
+		return err
+	} else if err := writeUint32(w, uint32(len(serialized))); err != nil {
+		return err
+	} else {
+		_, err := w.Write(serialized)
+		return err
+	}
+}
+
+func readBitmap(r io.Reader) (cardinality.Duplex[uint32], error) {
+	if length, err := readUint32(r); err != nil {
+		return nil, err
+	} else if length > maxSerializedBitmapLength {
+		return nil, fmt.Errorf("%w: bitmap length %d exceeds the limit of %d", ErrPathAggregatorFormat, length, maxSerializedBitmapLength)
+	} else {
+		var (
+			serialized bytes.Buffer
+			bitmap     = roaring.New()
+		)
+
+		// The buffer grows with the bytes actually read so that a truncated stream does not allocate its full prefix
+		if _, err := io.CopyN(&serialized, r, int64(length)); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, io.ErrUnexpectedEOF
+			}
+
+			return nil, err
+		} else if err := bitmap.UnmarshalBinary(serialized.Bytes()); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrPathAggregatorFormat, err)
+		}
+
+		duplex := cardinality.NewBitmap32()
+		duplex.Add(bitmap.ToArray()...)
+
+		return duplex, nil
+	}
+}
+
+func (s IDA) writeTo(w io.Writer, validityToken string) error {
+	if _, err := w.Write(pathAggregatorMagic[:]); err != nil {
+		return err
+	} else if err := writeUint32(w, pathAggregatorFormatVersion); err != nil {
+		return err
+	} else if err := writeUint32(w, uint32(len(validityToken))); err != nil {
+		return err
+	} else if _, err := io.WriteString(w, validityToken); err != nil {
+		return err
+	} else if err := writeBitmap(w, s.resolved); err != nil {
+		return err
+	} else if err := writeUint32(w, uint32(s.cardinalities.Len())); err != nil {
+		return err
+	}
+
+	var err error
+
+	s.cardinalities.Each(func(key uint32, value cardinality.Provider[uint32]) bool {
+		if err = writeUint32(w, key); err == nil {
+			err = writeBitmap(w, value)
+		}
+
+		return err == nil
+	})
+
+	if err != nil {
+		return err
+	} else if err := writeUint32(w, uint32(len(s.dependencies))); err != nil {
+		return err
+	}
+
+	for key, dependencies := range s.dependencies {
+		if err := writeUint32(w, key); err != nil {
+			return err
+		} else if err := writeBitmap(w, dependencies); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readIDA(r io.Reader) (IDA, string, error) {
+	var (
+		magic      [4]byte
+		aggregator = NewIDA(func() cardinality.Provider[uint32] {
+			return cardinality.NewBitmap32()
+		})
+	)
+
+	if _, err := io.ReadFull(r, magic[:]); err != nil {
+		return aggregator, "", err
+	} else if magic != pathAggregatorMagic {
+		return aggregator, "", fmt.Errorf("%w: unrecognized header", ErrPathAggregatorFormat)
+	}
+
+	if formatVersion, err := readUint32(r); err != nil {
+		return aggregator, "", err
+	} else if formatVersion != pathAggregatorFormatVersion {
+		return aggregator, "", fmt.Errorf("%w: unsupported format version %d", ErrPathAggregatorFormat, formatVersion)
+	}
+
+	tokenLength, err := readUint32(r)
+	if err != nil {
+		return aggregator, "", err
+	}
+
+	validityToken := make([]byte, tokenLength)
+
+	if _, err := io.ReadFull(r, validityToken); err != nil {
+		return aggregator, "", err
+	} else if resolved, err := readBitmap(r); err != nil {
+		return aggregator, "", err
+	} else {
+		aggregator.resolved = resolved
+	}
+
+	if numCardinalities, err := readUint32(r); err != nil {
+		return aggregator, "", err
+	} else {
+		for idx := uint32(0); idx < numCardinalities; idx++ {
+			if key, err := readUint32(r); err != nil {
+				return aggregator, "", err
+			} else if members, err := readBitmap(r); err != nil {
+				return aggregator, "", err
+			} else {
+				aggregator.cardinalities.Put(key, members)
+			}
+		}
+	}
+
+	if numDependencies, err := readUint32(r); err != nil {
+		return aggregator, "", err
+	} else {
+		for idx := uint32(0); idx < numDependencies; idx++ {
+			if key, err := readUint32(r); err != nil {
+				return aggregator, "", err
+			} else if dependencies, err := readBitmap(r); err != nil {
+				return aggregator, "", err
+			} else {
+				aggregator.dependencies[key] = dependencies
+			}
+		}
+	}
+
+	return aggregator, string(validityToken), nil
+}
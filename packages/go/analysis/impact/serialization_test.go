@@ -0,0 +1,130 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package impact_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/specterops/bloodhound/analysis/impact"
+	"github.com/specterops/bloodhound/dawgs/cardinality"
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/stretchr/testify/require"
+)
+
+func newSerializationTestAggregator() (impact.PathAggregator, []uint32) {
+	resetNextID()
+
+	var (
+		node0 = getNextID()
+		node1 = getNextID()
+		node2 = getNextID()
+		node3 = getNextID()
+
+		rootSegment  = graph.NewRootIDSegment(node0)
+		node1Segment = idDescend(rootSegment, node1)
+		node2Segment = idDescend(node1Segment, node2)
+		node3Segment = idDescend(rootSegment, node3)
+
+		agg = impact.NewIDA(func() cardinality.Provider[uint32] {
+			return cardinality.NewBitmap32()
+		})
+	)
+
+	agg.AddPath(node2Segment)
+
+	// Leave a dependency unresolved so that it has to survive serialization
+	agg.AddShortcut(idDescend(node3Segment, node1))
+
+	return impact.NewThreadSafeAggregator(agg), []uint32{node0.Uint32(), node1.Uint32(), node2.Uint32(), node3.Uint32()}
+}
+
+func TestSerializePathAggregator(t *testing.T) {
+	var (
+		buffer       = &bytes.Buffer{}
+		agg, nodeIDs = newSerializationTestAggregator()
+	)
+
+	require.Nil(t, impact.SerializePathAggregator(buffer, agg))
+
+	loaded, err := impact.LoadPathAggregator(buffer)
+	require.Nil(t, err)
+
+	for _, nodeID := range nodeIDs {
+		var (
+			expected = agg.Cardinality(nodeID).(cardinality.Duplex[uint32])
+			actual   = loaded.Cardinality(nodeID).(cardinality.Duplex[uint32])
+		)
+
+		require.Equal(t, expected.Slice(), actual.Slice())
+		require.Equal(t, agg.Contains(nodeID), loaded.Contains(nodeID))
+	}
+}
+
+func TestLoadPathAggregatorIfValid(t *testing.T) {
+	var (
+		buffer = &bytes.Buffer{}
+		agg, _ = newSerializationTestAggregator()
+	)
+
+	require.Nil(t, impact.SerializePathAggregatorWithToken(buffer, agg, "ingest-1"))
+	serialized := buffer.Bytes()
+
+	_, err := impact.LoadPathAggregatorIfValid(bytes.NewReader(serialized), "ingest-2")
+	require.ErrorIs(t, err, impact.ErrStalePathAggregator)
+
+	_, err = impact.LoadPathAggregatorIfValid(bytes.NewReader(serialized), "ingest-1")
+	require.Nil(t, err)
+
+	_, err = impact.LoadPathAggregator(bytes.NewReader([]byte("not an aggregator")))
+	require.ErrorIs(t, err, impact.ErrPathAggregatorFormat)
+}
+
+func TestLoadPathAggregator_BitmapLength(t *testing.T) {
+	header := &bytes.Buffer{}
+	header.WriteString("BHPA")
+	require.Nil(t, binary.Write(header, binary.LittleEndian, uint32(1)))
+	require.Nil(t, binary.Write(header, binary.LittleEndian, uint32(0)))
+
+	// A length prefix larger than any bitmap can serialize to is rejected as malformed
+	oversized := bytes.NewBuffer(bytes.Clone(header.Bytes()))
+	require.Nil(t, binary.Write(oversized, binary.LittleEndian, uint32(math.MaxUint32)))
+
+	_, err := impact.LoadPathAggregator(oversized)
+	require.ErrorIs(t, err, impact.ErrPathAggregatorFormat)
+
+	// A plausible length prefix with too few bytes behind it is a truncated stream
+	truncated := bytes.NewBuffer(bytes.Clone(header.Bytes()))
+	require.Nil(t, binary.Write(truncated, binary.LittleEndian, uint32(1<<20)))
+	truncated.WriteString("short")
+
+	_, err = impact.LoadPathAggregator(truncated)
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestSerializePathAggregator_InexactProvider(t *testing.T) {
+	agg := impact.NewIDA(func() cardinality.Provider[uint32] {
+		return cardinality.NewHyperLogLog32()
+	})
+
+	agg.AddPath(idDescend(graph.NewRootIDSegment(0), 1))
+
+	require.ErrorIs(t, impact.SerializePathAggregator(&bytes.Buffer{}, agg), impact.ErrUnsupportedPathAggregator)
+}
@@ -19,7 +19,9 @@ package analysis
 import (
 	"context"
 	"sort"
+	"time"
 
+	"github.com/RoaringBitmap/roaring/roaring64"
 	"github.com/specterops/bloodhound/dawgs/graph"
 	"github.com/specterops/bloodhound/dawgs/ops"
 	"github.com/specterops/bloodhound/dawgs/query"
@@ -109,10 +111,44 @@ func (s PostProcessingStats) LogStats() {
 	}
 }
 
+// ValidUntilProperty is the relationship property holding the time after which a computed relationship is considered
+// stale and is removed by ExpireStaleComputedEdges
+const ValidUntilProperty = "validuntil"
+
+// SourceCollectedAtProperty is the relationship property holding the collection time of the data a computed
+// relationship was derived from
+const SourceCollectedAtProperty = "sourcecollectedat"
+
 type CreatePostRelationshipJob struct {
-	FromID graph.ID
-	ToID   graph.ID
-	Kind   graph.Kind
+	FromID        graph.ID
+	ToID          graph.ID
+	Kind          graph.Kind
+	RelProperties map[string]any
+
+	// TTL, when positive, stamps the created relationship with a ValidUntilProperty of the write time plus TTL. This
+	// suits relationships derived from transient conditions such as an active session.
+	TTL time.Duration
+
+	// Cost overrides the EdgeCost stamped on the created relationship. When unset, DefaultEdgeCost for the job's kind
+	// is used.
+	Cost EdgeCost
+
+	// SourceCollectedAt, when set, stamps the created relationship with a SourceCollectedAtProperty recording when the
+	// data it was derived from, such as a session, was collected. The zero value means the collection time is unknown.
+	SourceCollectedAt time.Time
+
+	// SourceCollector, when set, stamps the created relationship with a SourceCollectorProperty naming the collector
+	// whose data produced it, typically as returned by CollectorAttribution.Source
+	SourceCollector string
+}
+
+// EdgeCost returns the cost the relationship created by this job is stamped with
+func (s CreatePostRelationshipJob) EdgeCost() EdgeCost {
+	if s.Cost != EdgeCostUnscored {
+		return s.Cost
+	}
+
+	return DefaultEdgeCost(s.Kind)
 }
 
 type DeleteRelationshipJob struct {
@@ -121,6 +157,12 @@ type DeleteRelationshipJob struct {
 }
 
 func DeleteTransitEdges(ctx context.Context, db graph.Database, fromKind, toKind graph.Kind, targetRelationships ...graph.Kind) (*AtomicPostProcessingStats, error) {
+	return DeleteTransitEdgesMatching(ctx, db, fromKind, toKind, nil, targetRelationships...)
+}
+
+// DeleteTransitEdgesMatching behaves like DeleteTransitEdges but only deletes relationships that also satisfy the given
+// criteria. A nil criteria deletes every matching relationship.
+func DeleteTransitEdgesMatching(ctx context.Context, db graph.Database, fromKind, toKind graph.Kind, additionalCriteria graph.Criteria, targetRelationships ...graph.Kind) (*AtomicPostProcessingStats, error) {
 	defer log.Measure(log.LevelInfo, "Finished deleting transit edges")()
 
 	var (
@@ -133,11 +175,17 @@ func DeleteTransitEdges(ctx context.Context, db graph.Database, fromKind, toKind
 
 		if err := db.ReadTransaction(ctx, func(tx graph.Transaction) error {
 			fetchedRelationshipIDs, err := ops.FetchRelationshipIDs(tx.Relationships().Filterf(func() graph.Criteria {
-				return query.And(
+				criteria := []graph.Criteria{
 					query.Kind(query.Start(), fromKind),
 					query.Kind(query.Relationship(), closureKindCopy),
 					query.Kind(query.End(), toKind),
-				)
+				}
+
+				if additionalCriteria != nil {
+					criteria = append(criteria, additionalCriteria)
+				}
+
+				return query.And(criteria...)
 			}))
 
 			stats.AddRelationshipsDeleted(closureKindCopy, int32(len(fetchedRelationshipIDs)))
@@ -160,13 +208,100 @@ func DeleteTransitEdges(ctx context.Context, db graph.Database, fromKind, toKind
 	})
 }
 
+// FindEdgesFromOlderVersions returns the IDs of relationships of the given kind that were produced by an analysis
+// version older than the one given, including relationships that predate version stamping altogether.
+func FindEdgesFromOlderVersions(tx graph.Transaction, kind graph.Kind, version int) ([]graph.ID, error) {
+	return ops.FetchRelationshipIDs(tx.Relationships().Filterf(func() graph.Criteria {
+		return query.And(
+			query.Kind(query.Relationship(), kind),
+			query.Or(
+				query.Not(query.Exists(query.RelationshipProperty(AnalysisVersionProperty))),
+				query.LessThan(query.RelationshipProperty(AnalysisVersionProperty), version),
+			),
+		)
+	}))
+}
+
+// ExpireStaleComputedEdges deletes every relationship of the given kinds whose ValidUntilProperty lies in the past. Only
+// post-processed relationships carry the property, so callers pass the kinds their post-processing emits to keep the
+// scan off ingested relationships. The datapipe only runs it when the expire_computed_edges configuration option is set.
+func ExpireStaleComputedEdges(ctx context.Context, db graph.Database, kinds ...graph.Kind) (*AtomicPostProcessingStats, error) {
+	defer log.Measure(log.LevelInfo, "Finished expiring stale computed edges")()
+
+	var (
+		relationshipIDs []graph.ID
+		stats           = NewAtomicPostProcessingStats()
+		now             = time.Now().UTC()
+	)
+
+	if err := db.ReadTransaction(ctx, func(tx graph.Transaction) error {
+		return tx.Relationships().Filterf(func() graph.Criteria {
+			return query.And(
+				query.KindIn(query.Relationship(), kinds...),
+				query.LessThan(query.RelationshipProperty(ValidUntilProperty), now),
+			)
+		}).Fetch(func(cursor graph.Cursor[*graph.Relationship]) error {
+			for relationship := range cursor.Chan() {
+				stats.AddRelationshipsDeleted(relationship.Kind, 1)
+				relationshipIDs = append(relationshipIDs, relationship.ID)
+			}
+
+			return cursor.Error()
+		})
+	}); err != nil {
+		return &stats, err
+	}
+
+	return &stats, db.BatchOperation(ctx, func(batch graph.Batch) error {
+		for _, relationshipID := range relationshipIDs {
+			if err := batch.DeleteRelationship(relationshipID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// EncodeEdgePair packs the start and end node IDs of a relationship into a single uint64 so that sets of relationships
+// can be held in a roaring64 bitmap. Node IDs are truncated to 32 bits as elsewhere in post-processing.
+func EncodeEdgePair(fromID, toID graph.ID) uint64 {
+	return uint64(fromID.Uint32())<<32 | uint64(toID.Uint32())
+}
+
+// DecodeEdgePair reverses EncodeEdgePair
+func DecodeEdgePair(pair uint64) (graph.ID, graph.ID) {
+	return graph.ID(pair >> 32), graph.ID(uint32(pair))
+}
+
+// ComputeStaleEdges returns the edge pairs present in existing but absent from current. Both bitmaps hold pairs encoded
+// with EncodeEdgePair. Neither input is modified.
+func ComputeStaleEdges(current, existing *roaring64.Bitmap) *roaring64.Bitmap {
+	return roaring64.AndNot(existing, current)
+}
+
+// FetchEdgePairBitmap returns the encoded start and end node pairs of every relationship of the given kind
+func FetchEdgePairBitmap(tx graph.Transaction, kind graph.Kind) (*roaring64.Bitmap, error) {
+	pairs := roaring64.New()
+
+	return pairs, tx.Relationships().Filterf(func() graph.Criteria {
+		return query.Kind(query.Relationship(), kind)
+	}).FetchTriples(func(cursor graph.Cursor[graph.RelationshipTripleResult]) error {
+		for result := range cursor.Chan() {
+			pairs.Add(EncodeEdgePair(result.StartID, result.EndID))
+		}
+
+		return cursor.Error()
+	})
+}
+
 func NodesWithoutRelationshipsFilter() graph.Criteria {
 	return query.And(
 		// Nodes without relationships
 		query.Not(query.HasRelationships(query.Node())),
 
-		// And that are not migration related
-		query.Not(query.Kind(query.Node(), common.MigrationData)),
+		// And that are not migration or post-processing bookkeeping
+		query.Not(query.KindIn(query.Node(), common.MigrationData, common.PostProcessingRunSummary, common.PostProcessingConfig)),
 	)
 }
 
@@ -18,54 +18,596 @@ package analysis
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/RoaringBitmap/roaring/roaring64"
 	"github.com/specterops/bloodhound/dawgs/graph"
 	"github.com/specterops/bloodhound/dawgs/ops"
+	"github.com/specterops/bloodhound/dawgs/query"
+	"github.com/specterops/bloodhound/dawgs/util/channels"
 	"github.com/specterops/bloodhound/graphschema/common"
 	"github.com/specterops/bloodhound/log"
 )
 
+// ErrReaderPanic is wrapped by the reader errors recorded for readers that panicked
+var ErrReaderPanic = errors.New("post-processing reader panicked")
+
 type StatTrackedOperation[T any] struct {
 	Stats     AtomicPostProcessingStats
 	Operation *ops.Operation[T]
+
+	name        string
+	readDB      graph.Database
+	readerRetry *ReaderRetryPolicy
+}
+
+// ReaderRetryPolicy controls how a post-processing operation retries readers that fail with a retryable error, such as
+// one caused by transient database contention
+type ReaderRetryPolicy struct {
+	// MaxAttempts is the number of times a reader is run, including the first attempt. Values below two disable retries.
+	MaxAttempts int
+
+	// InitialBackoff is the wait before the second attempt. Each later wait is twice the previous one, capped at
+	// MaxBackoff when MaxBackoff is positive.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// Retryable returns true if the given reader error is worth retrying. When nil every error is retried except for the
+	// cancellation or expiry of the operation's context.
+	Retryable func(err error) bool
+}
+
+func (s ReaderRetryPolicy) retryable(err error) bool {
+	if s.Retryable != nil {
+		return s.Retryable(err)
+	}
+
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// DefaultSingleTransactionLimit is the number of buffered jobs a SingleTransaction operation will hold before falling
+// back to batched writes.
+const DefaultSingleTransactionLimit = 250_000
+
+// EdgeSink receives every relationship created by a post-processing operation once it has been handed to the graph.
+// Implementations may forward the relationship to an external system such as a SIEM.
+type EdgeSink interface {
+	Emit(ctx context.Context, job CreatePostRelationshipJob) error
+}
+
+// MetricsRecorder receives post-processing throughput and latency measurements so that they can be exported to a
+// monitoring system. Implementations must be safe for concurrent use.
+type MetricsRecorder interface {
+	IncEdgesCreated(kind graph.Kind, n int)
+	IncEdgesDeleted(kind graph.Kind, n int)
+	ObserveDuration(stage string, d time.Duration)
+}
+
+// MeasureStage starts timing the given stage and returns a function that reports the elapsed time to recorder. It is
+// meant to be deferred in the same way as log.Measure. A nil recorder is ignored.
+func MeasureStage(recorder MetricsRecorder, stage string) func() {
+	then := time.Now()
+
+	return func() {
+		if recorder != nil {
+			recorder.ObserveDuration(stage, time.Since(then))
+		}
+	}
+}
+
+// PostRelationshipOperationOptions controls how a post-processing operation writes the relationships it creates.
+type PostRelationshipOperationOptions struct {
+	// SingleTransaction buffers every job submitted to the operation and writes them in one write transaction once the
+	// operation's readers have drained. Either all created relationships become visible or none do. Only the
+	// operation's own writes are covered: relationships deleted before it runs, such as by DeleteTransitEdges when
+	// stale post-processed relationships are purged, are committed by their own transactions and stay deleted when
+	// the operation fails.
+	SingleTransaction bool
+
+	// SingleTransactionLimit caps the number of jobs a SingleTransaction operation will buffer. Once exceeded the
+	// operation logs a warning and falls back to batched writes, giving up atomicity. Zero selects
+	// DefaultSingleTransactionLimit.
+	SingleTransactionLimit int
+
+	// WriteBatchSize commits the operation's write batch after every WriteBatchSize jobs written. Smaller batches hold
+	// less in memory and release locks sooner while larger batches favor throughput. Zero leaves flushing to the graph
+	// driver's configured batch write size. Jobs buffered by SingleTransaction are not affected, though jobs written
+	// after it falls back to batched writes are. Batched writes are not atomic: when a write or commit fails, the
+	// relationships of every batch committed before it remain in the graph. Use SingleTransaction where partial output
+	// can not be tolerated.
+	WriteBatchSize int
+
+	// Sink, when set, is sent every job written by the operation whose kind has no entry in KindSinks
+	Sink EdgeSink
+
+	// KindSinks routes the jobs of the listed kinds to their own sink in place of Sink. Kinds without an entry fall
+	// back to Sink, or are not sent anywhere when Sink is unset. A kind mapped to a nil sink is written to the graph
+	// only, even when Sink is set.
+	KindSinks map[graph.Kind]EdgeSink
+
+	// SinkErrorsFatal fails the operation when Sink or one of KindSinks returns an error. Sink errors are otherwise logged and ignored so
+	// that an unavailable downstream system does not block post-processing.
+	SinkErrorsFatal bool
+
+	// Upsert writes every job as a merge on its start node, end node and kind. A relationship left behind by an earlier
+	// or overlapping analysis run has its properties updated instead of being duplicated. Upserted writes that find an
+	// existing relationship are not counted as created in the operation's stats.
+	Upsert bool
+
+	// ReadDB, when set, serves the operation's readers while every write, including Upsert lookups, goes to the
+	// database handle the operation was created with. This allows the read heavy part of post-processing to run
+	// against a replica. Readers may observe replication lag and must not depend on writes made by the operation.
+	ReadDB graph.Database
+
+	// JobTransform, when set, is applied to every job as the writer receives it and before anything else is done with
+	// the job. The transformed job is what gets written, counted in the operation's stats and sent to Sink, and with
+	// Upsert it is the transformed start node, end node and kind that are matched against existing relationships.
+	// Deduplication performed by a processor while submitting jobs has already happened by this point, while the
+	// operation's own deduplication of jobs sharing a start node, end node and kind happens afterwards. Returning a job
+	// without a kind, such as the zero value, drops it. A transform must not modify RelProperties in place as the map
+	// may be shared between jobs.
+	JobTransform func(CreatePostRelationshipJob) CreatePostRelationshipJob
+
+	// SelfLoopKinds lists the kinds for which a job starting and ending on the same node is written. A self-loop of any
+	// other kind, such as a computer that can RDP to itself, is never meaningful and points to a bug in the processor
+	// that submitted it. Those jobs are dropped after JobTransform has been applied and counted in the operation's
+	// stats.
+	SelfLoopKinds graph.Kinds
+
+	// Deterministic holds back every job until the operation's readers have drained and then writes them sorted by start
+	// node, end node and kind, so that repeated runs over the same graph produce relationships and Sink output in the
+	// same order. This trades away streaming: every job is kept in memory and nothing is written until all readers have
+	// finished, so peak memory grows with the operation's output and the write phase can no longer overlap reading.
+	// Jobs are sorted after JobTransform has been applied and self-loops have been dropped.
+	Deterministic bool
+
+	// ReaderRetry, when set, reruns readers submitted through StatTrackedOperation.SubmitReader that fail with a
+	// retryable error, waiting with exponential backoff between attempts. Jobs submitted by a reader are held back until
+	// its attempt succeeds, so a failed attempt never leaves partial output behind, at the cost of keeping each reader's
+	// output in memory. Retries run in a read transaction of their own. A reader that exhausts its attempts, or fails with
+	// an error that is not retryable, fails the operation as it would without retries.
+	ReaderRetry *ReaderRetryPolicy
+
+	// Denylist, when set, suppresses every job starting or ending on one of the listed node IDs, keeping principals such
+	// as monitoring service accounts out of computed relationships regardless of the processor submitting them. The
+	// check is made after JobTransform has been applied and suppressed jobs are counted in the operation's stats. The
+	// bitmap is only read and may be shared between concurrently running operations as long as nothing modifies it.
+	Denylist *roaring64.Bitmap
+}
+
+// sinkFor returns the sink that jobs of the given kind are sent to, or nil when they are not sent anywhere
+func (s PostRelationshipOperationOptions) sinkFor(kind graph.Kind) EdgeSink {
+	if kindSink, registered := s.KindSinks[kind]; registered {
+		return kindSink
+	}
+
+	return s.Sink
+}
+
+// transformJob applies the given transform, if any, to job. The returned bool is false when the transform dropped the
+// job.
+func transformJob(transform func(CreatePostRelationshipJob) CreatePostRelationshipJob, job CreatePostRelationshipJob) (CreatePostRelationshipJob, bool) {
+	if transform == nil {
+		return job, true
+	}
+
+	transformedJob := transform(job)
+	return transformedJob, transformedJob.Kind != nil
+}
+
+// sortPostRelationshipJobs orders jobs by start node, then end node, then kind name
+func sortPostRelationshipJobs(jobs []CreatePostRelationshipJob) {
+	sort.Slice(jobs, func(i, j int) bool {
+		if jobs[i].FromID != jobs[j].FromID {
+			return jobs[i].FromID < jobs[j].FromID
+		} else if jobs[i].ToID != jobs[j].ToID {
+			return jobs[i].ToID < jobs[j].ToID
+		}
+
+		return jobs[i].Kind.String() < jobs[j].Kind.String()
+	})
+}
+
+// isDenylisted returns true when job starts or ends on a node in denylist
+func isDenylisted(denylist *roaring64.Bitmap, job CreatePostRelationshipJob) bool {
+	return denylist != nil && (denylist.Contains(job.FromID.Uint64()) || denylist.Contains(job.ToID.Uint64()))
+}
+
+// isDroppedSelfLoop returns true when job starts and ends on the same node and its kind is not one of selfLoopKinds
+func isDroppedSelfLoop(selfLoopKinds graph.Kinds, job CreatePostRelationshipJob) bool {
+	return job.FromID == job.ToID && !selfLoopKinds.ContainsOneOf(job.Kind)
+}
+
+// updateExistingRelationship merges the given properties into the relationship matching the job's start node, end
+// node and kind. It returns false, without error, when no such relationship exists.
+func updateExistingRelationship(relationships func() graph.RelationshipQuery, job CreatePostRelationshipJob, properties *graph.Properties) (bool, error) {
+	if existing, err := relationships().Filterf(func() graph.Criteria {
+		return query.And(
+			query.Equals(query.StartID(), job.FromID),
+			query.Equals(query.EndID(), job.ToID),
+			query.Kind(query.Relationship(), job.Kind),
+		)
+	}).First(); err != nil {
+		if graph.IsErrNotFound(err) {
+			return false, nil
+		}
+
+		return false, err
+	} else {
+		mergedProperties := existing.Properties.Clone().SetAll(properties.Map)
+
+		return true, relationships().Filterf(func() graph.Criteria {
+			return query.Equals(query.RelationshipID(), existing.ID)
+		}).Update(mergedProperties)
+	}
 }
 
 func NewPostRelationshipOperation(ctx context.Context, db graph.Database, operationName string) StatTrackedOperation[CreatePostRelationshipJob] {
+	return NewPostRelationshipOperationWithOptions(ctx, db, operationName, PostRelationshipOperationOptions{})
+}
+
+// NewReplicatedPostRelationshipOperation creates a post-processing operation that reads from readDB and writes to
+// writeDB. See PostRelationshipOperationOptions.ReadDB.
+func NewReplicatedPostRelationshipOperation(ctx context.Context, readDB, writeDB graph.Database, operationName string) StatTrackedOperation[CreatePostRelationshipJob] {
+	return NewPostRelationshipOperationWithOptions(ctx, writeDB, operationName, PostRelationshipOperationOptions{
+		ReadDB: readDB,
+	})
+}
+
+// NewPostRelationshipOperationWithOptions creates a post-processing operation writing the jobs submitted by its readers
+// as relationships. Jobs sharing a start node, end node and kind are written once; later duplicates, such as the same
+// pair reached through two groups, are dropped after JobTransform has been applied and counted in DuplicatesDropped.
+func NewPostRelationshipOperationWithOptions(ctx context.Context, db graph.Database, operationName string, options PostRelationshipOperationOptions) StatTrackedOperation[CreatePostRelationshipJob] {
 	operation := StatTrackedOperation[CreatePostRelationshipJob]{}
-	operation.NewOperation(ctx, db)
+	operation.NewOperationWithReadDB(ctx, options.ReadDB, db)
 	operation.Operation.SubmitWriter(func(ctx context.Context, batch graph.Batch, inC <-chan CreatePostRelationshipJob) error {
 		defer log.Measure(log.LevelInfo, operationName)()
 
 		var (
-			relProp = NewPropertiesWithLastSeen()
+			relProp        = NewComputedRelationshipProperties()
+			numUncommitted = 0
+			seenJobs       = map[graph.Kind]*roaring64.Bitmap{}
+			jobProperties  = func(nextJob CreatePostRelationshipJob) *graph.Properties {
+				cost := nextJob.EdgeCost()
+
+				if len(nextJob.RelProperties) == 0 && nextJob.TTL <= 0 && cost == EdgeCostUnscored && nextJob.SourceCollectedAt.IsZero() && nextJob.SourceCollector == "" {
+					return relProp
+				}
+
+				properties := relProp.Clone().SetAll(nextJob.RelProperties)
+
+				if nextJob.TTL > 0 {
+					properties.Set(ValidUntilProperty, time.Now().UTC().Add(nextJob.TTL))
+				}
+
+				if !nextJob.SourceCollectedAt.IsZero() {
+					properties.Set(SourceCollectedAtProperty, nextJob.SourceCollectedAt.UTC())
+				}
+
+				if nextJob.SourceCollector != "" {
+					properties.Set(SourceCollectorProperty, nextJob.SourceCollector)
+				}
+
+				if cost != EdgeCostUnscored {
+					properties.Set(EdgeCostProperty, int(cost))
+				}
+
+				return properties
+			}
+			emitJob = func(nextJob CreatePostRelationshipJob) error {
+				if sink := options.sinkFor(nextJob.Kind); sink == nil {
+					return nil
+				} else if err := sink.Emit(ctx, nextJob); err != nil {
+					if options.SinkErrorsFatal {
+						return err
+					}
+
+					log.Errorf("%s failed to emit %s relationship from %d to %d to the edge sink: %v", operationName, nextJob.Kind, nextJob.FromID, nextJob.ToID, err)
+				}
+
+				return nil
+			}
+			acceptJob = func(nextJob CreatePostRelationshipJob) (CreatePostRelationshipJob, bool) {
+				if transformedJob, keep := transformJob(options.JobTransform, nextJob); !keep {
+					return transformedJob, false
+				} else if isDroppedSelfLoop(options.SelfLoopKinds, transformedJob) {
+					operation.Stats.AddSelfLoopsDropped(transformedJob.Kind, 1)
+					return transformedJob, false
+				} else if isDenylisted(options.Denylist, transformedJob) {
+					operation.Stats.AddDenylistSuppressed(transformedJob.Kind, 1)
+					return transformedJob, false
+				} else if seenPairs, seen := seenJobs[transformedJob.Kind]; !seen {
+					seenJobs[transformedJob.Kind] = roaring64.BitmapOf(EncodeEdgePair(transformedJob.FromID, transformedJob.ToID))
+					return transformedJob, true
+				} else if !seenPairs.CheckedAdd(EncodeEdgePair(transformedJob.FromID, transformedJob.ToID)) {
+					operation.Stats.AddDuplicatesDropped(transformedJob.Kind, 1)
+					return transformedJob, false
+				} else {
+					return transformedJob, true
+				}
+			}
+			batchWriteJob = func(nextJob CreatePostRelationshipJob) error {
+				properties := jobProperties(nextJob)
+
+				if options.Upsert {
+					if updated, err := updateExistingRelationship(batch.Relationships, nextJob, properties); err != nil {
+						return err
+					} else if updated {
+						return emitJob(nextJob)
+					}
+				}
+
+				if err := batch.CreateRelationshipByIDs(nextJob.FromID, nextJob.ToID, nextJob.Kind, properties); err != nil {
+					return err
+				}
+
+				if numUncommitted++; options.WriteBatchSize > 0 && numUncommitted >= options.WriteBatchSize {
+					if err := batch.Commit(); err != nil {
+						return err
+					}
+
+					numUncommitted = 0
+				}
+
+				operation.Stats.AddRelationshipsCreated(nextJob.Kind, 1)
+				return emitJob(nextJob)
+			}
 		)
 
-		for nextJob := range inC {
-			if err := batch.CreateRelationshipByIDs(nextJob.FromID, nextJob.ToID, nextJob.Kind, relProp); err != nil {
-				return err
+		if options.Deterministic {
+			var acceptedJobs []CreatePostRelationshipJob
+
+			for nextJob := range inC {
+				if transformedJob, keep := acceptJob(nextJob); keep {
+					acceptedJobs = append(acceptedJobs, transformedJob)
+				}
+			}
+
+			sortPostRelationshipJobs(acceptedJobs)
+
+			// Replay the sorted jobs through the regular write paths below. They have already been accepted.
+			sortedC := make(chan CreatePostRelationshipJob, len(acceptedJobs))
+
+			for _, acceptedJob := range acceptedJobs {
+				sortedC <- acceptedJob
+			}
+
+			close(sortedC)
+
+			inC = sortedC
+			acceptJob = func(nextJob CreatePostRelationshipJob) (CreatePostRelationshipJob, bool) {
+				return nextJob, true
 			}
+		}
+
+		if !options.SingleTransaction {
+			for nextJob := range inC {
+				if transformedJob, keep := acceptJob(nextJob); !keep {
+					continue
+				} else if err := batchWriteJob(transformedJob); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}
+
+		var (
+			bufferLimit = options.SingleTransactionLimit
+			buffered    []CreatePostRelationshipJob
+			fellBack    = false
+		)
 
-			operation.Stats.AddRelationshipsCreated(nextJob.Kind, 1)
+		if bufferLimit <= 0 {
+			bufferLimit = DefaultSingleTransactionLimit
+		}
+
+		for receivedJob := range inC {
+			nextJob, keep := acceptJob(receivedJob)
+
+			if !keep {
+				continue
+			}
+
+			if fellBack {
+				if err := batchWriteJob(nextJob); err != nil {
+					return err
+				}
+			} else if len(buffered) < bufferLimit {
+				buffered = append(buffered, nextJob)
+			} else {
+				log.Warnf("%s exceeded the single transaction limit of %d jobs; falling back to batched writes", operationName, bufferLimit)
+				fellBack = true
+
+				for _, bufferedJob := range append(buffered, nextJob) {
+					if err := batchWriteJob(bufferedJob); err != nil {
+						return err
+					}
+				}
+
+				buffered = nil
+			}
+		}
+
+		if fellBack || len(buffered) == 0 {
+			return nil
+		}
+
+		updatedJobs := make([]bool, len(buffered))
+
+		if err := db.WriteTransaction(ctx, func(tx graph.Transaction) error {
+			for idx, bufferedJob := range buffered {
+				properties := jobProperties(bufferedJob)
+
+				if options.Upsert {
+					if updated, err := updateExistingRelationship(tx.Relationships, bufferedJob, properties); err != nil {
+						return err
+					} else if updated {
+						updatedJobs[idx] = true
+						continue
+					}
+				}
+
+				if _, err := tx.CreateRelationshipByIDs(bufferedJob.FromID, bufferedJob.ToID, bufferedJob.Kind, properties); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		// Stats are only recorded and jobs only emitted once the transaction has committed so a rolled back operation reports
+		// nothing
+		for idx, bufferedJob := range buffered {
+			if !updatedJobs[idx] {
+				operation.Stats.AddRelationshipsCreated(bufferedJob.Kind, 1)
+			}
+
+			if err := emitJob(bufferedJob); err != nil {
+				return err
+			}
 		}
 
 		return nil
 	})
+
+	operation.name = operationName
+	operation.readerRetry = options.ReaderRetry
 	return operation
 }
 
 func (s *StatTrackedOperation[T]) NewOperation(ctx context.Context, db graph.Database) {
+	s.NewOperationWithReadDB(ctx, nil, db)
+}
+
+// NewOperationWithReadDB starts an operation whose readers use readDB and whose writers use writeDB. A nil readDB
+// sends reads to writeDB.
+func (s *StatTrackedOperation[T]) NewOperationWithReadDB(ctx context.Context, readDB, writeDB graph.Database) {
 	s.Stats = NewAtomicPostProcessingStats()
+	s.readDB = readDB
+
+	if s.readDB == nil {
+		s.readDB = writeDB
+	}
+
 	s.Operation = ops.StartNewOperation[T](ops.OperationContext{
 		Parent:     ctx,
-		DB:         db,
+		DB:         writeDB,
+		ReadDB:     readDB,
 		NumReaders: MaximumDatabaseParallelWorkers,
 		NumWriters: 1,
 	})
 }
 
+// SubmitReader submits reader to the operation. A panic raised by reader is recovered and logged along with its stack
+// trace, then recorded in Stats as a reader error wrapping ErrReaderPanic. The panicking reader's remaining work is lost
+// but the operation's other readers proceed and the operation does not fail. Readers submitted directly to Operation
+// are not protected. Failed readers are retried when the operation was created with a ReaderRetryPolicy; panics are never
+// retried.
+func (s *StatTrackedOperation[T]) SubmitReader(reader ops.ReaderFunc[T]) error {
+	return s.Operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- T) (err error) {
+		defer func() {
+			if recovery := recover(); recovery != nil {
+				log.Errorf("[panic recovery] %s reader: %v - [stack trace] %s", s.name, recovery, debug.Stack())
+
+				s.Stats.AddReaderError(fmt.Errorf("%w: %v", ErrReaderPanic, recovery))
+				err = nil
+			}
+		}()
+
+		if s.readerRetry == nil || s.readerRetry.MaxAttempts < 2 {
+			return reader(ctx, tx, outC)
+		}
+
+		return runReaderWithRetry(ctx, s.name, *s.readerRetry, s.readDB, tx, outC, reader)
+	})
+}
+
+// runReaderWithRetry runs reader according to the given policy, forwarding the jobs of the first successful attempt to
+// outC. The first attempt uses the transaction handed to the reader by the operation while later attempts open a read
+// transaction of their own, as the transaction of a failed attempt may no longer be usable.
+func runReaderWithRetry[T any](ctx context.Context, operationName string, policy ReaderRetryPolicy, readDB graph.Database, tx graph.Transaction, outC chan<- T, reader ops.ReaderFunc[T]) error {
+	backoff := policy.InitialBackoff
+
+	for attempt := 1; ; attempt++ {
+		var (
+			jobs []T
+			err  error
+		)
+
+		if attempt == 1 {
+			jobs, err = bufferReaderAttempt(ctx, tx, reader)
+		} else {
+			err = readDB.ReadTransaction(ctx, func(tx graph.Transaction) error {
+				var attemptErr error
+
+				jobs, attemptErr = bufferReaderAttempt(ctx, tx, reader)
+				return attemptErr
+			})
+		}
+
+		if err == nil {
+			for _, job := range jobs {
+				if !channels.Submit(ctx, outC, job) {
+					return nil
+				}
+			}
+
+			return nil
+		} else if !policy.retryable(err) {
+			return err
+		} else if attempt >= policy.MaxAttempts {
+			return fmt.Errorf("%s reader failed after %d attempts: %w", operationName, attempt, err)
+		}
+
+		log.Warnf("%s reader failed on attempt %d of %d, retrying in %s: %v", operationName, attempt, policy.MaxAttempts, backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+
+		if backoff *= 2; policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+}
+
+// bufferReaderAttempt runs reader once and returns the jobs it submitted along with its error
+func bufferReaderAttempt[T any](ctx context.Context, tx graph.Transaction, reader ops.ReaderFunc[T]) ([]T, error) {
+	var (
+		jobs      []T
+		jobC      = make(chan T)
+		collected = make(chan struct{})
+	)
+
+	go func() {
+		defer close(collected)
+
+		for job := range jobC {
+			jobs = append(jobs, job)
+		}
+	}()
+
+	err := func() error {
+		// Closed even when reader panics so that the collecting goroutine exits
+		defer close(jobC)
+		return reader(ctx, tx, jobC)
+	}()
+
+	<-collected
+	return jobs, err
+}
+
 func (s *StatTrackedOperation[T]) Done() error {
 	return s.Operation.Done()
 }
@@ -73,6 +615,10 @@ func (s *StatTrackedOperation[T]) Done() error {
 type AtomicPostProcessingStats struct {
 	RelationshipsCreated map[graph.Kind]*int32
 	RelationshipsDeleted map[graph.Kind]*int32
+	SelfLoopsDropped     map[graph.Kind]*int32
+	DenylistSuppressed   map[graph.Kind]*int32
+	DuplicatesDropped    map[graph.Kind]*int32
+	readerErrors         *[]error
 	mutex                *sync.Mutex
 }
 
@@ -80,10 +626,35 @@ func NewAtomicPostProcessingStats() AtomicPostProcessingStats {
 	return AtomicPostProcessingStats{
 		RelationshipsCreated: make(map[graph.Kind]*int32),
 		RelationshipsDeleted: make(map[graph.Kind]*int32),
+		SelfLoopsDropped:     make(map[graph.Kind]*int32),
+		DenylistSuppressed:   make(map[graph.Kind]*int32),
+		DuplicatesDropped:    make(map[graph.Kind]*int32),
+		readerErrors:         &[]error{},
 		mutex:                &sync.Mutex{},
 	}
 }
 
+// AddReaderError records the failure of one of an operation's readers that did not fail the operation as a whole
+func (s *AtomicPostProcessingStats) AddReaderError(err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	*s.readerErrors = append(*s.readerErrors, err)
+}
+
+// ReaderErrors returns a copy of the recorded reader errors
+func (s *AtomicPostProcessingStats) ReaderErrors() []error {
+	// Stats returned alongside an early error are not initialized
+	if s.readerErrors == nil {
+		return nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]error(nil), *s.readerErrors...)
+}
+
 func (s *AtomicPostProcessingStats) AddRelationshipsCreated(kind graph.Kind, numCreated int32) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
@@ -106,28 +677,189 @@ func (s *AtomicPostProcessingStats) AddRelationshipsDeleted(kind graph.Kind, num
 	}
 }
 
-func (s *AtomicPostProcessingStats) Merge(other *AtomicPostProcessingStats) {
+// AddSelfLoopsDropped records jobs of the given kind that were dropped for starting and ending on the same node
+func (s *AtomicPostProcessingStats) AddSelfLoopsDropped(kind graph.Kind, numDropped int32) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	for key, value := range other.RelationshipsCreated {
-		if val, ok := s.RelationshipsCreated[key]; !ok {
-			s.RelationshipsCreated[key] = value
-		} else {
-			atomic.AddInt32(val, *value)
+	if val, ok := s.SelfLoopsDropped[kind]; !ok {
+		s.SelfLoopsDropped[kind] = &numDropped
+	} else {
+		atomic.AddInt32(val, numDropped)
+	}
+}
+
+// AddDenylistSuppressed records jobs of the given kind that were suppressed for starting or ending on a denylisted node
+func (s *AtomicPostProcessingStats) AddDenylistSuppressed(kind graph.Kind, numSuppressed int32) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if val, ok := s.DenylistSuppressed[kind]; !ok {
+		s.DenylistSuppressed[kind] = &numSuppressed
+	} else {
+		atomic.AddInt32(val, numSuppressed)
+	}
+}
+
+// AddDuplicatesDropped records jobs of the given kind that were dropped for repeating the start node, end node and kind
+// of a job already written by the operation
+func (s *AtomicPostProcessingStats) AddDuplicatesDropped(kind graph.Kind, numDropped int32) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if val, ok := s.DuplicatesDropped[kind]; !ok {
+		s.DuplicatesDropped[kind] = &numDropped
+	} else {
+		atomic.AddInt32(val, numDropped)
+	}
+}
+
+// counterSnapshot returns a copy of the given counters in the same way snapshot does
+func (s *AtomicPostProcessingStats) counterSnapshot(counters map[graph.Kind]*int32) map[graph.Kind]int32 {
+	// Stats returned alongside an early error are not initialized
+	if s.mutex == nil {
+		return nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	copied := make(map[graph.Kind]int32, len(counters))
+
+	for key, value := range counters {
+		copied[key] = atomic.LoadInt32(value)
+	}
+
+	return copied
+}
+
+// selfLoopsDroppedSnapshot returns a copy of the dropped self-loop counters
+func (s *AtomicPostProcessingStats) selfLoopsDroppedSnapshot() map[graph.Kind]int32 {
+	return s.counterSnapshot(s.SelfLoopsDropped)
+}
+
+// DenylistSuppressedSnapshot returns a copy of the denylist suppression counters
+func (s *AtomicPostProcessingStats) DenylistSuppressedSnapshot() map[graph.Kind]int32 {
+	return s.counterSnapshot(s.DenylistSuppressed)
+}
+
+// DuplicatesDroppedSnapshot returns a copy of the dropped duplicate counters
+func (s *AtomicPostProcessingStats) DuplicatesDroppedSnapshot() map[graph.Kind]int32 {
+	return s.counterSnapshot(s.DuplicatesDropped)
+}
+
+// snapshot returns a copy of the created and deleted counters. Counters are copied by value so that merging one set of
+// stats into another never aliases the underlying counters.
+func (s *AtomicPostProcessingStats) snapshot() (map[graph.Kind]int32, map[graph.Kind]int32) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var (
+		created = make(map[graph.Kind]int32, len(s.RelationshipsCreated))
+		deleted = make(map[graph.Kind]int32, len(s.RelationshipsDeleted))
+	)
+
+	for key, value := range s.RelationshipsCreated {
+		created[key] = atomic.LoadInt32(value)
+	}
+
+	for key, value := range s.RelationshipsDeleted {
+		deleted[key] = atomic.LoadInt32(value)
+	}
+
+	return created, deleted
+}
+
+// CreatedKinds returns the kinds of which at least one relationship was created
+func (s *AtomicPostProcessingStats) CreatedKinds() graph.Kinds {
+	var (
+		created, _   = s.snapshot()
+		createdKinds = make(graph.Kinds, 0, len(created))
+	)
+
+	for kind, numCreated := range created {
+		if numCreated > 0 {
+			createdKinds = append(createdKinds, kind)
 		}
 	}
 
-	for key, value := range other.RelationshipsDeleted {
-		if val, ok := s.RelationshipsDeleted[key]; !ok {
-			s.RelationshipsDeleted[key] = value
-		} else {
-			atomic.AddInt32(val, *value)
+	return createdKinds
+}
+
+func (s *AtomicPostProcessingStats) Merge(other *AtomicPostProcessingStats) {
+	created, deleted := other.snapshot()
+
+	for key, value := range created {
+		s.AddRelationshipsCreated(key, value)
+	}
+
+	for key, value := range deleted {
+		s.AddRelationshipsDeleted(key, value)
+	}
+
+	for key, value := range other.selfLoopsDroppedSnapshot() {
+		s.AddSelfLoopsDropped(key, value)
+	}
+
+	for key, value := range other.DenylistSuppressedSnapshot() {
+		s.AddDenylistSuppressed(key, value)
+	}
+
+	for key, value := range other.DuplicatesDroppedSnapshot() {
+		s.AddDuplicatesDropped(key, value)
+	}
+
+	for _, readerErr := range other.ReaderErrors() {
+		s.AddReaderError(readerErr)
+	}
+}
+
+// Record reports the created and deleted counters to recorder. Zero counters are skipped and a nil recorder is ignored.
+func (s *AtomicPostProcessingStats) Record(recorder MetricsRecorder) {
+	if recorder == nil {
+		return
+	}
+
+	created, deleted := s.snapshot()
+
+	for kind, numCreated := range created {
+		if numCreated > 0 {
+			recorder.IncEdgesCreated(kind, int(numCreated))
+		}
+	}
+
+	for kind, numDeleted := range deleted {
+		if numDeleted > 0 {
+			recorder.IncEdgesDeleted(kind, int(numDeleted))
 		}
 	}
 }
 
 func (s *AtomicPostProcessingStats) LogStats() {
+	// Failed readers leave post-processing incomplete and are reported regardless of the log level
+	if readerErrors := s.ReaderErrors(); len(readerErrors) > 0 {
+		log.Warnf("%d post-processing readers failed; computed relationships may be incomplete: %v", len(readerErrors), errors.Join(readerErrors...))
+	}
+
+	// Self-loops are only dropped to guard against processor bugs, so they are reported regardless of the log level too
+	for kind, numDropped := range s.selfLoopsDroppedSnapshot() {
+		if numDropped > 0 {
+			log.Warnf("Dropped %d %s relationships that started and ended on the same node", numDropped, kind)
+		}
+	}
+
+	for kind, numSuppressed := range s.DenylistSuppressedSnapshot() {
+		if numSuppressed > 0 {
+			log.Infof("Suppressed %d %s relationships involving denylisted nodes", numSuppressed, kind)
+		}
+	}
+
+	for kind, numDropped := range s.DuplicatesDroppedSnapshot() {
+		if numDropped > 0 {
+			log.Debugf("Dropped %d duplicate %s relationships", numDropped, kind)
+		}
+	}
+
 	// Only output stats during debug runs
 	if log.GlobalLevel() > log.LevelDebug {
 		return
@@ -156,3 +888,8 @@ func NewPropertiesWithLastSeen() *graph.Properties {
 
 	return newProperties
 }
+
+// NewComputedRelationshipProperties returns the base properties for a relationship created by post-processing
+func NewComputedRelationshipProperties() *graph.Properties {
+	return NewPropertiesWithLastSeen().Set(AnalysisVersionProperty, AnalysisVersion)
+}
@@ -0,0 +1,943 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package analysis_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RoaringBitmap/roaring/roaring64"
+	"github.com/specterops/bloodhound/analysis"
+	"github.com/specterops/bloodhound/dawgs/graph"
+	graph_mocks "github.com/specterops/bloodhound/dawgs/graph/mocks"
+	"github.com/specterops/bloodhound/dawgs/util/channels"
+	"github.com/specterops/bloodhound/graphschema/ad"
+	"github.com/specterops/bloodhound/graphschema/common"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func newMockPostDatabase(ctrl *gomock.Controller, batch graph.Batch, tx graph.Transaction) *graph_mocks.MockDatabase {
+	mockDB := graph_mocks.NewMockDatabase(ctrl)
+
+	mockDB.EXPECT().ReadTransaction(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, logic func(tx graph.Transaction) error, options ...graph.TransactionOption) error {
+		return logic(tx)
+	}).AnyTimes()
+
+	mockDB.EXPECT().BatchOperation(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, logic func(batch graph.Batch) error) error {
+		return logic(batch)
+	}).AnyTimes()
+
+	return mockDB
+}
+
+func submitAdminToJobs(t *testing.T, operation analysis.StatTrackedOperation[analysis.CreatePostRelationshipJob], numJobs int) {
+	require.Nil(t, operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		for idx := 0; idx < numJobs; idx++ {
+			if !channels.Submit(ctx, outC, analysis.CreatePostRelationshipJob{
+				FromID: graph.ID(idx),
+				ToID:   graph.ID(idx + 1),
+				Kind:   ad.AdminTo,
+			}) {
+				return nil
+			}
+		}
+
+		return nil
+	}))
+}
+
+type recordingEdgeSink struct {
+	jobs []analysis.CreatePostRelationshipJob
+	err  error
+}
+
+func (s *recordingEdgeSink) Emit(ctx context.Context, job analysis.CreatePostRelationshipJob) error {
+	s.jobs = append(s.jobs, job)
+	return s.err
+}
+
+func TestNewPostRelationshipOperationWithOptions_SingleTransaction(t *testing.T) {
+	var (
+		ctrl      = gomock.NewController(t)
+		mockBatch = graph_mocks.NewMockBatch(ctrl)
+		mockTx    = graph_mocks.NewMockTransaction(ctrl)
+		mockDB    = newMockPostDatabase(ctrl, mockBatch, mockTx)
+	)
+
+	mockDB.EXPECT().WriteTransaction(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, logic func(tx graph.Transaction) error, options ...graph.TransactionOption) error {
+		return logic(mockTx)
+	}).Times(1)
+
+	mockTx.EXPECT().CreateRelationshipByIDs(gomock.Any(), gomock.Any(), ad.AdminTo, gomock.Any()).Return(&graph.Relationship{}, nil).Times(3)
+
+	operation := analysis.NewPostRelationshipOperationWithOptions(context.Background(), mockDB, "test", analysis.PostRelationshipOperationOptions{
+		SingleTransaction: true,
+	})
+
+	submitAdminToJobs(t, operation, 3)
+
+	require.Nil(t, operation.Done())
+	require.Equal(t, int32(3), *operation.Stats.RelationshipsCreated[ad.AdminTo])
+}
+
+func TestNewPostRelationshipOperationWithOptions_SingleTransactionFallback(t *testing.T) {
+	var (
+		ctrl      = gomock.NewController(t)
+		mockBatch = graph_mocks.NewMockBatch(ctrl)
+		mockTx    = graph_mocks.NewMockTransaction(ctrl)
+		mockDB    = newMockPostDatabase(ctrl, mockBatch, mockTx)
+	)
+
+	mockBatch.EXPECT().CreateRelationshipByIDs(gomock.Any(), gomock.Any(), ad.AdminTo, gomock.Any()).Return(nil).Times(3)
+
+	operation := analysis.NewPostRelationshipOperationWithOptions(context.Background(), mockDB, "test", analysis.PostRelationshipOperationOptions{
+		SingleTransaction:      true,
+		SingleTransactionLimit: 2,
+	})
+
+	submitAdminToJobs(t, operation, 3)
+
+	require.Nil(t, operation.Done())
+	require.Equal(t, int32(3), *operation.Stats.RelationshipsCreated[ad.AdminTo])
+}
+
+func TestNewComputedRelationshipProperties(t *testing.T) {
+	properties := analysis.NewComputedRelationshipProperties()
+
+	version, err := properties.Get(analysis.AnalysisVersionProperty).Int()
+	require.Nil(t, err)
+	require.Equal(t, analysis.AnalysisVersion, version)
+	require.True(t, properties.Exists(common.LastSeen.String()))
+}
+
+// The following tests are intended to be run with -race to catch unsynchronized access to the stat counters
+func TestAtomicPostProcessingStats_ConcurrentAdds(t *testing.T) {
+	var (
+		stats         = analysis.NewAtomicPostProcessingStats()
+		numGoroutines = 64
+		numAdds       = 1000
+		workerWG      = &sync.WaitGroup{}
+	)
+
+	for workerID := 0; workerID < numGoroutines; workerID++ {
+		workerWG.Add(1)
+
+		go func() {
+			defer workerWG.Done()
+
+			for idx := 0; idx < numAdds; idx++ {
+				stats.AddRelationshipsCreated(ad.DCSync, 1)
+				stats.AddRelationshipsDeleted(ad.DCSync, 1)
+			}
+		}()
+	}
+
+	workerWG.Wait()
+
+	require.Equal(t, int32(numGoroutines*numAdds), *stats.RelationshipsCreated[ad.DCSync])
+	require.Equal(t, int32(numGoroutines*numAdds), *stats.RelationshipsDeleted[ad.DCSync])
+}
+
+func TestAtomicPostProcessingStats_ConcurrentReaders(t *testing.T) {
+	var (
+		ctrl          = gomock.NewController(t)
+		mockBatch     = graph_mocks.NewMockBatch(ctrl)
+		mockTx        = graph_mocks.NewMockTransaction(ctrl)
+		mockDB        = newMockPostDatabase(ctrl, mockBatch, mockTx)
+		numReaders    = 32
+		jobsPerReader = 500
+	)
+
+	mockBatch.EXPECT().CreateRelationshipByIDs(gomock.Any(), gomock.Any(), ad.DCSync, gomock.Any()).Return(nil).Times(numReaders * jobsPerReader)
+
+	operation := analysis.NewPostRelationshipOperation(context.Background(), mockDB, "test")
+
+	for readerID := 0; readerID < numReaders; readerID++ {
+		// Each reader submits its own pairs as repeated pairs are only written once
+		firstID := readerID * jobsPerReader
+
+		require.Nil(t, operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+			for idx := firstID; idx < firstID+jobsPerReader; idx++ {
+				outC <- analysis.CreatePostRelationshipJob{
+					FromID: graph.ID(idx),
+					ToID:   graph.ID(idx + 1),
+					Kind:   ad.DCSync,
+				}
+			}
+
+			return nil
+		}))
+	}
+
+	require.Nil(t, operation.Done())
+	require.Equal(t, int32(numReaders*jobsPerReader), *operation.Stats.RelationshipsCreated[ad.DCSync])
+}
+
+func TestAtomicPostProcessingStats_MergeDoesNotAlias(t *testing.T) {
+	var (
+		source     = analysis.NewAtomicPostProcessingStats()
+		aggregateA = analysis.NewAtomicPostProcessingStats()
+		aggregateB = analysis.NewAtomicPostProcessingStats()
+	)
+
+	source.AddRelationshipsCreated(ad.DCSync, 2)
+
+	aggregateA.Merge(&source)
+	aggregateB.Merge(&source)
+	aggregateA.AddRelationshipsCreated(ad.DCSync, 3)
+
+	require.Equal(t, int32(2), *source.RelationshipsCreated[ad.DCSync])
+	require.Equal(t, int32(5), *aggregateA.RelationshipsCreated[ad.DCSync])
+	require.Equal(t, int32(2), *aggregateB.RelationshipsCreated[ad.DCSync])
+}
+
+func TestNewPostRelationshipOperation_EdgeCost(t *testing.T) {
+	var (
+		ctrl      = gomock.NewController(t)
+		mockBatch = graph_mocks.NewMockBatch(ctrl)
+		mockTx    = graph_mocks.NewMockTransaction(ctrl)
+		mockDB    = newMockPostDatabase(ctrl, mockBatch, mockTx)
+		written   = map[graph.ID]*graph.Properties{}
+	)
+
+	mockBatch.EXPECT().CreateRelationshipByIDs(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(startNodeID, endNodeID graph.ID, kind graph.Kind, properties *graph.Properties) error {
+		written[startNodeID] = properties
+		return nil
+	}).Times(3)
+
+	operation := analysis.NewPostRelationshipOperation(context.Background(), mockDB, "test")
+
+	require.Nil(t, operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		outC <- analysis.CreatePostRelationshipJob{FromID: 1, ToID: 4, Kind: ad.CanApplyGPO}
+		outC <- analysis.CreatePostRelationshipJob{FromID: 2, ToID: 4, Kind: ad.CanTakeOver, Cost: analysis.EdgeCostMedium}
+		outC <- analysis.CreatePostRelationshipJob{FromID: 3, ToID: 4, Kind: ad.HasSession}
+		return nil
+	}))
+
+	require.Nil(t, operation.Done())
+
+	defaultCost, err := written[1].Get(analysis.EdgeCostProperty).Int()
+	require.Nil(t, err)
+	require.Equal(t, int(analysis.DefaultEdgeCost(ad.CanApplyGPO)), defaultCost)
+
+	overriddenCost, err := written[2].Get(analysis.EdgeCostProperty).Int()
+	require.Nil(t, err)
+	require.Equal(t, int(analysis.EdgeCostMedium), overriddenCost)
+
+	require.False(t, written[3].Exists(analysis.EdgeCostProperty))
+}
+
+type recordingMetricsRecorder struct {
+	created   map[graph.Kind]int
+	deleted   map[graph.Kind]int
+	durations map[string]time.Duration
+}
+
+func newRecordingMetricsRecorder() *recordingMetricsRecorder {
+	return &recordingMetricsRecorder{
+		created:   make(map[graph.Kind]int),
+		deleted:   make(map[graph.Kind]int),
+		durations: make(map[string]time.Duration),
+	}
+}
+
+func (s *recordingMetricsRecorder) IncEdgesCreated(kind graph.Kind, n int) {
+	s.created[kind] += n
+}
+
+func (s *recordingMetricsRecorder) IncEdgesDeleted(kind graph.Kind, n int) {
+	s.deleted[kind] += n
+}
+
+func (s *recordingMetricsRecorder) ObserveDuration(stage string, d time.Duration) {
+	s.durations[stage] += d
+}
+
+func TestAtomicPostProcessingStats_Record(t *testing.T) {
+	var (
+		recorder = newRecordingMetricsRecorder()
+		stats    = analysis.NewAtomicPostProcessingStats()
+	)
+
+	stats.AddRelationshipsCreated(ad.DCSync, 2)
+	stats.AddRelationshipsCreated(ad.AdminTo, 0)
+	stats.AddRelationshipsDeleted(ad.DCSync, 3)
+
+	stats.Record(recorder)
+	stats.Record(nil)
+
+	require.Equal(t, map[graph.Kind]int{ad.DCSync: 2}, recorder.created)
+	require.Equal(t, map[graph.Kind]int{ad.DCSync: 3}, recorder.deleted)
+}
+
+func TestMeasureStage(t *testing.T) {
+	recorder := newRecordingMetricsRecorder()
+
+	measure := analysis.MeasureStage(recorder, "DCSync Post Processing")
+	time.Sleep(time.Millisecond)
+	measure()
+
+	require.GreaterOrEqual(t, recorder.durations["DCSync Post Processing"], time.Millisecond)
+
+	// A nil recorder must be safe to measure against
+	analysis.MeasureStage(nil, "DCSync Post Processing")()
+}
+
+func TestNewPostRelationshipOperationWithOptions_Sink(t *testing.T) {
+	var (
+		ctrl      = gomock.NewController(t)
+		mockBatch = graph_mocks.NewMockBatch(ctrl)
+		mockTx    = graph_mocks.NewMockTransaction(ctrl)
+		mockDB    = newMockPostDatabase(ctrl, mockBatch, mockTx)
+		sink      = &recordingEdgeSink{
+			err: errors.New("sink unavailable"),
+		}
+	)
+
+	mockBatch.EXPECT().CreateRelationshipByIDs(gomock.Any(), gomock.Any(), ad.AdminTo, gomock.Any()).Return(nil).Times(3)
+
+	operation := analysis.NewPostRelationshipOperationWithOptions(context.Background(), mockDB, "test", analysis.PostRelationshipOperationOptions{
+		Sink: sink,
+	})
+
+	submitAdminToJobs(t, operation, 3)
+
+	// Sink errors are not fatal by default
+	require.Nil(t, operation.Done())
+	require.Equal(t, 3, len(sink.jobs))
+	require.Equal(t, int32(3), *operation.Stats.RelationshipsCreated[ad.AdminTo])
+}
+
+func TestNewPostRelationshipOperationWithOptions_SinkErrorsFatal(t *testing.T) {
+	var (
+		ctrl      = gomock.NewController(t)
+		mockBatch = graph_mocks.NewMockBatch(ctrl)
+		mockTx    = graph_mocks.NewMockTransaction(ctrl)
+		mockDB    = newMockPostDatabase(ctrl, mockBatch, mockTx)
+		sinkErr   = errors.New("sink unavailable")
+		sink      = &recordingEdgeSink{
+			err: sinkErr,
+		}
+	)
+
+	mockBatch.EXPECT().CreateRelationshipByIDs(gomock.Any(), gomock.Any(), ad.AdminTo, gomock.Any()).Return(nil).MinTimes(1)
+
+	operation := analysis.NewPostRelationshipOperationWithOptions(context.Background(), mockDB, "test", analysis.PostRelationshipOperationOptions{
+		Sink:            sink,
+		SinkErrorsFatal: true,
+	})
+
+	submitAdminToJobs(t, operation, 3)
+
+	require.ErrorIs(t, operation.Done(), sinkErr)
+	require.Equal(t, 1, len(sink.jobs))
+}
+
+func TestNewPostRelationshipOperationWithOptions_KindSinks(t *testing.T) {
+	var (
+		ctrl          = gomock.NewController(t)
+		mockBatch     = graph_mocks.NewMockBatch(ctrl)
+		mockTx        = graph_mocks.NewMockTransaction(ctrl)
+		mockDB        = newMockPostDatabase(ctrl, mockBatch, mockTx)
+		defaultSink   = &recordingEdgeSink{}
+		alertingSink  = &recordingEdgeSink{}
+		submittedJobs = []analysis.CreatePostRelationshipJob{
+			{FromID: 1, ToID: 2, Kind: ad.DCSync},
+			{FromID: 3, ToID: 4, Kind: ad.CanRDP},
+			{FromID: 5, ToID: 6, Kind: ad.AdminTo},
+		}
+	)
+
+	mockBatch.EXPECT().CreateRelationshipByIDs(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(len(submittedJobs))
+
+	operation := analysis.NewPostRelationshipOperationWithOptions(context.Background(), mockDB, "test", analysis.PostRelationshipOperationOptions{
+		Sink: defaultSink,
+		KindSinks: map[graph.Kind]analysis.EdgeSink{
+			ad.DCSync: alertingSink,
+			ad.CanRDP: nil,
+		},
+	})
+
+	require.Nil(t, operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		for _, job := range submittedJobs {
+			if !channels.Submit(ctx, outC, job) {
+				return nil
+			}
+		}
+
+		return nil
+	}))
+
+	require.Nil(t, operation.Done())
+
+	// CanRDP is registered without a sink so it is only written to the graph, while AdminTo falls back to Sink
+	require.Equal(t, []analysis.CreatePostRelationshipJob{submittedJobs[0]}, alertingSink.jobs)
+	require.Equal(t, []analysis.CreatePostRelationshipJob{submittedJobs[2]}, defaultSink.jobs)
+}
+
+func TestNewPostRelationshipOperationWithOptions_SelfLoops(t *testing.T) {
+	var (
+		ctrl          = gomock.NewController(t)
+		mockBatch     = graph_mocks.NewMockBatch(ctrl)
+		mockTx        = graph_mocks.NewMockTransaction(ctrl)
+		mockDB        = newMockPostDatabase(ctrl, mockBatch, mockTx)
+		submittedJobs = []analysis.CreatePostRelationshipJob{
+			{FromID: 1, ToID: 2, Kind: ad.CanRDP},
+			{FromID: 3, ToID: 3, Kind: ad.CanRDP},
+			{FromID: 4, ToID: 4, Kind: ad.CanRDP},
+			{FromID: 5, ToID: 5, Kind: ad.SameForestTrust},
+		}
+	)
+
+	// The self-loops of CanRDP, such as a computer whose local group expansion reached its own account, are dropped
+	mockBatch.EXPECT().CreateRelationshipByIDs(graph.ID(1), graph.ID(2), ad.CanRDP, gomock.Any()).Return(nil)
+	mockBatch.EXPECT().CreateRelationshipByIDs(graph.ID(5), graph.ID(5), ad.SameForestTrust, gomock.Any()).Return(nil)
+
+	operation := analysis.NewPostRelationshipOperationWithOptions(context.Background(), mockDB, "test", analysis.PostRelationshipOperationOptions{
+		SelfLoopKinds: graph.Kinds{ad.SameForestTrust},
+	})
+
+	require.Nil(t, operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		for _, job := range submittedJobs {
+			if !channels.Submit(ctx, outC, job) {
+				return nil
+			}
+		}
+
+		return nil
+	}))
+
+	require.Nil(t, operation.Done())
+	require.Equal(t, int32(1), *operation.Stats.RelationshipsCreated[ad.CanRDP])
+	require.Equal(t, int32(2), *operation.Stats.SelfLoopsDropped[ad.CanRDP])
+	require.NotContains(t, operation.Stats.SelfLoopsDropped, ad.SameForestTrust)
+
+	mergedStats := analysis.NewAtomicPostProcessingStats()
+	mergedStats.Merge(&operation.Stats)
+	require.Equal(t, int32(2), *mergedStats.SelfLoopsDropped[ad.CanRDP])
+}
+
+func TestNewPostRelationshipOperationWithOptions_Denylist(t *testing.T) {
+	var (
+		ctrl          = gomock.NewController(t)
+		mockBatch     = graph_mocks.NewMockBatch(ctrl)
+		mockTx        = graph_mocks.NewMockTransaction(ctrl)
+		mockDB        = newMockPostDatabase(ctrl, mockBatch, mockTx)
+		submittedJobs = []analysis.CreatePostRelationshipJob{
+			{FromID: 1, ToID: 2, Kind: ad.CanRDP},
+			{FromID: 3, ToID: 2, Kind: ad.CanRDP},
+			{FromID: 1, ToID: 4, Kind: ad.AdminTo},
+		}
+	)
+
+	// Node 3 is denylisted as a source and node 4 as a target
+	mockBatch.EXPECT().CreateRelationshipByIDs(graph.ID(1), graph.ID(2), ad.CanRDP, gomock.Any()).Return(nil)
+
+	operation := analysis.NewPostRelationshipOperationWithOptions(context.Background(), mockDB, "test", analysis.PostRelationshipOperationOptions{
+		Denylist: roaring64.BitmapOf(3, 4),
+	})
+
+	require.Nil(t, operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		for _, job := range submittedJobs {
+			if !channels.Submit(ctx, outC, job) {
+				return nil
+			}
+		}
+
+		return nil
+	}))
+
+	require.Nil(t, operation.Done())
+	require.Equal(t, int32(1), *operation.Stats.RelationshipsCreated[ad.CanRDP])
+	require.NotContains(t, operation.Stats.RelationshipsCreated, ad.AdminTo)
+	require.Equal(t, map[graph.Kind]int32{ad.CanRDP: 1, ad.AdminTo: 1}, operation.Stats.DenylistSuppressedSnapshot())
+
+	mergedStats := analysis.NewAtomicPostProcessingStats()
+	mergedStats.Merge(&operation.Stats)
+	require.Equal(t, map[graph.Kind]int32{ad.CanRDP: 1, ad.AdminTo: 1}, mergedStats.DenylistSuppressedSnapshot())
+}
+
+func TestNewPostRelationshipOperationWithOptions_WriteBatchSize(t *testing.T) {
+	var (
+		ctrl      = gomock.NewController(t)
+		mockBatch = graph_mocks.NewMockBatch(ctrl)
+		mockTx    = graph_mocks.NewMockTransaction(ctrl)
+		mockDB    = newMockPostDatabase(ctrl, mockBatch, mockTx)
+	)
+
+	mockBatch.EXPECT().CreateRelationshipByIDs(gomock.Any(), gomock.Any(), ad.AdminTo, gomock.Any()).Return(nil).Times(7)
+
+	// The remainder of the last partial batch is left for the driver to commit
+	mockBatch.EXPECT().Commit().Return(nil).Times(2)
+
+	operation := analysis.NewPostRelationshipOperationWithOptions(context.Background(), mockDB, "test", analysis.PostRelationshipOperationOptions{
+		WriteBatchSize: 3,
+	})
+
+	submitAdminToJobs(t, operation, 7)
+
+	require.Nil(t, operation.Done())
+	require.Equal(t, int32(7), *operation.Stats.RelationshipsCreated[ad.AdminTo])
+}
+
+func TestNewPostRelationshipOperationWithOptions_WriteBatchSizePartialCommit(t *testing.T) {
+	var (
+		ctrl      = gomock.NewController(t)
+		mockBatch = graph_mocks.NewMockBatch(ctrl)
+		mockTx    = graph_mocks.NewMockTransaction(ctrl)
+		mockDB    = newMockPostDatabase(ctrl, mockBatch, mockTx)
+		commitErr = errors.New("commit failed")
+	)
+
+	mockBatch.EXPECT().CreateRelationshipByIDs(gomock.Any(), gomock.Any(), ad.AdminTo, gomock.Any()).Return(nil).Times(6)
+
+	// The first batch of three is committed and stays in the graph when the second commit fails. Nothing after the
+	// failed commit is written.
+	gomock.InOrder(
+		mockBatch.EXPECT().Commit().Return(nil),
+		mockBatch.EXPECT().Commit().Return(commitErr),
+	)
+
+	operation := analysis.NewPostRelationshipOperationWithOptions(context.Background(), mockDB, "test", analysis.PostRelationshipOperationOptions{
+		WriteBatchSize: 3,
+	})
+
+	submitAdminToJobs(t, operation, 9)
+
+	require.ErrorIs(t, operation.Done(), commitErr)
+}
+
+func TestNewPostRelationshipOperationWithOptions_DuplicateJobs(t *testing.T) {
+	var (
+		ctrl          = gomock.NewController(t)
+		mockBatch     = graph_mocks.NewMockBatch(ctrl)
+		mockTx        = graph_mocks.NewMockTransaction(ctrl)
+		mockDB        = newMockPostDatabase(ctrl, mockBatch, mockTx)
+		submittedJobs = []analysis.CreatePostRelationshipJob{
+			{FromID: 1, ToID: 2, Kind: ad.AdminTo},
+			{FromID: 1, ToID: 2, Kind: ad.AdminTo},
+			{FromID: 1, ToID: 2, Kind: ad.CanRDP},
+			{FromID: 2, ToID: 1, Kind: ad.AdminTo},
+			{FromID: 1, ToID: 2, Kind: ad.AdminTo},
+		}
+	)
+
+	// Only the repeated AdminTo from 1 to 2 is dropped; the same pair with another kind or reversed is written
+	mockBatch.EXPECT().CreateRelationshipByIDs(graph.ID(1), graph.ID(2), ad.AdminTo, gomock.Any()).Return(nil).Times(1)
+	mockBatch.EXPECT().CreateRelationshipByIDs(graph.ID(1), graph.ID(2), ad.CanRDP, gomock.Any()).Return(nil).Times(1)
+	mockBatch.EXPECT().CreateRelationshipByIDs(graph.ID(2), graph.ID(1), ad.AdminTo, gomock.Any()).Return(nil).Times(1)
+
+	operation := analysis.NewPostRelationshipOperationWithOptions(context.Background(), mockDB, "test", analysis.PostRelationshipOperationOptions{})
+
+	require.Nil(t, operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		for _, job := range submittedJobs {
+			if !channels.Submit(ctx, outC, job) {
+				return nil
+			}
+		}
+
+		return nil
+	}))
+
+	require.Nil(t, operation.Done())
+	require.Equal(t, int32(2), *operation.Stats.RelationshipsCreated[ad.AdminTo])
+	require.Equal(t, int32(1), *operation.Stats.RelationshipsCreated[ad.CanRDP])
+	require.Equal(t, map[graph.Kind]int32{ad.AdminTo: 2}, operation.Stats.DuplicatesDroppedSnapshot())
+
+	mergedStats := analysis.NewAtomicPostProcessingStats()
+	mergedStats.Merge(&operation.Stats)
+	require.Equal(t, map[graph.Kind]int32{ad.AdminTo: 2}, mergedStats.DuplicatesDroppedSnapshot())
+}
+
+func TestNewPostRelationshipOperationWithOptions_Deterministic(t *testing.T) {
+	var (
+		ctrl      = gomock.NewController(t)
+		mockBatch = graph_mocks.NewMockBatch(ctrl)
+		mockTx    = graph_mocks.NewMockTransaction(ctrl)
+		mockDB    = newMockPostDatabase(ctrl, mockBatch, mockTx)
+		sink      = &recordingEdgeSink{}
+		written   []analysis.CreatePostRelationshipJob
+	)
+
+	mockBatch.EXPECT().CreateRelationshipByIDs(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(startNodeID, endNodeID graph.ID, kind graph.Kind, properties *graph.Properties) error {
+		written = append(written, analysis.CreatePostRelationshipJob{FromID: startNodeID, ToID: endNodeID, Kind: kind})
+		return nil
+	}).Times(5)
+
+	operation := analysis.NewPostRelationshipOperationWithOptions(context.Background(), mockDB, "test", analysis.PostRelationshipOperationOptions{
+		Deterministic: true,
+		Sink:          sink,
+	})
+
+	// Two readers submitting concurrently interleave their jobs unpredictably
+	require.Nil(t, operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		outC <- analysis.CreatePostRelationshipJob{FromID: 3, ToID: 1, Kind: ad.CanRDP}
+		outC <- analysis.CreatePostRelationshipJob{FromID: 1, ToID: 2, Kind: ad.CanRDP}
+		return nil
+	}))
+
+	require.Nil(t, operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		outC <- analysis.CreatePostRelationshipJob{FromID: 1, ToID: 2, Kind: ad.AdminTo}
+		outC <- analysis.CreatePostRelationshipJob{FromID: 2, ToID: 1, Kind: ad.AdminTo}
+		outC <- analysis.CreatePostRelationshipJob{FromID: 1, ToID: 3, Kind: ad.AdminTo}
+		return nil
+	}))
+
+	require.Nil(t, operation.Done())
+
+	expected := []analysis.CreatePostRelationshipJob{
+		{FromID: 1, ToID: 2, Kind: ad.AdminTo},
+		{FromID: 1, ToID: 2, Kind: ad.CanRDP},
+		{FromID: 1, ToID: 3, Kind: ad.AdminTo},
+		{FromID: 2, ToID: 1, Kind: ad.AdminTo},
+		{FromID: 3, ToID: 1, Kind: ad.CanRDP},
+	}
+
+	require.Equal(t, expected, written)
+	require.Equal(t, expected, sink.jobs)
+}
+
+func TestNewPostRelationshipOperation_TTL(t *testing.T) {
+	var (
+		ctrl       = gomock.NewController(t)
+		mockBatch  = graph_mocks.NewMockBatch(ctrl)
+		mockTx     = graph_mocks.NewMockTransaction(ctrl)
+		mockDB     = newMockPostDatabase(ctrl, mockBatch, mockTx)
+		written    = map[graph.ID]*graph.Properties{}
+		submitTime = time.Now().UTC()
+	)
+
+	mockBatch.EXPECT().CreateRelationshipByIDs(gomock.Any(), gomock.Any(), ad.HasSession, gomock.Any()).DoAndReturn(func(startNodeID, endNodeID graph.ID, kind graph.Kind, properties *graph.Properties) error {
+		written[startNodeID] = properties
+		return nil
+	}).Times(2)
+
+	operation := analysis.NewPostRelationshipOperation(context.Background(), mockDB, "test")
+
+	require.Nil(t, operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		outC <- analysis.CreatePostRelationshipJob{FromID: 1, ToID: 3, Kind: ad.HasSession, TTL: time.Hour}
+		outC <- analysis.CreatePostRelationshipJob{FromID: 2, ToID: 3, Kind: ad.HasSession}
+		return nil
+	}))
+
+	require.Nil(t, operation.Done())
+
+	validUntil, err := written[1].Get(analysis.ValidUntilProperty).Time()
+	require.Nil(t, err)
+	require.True(t, validUntil.After(submitTime.Add(59*time.Minute)))
+
+	require.False(t, written[2].Exists(analysis.ValidUntilProperty))
+}
+
+func TestNewPostRelationshipOperation_SourceCollectedAt(t *testing.T) {
+	var (
+		ctrl        = gomock.NewController(t)
+		mockBatch   = graph_mocks.NewMockBatch(ctrl)
+		mockTx      = graph_mocks.NewMockTransaction(ctrl)
+		mockDB      = newMockPostDatabase(ctrl, mockBatch, mockTx)
+		written     = map[graph.ID]*graph.Properties{}
+		collectedAt = time.Date(2024, time.January, 2, 3, 4, 5, 0, time.FixedZone("UTC+2", 2*60*60))
+	)
+
+	mockBatch.EXPECT().CreateRelationshipByIDs(gomock.Any(), gomock.Any(), ad.CanImpersonate, gomock.Any()).DoAndReturn(func(startNodeID, endNodeID graph.ID, kind graph.Kind, properties *graph.Properties) error {
+		written[startNodeID] = properties
+		return nil
+	}).Times(2)
+
+	operation := analysis.NewPostRelationshipOperation(context.Background(), mockDB, "test")
+
+	require.Nil(t, operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		outC <- analysis.CreatePostRelationshipJob{FromID: 1, ToID: 3, Kind: ad.CanImpersonate, SourceCollectedAt: collectedAt}
+		outC <- analysis.CreatePostRelationshipJob{FromID: 2, ToID: 3, Kind: ad.CanImpersonate}
+		return nil
+	}))
+
+	require.Nil(t, operation.Done())
+
+	sourceCollectedAt, err := written[1].Get(analysis.SourceCollectedAtProperty).Time()
+	require.Nil(t, err)
+	require.True(t, collectedAt.Equal(sourceCollectedAt))
+	require.Equal(t, time.UTC, sourceCollectedAt.Location())
+
+	require.False(t, written[2].Exists(analysis.SourceCollectedAtProperty))
+}
+
+func TestNewPostRelationshipOperation_SourceCollector(t *testing.T) {
+	var (
+		ctrl      = gomock.NewController(t)
+		mockBatch = graph_mocks.NewMockBatch(ctrl)
+		mockTx    = graph_mocks.NewMockTransaction(ctrl)
+		mockDB    = newMockPostDatabase(ctrl, mockBatch, mockTx)
+		written   = map[graph.ID]*graph.Properties{}
+	)
+
+	mockBatch.EXPECT().CreateRelationshipByIDs(gomock.Any(), gomock.Any(), ad.AdminTo, gomock.Any()).DoAndReturn(func(startNodeID, endNodeID graph.ID, kind graph.Kind, properties *graph.Properties) error {
+		written[startNodeID] = properties
+		return nil
+	}).Times(2)
+
+	operation := analysis.NewPostRelationshipOperation(context.Background(), mockDB, "test")
+
+	require.Nil(t, operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		outC <- analysis.CreatePostRelationshipJob{FromID: 1, ToID: 3, Kind: ad.AdminTo, SourceCollector: "collector-a"}
+		outC <- analysis.CreatePostRelationshipJob{FromID: 2, ToID: 3, Kind: ad.AdminTo}
+		return nil
+	}))
+
+	require.Nil(t, operation.Done())
+
+	sourceCollector, err := written[1].Get(analysis.SourceCollectorProperty).String()
+	require.Nil(t, err)
+	require.Equal(t, "collector-a", sourceCollector)
+
+	require.False(t, written[2].Exists(analysis.SourceCollectorProperty))
+}
+
+func TestNewPostRelationshipOperationWithOptions_Upsert(t *testing.T) {
+	var (
+		ctrl      = gomock.NewController(t)
+		mockBatch = graph_mocks.NewMockBatch(ctrl)
+		mockTx    = graph_mocks.NewMockTransaction(ctrl)
+		mockQuery = graph_mocks.NewMockRelationshipQuery(ctrl)
+		mockDB    = newMockPostDatabase(ctrl, mockBatch, mockTx)
+		existing  = graph.NewRelationship(10, 1, 2, graph.NewProperties().Set("collected", true), ad.AdminTo)
+	)
+
+	mockBatch.EXPECT().Relationships().Return(mockQuery).AnyTimes()
+	mockQuery.EXPECT().Filterf(gomock.Any()).Return(mockQuery).AnyTimes()
+
+	// The first job finds a relationship left behind by an earlier run while the second does not
+	gomock.InOrder(
+		mockQuery.EXPECT().First().Return(existing, nil),
+		mockQuery.EXPECT().First().Return(nil, graph.ErrNoResultsFound),
+	)
+
+	mockQuery.EXPECT().Update(gomock.Any()).DoAndReturn(func(properties *graph.Properties) error {
+		require.True(t, properties.Exists("collected"))
+		require.True(t, properties.Exists(analysis.AnalysisVersionProperty))
+		return nil
+	}).Times(1)
+
+	mockBatch.EXPECT().CreateRelationshipByIDs(graph.ID(3), graph.ID(4), ad.AdminTo, gomock.Any()).Return(nil).Times(1)
+
+	operation := analysis.NewPostRelationshipOperationWithOptions(context.Background(), mockDB, "test", analysis.PostRelationshipOperationOptions{
+		Upsert: true,
+	})
+
+	require.Nil(t, operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		outC <- analysis.CreatePostRelationshipJob{FromID: 1, ToID: 2, Kind: ad.AdminTo}
+		outC <- analysis.CreatePostRelationshipJob{FromID: 3, ToID: 4, Kind: ad.AdminTo}
+		return nil
+	}))
+
+	require.Nil(t, operation.Done())
+	require.Equal(t, int32(1), *operation.Stats.RelationshipsCreated[ad.AdminTo])
+}
+
+func TestNewReplicatedPostRelationshipOperation(t *testing.T) {
+	var (
+		ctrl      = gomock.NewController(t)
+		mockBatch = graph_mocks.NewMockBatch(ctrl)
+		mockTx    = graph_mocks.NewMockTransaction(ctrl)
+		readDB    = graph_mocks.NewMockDatabase(ctrl)
+		writeDB   = graph_mocks.NewMockDatabase(ctrl)
+	)
+
+	// Readers must only open transactions against the replica and writers must only batch against the primary
+	readDB.EXPECT().ReadTransaction(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, logic func(tx graph.Transaction) error, options ...graph.TransactionOption) error {
+		return logic(mockTx)
+	}).MinTimes(1)
+
+	writeDB.EXPECT().BatchOperation(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, logic func(batch graph.Batch) error) error {
+		return logic(mockBatch)
+	}).Times(1)
+
+	mockBatch.EXPECT().CreateRelationshipByIDs(gomock.Any(), gomock.Any(), ad.AdminTo, gomock.Any()).Return(nil).Times(3)
+
+	operation := analysis.NewReplicatedPostRelationshipOperation(context.Background(), readDB, writeDB, "test")
+	submitAdminToJobs(t, operation, 3)
+
+	require.Nil(t, operation.Done())
+	require.Equal(t, int32(3), *operation.Stats.RelationshipsCreated[ad.AdminTo])
+}
+
+func TestNewPostRelationshipOperationWithOptions_JobTransform(t *testing.T) {
+	var (
+		ctrl      = gomock.NewController(t)
+		mockBatch = graph_mocks.NewMockBatch(ctrl)
+		mockTx    = graph_mocks.NewMockTransaction(ctrl)
+		mockDB    = newMockPostDatabase(ctrl, mockBatch, mockTx)
+		sink      = &recordingEdgeSink{}
+		written   = map[graph.ID]*graph.Properties{}
+	)
+
+	mockBatch.EXPECT().CreateRelationshipByIDs(gomock.Any(), gomock.Any(), ad.AdminTo, gomock.Any()).DoAndReturn(func(startNodeID, endNodeID graph.ID, kind graph.Kind, properties *graph.Properties) error {
+		written[startNodeID] = properties
+		return nil
+	}).Times(2)
+
+	operation := analysis.NewPostRelationshipOperationWithOptions(context.Background(), mockDB, "test", analysis.PostRelationshipOperationOptions{
+		Sink: sink,
+		JobTransform: func(job analysis.CreatePostRelationshipJob) analysis.CreatePostRelationshipJob {
+			// Drop the job from the first node and tag everything else
+			if job.FromID == 0 {
+				return analysis.CreatePostRelationshipJob{}
+			}
+
+			job.RelProperties = map[string]any{"customer": "acme"}
+			return job
+		},
+	})
+
+	submitAdminToJobs(t, operation, 3)
+
+	require.Nil(t, operation.Done())
+	require.Equal(t, int32(2), *operation.Stats.RelationshipsCreated[ad.AdminTo])
+	require.NotContains(t, written, graph.ID(0))
+
+	for _, properties := range written {
+		customer, err := properties.Get("customer").String()
+		require.Nil(t, err)
+		require.Equal(t, "acme", customer)
+	}
+
+	// The sink is sent the transformed jobs
+	require.Equal(t, 2, len(sink.jobs))
+
+	for _, job := range sink.jobs {
+		require.Equal(t, "acme", job.RelProperties["customer"])
+	}
+}
+
+func TestStatTrackedOperation_SubmitReaderRecoversPanic(t *testing.T) {
+	var (
+		ctrl      = gomock.NewController(t)
+		mockBatch = graph_mocks.NewMockBatch(ctrl)
+		mockTx    = graph_mocks.NewMockTransaction(ctrl)
+		mockDB    = newMockPostDatabase(ctrl, mockBatch, mockTx)
+	)
+
+	mockBatch.EXPECT().CreateRelationshipByIDs(gomock.Any(), gomock.Any(), ad.AdminTo, gomock.Any()).Return(nil).Times(20)
+
+	operation := analysis.NewPostRelationshipOperation(context.Background(), mockDB, "test")
+
+	submitAdminToJobs(t, operation, 10)
+
+	require.Nil(t, operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		// Dereferencing a missing node panics in the same way a reader hitting an unexpected nil would
+		var node *graph.Node
+		_, err := node.Properties.Get(common.Name.String()).String()
+		return err
+	}))
+
+	// Readers submitted after the panic still have their jobs written. Their pairs differ from the first reader's as
+	// repeated pairs are only written once.
+	require.Nil(t, operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		for idx := 10; idx < 20; idx++ {
+			if !channels.Submit(ctx, outC, analysis.CreatePostRelationshipJob{
+				FromID: graph.ID(idx),
+				ToID:   graph.ID(idx + 1),
+				Kind:   ad.AdminTo,
+			}) {
+				return nil
+			}
+		}
+
+		return nil
+	}))
+
+	require.Nil(t, operation.Done())
+	require.Equal(t, int32(20), *operation.Stats.RelationshipsCreated[ad.AdminTo])
+
+	readerErrors := operation.Stats.ReaderErrors()
+	require.Len(t, readerErrors, 1)
+	require.ErrorIs(t, readerErrors[0], analysis.ErrReaderPanic)
+
+	// Reader errors survive aggregation
+	aggregate := analysis.NewAtomicPostProcessingStats()
+	aggregate.Merge(&operation.Stats)
+	require.Len(t, aggregate.ReaderErrors(), 1)
+}
+
+func TestNewPostRelationshipOperationWithOptions_ReaderRetry(t *testing.T) {
+	var (
+		ctrl           = gomock.NewController(t)
+		mockBatch      = graph_mocks.NewMockBatch(ctrl)
+		mockTx         = graph_mocks.NewMockTransaction(ctrl)
+		mockDB         = newMockPostDatabase(ctrl, mockBatch, mockTx)
+		errContention  = errors.New("deadlock detected")
+		errMalformed   = errors.New("malformed query")
+		flakyAttempts  = 0
+		brokenAttempts = 0
+	)
+
+	// Only the jobs of the successful attempt are written
+	mockBatch.EXPECT().CreateRelationshipByIDs(gomock.Any(), gomock.Any(), ad.AdminTo, gomock.Any()).Return(nil).Times(2)
+
+	operation := analysis.NewPostRelationshipOperationWithOptions(context.Background(), mockDB, "test", analysis.PostRelationshipOperationOptions{
+		ReaderRetry: &analysis.ReaderRetryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Millisecond,
+			Retryable: func(err error) bool {
+				return errors.Is(err, errContention)
+			},
+		},
+	})
+
+	require.Nil(t, operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		flakyAttempts++
+
+		for idx := 0; idx < 2; idx++ {
+			if !channels.Submit(ctx, outC, analysis.CreatePostRelationshipJob{FromID: graph.ID(idx), ToID: 10, Kind: ad.AdminTo}) {
+				return nil
+			}
+		}
+
+		if flakyAttempts < 3 {
+			return errContention
+		}
+
+		return nil
+	}))
+
+	require.Nil(t, operation.Done())
+	require.Equal(t, 3, flakyAttempts)
+	require.Equal(t, int32(2), *operation.Stats.RelationshipsCreated[ad.AdminTo])
+
+	// Errors rejected by the predicate fail the operation without being retried
+	operation = analysis.NewPostRelationshipOperationWithOptions(context.Background(), mockDB, "test", analysis.PostRelationshipOperationOptions{
+		ReaderRetry: &analysis.ReaderRetryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Millisecond,
+			Retryable: func(err error) bool {
+				return errors.Is(err, errContention)
+			},
+		},
+	})
+
+	require.Nil(t, operation.SubmitReader(func(ctx context.Context, tx graph.Transaction, outC chan<- analysis.CreatePostRelationshipJob) error {
+		brokenAttempts++
+		return errMalformed
+	}))
+
+	require.ErrorIs(t, operation.Done(), errMalformed)
+	require.Equal(t, 1, brokenAttempts)
+}
@@ -0,0 +1,70 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package analysis_test
+
+import (
+	"testing"
+
+	"github.com/RoaringBitmap/roaring/roaring64"
+	"github.com/specterops/bloodhound/analysis"
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeEdgePair(t *testing.T) {
+	pair := analysis.EncodeEdgePair(graph.ID(7), graph.ID(0xFFFFFFFF))
+
+	fromID, toID := analysis.DecodeEdgePair(pair)
+	require.Equal(t, graph.ID(7), fromID)
+	require.Equal(t, graph.ID(0xFFFFFFFF), toID)
+
+	// Direction matters
+	require.NotEqual(t, pair, analysis.EncodeEdgePair(graph.ID(0xFFFFFFFF), graph.ID(7)))
+}
+
+func TestComputeStaleEdges(t *testing.T) {
+	var (
+		kept    = analysis.EncodeEdgePair(1, 2)
+		stale   = analysis.EncodeEdgePair(1, 3)
+		created = analysis.EncodeEdgePair(4, 2)
+
+		current  = roaring64.BitmapOf(kept, created)
+		existing = roaring64.BitmapOf(kept, stale)
+	)
+
+	require.Equal(t, []uint64{stale}, analysis.ComputeStaleEdges(current, existing).ToArray())
+
+	// The inputs are left untouched
+	require.Equal(t, uint64(2), current.GetCardinality())
+	require.Equal(t, uint64(2), existing.GetCardinality())
+
+	require.True(t, analysis.ComputeStaleEdges(existing, existing).IsEmpty())
+}
+
+func TestCollectorAttribution_Source(t *testing.T) {
+	collectors := analysis.CollectorAttribution{
+		1: "collector-a",
+		2: "collector-a",
+		3: "collector-b",
+	}
+
+	require.Equal(t, "collector-a", collectors.Source(1, 2))
+	require.Equal(t, "collector-a", collectors.Source(1, 4))
+	require.Equal(t, analysis.SourceCollectorMultiple, collectors.Source(1, 3))
+	require.Equal(t, "", collectors.Source(4, 5))
+	require.Equal(t, "", analysis.CollectorAttribution{}.Source(1, 2))
+}
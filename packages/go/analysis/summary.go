@@ -0,0 +1,85 @@
+// Copyright 2023 Specter Ops, Inc.
+// 
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// 
+//     http://www.apache.org/licenses/LICENSE-2.0
+// 
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// 
+// SPDX-License-Identifier: Apache-2.0
+
+package analysis
+
+import (
+	"context"
+	"time"
+
+	"github.com/specterops/bloodhound/dawgs/graph"
+	"github.com/specterops/bloodhound/dawgs/query"
+	"github.com/specterops/bloodhound/graphschema/common"
+)
+
+// Properties of the singleton common.PostProcessingRunSummary node WriteRunSummary records the outcome of the latest
+// post-processing run on. Per-kind counts are stored under RunSummaryCreatedPrefix and RunSummaryDeletedPrefix followed
+// by the kind name.
+const (
+	RunSummaryCompletedAtProperty          = "completedat"
+	RunSummaryDurationProperty             = "durationms"
+	RunSummaryRelationshipsCreatedProperty = "relationshipscreated"
+	RunSummaryRelationshipsDeletedProperty = "relationshipsdeleted"
+	RunSummaryReaderErrorsProperty         = "readererrors"
+	RunSummaryCreatedPrefix                = "created_"
+	RunSummaryDeletedPrefix                = "deleted_"
+)
+
+// WriteRunSummary replaces the common.PostProcessingRunSummary node with one holding the totals and per-kind counts of
+// the given stats, the run's duration in milliseconds, its completion time and the analysis version. Kinds without
+// created or deleted relationships are left out. The previous summary is removed in the same transaction, so readers
+// always find exactly one summary once a run has completed. Callers should only write the summary of a run that
+// completed without error; the datapipe keeps the previous summary when post-processing fails.
+func WriteRunSummary(ctx context.Context, db graph.Database, stats *AtomicPostProcessingStats, duration time.Duration) error {
+	var (
+		created, deleted = stats.snapshot()
+		properties       = graph.NewProperties()
+		totalCreated     int64
+		totalDeleted     int64
+	)
+
+	for kind, numCreated := range created {
+		if numCreated > 0 {
+			properties.Set(RunSummaryCreatedPrefix+kind.String(), int64(numCreated))
+			totalCreated += int64(numCreated)
+		}
+	}
+
+	for kind, numDeleted := range deleted {
+		if numDeleted > 0 {
+			properties.Set(RunSummaryDeletedPrefix+kind.String(), int64(numDeleted))
+			totalDeleted += int64(numDeleted)
+		}
+	}
+
+	properties.Set(RunSummaryRelationshipsCreatedProperty, totalCreated)
+	properties.Set(RunSummaryRelationshipsDeletedProperty, totalDeleted)
+	properties.Set(RunSummaryReaderErrorsProperty, int64(len(stats.ReaderErrors())))
+	properties.Set(RunSummaryDurationProperty, duration.Milliseconds())
+	properties.Set(RunSummaryCompletedAtProperty, time.Now().UTC())
+	properties.Set(AnalysisVersionProperty, AnalysisVersion)
+
+	return db.WriteTransaction(ctx, func(tx graph.Transaction) error {
+		if err := tx.Nodes().Filterf(func() graph.Criteria {
+			return query.Kind(query.Node(), common.PostProcessingRunSummary)
+		}).Delete(); err != nil {
+			return err
+		}
+
+		_, err := tx.CreateNode(properties, common.PostProcessingRunSummary)
+		return err
+	})
+}
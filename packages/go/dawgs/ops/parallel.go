@@ -121,8 +121,13 @@ func (s *writer[T]) Start(waitGroup *sync.WaitGroup, db graph.Database) {
 }
 
 type OperationContext struct {
-	Parent            context.Context
-	DB                graph.Database
+	Parent context.Context
+	DB     graph.Database
+
+	// ReadDB, when set, is used by readers in place of DB. This allows reads to be served by a replica while writes go
+	// to DB.
+	ReadDB graph.Database
+
 	NumReaders        int
 	NumWriters        int
 	ReaderJobCapacity int
@@ -137,6 +142,14 @@ func (s OperationContext) GetParent() context.Context {
 	return s.Parent
 }
 
+func (s OperationContext) GetReadDB() graph.Database {
+	if s.ReadDB == nil {
+		return s.DB
+	}
+
+	return s.ReadDB
+}
+
 func (s OperationContext) GetReaderJobCapacity() int {
 	if s.ReaderJobCapacity == 0 {
 		return s.NumReaders * 2
@@ -202,7 +215,7 @@ func (s *Operation[T]) Start() {
 		}
 
 		s.readers = append(s.readers, reader)
-		reader.Start(s.readerWG, s.opCtx.DB)
+		reader.Start(s.readerWG, s.opCtx.GetReadDB())
 	}
 
 	for i := 0; i < s.opCtx.NumWriters; i++ {
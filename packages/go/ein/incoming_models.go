@@ -29,6 +29,12 @@ const (
 	TrustDirectionBidirectional     = "Bidirectional"
 	IgnoredName                     = "IGNOREME"
 	UserRightRemoteInteractiveLogon = "SeRemoteInteractiveLogonRight"
+	UserRightDenyInteractiveLogon   = "SeDenyInteractiveLogonRight"
+	UserRightDenyNetworkLogon       = "SeDenyNetworkLogonRight"
+	UserRightDenyRemoteInteractive  = "SeDenyRemoteInteractiveLogonRight"
+	UserRightDebug                  = "SeDebugPrivilege"
+	UserRightTakeOwnership          = "SeTakeOwnershipPrivilege"
+	UserRightImpersonate            = "SeImpersonatePrivilege"
 )
 
 func parseADKind(rawKindStr string) graph.Kind {
@@ -25,53 +25,74 @@ import (
 )
 
 var (
-	Entity                          = graph.StringKind("Base")
-	User                            = graph.StringKind("User")
-	Computer                        = graph.StringKind("Computer")
-	Group                           = graph.StringKind("Group")
-	GPO                             = graph.StringKind("GPO")
-	OU                              = graph.StringKind("OU")
-	Container                       = graph.StringKind("Container")
-	Domain                          = graph.StringKind("Domain")
-	LocalGroup                      = graph.StringKind("ADLocalGroup")
-	LocalUser                       = graph.StringKind("ADLocalUser")
-	Owns                            = graph.StringKind("Owns")
-	GenericAll                      = graph.StringKind("GenericAll")
-	GenericWrite                    = graph.StringKind("GenericWrite")
-	WriteOwner                      = graph.StringKind("WriteOwner")
-	WriteDACL                       = graph.StringKind("WriteDacl")
-	MemberOf                        = graph.StringKind("MemberOf")
-	ForceChangePassword             = graph.StringKind("ForceChangePassword")
-	AllExtendedRights               = graph.StringKind("AllExtendedRights")
-	AddMember                       = graph.StringKind("AddMember")
-	HasSession                      = graph.StringKind("HasSession")
-	Contains                        = graph.StringKind("Contains")
-	GPLink                          = graph.StringKind("GPLink")
-	AllowedToDelegate               = graph.StringKind("AllowedToDelegate")
-	GetChanges                      = graph.StringKind("GetChanges")
-	GetChangesAll                   = graph.StringKind("GetChangesAll")
-	GetChangesInFilteredSet         = graph.StringKind("GetChangesInFilteredSet")
-	TrustedBy                       = graph.StringKind("TrustedBy")
-	AllowedToAct                    = graph.StringKind("AllowedToAct")
-	AdminTo                         = graph.StringKind("AdminTo")
-	CanPSRemote                     = graph.StringKind("CanPSRemote")
-	CanRDP                          = graph.StringKind("CanRDP")
-	ExecuteDCOM                     = graph.StringKind("ExecuteDCOM")
-	HasSIDHistory                   = graph.StringKind("HasSIDHistory")
-	AddSelf                         = graph.StringKind("AddSelf")
-	DCSync                          = graph.StringKind("DCSync")
-	ReadLAPSPassword                = graph.StringKind("ReadLAPSPassword")
-	ReadGMSAPassword                = graph.StringKind("ReadGMSAPassword")
-	DumpSMSAPassword                = graph.StringKind("DumpSMSAPassword")
-	SQLAdmin                        = graph.StringKind("SQLAdmin")
-	AddAllowedToAct                 = graph.StringKind("AddAllowedToAct")
-	WriteSPN                        = graph.StringKind("WriteSPN")
-	AddKeyCredentialLink            = graph.StringKind("AddKeyCredentialLink")
-	LocalToComputer                 = graph.StringKind("LocalToComputer")
-	MemberOfLocalGroup              = graph.StringKind("MemberOfLocalGroup")
-	RemoteInteractiveLogonPrivilege = graph.StringKind("RemoteInteractiveLogonPrivilege")
-	SyncLAPSPassword                = graph.StringKind("SyncLAPSPassword")
-	WriteAccountRestrictions        = graph.StringKind("WriteAccountRestrictions")
+	Entity                              = graph.StringKind("Base")
+	User                                = graph.StringKind("User")
+	Computer                            = graph.StringKind("Computer")
+	Group                               = graph.StringKind("Group")
+	GPO                                 = graph.StringKind("GPO")
+	OU                                  = graph.StringKind("OU")
+	Container                           = graph.StringKind("Container")
+	Domain                              = graph.StringKind("Domain")
+	LocalGroup                          = graph.StringKind("ADLocalGroup")
+	LocalUser                           = graph.StringKind("ADLocalUser")
+	Owns                                = graph.StringKind("Owns")
+	GenericAll                          = graph.StringKind("GenericAll")
+	GenericWrite                        = graph.StringKind("GenericWrite")
+	WriteOwner                          = graph.StringKind("WriteOwner")
+	WriteDACL                           = graph.StringKind("WriteDacl")
+	MemberOf                            = graph.StringKind("MemberOf")
+	ForceChangePassword                 = graph.StringKind("ForceChangePassword")
+	AllExtendedRights                   = graph.StringKind("AllExtendedRights")
+	AddMember                           = graph.StringKind("AddMember")
+	HasSession                          = graph.StringKind("HasSession")
+	Contains                            = graph.StringKind("Contains")
+	GPLink                              = graph.StringKind("GPLink")
+	AllowedToDelegate                   = graph.StringKind("AllowedToDelegate")
+	GetChanges                          = graph.StringKind("GetChanges")
+	GetChangesAll                       = graph.StringKind("GetChangesAll")
+	GetChangesInFilteredSet             = graph.StringKind("GetChangesInFilteredSet")
+	TrustedBy                           = graph.StringKind("TrustedBy")
+	AllowedToAct                        = graph.StringKind("AllowedToAct")
+	AdminTo                             = graph.StringKind("AdminTo")
+	CanPSRemote                         = graph.StringKind("CanPSRemote")
+	CanRDP                              = graph.StringKind("CanRDP")
+	ExecuteDCOM                         = graph.StringKind("ExecuteDCOM")
+	HasSIDHistory                       = graph.StringKind("HasSIDHistory")
+	AddSelf                             = graph.StringKind("AddSelf")
+	DCSync                              = graph.StringKind("DCSync")
+	ReadLAPSPassword                    = graph.StringKind("ReadLAPSPassword")
+	ReadGMSAPassword                    = graph.StringKind("ReadGMSAPassword")
+	DumpSMSAPassword                    = graph.StringKind("DumpSMSAPassword")
+	SQLAdmin                            = graph.StringKind("SQLAdmin")
+	AddAllowedToAct                     = graph.StringKind("AddAllowedToAct")
+	WriteSPN                            = graph.StringKind("WriteSPN")
+	AddKeyCredentialLink                = graph.StringKind("AddKeyCredentialLink")
+	LocalToComputer                     = graph.StringKind("LocalToComputer")
+	MemberOfLocalGroup                  = graph.StringKind("MemberOfLocalGroup")
+	RemoteInteractiveLogonPrivilege     = graph.StringKind("RemoteInteractiveLogonPrivilege")
+	SyncLAPSPassword                    = graph.StringKind("SyncLAPSPassword")
+	WriteAccountRestrictions            = graph.StringKind("WriteAccountRestrictions")
+	SameForestTrust                     = graph.StringKind("SameForestTrust")
+	CrossForestTrust                    = graph.StringKind("CrossForestTrust")
+	ImpliedGenericAll                   = graph.StringKind("ImpliedGenericAll")
+	DenyInteractiveLogonPrivilege       = graph.StringKind("DenyInteractiveLogonPrivilege")
+	DenyNetworkLogonPrivilege           = graph.StringKind("DenyNetworkLogonPrivilege")
+	SyncedToEntraUser                   = graph.StringKind("SyncedToEntraUser")
+	WriteGPLink                         = graph.StringKind("WriteGPLink")
+	CanApplyGPO                         = graph.StringKind("CanApplyGPO")
+	DenyRemoteInteractiveLogonPrivilege = graph.StringKind("DenyRemoteInteractiveLogonPrivilege")
+	HasTrustKeys                        = graph.StringKind("HasTrustKeys")
+	CanTakeOver                         = graph.StringKind("CanTakeOver")
+	CanImpersonate                      = graph.StringKind("CanImpersonate")
+	SharedAdminLateral                  = graph.StringKind("SharedAdminLateral")
+	RDPSessionCapture                   = graph.StringKind("RDPSessionCapture")
+	SyncedToEntraRole                   = graph.StringKind("SyncedToEntraRole")
+	DebugPrivilege                      = graph.StringKind("DebugPrivilege")
+	TakeOwnershipPrivilege              = graph.StringKind("TakeOwnershipPrivilege")
+	ImpersonatePrivilege                = graph.StringKind("ImpersonatePrivilege")
+	CanDCSyncViaReset                   = graph.StringKind("CanDCSyncViaReset")
+	AdminToViaGMSA                      = graph.StringKind("AdminToViaGMSA")
+	AccountOperatorControl              = graph.StringKind("AccountOperatorControl")
 )
 
 type Property string
@@ -301,13 +322,13 @@ func Nodes() []graph.Kind {
 	return []graph.Kind{Entity, User, Computer, Group, GPO, OU, Container, Domain, LocalGroup, LocalUser}
 }
 func Relationships() []graph.Kind {
-	return []graph.Kind{Owns, GenericAll, GenericWrite, WriteOwner, WriteDACL, MemberOf, ForceChangePassword, AllExtendedRights, AddMember, HasSession, Contains, GPLink, AllowedToDelegate, GetChanges, GetChangesAll, GetChangesInFilteredSet, TrustedBy, AllowedToAct, AdminTo, CanPSRemote, CanRDP, ExecuteDCOM, HasSIDHistory, AddSelf, DCSync, ReadLAPSPassword, ReadGMSAPassword, DumpSMSAPassword, SQLAdmin, AddAllowedToAct, WriteSPN, AddKeyCredentialLink, LocalToComputer, MemberOfLocalGroup, RemoteInteractiveLogonPrivilege, SyncLAPSPassword, WriteAccountRestrictions}
+	return []graph.Kind{Owns, GenericAll, GenericWrite, WriteOwner, WriteDACL, MemberOf, ForceChangePassword, AllExtendedRights, AddMember, HasSession, Contains, GPLink, AllowedToDelegate, GetChanges, GetChangesAll, GetChangesInFilteredSet, TrustedBy, AllowedToAct, AdminTo, CanPSRemote, CanRDP, ExecuteDCOM, HasSIDHistory, AddSelf, DCSync, ReadLAPSPassword, ReadGMSAPassword, DumpSMSAPassword, SQLAdmin, AddAllowedToAct, WriteSPN, AddKeyCredentialLink, LocalToComputer, MemberOfLocalGroup, RemoteInteractiveLogonPrivilege, SyncLAPSPassword, WriteAccountRestrictions, SameForestTrust, CrossForestTrust, ImpliedGenericAll, DenyInteractiveLogonPrivilege, DenyNetworkLogonPrivilege, SyncedToEntraUser, WriteGPLink, CanApplyGPO, DenyRemoteInteractiveLogonPrivilege, HasTrustKeys, CanTakeOver, CanImpersonate, SharedAdminLateral, RDPSessionCapture, SyncedToEntraRole, DebugPrivilege, TakeOwnershipPrivilege, ImpersonatePrivilege, CanDCSyncViaReset, AdminToViaGMSA, AccountOperatorControl}
 }
 func ACLRelationships() []graph.Kind {
-	return []graph.Kind{AllExtendedRights, ForceChangePassword, AddMember, AddAllowedToAct, GenericAll, WriteDACL, WriteOwner, GenericWrite, ReadLAPSPassword, ReadGMSAPassword, Owns, AddSelf, WriteSPN, AddKeyCredentialLink, GetChanges, GetChangesAll, GetChangesInFilteredSet, WriteAccountRestrictions, SyncLAPSPassword, DCSync}
+	return []graph.Kind{AllExtendedRights, ForceChangePassword, AddMember, AddAllowedToAct, GenericAll, WriteDACL, WriteOwner, GenericWrite, ReadLAPSPassword, ReadGMSAPassword, Owns, AddSelf, WriteSPN, AddKeyCredentialLink, GetChanges, GetChangesAll, GetChangesInFilteredSet, WriteAccountRestrictions, SyncLAPSPassword, DCSync, WriteGPLink}
 }
 func PathfindingRelationships() []graph.Kind {
-	return []graph.Kind{Owns, GenericAll, GenericWrite, WriteOwner, WriteDACL, MemberOf, ForceChangePassword, AllExtendedRights, AddMember, HasSession, Contains, GPLink, AllowedToDelegate, TrustedBy, AllowedToAct, AdminTo, CanPSRemote, CanRDP, ExecuteDCOM, HasSIDHistory, AddSelf, DCSync, ReadLAPSPassword, ReadGMSAPassword, DumpSMSAPassword, SQLAdmin, AddAllowedToAct, WriteSPN, AddKeyCredentialLink, SyncLAPSPassword, WriteAccountRestrictions}
+	return []graph.Kind{Owns, GenericAll, GenericWrite, WriteOwner, WriteDACL, MemberOf, ForceChangePassword, AllExtendedRights, AddMember, HasSession, Contains, GPLink, AllowedToDelegate, TrustedBy, AllowedToAct, AdminTo, CanPSRemote, CanRDP, ExecuteDCOM, HasSIDHistory, AddSelf, DCSync, ReadLAPSPassword, ReadGMSAPassword, DumpSMSAPassword, SQLAdmin, AddAllowedToAct, WriteSPN, AddKeyCredentialLink, SyncLAPSPassword, WriteAccountRestrictions, SameForestTrust, CrossForestTrust, ImpliedGenericAll, SyncedToEntraUser, WriteGPLink, CanApplyGPO, HasTrustKeys, CanTakeOver, CanImpersonate, SharedAdminLateral, RDPSessionCapture, SyncedToEntraRole, CanDCSyncViaReset, AdminToViaGMSA, AccountOperatorControl}
 }
 func IsACLKind(s graph.Kind) bool {
 	for _, acl := range ACLRelationships() {
@@ -25,7 +25,9 @@ import (
 )
 
 var (
-	MigrationData = graph.StringKind("MigrationData")
+	MigrationData            = graph.StringKind("MigrationData")
+	PostProcessingRunSummary = graph.StringKind("PostProcessingRunSummary")
+	PostProcessingConfig     = graph.StringKind("PostProcessingConfig")
 )
 
 type Property string
@@ -175,11 +177,11 @@ func (s Property) Is(others ...graph.Kind) bool {
 	return false
 }
 func Nodes() []graph.Kind {
-	return []graph.Kind{MigrationData}
+	return []graph.Kind{MigrationData, PostProcessingRunSummary, PostProcessingConfig}
 }
 func Relationships() []graph.Kind {
 	return []graph.Kind{}
 }
 func NodeKinds() []graph.Kind {
-	return []graph.Kind{MigrationData}
+	return []graph.Kind{MigrationData, PostProcessingRunSummary, PostProcessingConfig}
 }
@@ -90,6 +90,27 @@ export enum ActiveDirectoryRelationshipKind {
     RemoteInteractiveLogonPrivilege = 'RemoteInteractiveLogonPrivilege',
     SyncLAPSPassword = 'SyncLAPSPassword',
     WriteAccountRestrictions = 'WriteAccountRestrictions',
+    SameForestTrust = 'SameForestTrust',
+    CrossForestTrust = 'CrossForestTrust',
+    ImpliedGenericAll = 'ImpliedGenericAll',
+    DenyInteractiveLogonPrivilege = 'DenyInteractiveLogonPrivilege',
+    DenyNetworkLogonPrivilege = 'DenyNetworkLogonPrivilege',
+    SyncedToEntraUser = 'SyncedToEntraUser',
+    WriteGPLink = 'WriteGPLink',
+    CanApplyGPO = 'CanApplyGPO',
+    DenyRemoteInteractiveLogonPrivilege = 'DenyRemoteInteractiveLogonPrivilege',
+    HasTrustKeys = 'HasTrustKeys',
+    CanTakeOver = 'CanTakeOver',
+    CanImpersonate = 'CanImpersonate',
+    SharedAdminLateral = 'SharedAdminLateral',
+    RDPSessionCapture = 'RDPSessionCapture',
+    SyncedToEntraRole = 'SyncedToEntraRole',
+    DebugPrivilege = 'DebugPrivilege',
+    TakeOwnershipPrivilege = 'TakeOwnershipPrivilege',
+    ImpersonatePrivilege = 'ImpersonatePrivilege',
+    CanDCSyncViaReset = 'CanDCSyncViaReset',
+    AdminToViaGMSA = 'AdminToViaGMSA',
+    AccountOperatorControl = 'AccountOperatorControl',
 }
 export function ActiveDirectoryRelationshipKindToDisplay(value: ActiveDirectoryRelationshipKind): string | undefined {
     switch (value) {
@@ -167,6 +188,48 @@ export function ActiveDirectoryRelationshipKindToDisplay(value: ActiveDirectoryR
             return 'SyncLAPSPassword';
         case ActiveDirectoryRelationshipKind.WriteAccountRestrictions:
             return 'WriteAccountRestrictions';
+        case ActiveDirectoryRelationshipKind.SameForestTrust:
+            return 'SameForestTrust';
+        case ActiveDirectoryRelationshipKind.CrossForestTrust:
+            return 'CrossForestTrust';
+        case ActiveDirectoryRelationshipKind.ImpliedGenericAll:
+            return 'ImpliedGenericAll';
+        case ActiveDirectoryRelationshipKind.DenyInteractiveLogonPrivilege:
+            return 'DenyInteractiveLogonPrivilege';
+        case ActiveDirectoryRelationshipKind.DenyNetworkLogonPrivilege:
+            return 'DenyNetworkLogonPrivilege';
+        case ActiveDirectoryRelationshipKind.SyncedToEntraUser:
+            return 'SyncedToEntraUser';
+        case ActiveDirectoryRelationshipKind.WriteGPLink:
+            return 'WriteGPLink';
+        case ActiveDirectoryRelationshipKind.CanApplyGPO:
+            return 'CanApplyGPO';
+        case ActiveDirectoryRelationshipKind.DenyRemoteInteractiveLogonPrivilege:
+            return 'DenyRemoteInteractiveLogonPrivilege';
+        case ActiveDirectoryRelationshipKind.HasTrustKeys:
+            return 'HasTrustKeys';
+        case ActiveDirectoryRelationshipKind.CanTakeOver:
+            return 'CanTakeOver';
+        case ActiveDirectoryRelationshipKind.CanImpersonate:
+            return 'CanImpersonate';
+        case ActiveDirectoryRelationshipKind.SharedAdminLateral:
+            return 'SharedAdminLateral';
+        case ActiveDirectoryRelationshipKind.RDPSessionCapture:
+            return 'RDPSessionCapture';
+        case ActiveDirectoryRelationshipKind.SyncedToEntraRole:
+            return 'SyncedToEntraRole';
+        case ActiveDirectoryRelationshipKind.DebugPrivilege:
+            return 'DebugPrivilege';
+        case ActiveDirectoryRelationshipKind.TakeOwnershipPrivilege:
+            return 'TakeOwnershipPrivilege';
+        case ActiveDirectoryRelationshipKind.ImpersonatePrivilege:
+            return 'ImpersonatePrivilege';
+        case ActiveDirectoryRelationshipKind.CanDCSyncViaReset:
+            return 'CanDCSyncViaReset';
+        case ActiveDirectoryRelationshipKind.AdminToViaGMSA:
+            return 'AdminToViaGMSA';
+        case ActiveDirectoryRelationshipKind.AccountOperatorControl:
+            return 'AccountOperatorControl';
         default:
             return undefined;
     }
@@ -294,6 +357,21 @@ export function ActiveDirectoryPathfindingEdges(): ActiveDirectoryRelationshipKi
         ActiveDirectoryRelationshipKind.AddKeyCredentialLink,
         ActiveDirectoryRelationshipKind.SyncLAPSPassword,
         ActiveDirectoryRelationshipKind.WriteAccountRestrictions,
+        ActiveDirectoryRelationshipKind.SameForestTrust,
+        ActiveDirectoryRelationshipKind.CrossForestTrust,
+        ActiveDirectoryRelationshipKind.ImpliedGenericAll,
+        ActiveDirectoryRelationshipKind.SyncedToEntraUser,
+        ActiveDirectoryRelationshipKind.WriteGPLink,
+        ActiveDirectoryRelationshipKind.CanApplyGPO,
+        ActiveDirectoryRelationshipKind.HasTrustKeys,
+        ActiveDirectoryRelationshipKind.CanTakeOver,
+        ActiveDirectoryRelationshipKind.CanImpersonate,
+        ActiveDirectoryRelationshipKind.SharedAdminLateral,
+        ActiveDirectoryRelationshipKind.RDPSessionCapture,
+        ActiveDirectoryRelationshipKind.SyncedToEntraRole,
+        ActiveDirectoryRelationshipKind.CanDCSyncViaReset,
+        ActiveDirectoryRelationshipKind.AdminToViaGMSA,
+        ActiveDirectoryRelationshipKind.AccountOperatorControl,
     ];
 }
 export enum AzureNodeKind {
@@ -656,11 +734,17 @@ export function AzurePathfindingEdges(): AzureRelationshipKind[] {
 }
 export enum CommonNodeKind {
     MigrationData = 'MigrationData',
+    PostProcessingRunSummary = 'PostProcessingRunSummary',
+    PostProcessingConfig = 'PostProcessingConfig',
 }
 export function CommonNodeKindToDisplay(value: CommonNodeKind): string | undefined {
     switch (value) {
         case CommonNodeKind.MigrationData:
             return 'MigrationData';
+        case CommonNodeKind.PostProcessingRunSummary:
+            return 'PostProcessingRunSummary';
+        case CommonNodeKind.PostProcessingConfig:
+            return 'PostProcessingConfig';
         default:
             return undefined;
     }